@@ -0,0 +1,251 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// -----------------------------------------------------------------------------
+// Degraded Condition Reasons
+// -----------------------------------------------------------------------------
+
+// The reasons below are the complete set of reasons the operator sets on the
+// Degraded condition of Engines and RuleSets (and on the Ready condition when
+// marking them Degraded), and of the Warning events emitted alongside. The
+// reasons of the other events the operator emits are defined under Event
+// Reasons below. They are part of the API: existing reasons keep their
+// meaning, though new ones may be added, so consumers should treat an
+// unrecognized reason as a generic failure.
+
+// Engine reasons.
+const (
+	// ReasonInvalidConfiguration means the Engine's driver configuration
+	// isn't supported.
+	ReasonInvalidConfiguration = "InvalidConfiguration"
+
+	// ReasonImageNotPinned means the Engine requires a WASM image pinned by
+	// digest, but its image is referenced by tag.
+	ReasonImageNotPinned = "ImageNotPinned"
+
+	// ReasonGatewayNotFound means no Gateway matches the workload selector of
+	// a gateway mode Engine.
+	ReasonGatewayNotFound = "GatewayNotFound"
+
+	// ReasonSelectorConflict means the Engine's workload selector overlaps
+	// that of an existing Engine.
+	ReasonSelectorConflict = "SelectorConflict"
+
+	// ReasonProvisioningFailed means the Engine's data plane resources could
+	// not be applied.
+	ReasonProvisioningFailed = "ProvisioningFailed"
+
+	// ReasonRuleSetNotReady means a RuleSet the Engine loads rules from isn't
+	// Ready.
+	ReasonRuleSetNotReady = "RuleSetNotReady"
+
+	// ReasonRulesInvalid means one of the Engine's RuleSets failed rule
+	// validation, while the Engine requires valid rules before provisioning.
+	ReasonRulesInvalid = "RulesInvalid"
+)
+
+// RuleSet reasons.
+const (
+	// ReasonConfigMapNotFound means a referenced ConfigMap doesn't exist.
+	ReasonConfigMapNotFound = "ConfigMapNotFound"
+
+	// ReasonSecretNotFound means a referenced Secret doesn't exist.
+	ReasonSecretNotFound = "SecretNotFound"
+
+	// ReasonConfigMapAccessError means a referenced ConfigMap could not be
+	// read.
+	ReasonConfigMapAccessError = "ConfigMapAccessError"
+
+	// ReasonSecretAccessError means a referenced Secret could not be read.
+	ReasonSecretAccessError = "SecretAccessError"
+
+	// ReasonInvalidConfigMap means a referenced ConfigMap has no "rules" key,
+	// or its rules are invalid.
+	ReasonInvalidConfigMap = "InvalidConfigMap"
+
+	// ReasonInvalidSecret means a referenced Secret has no "rules" key, or
+	// its rules are invalid.
+	ReasonInvalidSecret = "InvalidSecret"
+
+	// ReasonInvalidRuleSource means the rules of an Inline source are
+	// invalid.
+	ReasonInvalidRuleSource = "InvalidRuleSource"
+
+	// ReasonInvalidURLSource means the rules fetched for a URL source are
+	// invalid.
+	ReasonInvalidURLSource = "InvalidURLSource"
+
+	// ReasonInvalidRules means a source's rules are invalid independently of
+	// the source, e.g. they define the same rule id twice.
+	ReasonInvalidRules = "InvalidRules"
+
+	// ReasonInvalidAggregatedRules means each source's rules are valid, but
+	// their aggregate is not. Engines with multiple RuleSets are Degraded with
+	// it when the aggregate of their RuleSets' rules is invalid.
+	ReasonInvalidAggregatedRules = "InvalidAggregatedRules"
+
+	// ReasonDuplicateRuleID means several sources, or several RuleSets of an
+	// Engine, define the same rule id.
+	ReasonDuplicateRuleID = "DuplicateRuleID"
+
+	// ReasonInvalidPluginConfig means the configuration of a CRS plugin is
+	// invalid.
+	ReasonInvalidPluginConfig = "InvalidPluginConfig"
+
+	// ReasonRefNotPermitted means a cross-namespace ConfigMap reference isn't
+	// permitted by any ReferenceGrant.
+	ReasonRefNotPermitted = "RefNotPermitted"
+
+	// ReasonURLSourcesDisabled means the RuleSet has URL sources, which the
+	// operator isn't configured to fetch.
+	ReasonURLSourcesDisabled = "URLSourcesDisabled"
+
+	// ReasonURLHostNotAllowed means the RuleSet has a URL source whose host
+	// isn't among the hosts the operator is allowed to fetch rules from.
+	ReasonURLHostNotAllowed = "URLHostNotAllowed"
+
+	// ReasonSecretSourcesDisabled means the RuleSet has Secret sources, which
+	// the operator only accepts if its cache server requires an auth token.
+	ReasonSecretSourcesDisabled = "SecretSourcesDisabled"
+
+	// ReasonFetchFailed means the rules of a URL source could not be fetched.
+	ReasonFetchFailed = "FetchFailed"
+
+	// ReasonRuleSetTooLarge means the RuleSet's aggregated rules exceed the
+	// operator's size limit.
+	ReasonRuleSetTooLarge = "RuleSetTooLarge"
+)
+
+// DegradedReasons returns the complete set of Degraded condition reasons.
+func DegradedReasons() []string {
+	return []string{
+		ReasonInvalidConfiguration,
+		ReasonImageNotPinned,
+		ReasonGatewayNotFound,
+		ReasonSelectorConflict,
+		ReasonProvisioningFailed,
+		ReasonRuleSetNotReady,
+		ReasonRulesInvalid,
+		ReasonConfigMapNotFound,
+		ReasonSecretNotFound,
+		ReasonConfigMapAccessError,
+		ReasonSecretAccessError,
+		ReasonInvalidConfigMap,
+		ReasonInvalidSecret,
+		ReasonInvalidRuleSource,
+		ReasonInvalidURLSource,
+		ReasonInvalidRules,
+		ReasonInvalidAggregatedRules,
+		ReasonDuplicateRuleID,
+		ReasonInvalidPluginConfig,
+		ReasonRefNotPermitted,
+		ReasonURLSourcesDisabled,
+		ReasonURLHostNotAllowed,
+		ReasonSecretSourcesDisabled,
+		ReasonFetchFailed,
+		ReasonRuleSetTooLarge,
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Event Reasons
+// -----------------------------------------------------------------------------
+
+// The reasons below are the complete set of reasons of the events the
+// operator emits without marking the resource Degraded, and are part of the
+// API in the same way as the Degraded condition reasons.
+
+// Warning event reasons.
+const (
+	// EventReasonPinnedVersionNotFound means the rules version pinned by the
+	// Engine isn't cached.
+	EventReasonPinnedVersionNotFound = "PinnedVersionNotFound"
+
+	// EventReasonPinnedVersionOutdated means the rules version pinned by the
+	// Engine is outdated by a newer version.
+	EventReasonPinnedVersionOutdated = "PinnedVersionOutdated"
+
+	// EventReasonNoEffectiveRules means the RuleSets the Engine loads rules
+	// from contain no rules, so that it blocks no requests.
+	EventReasonNoEffectiveRules = "NoEffectiveRules"
+
+	// EventReasonRuleMissingAction means some of the RuleSet's rules have
+	// neither a disruptive nor a flow action, so that they match without
+	// effect.
+	EventReasonRuleMissingAction = "RuleMissingAction"
+
+	// EventReasonCacheOverCapacity means the RuleSet cache still exceeds its
+	// max size after garbage collection. It is emitted on the RuleSet or
+	// Engine whose rules retain the most data.
+	EventReasonCacheOverCapacity = "CacheOverCapacity"
+)
+
+// Normal event reasons.
+const (
+	// EventReasonRulesCached means a new version of the RuleSet's rules was
+	// cached. It is also the reason of the RuleSet's Ready condition.
+	EventReasonRulesCached = "RulesCached"
+
+	// EventReasonRulesUnchanged means the RuleSet's rules are unchanged since
+	// the latest cached version.
+	EventReasonRulesUnchanged = "RulesUnchanged"
+
+	// EventReasonDuplicateRuleIDsReplaced means rules of the RuleSet's
+	// sources were replaced by later sources defining the same rule ids.
+	EventReasonDuplicateRuleIDsReplaced = "DuplicateRuleIDsReplaced"
+
+	// EventReasonRuleValidationWarning means rule validation found
+	// questionable rules, which don't fail validation.
+	EventReasonRuleValidationWarning = "RuleValidationWarning"
+
+	// EventReasonReconciliationPaused means reconciliation of the resource
+	// was paused. It is also the reason of its Paused condition.
+	EventReasonReconciliationPaused = "ReconciliationPaused"
+
+	// EventReasonReconciliationResumed means reconciliation of the resource
+	// was resumed.
+	EventReasonReconciliationResumed = "ReconciliationResumed"
+
+	// EventReasonWasmPluginCreated means the Engine's WasmPlugin was created
+	// or updated.
+	EventReasonWasmPluginCreated = "WasmPluginCreated"
+
+	// EventReasonEnvoyExtensionPolicyCreated means the Engine's
+	// EnvoyExtensionPolicy was created or updated.
+	EventReasonEnvoyExtensionPolicyCreated = "EnvoyExtensionPolicyCreated"
+)
+
+// EventReasons returns the complete set of event reasons other than the
+// Degraded condition reasons.
+func EventReasons() []string {
+	return []string{
+		EventReasonPinnedVersionNotFound,
+		EventReasonPinnedVersionOutdated,
+		EventReasonNoEffectiveRules,
+		EventReasonRuleMissingAction,
+		EventReasonCacheOverCapacity,
+		EventReasonRulesCached,
+		EventReasonRulesUnchanged,
+		EventReasonDuplicateRuleIDsReplaced,
+		EventReasonRuleValidationWarning,
+		EventReasonReconciliationPaused,
+		EventReasonReconciliationResumed,
+		EventReasonWasmPluginCreated,
+		EventReasonEnvoyExtensionPolicyCreated,
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// -----------------------------------------------------------------------------
+// Engine Driver - Envoy Configuration
+// -----------------------------------------------------------------------------
+
+// EnvoyDriverConfig defines Envoy-specific integration mechanisms that will
+// be used to deploy and manage the Engine with Envoy Gateway.
+//
+// Exactly one mode must be specified.
+//
+// +kubebuilder:validation:XValidation:rule="[has(self.extProc)].filter(x, x).size() == 1",message="exactly one integration mechanism (ExtProc, etc) must be specified"
+type EnvoyDriverConfig struct {
+	// ExtProc configures the Engine to be wired into Envoy through the
+	// external processing (ext_proc) filter.
+	//
+	// +optional
+	ExtProc *EnvoyExtProcConfig `json:"extProc,omitempty"`
+}
+
+// -----------------------------------------------------------------------------
+// Engine Driver - Envoy ExtProc Configuration
+// -----------------------------------------------------------------------------
+
+// EnvoyExtProcConfig defines configuration for attaching a Coraza external
+// processing service to an Envoy Gateway.
+//
+// The Coraza ext_proc service is not deployed by the operator. It must load
+// the Engine's RuleSet from the RuleSet cache server itself, using the cache
+// key and cache server address the operator annotates the Engine's
+// EnvoyExtensionPolicy with.
+type EnvoyExtProcConfig struct {
+	// GatewayName is the name of the Gateway, in the same namespace as the
+	// Engine, whose traffic is sent to the ext_proc service.
+	//
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	GatewayName string `json:"gatewayName"`
+
+	// Service is the Coraza ext_proc Service in the same namespace as the
+	// Engine.
+	//
+	// +required
+	Service EnvoyExtProcService `json:"service"`
+}
+
+// EnvoyExtProcService references the Service implementing the ext_proc API.
+type EnvoyExtProcService struct {
+	// Name is the name of the Service.
+	//
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	Name string `json:"name"`
+
+	// Port is the gRPC port of the Service.
+	//
+	// +required
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -30,13 +31,36 @@ import (
 // Exactly one mode must be specified.
 //
 // +kubebuilder:validation:XValidation:rule="[has(self.wasm)].filter(x, x).size() == 1",message="exactly one integration mechanism (Wasm, etc) must be specified"
+// +kubebuilder:validation:XValidation:rule="!has(self.wasm) || has(self.wasm.workloadSelector) || has(self.gatewayName)",message="workloadSelector is required unless gatewayName is set"
+// +kubebuilder:validation:XValidation:rule="!has(self.wasm) || !has(self.gatewayName) || self.wasm.mode == 'gateway'",message="gatewayName is only supported when mode is gateway"
+// +kubebuilder:validation:XValidation:rule="!has(self.wasm) || !has(self.gatewayName) || !has(self.wasm.workloadSelector) || (!has(self.wasm.workloadSelector.matchExpressions) && has(self.wasm.workloadSelector.matchLabels) && self.wasm.workloadSelector.matchLabels == {'gateway.networking.k8s.io/gateway-name': self.gatewayName})",message="workloadSelector must only match the gatewayName's gateway.networking.k8s.io/gateway-name label when both are set"
 type IstioDriverConfig struct {
 	// Wasm configures the Engine to be deployed as a WebAssembly plugin.
 	//
 	// +optional
 	Wasm *IstioWasmConfig `json:"wasm,omitempty"`
+
+	// GatewayName is the name of a Gateway, in the same namespace as the
+	// Engine, to attach the WAF to in "gateway" mode. It is a convenience
+	// for a workloadSelector matching the Gateway's Pods by their
+	// "gateway.networking.k8s.io/gateway-name" label.
+	//
+	// At least one of GatewayName or the Wasm workloadSelector must be set.
+	// They may both be set only if the workloadSelector is the one derived
+	// from GatewayName, i.e. its matchLabels only match the Gateway's
+	// "gateway.networking.k8s.io/gateway-name" label, which the Engine
+	// defaulting webhook, when enabled, populates.
+	//
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	GatewayName string `json:"gatewayName,omitempty"`
 }
 
+// GatewayNameLabel is the label Gateway API implementations set on the Pods
+// they deploy for a Gateway, with the Gateway's name as its value.
+const GatewayNameLabel = "gateway.networking.k8s.io/gateway-name"
+
 // -----------------------------------------------------------------------------
 // Engine Driver - Istio Wasm Configuration
 // -----------------------------------------------------------------------------
@@ -44,23 +68,56 @@ type IstioDriverConfig struct {
 // IstioWasmConfig defines configuration for deploying the Engine as a WASM
 // plugin with Istio.
 //
-// +kubebuilder:validation:XValidation:rule="self.mode == 'gateway' ? has(self.workloadSelector) : true",message="workloadSelector is required when mode is gateway"
+// +kubebuilder:validation:XValidation:rule="self.mode == 'gateway' && has(self.matchContext) ? self.matchContext == 'gateway' : true",message="matchContext must be gateway when mode is gateway"
+// +kubebuilder:validation:XValidation:rule="self.mode == 'sidecar' && has(self.matchContext) ? self.matchContext != 'gateway' : true",message="matchContext must be inbound or outbound when mode is sidecar"
+// +kubebuilder:validation:XValidation:rule="has(self.requireDigest) && self.requireDigest ? self.image.matches('@sha256:[a-f0-9]{64}$') : true",message="image must be pinned by a sha256 digest when requireDigest is set"
+// +kubebuilder:validation:XValidation:rule="!has(self.workloadSelector) || !has(self.workloadSelector.matchExpressions) || size(self.workloadSelector.matchExpressions) == 0",message="workloadSelector matchExpressions are not supported, use matchLabels"
+// +kubebuilder:validation:XValidation:rule="!has(self.workloadSelector) || (has(self.workloadSelector.matchLabels) && size(self.workloadSelector.matchLabels) > 0)",message="workloadSelector matchLabels must not be empty, as the WAF would be attached to every workload in the namespace"
 type IstioWasmConfig struct {
 	// Mode specifies what mechanism will be used to integrate the WAF with
 	// Istio.
 	//
-	// Currently only supports "Gateway" mode, utilizing Gateway API resources.
+	// "gateway" attaches the WAF to Gateway API Gateways, enforcing rules on
+	// north-south traffic. "sidecar" attaches the WAF to the sidecars of mesh
+	// workloads, enforcing rules on east-west traffic.
 	//
 	// +required
 	// +kubebuilder:default=gateway
 	Mode IstioIntegrationMode `json:"mode"`
 
 	// WorkloadSelector specifies the selection criteria for attaching the WAF to
-	// Istio resources.
+	// Istio resources: the Gateway Pods in "gateway" mode, or the workload
+	// Pods whose sidecars run the WAF in "sidecar" mode. It is required,
+	// unless the Istio driver's gatewayName is set, and must set non-empty
+	// matchLabels and no matchExpressions (which WasmPlugins don't support),
+	// so that the WAF is never attached to every workload in the namespace.
 	//
 	// +optional
 	WorkloadSelector *metav1.LabelSelector `json:"workloadSelector,omitempty"`
 
+	// MatchContext specifies the traffic context the WASM plugin is applied
+	// in. "gateway" applies the plugin to traffic entering a Gateway, while
+	// "inbound" and "outbound" apply it to a sidecar's inbound and outbound
+	// traffic respectively.
+	//
+	// Must be "gateway" when mode is "gateway", and "inbound" or "outbound"
+	// when mode is "sidecar". Defaults to "gateway" in gateway mode, and to
+	// "inbound" in sidecar mode.
+	//
+	// +optional
+	MatchContext IstioMatchContext `json:"matchContext,omitempty"`
+
+	// Phase specifies where in the filter chain the WASM plugin is inserted,
+	// relative to Istio's authentication, authorization and stats filters.
+	// Use "AUTHZ" to run the WAF after authentication, or "AUTHN" to run it
+	// before authentication.
+	//
+	// When omitted, the plugin is inserted at Istio's default position, just
+	// before the router filter.
+	//
+	// +optional
+	Phase IstioWasmPhase `json:"phase,omitempty"`
+
 	// Image is the OCI image reference for the Coraza WASM plugin.
 	//
 	// +required
@@ -69,6 +126,26 @@ type IstioWasmConfig struct {
 	// +kubebuilder:validation:Pattern=`^oci://`
 	Image string `json:"image"`
 
+	// ImagePullSecret is the name of a Secret in the Engine's namespace
+	// holding the credentials (a docker pull secret) used to pull the Image
+	// from a private OCI registry.
+	//
+	// When omitted, the Image is pulled without credentials.
+	//
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	ImagePullSecret string `json:"imagePullSecret,omitempty"`
+
+	// RequireDigest, when true, requires the Image to be pinned by digest
+	// (e.g. "oci://ghcr.io/org/coraza-wasm@sha256:<digest>"), rejecting
+	// references by tag alone. Whether or not it's set, the digest of a
+	// pinned Image is verified by Istio when the module is pulled.
+	//
+	// +optional
+	RequireDigest bool `json:"requireDigest,omitempty"`
+
 	// RuleSetCacheServer contains configuration for the ruleset cache server.
 	//
 	// When omitted, no cache server will be used and no rulesets will be
@@ -77,6 +154,19 @@ type IstioWasmConfig struct {
 	//
 	// +optional
 	RuleSetCacheServer *RuleSetCacheServerConfig `json:"ruleSetCacheServer,omitempty"`
+
+	// PluginConfigOverrides are additional entries merged into the WASM
+	// plugin's configuration, for plugin options which have no first-class
+	// support in the Engine API yet (e.g. request body limits). Values may be
+	// any JSON value, e.g. numbers or objects, as the plugin expects them.
+	//
+	// Entries managed by the operator (the cache server configuration) can't
+	// be overridden.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxProperties=64
+	// +kubebuilder:validation:XValidation:rule="!['cache_server_instance', 'cache_server_cluster', 'rule_reload_interval_seconds', 'cache_server_auth_token_env'].exists(k, k in self)",message="pluginConfigOverrides must not set the operator managed keys cache_server_instance, cache_server_cluster, rule_reload_interval_seconds or cache_server_auth_token_env"
+	PluginConfigOverrides map[string]apiextensionsv1.JSON `json:"pluginConfigOverrides,omitempty"`
 }
 
 // -----------------------------------------------------------------------------
@@ -89,7 +179,8 @@ type IstioIntegrationConfig struct {
 	// Mode specifies what mechanism will be used to integrate the WAF with
 	// Istio.
 	//
-	// Currently only supports "Gateway" mode, utilizing Gateway API resources.
+	// Supports "gateway" mode, utilizing Gateway API resources, and "sidecar"
+	// mode, utilizing the sidecars of mesh workloads.
 	//
 	// +required
 	Mode IstioIntegrationMode `json:"mode"`
@@ -97,7 +188,8 @@ type IstioIntegrationConfig struct {
 	// WorkloadSelector specifies the selection criteria for attaching the WAF.
 	//
 	// When mode is "gateway", this selector is used to identify the Gateway
-	// Pods to which the WAF should be attached.
+	// Pods to which the WAF should be attached. When mode is "sidecar", it
+	// identifies the workload Pods whose sidecars the WAF is attached to.
 	//
 	// +required
 	WorkloadSelector metav1.LabelSelector `json:"workloadSelector"`
@@ -106,10 +198,62 @@ type IstioIntegrationConfig struct {
 // IstioIntegrationMode specifies what mechanism will be used to integrate the
 // WAF with Istio.
 //
-// +kubebuilder:validation:Enum=gateway
+// +kubebuilder:validation:Enum=gateway;sidecar
 type IstioIntegrationMode string
 
 const (
 	// IstioIntegrationModeGateway applies the filter at the Gateway level.
 	IstioIntegrationModeGateway IstioIntegrationMode = "gateway"
+
+	// IstioIntegrationModeSidecar applies the filter at the sidecars of mesh
+	// workloads.
+	IstioIntegrationModeSidecar IstioIntegrationMode = "sidecar"
+)
+
+// IstioMatchContext specifies the traffic context in which the WAF is applied.
+//
+// +kubebuilder:validation:Enum=gateway;inbound;outbound
+type IstioMatchContext string
+
+const (
+	// IstioMatchContextGateway applies the filter to traffic entering a
+	// Gateway.
+	IstioMatchContextGateway IstioMatchContext = "gateway"
+
+	// IstioMatchContextInbound applies the filter to a sidecar's inbound
+	// traffic.
+	IstioMatchContextInbound IstioMatchContext = "inbound"
+
+	// IstioMatchContextOutbound applies the filter to a sidecar's outbound
+	// traffic.
+	IstioMatchContextOutbound IstioMatchContext = "outbound"
+)
+
+// DefaultIstioMatchContext returns the match context used when an Engine
+// in the given integration mode doesn't specify one.
+func DefaultIstioMatchContext(mode IstioIntegrationMode) IstioMatchContext {
+	if mode == IstioIntegrationModeSidecar {
+		return IstioMatchContextInbound
+	}
+	return IstioMatchContextGateway
+}
+
+// IstioWasmPhase specifies the phase of the filter chain in which the WASM
+// plugin is inserted.
+//
+// +kubebuilder:validation:Enum=AUTHN;AUTHZ;STATS
+type IstioWasmPhase string
+
+const (
+	// IstioWasmPhaseAuthn inserts the plugin before Istio's authentication
+	// filters.
+	IstioWasmPhaseAuthn IstioWasmPhase = "AUTHN"
+
+	// IstioWasmPhaseAuthz inserts the plugin before Istio's authorization
+	// filters and after its authentication filters.
+	IstioWasmPhaseAuthz IstioWasmPhase = "AUTHZ"
+
+	// IstioWasmPhaseStats inserts the plugin before Istio's stats filters and
+	// after its authorization filters.
+	IstioWasmPhaseStats IstioWasmPhase = "STATS"
 )
@@ -24,10 +24,16 @@ package v1alpha1
 //
 // Exactly one driver must be specified.
 //
-// +kubebuilder:validation:XValidation:rule="[has(self.istio)].filter(x, x).size() == 1",message="exactly one driver must be specified"
+// +kubebuilder:validation:XValidation:rule="[has(self.istio), has(self.envoy)].filter(x, x).size() == 1",message="exactly one driver must be specified"
 type DriverConfig struct {
 	// Istio configures the Engine to integrate with Istio service mesh.
 	//
 	// +optional
 	Istio *IstioDriverConfig `json:"istio,omitempty"`
+
+	// Envoy configures the Engine to integrate with Envoy Gateway, without
+	// Istio.
+	//
+	// +optional
+	Envoy *EnvoyDriverConfig `json:"envoy,omitempty"`
 }
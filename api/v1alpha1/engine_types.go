@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -47,6 +48,7 @@ func init() {
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="RuleSet",type=string,JSONPath=`.spec.ruleSet.name`
 // +kubebuilder:printcolumn:name="Failure Policy",type=string,JSONPath=`.spec.failurePolicy`
+// +kubebuilder:printcolumn:name="WasmPlugin",type=string,JSONPath=`.status.wasmPluginName`
 // +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type Engine struct {
@@ -90,17 +92,38 @@ type EngineList struct {
 // -----------------------------------------------------------------------------
 
 // EngineSpec defines the desired state of an Engine.
+//
+// +kubebuilder:validation:XValidation:rule="has(self.ruleSet) || (has(self.ruleSets) && size(self.ruleSets) > 0)",message="at least one of ruleSet or ruleSets must be specified"
 type EngineSpec struct {
 	// RuleSet specifies the RuleSet resource that will be used to load rules
 	// into the Engine. The referenced RuleSet must be in the same namespace
 	// as the Engine.
 	//
-	// +required
-	RuleSet RuleSetReference `json:"ruleSet"`
+	// At least one of RuleSet or RuleSets must be specified.
+	//
+	// +optional
+	RuleSet RuleSetReference `json:"ruleSet,omitzero"`
+
+	// RuleSets specifies additional RuleSet resources whose rules are loaded
+	// into the Engine, e.g. an application specific overlay on a shared base
+	// RuleSet. The referenced RuleSets must be in the same namespace as the
+	// Engine.
+	//
+	// Rules are loaded in order: the RuleSet referenced by RuleSet first,
+	// followed by RuleSets in the order listed. Later rules take precedence,
+	// as they can remove or update earlier rules (e.g. SecRuleRemoveById).
+	// Rule ids must be unique across all of the Engine's RuleSets. A RuleSet
+	// referenced more than once is only loaded the first time.
+	//
+	// +optional
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=16
+	RuleSets []RuleSetReference `json:"ruleSets,omitempty"`
 
 	// Driver specifies the driver configuration for the engine. This
 	// determines how the WAF engine will be deployed and integrated with some
-	// implementation. Currently only supports Istio ingress Gateways.
+	// implementation. Currently supports Istio ingress Gateways and
+	// sidecars, and Envoy Gateways through an ext_proc service.
 	//
 	// +required
 	Driver DriverConfig `json:"driver"`
@@ -119,6 +142,20 @@ type EngineSpec struct {
 	// +required
 	// +kubebuilder:default=fail
 	FailurePolicy FailurePolicy `json:"failurePolicy"`
+
+	// Priority determines the order in which the Engine runs relative to
+	// other plugins attached to the same workload. Plugins with a higher
+	// priority run earlier in the filter chain.
+	//
+	// When omitted, the data plane's default priority (0) applies. Currently
+	// only used by the Istio driver in wasm mode, where Engines whose
+	// workload selectors overlap must set distinct priorities, or all but the
+	// first are Degraded with reason SelectorConflict.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=-1000
+	// +kubebuilder:validation:Maximum=1000
+	Priority *int32 `json:"priority,omitempty"`
 }
 
 // -----------------------------------------------------------------------------
@@ -135,6 +172,12 @@ type EngineStatus struct {
 	// - "Ready": the engine has been successfully deployed and is operational
 	// - "Progressing": the resource is being created or updated
 	// - "Degraded": the resource failed to reach or maintain its desired state
+	// - "RulesServed": the latest rules of the RuleSet were served to the
+	//   data plane, which doesn't confirm that it loaded them (best-effort,
+	//   only reported when the operator can observe it)
+	// - "RuleSetReady": all the RuleSets the engine loads rules from are Ready
+	// - "Paused": reconciliation is paused by the waf.k8s.coraza.io/paused
+	//   annotation
 	//
 	// The status of each condition is one of True, False, or Unknown.
 	//
@@ -144,6 +187,60 @@ type EngineStatus struct {
 	// +patchMergeKey=type
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// WasmPluginName is the name of the WasmPlugin provisioned for the Engine
+	// in its namespace, when using the Istio driver in wasm mode.
+	//
+	// +optional
+	WasmPluginName string `json:"wasmPluginName,omitempty"`
+
+	// OwnedResources lists the resources provisioned for the Engine by its
+	// driver (e.g. a WasmPlugin), which are removed when the Engine is
+	// deleted.
+	//
+	// +optional
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=32
+	OwnedResources []corev1.ObjectReference `json:"ownedResources,omitempty"`
+
+	// TopMatchedRules lists the rules that have matched the most requests on
+	// the data plane, ordered from most to least matches. It is only
+	// populated when a rule match statistics source is configured.
+	//
+	// +optional
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=100
+	TopMatchedRules []RuleMatchCount `json:"topMatchedRules,omitempty"`
+
+	// MatchStatisticsUpdated is the time at which TopMatchedRules was last
+	// refreshed.
+	//
+	// +optional
+	MatchStatisticsUpdated *metav1.Time `json:"matchStatisticsUpdated,omitempty"`
+
+	// ServedRuleSetUUID is the UUID of the version of the RuleSet's rules
+	// which the RuleSet cache most recently served to the data plane. It
+	// doesn't confirm that the data plane loaded them (e.g. it may fail to
+	// compile them), and with multiple data plane instances, it reflects
+	// whichever instance fetched the rules last.
+	//
+	// +optional
+	ServedRuleSetUUID string `json:"servedRuleSetUUID,omitempty"`
+}
+
+// RuleMatchCount is the number of times a rule matched on the data plane.
+type RuleMatchCount struct {
+	// RuleID is the SecLang id of the rule.
+	//
+	// +required
+	// +kubebuilder:validation:Minimum=1
+	RuleID int64 `json:"ruleID"`
+
+	// Matches is the number of times the rule matched.
+	//
+	// +required
+	// +kubebuilder:validation:Minimum=0
+	Matches int64 `json:"matches"`
 }
 
 // -----------------------------------------------------------------------------
@@ -0,0 +1,256 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Webhook - Setup
+// -----------------------------------------------------------------------------
+
+// SetupEngineWebhookWithManager registers the Engine defaulting and
+// validating webhooks with the manager's webhook server.
+func SetupEngineWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &Engine{}).
+		WithDefaulter(&EngineDefaulter{}).
+		WithValidator(&EngineValidator{}).
+		Complete()
+}
+
+// -----------------------------------------------------------------------------
+// Engine Webhook - Defaulter
+// -----------------------------------------------------------------------------
+
+// DefaultPollIntervalSeconds is the default RuleSet cache server poll
+// interval, matching the CRD schema default.
+const DefaultPollIntervalSeconds int32 = 15
+
+// +kubebuilder:webhook:path=/mutate-waf-k8s-coraza-io-v1alpha1-engine,mutating=true,failurePolicy=fail,sideEffects=None,groups=waf.k8s.coraza.io,resources=engines,verbs=create;update,versions=v1alpha1,name=mengine-v1alpha1.waf.k8s.coraza.io,admissionReviewVersions=v1
+
+// EngineDefaulter fills in the defaults of an Engine which the CRD schema
+// can not express, such as a workloadSelector derived from a Gateway name,
+// and normalizes Engines created before schema defaults were introduced.
+//
+// Only unset fields are defaulted, so defaulting is idempotent. Fields
+// unknown to the webhook are preserved, so it is safe to run against newer
+// versions of the CRD.
+//
+// +kubebuilder:object:generate=false
+type EngineDefaulter struct{}
+
+var _ admission.Defaulter[*Engine] = &EngineDefaulter{}
+
+// Default sets the defaults of an Engine.
+func (d *EngineDefaulter) Default(_ context.Context, engine *Engine) error {
+	if engine.Spec.FailurePolicy == "" {
+		engine.Spec.FailurePolicy = FailurePolicyFail
+	}
+
+	istio := engine.Spec.Driver.Istio
+	if istio == nil || istio.Wasm == nil {
+		return nil
+	}
+
+	wasm := istio.Wasm
+	if wasm.Mode == "" {
+		wasm.Mode = IstioIntegrationModeGateway
+	}
+	if wasm.MatchContext == "" {
+		wasm.MatchContext = DefaultIstioMatchContext(wasm.Mode)
+	}
+	if wasm.RuleSetCacheServer != nil && wasm.RuleSetCacheServer.PollIntervalSeconds == 0 {
+		wasm.RuleSetCacheServer.PollIntervalSeconds = DefaultPollIntervalSeconds
+	}
+	if wasm.Mode == IstioIntegrationModeGateway && istio.GatewayName != "" && wasm.WorkloadSelector == nil {
+		wasm.WorkloadSelector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{GatewayNameLabel: istio.GatewayName},
+		}
+	}
+
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+// Engine Webhook - Validator
+// -----------------------------------------------------------------------------
+
+// +kubebuilder:webhook:path=/validate-waf-k8s-coraza-io-v1alpha1-engine,mutating=false,failurePolicy=fail,sideEffects=None,groups=waf.k8s.coraza.io,resources=engines,verbs=create;update,versions=v1alpha1,name=vengine-v1alpha1.waf.k8s.coraza.io,admissionReviewVersions=v1
+
+// EngineValidator rejects Engines whose configuration is not coherent across
+// fields, so that users get immediate feedback on apply instead of a
+// Degraded Engine after reconciliation.
+//
+// Some of these invariants are also enforced by the CRD schema. They are
+// checked here as well so that the webhook is authoritative on its own and
+// reports every problem with the Engine at once.
+//
+// +kubebuilder:object:generate=false
+type EngineValidator struct{}
+
+var _ admission.Validator[*Engine] = &EngineValidator{}
+
+// ValidateCreate validates an Engine on creation.
+func (v *EngineValidator) ValidateCreate(_ context.Context, engine *Engine) (admission.Warnings, error) {
+	return validateEngine(engine)
+}
+
+// ValidateUpdate validates an Engine on update. Updates which leave the spec
+// unchanged are always allowed, so that Engines created before the webhook
+// was enabled can still have their metadata (e.g. finalizers) updated.
+func (v *EngineValidator) ValidateUpdate(_ context.Context, oldEngine, engine *Engine) (admission.Warnings, error) {
+	if equality.Semantic.DeepEqual(oldEngine.Spec, engine.Spec) {
+		return nil, nil
+	}
+
+	return validateEngine(engine)
+}
+
+// ValidateDelete allows all Engine deletions.
+func (v *EngineValidator) ValidateDelete(_ context.Context, _ *Engine) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// -----------------------------------------------------------------------------
+// Engine Webhook - Validation
+// -----------------------------------------------------------------------------
+
+// validateEngine returns an Invalid error listing every incoherent field of
+// the Engine, and warnings for configuration which is valid but likely a
+// mistake.
+func validateEngine(engine *Engine) (admission.Warnings, error) {
+	specPath := field.NewPath("spec")
+	warnings, errs := validateEngineRuleSets(engine, specPath)
+	errs = append(errs, validateEngineDriver(engine, specPath.Child("driver"))...)
+	if len(errs) == 0 {
+		return warnings, nil
+	}
+
+	return warnings, apierrors.NewInvalid(GroupVersion.WithKind("Engine").GroupKind(), engine.Name, errs)
+}
+
+// validateEngineRuleSets validates the Engine's RuleSet references.
+func validateEngineRuleSets(engine *Engine, specPath *field.Path) (admission.Warnings, field.ErrorList) {
+	var warnings admission.Warnings
+	var errs field.ErrorList
+
+	if engine.Spec.RuleSet.Name == "" && len(engine.Spec.RuleSets) == 0 {
+		errs = append(errs, field.Required(specPath.Child("ruleSet"), "at least one of ruleSet or ruleSets must be specified"))
+	}
+
+	seen := map[string]bool{engine.Spec.RuleSet.Name: engine.Spec.RuleSet.Name != ""}
+	for i, ref := range engine.Spec.RuleSets {
+		if seen[ref.Name] {
+			warnings = append(warnings, fmt.Sprintf("%s: RuleSet %q is referenced more than once and is only loaded the first time",
+				specPath.Child("ruleSets").Index(i), ref.Name))
+		}
+		seen[ref.Name] = true
+	}
+
+	return warnings, errs
+}
+
+// validateEngineDriver validates that exactly one driver and integration
+// mechanism is configured, and that the mechanism's configuration is
+// coherent.
+func validateEngineDriver(engine *Engine, driverPath *field.Path) field.ErrorList {
+	driver := engine.Spec.Driver
+	switch {
+	case driver.Istio != nil && driver.Envoy != nil:
+		return field.ErrorList{field.Forbidden(driverPath, "exactly one driver must be specified, got both istio and envoy")}
+	case driver.Istio != nil:
+		if driver.Istio.Wasm == nil {
+			return field.ErrorList{field.Required(driverPath.Child("istio", "wasm"), "the istio driver requires an integration mechanism")}
+		}
+		return validateIstioWasm(driver.Istio, driverPath.Child("istio"))
+	case driver.Envoy != nil:
+		if driver.Envoy.ExtProc == nil {
+			return field.ErrorList{field.Required(driverPath.Child("envoy", "extProc"), "the envoy driver requires an integration mechanism")}
+		}
+		return nil
+	default:
+		return field.ErrorList{field.Required(driverPath, "exactly one driver must be specified")}
+	}
+}
+
+// validateIstioWasm validates that the Istio WASM configuration's workload
+// selector (or gatewayName) and match context are coherent with its mode.
+func validateIstioWasm(istio *IstioDriverConfig, istioPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	wasm := istio.Wasm
+	wasmPath := istioPath.Child("wasm")
+
+	gatewayNamePath := istioPath.Child("gatewayName")
+	selectorPath := wasmPath.Child("workloadSelector")
+	switch {
+	case istio.GatewayName != "" && wasm.Mode != IstioIntegrationModeGateway:
+		errs = append(errs, field.Forbidden(gatewayNamePath, "gatewayName is only supported when mode is gateway"))
+	case istio.GatewayName != "" && wasm.WorkloadSelector != nil && !selectsGateway(wasm.WorkloadSelector, istio.GatewayName):
+		errs = append(errs, field.Forbidden(gatewayNamePath, "workloadSelector must only match the gatewayName's gateway.networking.k8s.io/gateway-name label when both are set"))
+	}
+
+	switch {
+	case wasm.WorkloadSelector == nil && wasm.Mode == IstioIntegrationModeGateway:
+		if istio.GatewayName == "" {
+			errs = append(errs, field.Required(selectorPath, "workloadSelector is required in gateway mode unless gatewayName is set"))
+		}
+	case wasm.WorkloadSelector == nil:
+		errs = append(errs, field.Required(selectorPath, fmt.Sprintf("workloadSelector is required in %s mode", wasm.Mode)))
+	case len(wasm.WorkloadSelector.MatchExpressions) > 0:
+		// The WasmPlugin selector only supports labels, so expressions would
+		// silently be dropped and the WAF attached more broadly than intended.
+		errs = append(errs, field.Forbidden(selectorPath.Child("matchExpressions"), "matchExpressions are not supported, use matchLabels"))
+	case len(wasm.WorkloadSelector.MatchLabels) == 0:
+		errs = append(errs, field.Required(selectorPath.Child("matchLabels"),
+			"matchLabels must not be empty, as the WAF would be attached to every workload in the namespace"))
+	}
+
+	contextPath := wasmPath.Child("matchContext")
+	switch wasm.Mode {
+	case IstioIntegrationModeGateway:
+		if wasm.MatchContext != "" && wasm.MatchContext != IstioMatchContextGateway {
+			errs = append(errs, field.Invalid(contextPath, wasm.MatchContext, "matchContext must be gateway when mode is gateway"))
+		}
+	case IstioIntegrationModeSidecar:
+		if wasm.MatchContext == IstioMatchContextGateway {
+			errs = append(errs, field.Invalid(contextPath, wasm.MatchContext, "matchContext must be inbound or outbound when mode is sidecar"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(wasmPath.Child("mode"), wasm.Mode,
+			[]IstioIntegrationMode{IstioIntegrationModeGateway, IstioIntegrationModeSidecar}))
+	}
+
+	return errs
+}
+
+// selectsGateway reports whether the selector is exactly the one derived
+// from the named Gateway, which is allowed alongside gatewayName as it is
+// populated by the EngineDefaulter.
+func selectsGateway(selector *metav1.LabelSelector, gatewayName string) bool {
+	return len(selector.MatchExpressions) == 0 &&
+		maps.Equal(selector.MatchLabels, map[string]string{GatewayNameLabel: gatewayName})
+}
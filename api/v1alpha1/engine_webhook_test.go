@@ -0,0 +1,462 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestEngineDefaulter(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(engine *Engine)
+		expected func(engine *Engine)
+	}{
+		{
+			name:     "defaults are left untouched",
+			mutate:   func(engine *Engine) {},
+			expected: func(engine *Engine) {},
+		},
+		{
+			name: "failure policy and mode are defaulted",
+			mutate: func(engine *Engine) {
+				engine.Spec.FailurePolicy = ""
+				engine.Spec.Driver.Istio.Wasm.Mode = ""
+			},
+			expected: func(engine *Engine) {},
+		},
+		{
+			name: "match context is defaulted in gateway mode",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.MatchContext = ""
+			},
+			expected: func(engine *Engine) {},
+		},
+		{
+			name: "match context is defaulted in sidecar mode",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.Mode = IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.MatchContext = ""
+			},
+			expected: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.Mode = IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.MatchContext = IstioMatchContextInbound
+			},
+		},
+		{
+			name: "explicit match context is kept",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.Mode = IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.MatchContext = IstioMatchContextOutbound
+			},
+			expected: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.Mode = IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.MatchContext = IstioMatchContextOutbound
+			},
+		},
+		{
+			name: "poll interval is defaulted",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.RuleSetCacheServer = &RuleSetCacheServerConfig{}
+			},
+			expected: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.RuleSetCacheServer = &RuleSetCacheServerConfig{PollIntervalSeconds: DefaultPollIntervalSeconds}
+			},
+		},
+		{
+			name: "explicit poll interval is kept",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.RuleSetCacheServer = &RuleSetCacheServerConfig{PollIntervalSeconds: 60}
+			},
+			expected: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.RuleSetCacheServer = &RuleSetCacheServerConfig{PollIntervalSeconds: 60}
+			},
+		},
+		{
+			name: "workloadSelector is derived from gatewayName",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.GatewayName = "my-gateway"
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = nil
+			},
+			expected: func(engine *Engine) {
+				engine.Spec.Driver.Istio.GatewayName = "my-gateway"
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{GatewayNameLabel: "my-gateway"},
+				}
+			},
+		},
+		{
+			name: "explicit workloadSelector is kept",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.GatewayName = "my-gateway"
+			},
+			expected: func(engine *Engine) {
+				engine.Spec.Driver.Istio.GatewayName = "my-gateway"
+			},
+		},
+		{
+			name: "gatewayName is ignored in sidecar mode",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.GatewayName = "my-gateway"
+				engine.Spec.Driver.Istio.Wasm.Mode = IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = nil
+			},
+			expected: func(engine *Engine) {
+				engine.Spec.Driver.Istio.GatewayName = "my-gateway"
+				engine.Spec.Driver.Istio.Wasm.Mode = IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = nil
+			},
+		},
+		{
+			name: "envoy engines are left untouched",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver = DriverConfig{Envoy: &EnvoyDriverConfig{ExtProc: &EnvoyExtProcConfig{GatewayName: "my-gateway"}}}
+			},
+			expected: func(engine *Engine) {
+				engine.Spec.Driver = DriverConfig{Envoy: &EnvoyDriverConfig{ExtProc: &EnvoyExtProcConfig{GatewayName: "my-gateway"}}}
+			},
+		},
+	}
+
+	defaulter := &EngineDefaulter{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newTestEngine("test-engine")
+			tt.mutate(engine)
+			expected := newTestEngine("test-engine")
+			tt.expected(expected)
+
+			require.NoError(t, defaulter.Default(context.Background(), engine))
+			assert.Equal(t, expected, engine)
+
+			t.Log("Verifying defaulting is idempotent")
+			require.NoError(t, defaulter.Default(context.Background(), engine))
+			assert.Equal(t, expected, engine)
+		})
+	}
+}
+
+func TestEngineValidator(t *testing.T) {
+	tests := []struct {
+		name             string
+		mutate           func(engine *Engine)
+		expectedErrors   []string
+		expectedWarnings []string
+	}{
+		{
+			name:   "valid gateway engine",
+			mutate: func(engine *Engine) {},
+		},
+		{
+			name: "valid sidecar engine",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.Mode = IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.MatchContext = IstioMatchContextInbound
+			},
+		},
+		{
+			name: "valid envoy engine",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver = DriverConfig{Envoy: &EnvoyDriverConfig{ExtProc: &EnvoyExtProcConfig{
+					GatewayName: "gateway",
+					Service:     EnvoyExtProcService{Name: "coraza", Port: 9002},
+				}}}
+			},
+		},
+		{
+			name: "no ruleset specified",
+			mutate: func(engine *Engine) {
+				engine.Spec.RuleSet.Name = ""
+			},
+			expectedErrors: []string{"spec.ruleSet: Required value: at least one of ruleSet or ruleSets must be specified"},
+		},
+		{
+			name: "duplicate ruleset references",
+			mutate: func(engine *Engine) {
+				engine.Spec.RuleSets = []RuleSetReference{{Name: "overlay"}, {Name: "base"}}
+			},
+			expectedWarnings: []string{`spec.ruleSets[1]: RuleSet "base" is referenced more than once and is only loaded the first time`},
+		},
+		{
+			name: "no driver specified",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver = DriverConfig{}
+			},
+			expectedErrors: []string{"spec.driver: Required value: exactly one driver must be specified"},
+		},
+		{
+			name: "both drivers specified",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Envoy = &EnvoyDriverConfig{}
+			},
+			expectedErrors: []string{"spec.driver: Forbidden: exactly one driver must be specified, got both istio and envoy"},
+		},
+		{
+			name: "istio driver without integration mechanism",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm = nil
+			},
+			expectedErrors: []string{"spec.driver.istio.wasm: Required value: the istio driver requires an integration mechanism"},
+		},
+		{
+			name: "envoy driver without integration mechanism",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver = DriverConfig{Envoy: &EnvoyDriverConfig{}}
+			},
+			expectedErrors: []string{"spec.driver.envoy.extProc: Required value: the envoy driver requires an integration mechanism"},
+		},
+		{
+			name: "gateway mode without workloadSelector",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = nil
+			},
+			expectedErrors: []string{"spec.driver.istio.wasm.workloadSelector: Required value: workloadSelector is required in gateway mode"},
+		},
+		{
+			name: "empty workloadSelector",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = &metav1.LabelSelector{}
+			},
+			expectedErrors: []string{"spec.driver.istio.wasm.workloadSelector.matchLabels: Required value: matchLabels must not be empty"},
+		},
+		{
+			name: "workloadSelector with matchExpressions",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector.MatchExpressions = []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: metav1.LabelSelectorOpExists},
+				}
+			},
+			expectedErrors: []string{"spec.driver.istio.wasm.workloadSelector.matchExpressions: Forbidden: matchExpressions are not supported"},
+		},
+		{
+			name: "gatewayName instead of workloadSelector",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.GatewayName = "my-gateway"
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = nil
+			},
+		},
+		{
+			name: "gatewayName with its derived workloadSelector",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.GatewayName = "my-gateway"
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector.MatchLabels = map[string]string{GatewayNameLabel: "my-gateway"}
+			},
+		},
+		{
+			name: "gatewayName with another workloadSelector",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.GatewayName = "my-gateway"
+			},
+			expectedErrors: []string{"spec.driver.istio.gatewayName: Forbidden: workloadSelector must only match the gatewayName's gateway.networking.k8s.io/gateway-name label when both are set"},
+		},
+		{
+			name: "gatewayName in sidecar mode",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.GatewayName = "my-gateway"
+				engine.Spec.Driver.Istio.Wasm.Mode = IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.MatchContext = IstioMatchContextInbound
+			},
+			expectedErrors: []string{"spec.driver.istio.gatewayName: Forbidden: gatewayName is only supported when mode is gateway"},
+		},
+		{
+			name: "sidecar engine without match context",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.Mode = IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.MatchContext = ""
+			},
+		},
+		{
+			name: "gateway mode with sidecar match context",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.MatchContext = IstioMatchContextInbound
+			},
+			expectedErrors: []string{`spec.driver.istio.wasm.matchContext: Invalid value: "inbound": matchContext must be gateway when mode is gateway`},
+		},
+		{
+			name: "sidecar mode with gateway match context",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.Mode = IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.MatchContext = IstioMatchContextGateway
+			},
+			expectedErrors: []string{`spec.driver.istio.wasm.matchContext: Invalid value: "gateway": matchContext must be inbound or outbound when mode is sidecar`},
+		},
+		{
+			name: "every problem is reported at once",
+			mutate: func(engine *Engine) {
+				engine.Spec.RuleSet.Name = ""
+				engine.Spec.Driver.Istio.Wasm.Mode = IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = nil
+			},
+			expectedErrors: []string{
+				"at least one of ruleSet or ruleSets must be specified",
+				"workloadSelector is required in sidecar mode",
+				"matchContext must be inbound or outbound when mode is sidecar",
+			},
+		},
+	}
+
+	validator := &EngineValidator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newTestEngine("test-engine")
+			tt.mutate(engine)
+
+			warnings, err := validator.ValidateCreate(context.Background(), engine)
+			assert.Len(t, warnings, len(tt.expectedWarnings))
+			for _, expected := range tt.expectedWarnings {
+				assert.Contains(t, warnings, expected)
+			}
+			if len(tt.expectedErrors) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, apierrors.IsInvalid(err), "expected an Invalid error, got: %v", err)
+			for _, expected := range tt.expectedErrors {
+				assert.Contains(t, err.Error(), expected)
+			}
+
+			t.Log("Verifying updates are validated the same way")
+			_, err = validator.ValidateUpdate(context.Background(), newTestEngine("test-engine"), engine)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestEngineValidator_ValidateUpdateUnchangedSpec(t *testing.T) {
+	t.Log("Updating only the metadata of an invalid Engine")
+	oldEngine := newTestEngine("test-engine")
+	oldEngine.Spec.Driver.Istio.Wasm.WorkloadSelector = &metav1.LabelSelector{}
+	engine := oldEngine.DeepCopy()
+	engine.Finalizers = []string{"waf.k8s.coraza.io/engine-finalizer"}
+
+	validator := &EngineValidator{}
+	warnings, err := validator.ValidateUpdate(context.Background(), oldEngine, engine)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
+
+func TestEngineWebhook(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating a valid Engine")
+	engine := newTestEngine("webhook-valid")
+	require.NoError(t, k8sClient.Create(ctx, engine))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, engine); err != nil {
+			t.Logf("Failed to delete engine: %v", err)
+		}
+	})
+
+	t.Log("Updating the Engine to an incoherent configuration")
+	engine.Spec.Driver.Istio.Wasm.WorkloadSelector = &metav1.LabelSelector{}
+	err := k8sClient.Update(ctx, engine)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "matchLabels must not be empty")
+
+	tests := []struct {
+		name          string
+		mutate        func(engine *Engine)
+		expectedError string
+	}{
+		{
+			name: "webhook-empty-selector",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = &metav1.LabelSelector{}
+			},
+			expectedError: "matchLabels must not be empty",
+		},
+		{
+			name: "webhook-match-expressions",
+			mutate: func(engine *Engine) {
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"gateway"}},
+					},
+				}
+			},
+			expectedError: "matchExpressions are not supported, use matchLabels",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newTestEngine(tt.name)
+			tt.mutate(engine)
+
+			err := k8sClient.Create(ctx, engine)
+			require.Error(t, err)
+			assert.True(t, apierrors.IsInvalid(err), "expected an Invalid error, got: %v", err)
+			assert.Contains(t, err.Error(), tt.expectedError)
+		})
+	}
+}
+
+func TestEngineWebhook_Defaulting(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating an Engine with a gatewayName and no workloadSelector")
+	engine := newTestEngine("webhook-gateway-name")
+	engine.Spec.Driver.Istio.GatewayName = "my-gateway"
+	engine.Spec.Driver.Istio.Wasm.WorkloadSelector = nil
+	require.NoError(t, k8sClient.Create(ctx, engine))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, engine); err != nil {
+			t.Logf("Failed to delete engine: %v", err)
+		}
+	})
+
+	t.Log("Verifying the workloadSelector was derived from the gatewayName")
+	var created Engine
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(engine), &created))
+	require.NotNil(t, created.Spec.Driver.Istio.Wasm.WorkloadSelector)
+	assert.Equal(t, map[string]string{GatewayNameLabel: "my-gateway"}, created.Spec.Driver.Istio.Wasm.WorkloadSelector.MatchLabels)
+	assert.Equal(t, "my-gateway", created.Spec.Driver.Istio.GatewayName)
+}
+
+// newTestEngine returns a valid Istio gateway mode Engine.
+func newTestEngine(name string) *Engine {
+	return &Engine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: EngineSpec{
+			RuleSet: RuleSetReference{Name: "base"},
+			Driver: DriverConfig{
+				Istio: &IstioDriverConfig{
+					Wasm: &IstioWasmConfig{
+						Mode:         IstioIntegrationModeGateway,
+						MatchContext: IstioMatchContextGateway,
+						Image:        "oci://fake-registry.io/fake-image:latest",
+						WorkloadSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"app": "gateway"},
+						},
+					},
+				},
+			},
+			FailurePolicy: FailurePolicyFail,
+		},
+	}
+}
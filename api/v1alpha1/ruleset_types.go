@@ -20,13 +20,91 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// RuleSourceReference is a reference to a ConfigMap that contains WAF rules.
+// RuleSourceKind is the kind of source that WAF rules are loaded from.
+//
+// +kubebuilder:validation:Enum=ConfigMap;Secret;Inline;URL
+type RuleSourceKind string
+
+const (
+	// RuleSourceKindConfigMap loads rules from the "rules" key of a ConfigMap
+	// in the same namespace as the RuleSet.
+	RuleSourceKindConfigMap RuleSourceKind = "ConfigMap"
+
+	// RuleSourceKindSecret loads rules from the "rules" key of a Secret in
+	// the same namespace as the RuleSet, for rules which embed sensitive
+	// values (e.g. API keys for @rbl lookups).
+	RuleSourceKindSecret RuleSourceKind = "Secret"
+
+	// RuleSourceKindInline loads rules directly from the Rules field of the
+	// source reference, without a ConfigMap.
+	RuleSourceKindInline RuleSourceKind = "Inline"
+
+	// RuleSourceKindURL loads rules over HTTP(S) from the URL field of the
+	// source reference. URL sources are only processed when the operator is
+	// started with URL rule sources enabled, as they require network egress.
+	RuleSourceKindURL RuleSourceKind = "URL"
+)
+
+// RuleSourceReference is a reference to a source of WAF rules, either a
+// ConfigMap, a Secret, rules provided inline or a remote URL.
+//
+// +kubebuilder:validation:XValidation:rule="(has(self.kind) && (self.kind == 'Inline' || self.kind == 'URL')) || has(self.name)",message="name is required for ConfigMap and Secret sources"
+// +kubebuilder:validation:XValidation:rule="!has(self.kind) || self.kind != 'Inline' || has(self.rules)",message="rules is required for Inline sources"
+// +kubebuilder:validation:XValidation:rule="!has(self.rules) || (has(self.kind) && self.kind == 'Inline')",message="rules may only be set for Inline sources"
+// +kubebuilder:validation:XValidation:rule="!has(self.kind) || self.kind != 'URL' || has(self.url)",message="url is required for URL sources"
+// +kubebuilder:validation:XValidation:rule="!has(self.url) || (has(self.kind) && self.kind == 'URL')",message="url may only be set for URL sources"
+// +kubebuilder:validation:XValidation:rule="!has(self.namespace) || !has(self.kind) || self.kind == 'ConfigMap'",message="namespace may only be set for ConfigMap sources"
 type RuleSourceReference struct {
-	// Name is the name of the ConfigMap in the same namespace as the RuleSet.
+	// Kind is the kind of rule source. When omitted, the source is a
+	// ConfigMap.
 	//
-	// +required
+	// +optional
+	// +kubebuilder:default=ConfigMap
+	Kind RuleSourceKind `json:"kind,omitempty"`
+
+	// Name is the name of the ConfigMap or Secret in the same namespace as
+	// the RuleSet (unless Namespace is set). It is required for ConfigMap and
+	// Secret sources, and for Inline and URL sources it may optionally be set
+	// to identify the source in status and events.
+	//
+	// +optional
 	// +kubebuilder:validation:MinLength=1
-	Name string `json:"name"`
+	Name string `json:"name,omitempty"`
+
+	// Namespace is the namespace of the ConfigMap for ConfigMap sources,
+	// defaulting to the namespace of the RuleSet. A ConfigMap in another
+	// namespace may only be referenced if a Gateway API ReferenceGrant in
+	// that namespace permits RuleSets from the RuleSet's namespace to
+	// reference it.
+	//
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	Namespace string `json:"namespace,omitempty"`
+
+	// Rules contains the SecLang rules for Inline sources.
+	//
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=65536
+	Rules string `json:"rules,omitempty"`
+
+	// URL is the http or https URL the rules are fetched from for URL
+	// sources. The response body must contain SecLang rules.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxLength=2048
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url,omitempty"`
+
+	// Priority determines the order the source is aggregated in relative to
+	// the RuleSet's other sources: sources with a lower priority are
+	// aggregated first, and sources with the same priority in the order
+	// they are listed. This allows e.g. grouping sources into phases without
+	// reordering the whole list. Defaults to 0.
+	//
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
 }
 
 // -----------------------------------------------------------------------------
@@ -45,6 +123,8 @@ func init() {
 //
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Rules",type=integer,JSONPath=`.status.ruleCount`
+// +kubebuilder:printcolumn:name="Bytes",type=integer,JSONPath=`.status.aggregatedBytes`
 // +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type RuleSet struct {
@@ -89,16 +169,134 @@ type RuleSetList struct {
 
 // RuleSetSpec defines the desired state of RuleSet.
 type RuleSetSpec struct {
-	// Rules is an ordered list of references to ConfigMaps that contain the
-	// firewall rules to be compiled into a complete set.
+	// Rules is an ordered list of rule sources that contain the firewall
+	// rules to be compiled into a complete set. Sources are aggregated by
+	// ascending priority and otherwise in the order they are listed,
+	// regardless of their kind.
 	//
-	// Each entry refers to a ConfigMap by name in the same namespace as
-	// the RuleSet. The ConfigMap must contain a "rules" key.
+	// ConfigMap and Secret sources refer to a ConfigMap or Secret by name in
+	// the same namespace as the RuleSet, which must contain a "rules" key.
+	// ConfigMaps in other namespaces may be referenced if a ReferenceGrant
+	// permits it. Inline sources carry their rules directly in the source
+	// entry, and URL sources are fetched over HTTP(S). Any InlineRules are
+	// aggregated after all of these sources.
 	//
 	// +required
 	// +kubebuilder:validation:MinItems=1
 	// +kubebuilder:validation:MaxItems=2048
 	Rules []RuleSourceReference `json:"rules"`
+
+	// InlineRules contains SecLang rules to aggregate after those of all
+	// the rule sources, e.g. for quick additions such as blocklist entries
+	// which don't warrant a separate source.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxLength=65536
+	InlineRules string `json:"inlineRules,omitempty"`
+
+	// Plugins lists Core Rule Set (CRS) plugins to configure. For each
+	// plugin, the SecAction which initializes it (enabling it and setting
+	// its variables) is rendered ahead of the aggregated rules. The plugin's
+	// own rules must still be provided by a rule source.
+	//
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=32
+	Plugins []CRSPlugin `json:"plugins,omitempty"`
+
+	// DuplicateRuleIDs determines how rule ids which are defined by more
+	// than one rule source are handled. Valid values are:
+	//
+	// - "Reject": the RuleSet is Degraded, listing the conflicting ids
+	// - "KeepLast": the rules of later sources replace the rules with the
+	//   same id in earlier sources, e.g. for overlays on a base RuleSet
+	//
+	// When omitted, this means the user has no opinion and the platform
+	// will choose a reasonable default, which is subject to change over time.
+	//
+	// The current default is Reject.
+	//
+	// +optional
+	// +kubebuilder:default=Reject
+	DuplicateRuleIDs DuplicateRuleIDPolicy `json:"duplicateRuleIDs,omitempty"`
+
+	// SourceSeparator determines what separates the rules of consecutive
+	// rule sources when they are aggregated. Sources always start on a new
+	// line, even if the previous source doesn't end with one. Valid values
+	// are:
+	//
+	// - "Newline": sources are separated by a newline
+	// - "BlankLine": sources are separated by an empty line, which keeps
+	//   them distinguishable in the aggregated rules
+	//
+	// When omitted, this means the user has no opinion and the platform
+	// will choose a reasonable default, which is subject to change over time.
+	//
+	// The current default is Newline.
+	//
+	// +optional
+	// +kubebuilder:default=Newline
+	SourceSeparator SourceSeparator `json:"sourceSeparator,omitempty"`
+}
+
+// SourceSeparator describes what separates the rules of consecutive rule
+// sources of a RuleSet.
+//
+// +kubebuilder:validation:Enum=Newline;BlankLine
+type SourceSeparator string
+
+const (
+	// SourceSeparatorNewline separates sources by a newline.
+	SourceSeparatorNewline SourceSeparator = "Newline"
+
+	// SourceSeparatorBlankLine separates sources by an empty line.
+	SourceSeparatorBlankLine SourceSeparator = "BlankLine"
+)
+
+// DuplicateRuleIDPolicy describes how rule ids defined by more than one rule
+// source of a RuleSet are handled.
+//
+// +kubebuilder:validation:Enum=Reject;KeepLast
+type DuplicateRuleIDPolicy string
+
+const (
+	// DuplicateRuleIDPolicyReject rejects RuleSets whose sources define the
+	// same rule id.
+	DuplicateRuleIDPolicyReject DuplicateRuleIDPolicy = "Reject"
+
+	// DuplicateRuleIDPolicyKeepLast keeps only the last definition of a
+	// rule id across the RuleSet's sources.
+	DuplicateRuleIDPolicyKeepLast DuplicateRuleIDPolicy = "KeepLast"
+)
+
+// CRSPluginName is the name of a recognized Core Rule Set plugin.
+//
+// +kubebuilder:validation:Enum=body-decompress-plugin
+type CRSPluginName string
+
+const (
+	// CRSPluginBodyDecompress is the CRS plugin which decompresses request
+	// bodies before they are inspected.
+	CRSPluginBodyDecompress CRSPluginName = "body-decompress-plugin"
+)
+
+// CRSPlugin configures a Core Rule Set plugin.
+type CRSPlugin struct {
+	// Name is the name of the plugin.
+	//
+	// +required
+	Name CRSPluginName `json:"name"`
+
+	// Settings are transaction variables to set when initializing the
+	// plugin, keyed by variable name without the "tx." prefix (e.g.
+	// "body-decompress-plugin_max_size": "1048576"). Names may contain
+	// letters, digits, '.', '_' and '-', and values may not contain quotes
+	// or backslashes.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxProperties=64
+	Settings map[string]string `json:"settings,omitempty"`
 }
 
 // -----------------------------------------------------------------------------
@@ -114,6 +312,8 @@ type RuleSetStatus struct {
 	// - "Ready": the RuleSet has been processed and and the rules have been cached
 	// - "Progressing": the resource is being created or updated
 	// - "Degraded": the resource failed to reach or maintain its desired state
+	// - "Paused": reconciliation is paused by the waf.k8s.coraza.io/paused
+	//   annotation
 	//
 	// The status of each condition is one of True, False, or Unknown.
 	//
@@ -123,6 +323,88 @@ type RuleSetStatus struct {
 	// +patchMergeKey=type
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// RuleCount is the number of rules (SecRule and SecAction directives) in
+	// the most recently cached aggregate. Configuration directives and
+	// comments are not counted, so a RuleCount of zero means the RuleSet
+	// enforces nothing.
+	//
+	// +optional
+	RuleCount *int32 `json:"ruleCount,omitempty"`
+
+	// AggregatedBytes is the size in bytes of the most recently cached
+	// aggregate, i.e. the rules served to the data plane.
+	//
+	// +optional
+	AggregatedBytes *int64 `json:"aggregatedBytes,omitempty"`
+
+	// ResolvedSources records provenance for each ConfigMap and Secret
+	// source of the most recently cached rules, in the order they were
+	// aggregated, including the resource version which was read. This shows
+	// exactly which versions of the sources the cached rules were built from.
+	//
+	// +optional
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=2048
+	ResolvedSources []ResolvedSource `json:"resolvedSources,omitempty"`
+
+	// ObservedFailures is the number of consecutive reconciliations which
+	// failed because a referenced rule source doesn't exist. Retries back off
+	// exponentially as it grows. It is reset once the rules are cached.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ObservedFailures int32 `json:"observedFailures,omitempty"`
+
+	// LastError describes the most recent of the ObservedFailures. It is
+	// cleared once the rules are cached.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxLength=32768
+	LastError string `json:"lastError,omitempty"`
+}
+
+// ResolvedSource records provenance for a ConfigMap or Secret rule source.
+type ResolvedSource struct {
+	// Name is the name of the ConfigMap or Secret.
+	//
+	// +required
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the ConfigMap, only set when it differs
+	// from the RuleSet's namespace.
+	//
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Kind is the kind of the rule source.
+	//
+	// +required
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind RuleSourceKind `json:"kind"`
+
+	// ResourceVersion is the resource version of the source which was read.
+	//
+	// +required
+	ResourceVersion string `json:"resourceVersion"`
+
+	// Bytes is the size in bytes of the rules read from the source.
+	//
+	// +required
+	// +kubebuilder:validation:Minimum=0
+	Bytes int64 `json:"bytes"`
+
+	// ValidationSkipped is set if the source opted out of rule validation
+	// with the coraza.io/validation: "false" annotation.
+	//
+	// +optional
+	ValidationSkipped bool `json:"validationSkipped,omitempty"`
+
+	// Labels contains the ConfigMap's labels whose keys are in the operator's
+	// provenance label allowlist.
+	//
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // -----------------------------------------------------------------------------
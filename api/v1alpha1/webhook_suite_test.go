@@ -0,0 +1,146 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// -----------------------------------------------------------------------------
+// Webhook Envtest Suite - Vars
+// -----------------------------------------------------------------------------
+
+var (
+	testEnv   *envtest.Environment
+	k8sClient client.Client
+)
+
+// -----------------------------------------------------------------------------
+// Webhook Envtest Suite - Main
+// -----------------------------------------------------------------------------
+
+func TestMain(m *testing.M) {
+	os.Exit(runWebhookSuite(m))
+}
+
+// runWebhookSuite starts an envtest API server with the webhook
+// configurations installed, serves the webhooks from a manager and runs the
+// tests against it.
+func runWebhookSuite(m *testing.M) int {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to add waf scheme: %v\n", err)
+		return 1
+	}
+
+	testEnv = &envtest.Environment{
+		CRDInstallOptions: envtest.CRDInstallOptions{
+			Paths:           []string{filepath.Join("..", "..", "config", "crd", "bases")},
+			CleanUpAfterUse: true,
+		},
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			Paths: []string{filepath.Join("..", "..", "config", "webhook", "manifests.yaml")},
+		},
+		Scheme:                      scheme,
+		DownloadBinaryAssets:        true,
+		DownloadBinaryAssetsVersion: os.Getenv("K8S_VERSION"),
+		ErrorIfCRDPathMissing:       true,
+	}
+	cfg, err := testEnv.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start test environment: %v\n", err)
+		return 1
+	}
+	defer func() {
+		if err := testEnv.Stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to stop test environment: %v\n", err)
+		}
+	}()
+
+	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create client: %v\n", err)
+		return 1
+	}
+
+	webhookOpts := &testEnv.WebhookInstallOptions
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme: scheme,
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Host:    webhookOpts.LocalServingHost,
+			Port:    webhookOpts.LocalServingPort,
+			CertDir: webhookOpts.LocalServingCertDir,
+		}),
+		Metrics:        metricsserver.Options{BindAddress: "0"},
+		LeaderElection: false,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create manager: %v\n", err)
+		return 1
+	}
+	if err := SetupEngineWebhookWithManager(mgr); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to setup Engine webhook: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := mgr.Start(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Manager stopped: %v\n", err)
+		}
+	}()
+
+	if err := waitForWebhookServer(webhookOpts, 10*time.Second); err != nil {
+		fmt.Fprintf(os.Stderr, "Webhook server did not start: %v\n", err)
+		return 1
+	}
+
+	return m.Run()
+}
+
+// waitForWebhookServer waits until the webhook server accepts TLS
+// connections, or the timeout expires.
+func waitForWebhookServer(opts *envtest.WebhookInstallOptions, timeout time.Duration) error {
+	addr := net.JoinHostPort(opts.LocalServingHost, fmt.Sprintf("%d", opts.LocalServingPort))
+	dialer := &net.Dialer{Timeout: time.Second}
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{InsecureSkipVerify: true})
+		if err == nil {
+			return conn.Close()
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -21,10 +21,34 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CRSPlugin) DeepCopyInto(out *CRSPlugin) {
+	*out = *in
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CRSPlugin.
+func (in *CRSPlugin) DeepCopy() *CRSPlugin {
+	if in == nil {
+		return nil
+	}
+	out := new(CRSPlugin)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DriverConfig) DeepCopyInto(out *DriverConfig) {
 	*out = *in
@@ -33,6 +57,11 @@ func (in *DriverConfig) DeepCopyInto(out *DriverConfig) {
 		*out = new(IstioDriverConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Envoy != nil {
+		in, out := &in.Envoy, &out.Envoy
+		*out = new(EnvoyDriverConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriverConfig.
@@ -108,7 +137,17 @@ func (in *EngineList) DeepCopyObject() runtime.Object {
 func (in *EngineSpec) DeepCopyInto(out *EngineSpec) {
 	*out = *in
 	out.RuleSet = in.RuleSet
+	if in.RuleSets != nil {
+		in, out := &in.RuleSets, &out.RuleSets
+		*out = make([]RuleSetReference, len(*in))
+		copy(*out, *in)
+	}
 	in.Driver.DeepCopyInto(&out.Driver)
+	if in.Priority != nil {
+		in, out := &in.Priority, &out.Priority
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EngineSpec.
@@ -131,6 +170,20 @@ func (in *EngineStatus) DeepCopyInto(out *EngineStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.OwnedResources != nil {
+		in, out := &in.OwnedResources, &out.OwnedResources
+		*out = make([]corev1.ObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.TopMatchedRules != nil {
+		in, out := &in.TopMatchedRules, &out.TopMatchedRules
+		*out = make([]RuleMatchCount, len(*in))
+		copy(*out, *in)
+	}
+	if in.MatchStatisticsUpdated != nil {
+		in, out := &in.MatchStatisticsUpdated, &out.MatchStatisticsUpdated
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EngineStatus.
@@ -143,6 +196,57 @@ func (in *EngineStatus) DeepCopy() *EngineStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvoyDriverConfig) DeepCopyInto(out *EnvoyDriverConfig) {
+	*out = *in
+	if in.ExtProc != nil {
+		in, out := &in.ExtProc, &out.ExtProc
+		*out = new(EnvoyExtProcConfig)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvoyDriverConfig.
+func (in *EnvoyDriverConfig) DeepCopy() *EnvoyDriverConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvoyDriverConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvoyExtProcConfig) DeepCopyInto(out *EnvoyExtProcConfig) {
+	*out = *in
+	out.Service = in.Service
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvoyExtProcConfig.
+func (in *EnvoyExtProcConfig) DeepCopy() *EnvoyExtProcConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvoyExtProcConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvoyExtProcService) DeepCopyInto(out *EnvoyExtProcService) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvoyExtProcService.
+func (in *EnvoyExtProcService) DeepCopy() *EnvoyExtProcService {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvoyExtProcService)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IstioDriverConfig) DeepCopyInto(out *IstioDriverConfig) {
 	*out = *in
@@ -192,6 +296,13 @@ func (in *IstioWasmConfig) DeepCopyInto(out *IstioWasmConfig) {
 		*out = new(RuleSetCacheServerConfig)
 		**out = **in
 	}
+	if in.PluginConfigOverrides != nil {
+		in, out := &in.PluginConfigOverrides, &out.PluginConfigOverrides
+		*out = make(map[string]apiextensionsv1.JSON, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioWasmConfig.
@@ -204,6 +315,43 @@ func (in *IstioWasmConfig) DeepCopy() *IstioWasmConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolvedSource) DeepCopyInto(out *ResolvedSource) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolvedSource.
+func (in *ResolvedSource) DeepCopy() *ResolvedSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolvedSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuleMatchCount) DeepCopyInto(out *RuleMatchCount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuleMatchCount.
+func (in *RuleMatchCount) DeepCopy() *RuleMatchCount {
+	if in == nil {
+		return nil
+	}
+	out := new(RuleMatchCount)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RuleSet) DeepCopyInto(out *RuleSet) {
 	*out = *in
@@ -301,6 +449,13 @@ func (in *RuleSetSpec) DeepCopyInto(out *RuleSetSpec) {
 		*out = make([]RuleSourceReference, len(*in))
 		copy(*out, *in)
 	}
+	if in.Plugins != nil {
+		in, out := &in.Plugins, &out.Plugins
+		*out = make([]CRSPlugin, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuleSetSpec.
@@ -323,6 +478,23 @@ func (in *RuleSetStatus) DeepCopyInto(out *RuleSetStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RuleCount != nil {
+		in, out := &in.RuleCount, &out.RuleCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AggregatedBytes != nil {
+		in, out := &in.AggregatedBytes, &out.AggregatedBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ResolvedSources != nil {
+		in, out := &in.ResolvedSources, &out.ResolvedSources
+		*out = make([]ResolvedSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuleSetStatus.
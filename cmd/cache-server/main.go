@@ -0,0 +1,117 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command cache-server runs the RuleSet cache server standalone, without the
+// controller manager. Rules are fed into it by external sources through the
+// authenticated admin API (PUT /rules/{instance}).
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/go-logr/logr"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets/cache"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "cache-server: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the given command line arguments and serves the cache until ctx
+// is cancelled.
+func run(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("cache-server", flag.ContinueOnError)
+	addr := fs.String("bind-address", fmt.Sprintf(":%d", cache.DefaultPort), "The address the cache server binds to")
+	gcInterval := fs.Duration("cache-gc-interval", cache.CacheGCInterval, "How often to check for and remove stale cache entries")
+	maxAge := fs.Duration("cache-max-age", cache.CacheMaxAge, "Maximum age of a cache entry before it's considered stale")
+	maxSize := fs.Int("cache-max-size", cache.CacheMaxSize, fmt.Sprintf("Maximum total size of all cached rules in bytes (default %dMB)", cache.CacheMaxSize/(1024*1024)))
+	maxVersions := fs.Int("cache-max-versions", cache.CacheMaxVersionsPerInstance, "Maximum number of versions retained per instance. Zero means unlimited")
+	requestTimeout := fs.Duration("request-timeout", cache.DefaultRequestTimeout, "Maximum time to respond to a request before failing it with 503. Zero disables the deadline")
+	maxConnections := fs.Int("max-connections", 0, "Maximum number of concurrently open connections. Zero leaves connections unlimited")
+	grpcAddr := fs.String("grpc-addr", "", "If set, the address to additionally serve the gRPC service on, which pushes new versions of rules to watching clients")
+	tlsCertFile := fs.String("tls-cert-file", "", "If set along with --tls-key-file, the cache server is served over HTTPS with this certificate")
+	tlsKeyFile := fs.String("tls-key-file", "", "The private key for --tls-cert-file")
+	adminTokenFile := fs.String("admin-token-file", "", "File containing the bearer token required by the admin endpoints (PUT and DELETE /rules/{instance}) (required)")
+	authTokenFile := fs.String("auth-token-file", "", "If set, requests to the rules endpoints must bear the token in this file as an \"Authorization: Bearer\" header")
+	verbosity := fs.Int("v", 0, fmt.Sprintf("Log verbosity. At %d or more, each request is logged along with its request ID", cache.AccessLogVerbosity))
+	snapshotPath := fs.String("snapshot-path", "", "If set, the cache is restored from this file at startup and snapshotted to it on graceful shutdown")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *adminTokenFile == "" {
+		return errors.New("--admin-token-file is required")
+	}
+	if (*tlsCertFile == "") != (*tlsKeyFile == "") {
+		return errors.New("--tls-cert-file and --tls-key-file must be set together")
+	}
+	gc := &cache.GarbageCollectionConfig{
+		GCInterval:             *gcInterval,
+		MaxAge:                 *maxAge,
+		MaxSize:                *maxSize,
+		MaxVersionsPerInstance: *maxVersions,
+	}
+	if err := gc.Validate(); err != nil {
+		return err
+	}
+
+	token, err := cache.LoadToken(*adminTokenFile)
+	if err != nil {
+		return err
+	}
+	var authToken string
+	if *authTokenFile != "" {
+		if authToken, err = cache.LoadToken(*authTokenFile); err != nil {
+			return err
+		}
+	}
+
+	logger := logr.FromSlogHandler(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.Level(-*verbosity)}))
+
+	rulesetCache := cache.NewRuleSetCache()
+	if *snapshotPath != "" {
+		if err := rulesetCache.Restore(*snapshotPath, logger); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	server := cache.NewServer(rulesetCache, *addr, logger, gc).WithAdminToken(token).WithAuthToken(authToken).WithRequestTimeout(*requestTimeout).WithMaxConnections(*maxConnections)
+	if *tlsCertFile != "" {
+		server.WithTLS(*tlsCertFile, *tlsKeyFile)
+	}
+	if *grpcAddr != "" {
+		server.WithGRPC(*grpcAddr)
+	}
+	if *snapshotPath != "" {
+		server.WithSnapshotPath(*snapshotPath)
+	}
+
+	return server.Start(ctx)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets/cache"
+)
+
+func TestRun_PutAndGetRules(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret\n"), 0o600))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	t.Log("Starting the standalone cache server")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- run(ctx, []string{"--bind-address", addr, "--admin-token-file", tokenFile})
+	}()
+
+	// Connections aren't kept alive, so that no idle connection delays the
+	// graceful shutdown of the server.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	baseURL := "http://" + addr + "/rules/default/standalone"
+	require.Eventually(t, func() bool {
+		resp, err := client.Get(baseURL)
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode == http.StatusNotFound
+	}, 5*time.Second, 50*time.Millisecond, "cache server should start serving")
+
+	t.Log("Verifying unauthenticated puts are rejected")
+	req, err := http.NewRequest(http.MethodPut, baseURL, strings.NewReader("SecRuleEngine On"))
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	t.Log("Putting rules through the admin API")
+	req, err = http.NewRequest(http.MethodPut, baseURL, strings.NewReader("SecRuleEngine On"))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	t.Log("Getting the rules back")
+	resp, err = client.Get(baseURL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "SecRuleEngine On")
+
+	t.Log("Stopping the cache server")
+	cancel()
+	select {
+	case err := <-errChan:
+		require.NoError(t, err)
+	case <-time.After(cache.GracefulShutdownTimeout + time.Second):
+		t.Fatal("cache server did not shut down in time")
+	}
+}
+
+func TestRun_RequiresAdminToken(t *testing.T) {
+	err := run(context.Background(), []string{"--bind-address", "127.0.0.1:0"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--admin-token-file is required")
+}
+
+func TestRun_RejectsInvalidGC(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret"), 0o600))
+
+	err := run(context.Background(), []string{"--bind-address", "127.0.0.1:0", "--admin-token-file", tokenFile, "--cache-gc-interval", "0s"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cache GC interval must be positive")
+}
@@ -22,6 +22,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
@@ -80,8 +81,27 @@ func main() {
 	var cacheGCInterval time.Duration
 	var cacheMaxAge time.Duration
 	var cacheMaxSize int
+	var cacheMaxVersions int
 	var cacheServerPort int
 	var envoyClusterName string
+	var engineBackoffBase time.Duration
+	var engineBackoffMax time.Duration
+	var cacheReadinessGate bool
+	var cacheSnapshotPath string
+	var validateAggregatedRules bool
+	var strictRuleValidation bool
+	var cacheAdminTokenFile string
+	var cacheAuthTokenFile string
+	var provenanceLabelKeys string
+	var cacheRequestTimeout time.Duration
+	var cacheMaxConnections int
+	var cacheGRPCPort int
+	var fieldManager string
+	var enableURLRuleSources bool
+	var urlRuleSourceRefreshInterval time.Duration
+	var urlRuleSourceAllowedHosts string
+	var enableWebhooks bool
+	var maxRuleSetSize int
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -97,8 +117,27 @@ func main() {
 	flag.DurationVar(&cacheGCInterval, "cache-gc-interval", cache.CacheGCInterval, "How often to check for and remove stale cache entries in the RuleSet cache")
 	flag.DurationVar(&cacheMaxAge, "cache-max-age", cache.CacheMaxAge, "Maximum age of a cache entry before it's considered stale in the RuleSet cache")
 	flag.IntVar(&cacheMaxSize, "cache-max-size", cache.CacheMaxSize, fmt.Sprintf("Maximum total size of all cached rules in the RuleSet cache in bytes (default %dMB)", cache.CacheMaxSize/(1024*1024)))
+	flag.IntVar(&cacheMaxVersions, "cache-max-versions", cache.CacheMaxVersionsPerInstance, "Maximum number of versions retained per RuleSet in the RuleSet cache. Zero means unlimited")
+	flag.IntVar(&maxRuleSetSize, "max-ruleset-size", controller.DefaultMaxRuleSetSize, fmt.Sprintf("Maximum size in bytes of the aggregated rules of a RuleSet. Larger RuleSets are Degraded and their last cached rules kept. Zero means unlimited (default %dMB)", controller.DefaultMaxRuleSetSize/(1024*1024)))
 	flag.IntVar(&cacheServerPort, "cache-server-port", controller.DefaultRuleSetCacheServerPort, fmt.Sprintf("Port number for the RuleSet cache server to listen on (default %d)", controller.DefaultRuleSetCacheServerPort))
+	flag.DurationVar(&cacheRequestTimeout, "cache-request-timeout", cache.DefaultRequestTimeout, "Maximum time the RuleSet cache server may take to respond to a request before failing it with 503. Zero disables the deadline")
+	flag.IntVar(&cacheMaxConnections, "cache-max-connections", 0, "Maximum number of concurrently open connections to the RuleSet cache server. Zero leaves connections unlimited")
+	flag.IntVar(&cacheGRPCPort, "cache-grpc-port", 0, "If set, the RuleSet cache server additionally serves its gRPC service, which pushes new versions of rules to watching clients, on this port")
 	flag.StringVar(&envoyClusterName, "envoy-cluster-name", "", "The Envoy cluster name pointing to the RuleSet cache server (required)")
+	flag.BoolVar(&cacheReadinessGate, "cache-readiness-gate", false, "If set, the RuleSet cache server responds with 503 until all existing RuleSets have been reconciled at startup")
+	flag.StringVar(&cacheSnapshotPath, "cache-snapshot-path", "", "If set, the RuleSet cache is restored from this file at startup, dropping the entries of RuleSets deleted meanwhile, and snapshotted to it on graceful shutdown")
+	flag.BoolVar(&validateAggregatedRules, "validate-aggregated-rules", true, "If set, the aggregated rules of each RuleSet, and of each Engine with multiple RuleSets, are compiled with Coraza before being cached, unless a source opted out of validation. This catches errors that only appear when sources are combined, at the cost of extra CPU and memory when rules change")
+	flag.BoolVar(&strictRuleValidation, "strict-rule-validation", false, "If set, rule validation warnings (e.g. multiple disruptive actions on a rule or an unsupported operator) are treated as errors and the RuleSet is Degraded. Otherwise warnings are only reported as events")
+	flag.StringVar(&cacheAdminTokenFile, "cache-admin-token-file", "", "If set, enables the RuleSet cache server admin endpoints (PUT and DELETE /rules/{instance}) for requests bearing the token in this file")
+	flag.StringVar(&cacheAuthTokenFile, "cache-auth-token-file", "", "If set, requests to the RuleSet cache server rules endpoints must bear the token in this file (e.g. a mounted Secret key) as an \"Authorization: Bearer\" header. Each Engine's data plane is given a token derived from it which only grants access to the Engine's rules. Secret rule sources are only accepted if it is set, as the cache server serves rules in plaintext")
+	flag.StringVar(&provenanceLabelKeys, "provenance-label-keys", "", "Comma-separated list of ConfigMap label keys (e.g. a CRS release label) to record in RuleSet status.resolvedSources for provenance. No labels are recorded when empty")
+	flag.StringVar(&fieldManager, "field-manager", controller.DefaultFieldManager, "The server-side apply field manager name used for resources managed by the operator. Set distinct names to run multiple operator instances side by side")
+	flag.BoolVar(&enableURLRuleSources, "enable-url-rule-sources", false, "If set, RuleSets may load rules from URL sources over HTTP(S). This requires network egress from the operator")
+	flag.StringVar(&urlRuleSourceAllowedHosts, "url-rule-source-allowed-hosts", "", "Comma-separated list of hosts (e.g. raw.githubusercontent.com) URL rule sources may be fetched from, including when following redirects. Any host is allowed when empty")
+	flag.DurationVar(&urlRuleSourceRefreshInterval, "url-rule-source-refresh-interval", controller.DefaultURLRuleSourceRefreshInterval, "How often URL rule sources are fetched again, and RuleSets using them are requeued to pick up changes")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "If set, the Engine defaulting and validating admission webhooks are served. This requires a webhook certificate (see --webhook-cert-path) and the webhook configurations in config/webhook")
+	flag.DurationVar(&engineBackoffBase, "engine-backoff-base-delay", controller.DefaultRateLimiterBaseDelay, "Initial delay before retrying a failed Engine reconciliation")
+	flag.DurationVar(&engineBackoffMax, "engine-backoff-max-delay", controller.DefaultRateLimiterMaxDelay, "Maximum delay between retries of a failed Engine reconciliation")
 
 	opts := zap.Options{
 		Development: true,
@@ -114,6 +153,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	if maxRuleSetSize < 0 {
+		setupLog.Error(errors.New("invalid flag value"), "max-ruleset-size must not be negative")
+		os.Exit(1)
+	}
+
+	if engineBackoffBase <= 0 || engineBackoffMax < engineBackoffBase {
+		setupLog.Error(errors.New("invalid flag value"), "engine-backoff-base-delay must be positive and not exceed engine-backoff-max-delay")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -202,23 +251,95 @@ func main() {
 
 	// set up the ruleset cache and start the cache server
 	rulesetCache := cache.NewRuleSetCache()
+	if cacheSnapshotPath != "" {
+		if err := rulesetCache.Restore(cacheSnapshotPath, setupLog); err != nil && !errors.Is(err, os.ErrNotExist) {
+			setupLog.Error(err, "unable to restore ruleset cache snapshot", "path", cacheSnapshotPath)
+			os.Exit(1)
+		}
+	}
 	cacheGC := &cache.GarbageCollectionConfig{
-		GCInterval: cacheGCInterval,
-		MaxAge:     cacheMaxAge,
-		MaxSize:    cacheMaxSize,
+		GCInterval:             cacheGCInterval,
+		MaxAge:                 cacheMaxAge,
+		MaxSize:                cacheMaxSize,
+		MaxVersionsPerInstance: cacheMaxVersions,
+	}
+	if err := cacheGC.Validate(); err != nil {
+		setupLog.Error(err, "invalid cache garbage collection configuration")
+		os.Exit(1)
+	}
+	cacheServer := cache.NewServer(rulesetCache, fmt.Sprintf(":%d", cacheServerPort), ctrl.Log, cacheGC).
+		WithRequestTimeout(cacheRequestTimeout).
+		WithMaxConnections(cacheMaxConnections).
+		WithOverCapacityHandler(controller.CacheOverCapacityEvents(mgr.GetClient(), mgr.GetEventRecorder("ruleset-cache")))
+	if cacheGRPCPort != 0 {
+		cacheServer.WithGRPC(fmt.Sprintf(":%d", cacheGRPCPort))
+	}
+	var cacheReadiness *cache.ReadinessGate
+	if cacheReadinessGate {
+		cacheReadiness = cache.NewReadinessGate()
+		cacheServer.WithReadinessGate(cacheReadiness)
+	}
+	if cacheSnapshotPath != "" {
+		cacheServer.WithSnapshotPath(cacheSnapshotPath)
+	}
+	if cacheAdminTokenFile != "" {
+		token, err := cache.LoadToken(cacheAdminTokenFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load cache admin token", "path", cacheAdminTokenFile)
+			os.Exit(1)
+		}
+		cacheServer.WithAdminToken(token)
+	}
+	var cacheAuthToken string
+	if cacheAuthTokenFile != "" {
+		cacheAuthToken, err = cache.LoadToken(cacheAuthTokenFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load cache auth token", "path", cacheAuthTokenFile)
+			os.Exit(1)
+		}
+		cacheServer.WithAuthToken(cacheAuthToken)
 	}
-	cacheServer := cache.NewServer(rulesetCache, fmt.Sprintf(":%d", cacheServerPort), ctrl.Log, cacheGC)
 	if err := mgr.Add(cacheServer); err != nil {
 		setupLog.Error(err, "unable to add cache server to manager")
 		os.Exit(1)
 	}
 
 	// set up controllers
-	if err := controller.SetupControllers(mgr, rulesetCache, envoyClusterName); err != nil {
+	engineRateLimiter := &controller.RateLimiterConfig{
+		BaseDelay: engineBackoffBase,
+		MaxDelay:  engineBackoffMax,
+	}
+	var urlRuleSources *controller.URLRuleSourceConfig
+	if enableURLRuleSources {
+		urlRuleSources = controller.DefaultURLRuleSourceConfig()
+		urlRuleSources.RefreshInterval = urlRuleSourceRefreshInterval
+		urlRuleSources.AllowedHosts = splitList(urlRuleSourceAllowedHosts)
+	}
+	if err := controller.SetupControllers(mgr, controller.ControllerOptions{
+		RuleSetCache:            rulesetCache,
+		EnvoyClusterName:        envoyClusterName,
+		EngineRateLimiter:       engineRateLimiter,
+		CacheReadiness:          cacheReadiness,
+		ValidateAggregatedRules: validateAggregatedRules,
+		StrictRuleValidation:    strictRuleValidation,
+		ProvenanceLabelKeys:     splitList(provenanceLabelKeys),
+		FieldManager:            fieldManager,
+		CacheAuthToken:          cacheAuthToken,
+		URLRuleSources:          urlRuleSources,
+		MaxRuleSetSize:          maxRuleSetSize,
+	}); err != nil {
 		setupLog.Error(err, "unable to setup controllers")
 		os.Exit(1)
 	}
 
+	// set up webhooks
+	if enableWebhooks {
+		if err := wafv1alpha1.SetupEngineWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to setup Engine webhook")
+			os.Exit(1)
+		}
+	}
+
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -236,3 +357,15 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitList splits a comma-separated list (e.g. of label keys), dropping
+// empty entries.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
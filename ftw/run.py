@@ -4,12 +4,12 @@ import subprocess
 import json
 import sys
 import os
-import threading
 import time
 import socket
 import urllib.request
 import urllib.error
 import tempfile
+import xml.etree.ElementTree as ET
 import yaml
 from contextlib import closing
 
@@ -115,30 +115,224 @@ class KubeHelper:
             check=False
         )
 
-    def stream_pod_logs(self, label_selector, output_file):
+    def get_gateway_pod(self, gateway_name):
         """
-        Stream logs from pods matching a label selector to a file (blocking call, should be run in a thread).
+        Get the name of a Running pod of a gateway.
+
+        Returns:
+            str: Name of the first Running pod, which isn't being deleted,
+                 with the gateway's gateway.networking.k8s.io/gateway-name
+                 label.
+        """
+        label_selector = f"gateway.networking.k8s.io/gateway-name={gateway_name}"
+        try:
+            result = self.kubectl("get", "pods", "-l", label_selector, "-o", "json")
+            data = json.loads(result.stdout)
+        except subprocess.CalledProcessError as e:
+            print(f"Error executing kubectl: {e.stderr}", file=sys.stderr)
+            sys.exit(1)
+        except json.JSONDecodeError as e:
+            print(f"Error parsing kubectl output: {e}", file=sys.stderr)
+            sys.exit(1)
+
+        pod_name = select_running_pod(data.get("items", []))
+        if pod_name is None:
+            print(f"Error: No Running pod found with label {label_selector} in namespace {self.namespace}", file=sys.stderr)
+            sys.exit(1)
+
+        return pod_name
+
+    def start_pod_log_stream(self, pod_name, output_file):
+        """
+        Start streaming logs from a pod to a file.
 
         Args:
-            label_selector: Label selector to find the pods (e.g., "gateway.networking.k8s.io/gateway-name=mygateway")
-            output_file: File path to write logs to
+            pod_name: Name of the pod to stream logs from
+            output_file: Open file to write logs to
+        Returns:
+            subprocess.Popen: The kubectl process streaming the logs, which
+                              runs until it is stopped or the pod goes away.
         """
-        with open(output_file, 'w', buffering=1) as f:
-            process = subprocess.Popen(
-                [
-                    "kubectl",
-                    "--kubeconfig", self.kubeconfig,
-                    "-n", self.namespace,
-                    "logs",
-                    "-l", label_selector,
-                    "-f",
-                    "--all-containers=true"
-                ],
-                stdout=f,
-                stderr=subprocess.STDOUT,
-                text=True
-            )
-            process.wait()
+        return subprocess.Popen(
+            [
+                "kubectl",
+                "--kubeconfig", self.kubeconfig,
+                "-n", self.namespace,
+                "logs",
+                f"pod/{pod_name}",
+                "-f",
+                "--all-containers=true"
+            ],
+            stdout=output_file,
+            stderr=subprocess.STDOUT,
+            text=True
+        )
+
+
+def log_stream_error(process):
+    """
+    Check that a log stream is still running.
+
+    kubectl stops streaming when the pod restarts or is deleted, after which
+    the logs of the remaining requests are missing and log based test results
+    are unreliable.
+
+    Args:
+        process: The kubectl process streaming the logs
+
+    Returns:
+        str: Description of why the stream ended, or None if it is still running.
+    """
+    returncode = process.poll()
+    if returncode is None:
+        return None
+    return f"log stream ended before the FTW run completed (kubectl exit code: {returncode})"
+
+
+def check_log_stream(process, exit_code):
+    """
+    Report a log stream which ended before the FTW run completed.
+
+    Args:
+        process: The kubectl process streaming the logs
+        exit_code: Exit code of the FTW run
+
+    Returns:
+        int: The exit code to exit with, which is non-zero if the log stream
+             ended, as the results may then be unreliable.
+    """
+    log_error = log_stream_error(process)
+    if log_error is None:
+        return exit_code
+    print(f"ERROR: {log_error}, results may be unreliable", file=sys.stderr)
+    return exit_code or 1
+
+
+def stop_process(process, name):
+    """
+    Stop a process, killing it if it doesn't terminate within 5 seconds.
+
+    Args:
+        process: The process to stop
+        name: Name of the process for logging
+    """
+    if process.poll() is not None:
+        return
+    print(f"\nStopping {name}...")
+    process.terminate()
+    try:
+        process.wait(timeout=5)
+    except subprocess.TimeoutExpired:
+        print(f"{name} did not stop gracefully, killing...")
+        process.kill()
+        process.wait()
+
+
+def select_running_pod(pods):
+    """
+    Select the pod to stream logs from.
+
+    Args:
+        pods: Pod objects, as listed by kubectl in JSON
+
+    Returns:
+        str: Name of the first pod in the Running phase which isn't being
+             deleted, or None if there is none.
+    """
+    for pod in pods:
+        metadata = pod.get("metadata", {})
+        # terminating pods are still Running until their containers exit
+        if metadata.get("deletionTimestamp"):
+            continue
+        if pod.get("status", {}).get("phase") == "Running":
+            return metadata.get("name")
+    return None
+
+
+def resolve_destination(dest_addr, port, service_ip_or_type, service_port):
+    """
+    Resolve the address and port to send test requests to.
+
+    Args:
+        dest_addr: Address provided with --dest-addr, or None
+        port: Port provided with --port, or None
+        service_ip_or_type: LoadBalancer IP or "ClusterIP" of the gateway's service, or None if not looked up
+        service_port: HTTP port of the gateway's service, or None if not looked up
+
+    Returns:
+        tuple: (ip_or_type, port) where ip_or_type is "ClusterIP" if the
+               service must be port-forwarded to.
+    """
+    ip_or_type = dest_addr or service_ip_or_type
+    if port is None:
+        port = service_port if service_port is not None else 80
+    return ip_or_type, port
+
+
+def parse_ftw_stats(output):
+    """
+    Parse the run statistics from go-ftw's JSON output.
+
+    Args:
+        output: Output of go-ftw run with "--output json"
+
+    Returns:
+        dict: The run statistics, with the test ids of each result (e.g. "success", "failed").
+
+    Raises:
+        ValueError: If the output doesn't contain the run statistics.
+    """
+    for line in reversed(output.splitlines()):
+        line = line.strip()
+        if not line.startswith("{"):
+            continue
+        stats = json.loads(line)
+        if "run" in stats:
+            return stats
+    raise ValueError("no run statistics found in go-ftw output")
+
+
+def write_junit(stats, output_file):
+    """
+    Write go-ftw run statistics as a JUnit XML report.
+
+    Each test is a testcase of the "ftw" testsuite: failed and forced-fail
+    tests are reported as failures, and skipped and ignored tests as skipped.
+
+    Args:
+        stats: Run statistics, as returned by parse_ftw_stats
+        output_file: File path to write the report to
+    """
+    results = [
+        ("success", None),
+        ("forced-pass", None),
+        ("failed", "failure"),
+        ("forced-fail", "failure"),
+        ("skipped", "skipped"),
+        ("ignored", "skipped"),
+    ]
+    runtime = stats.get("runtime") or {}
+
+    suite = ET.Element("testsuite", name="ftw")
+    counts = {"tests": 0, "failures": 0, "skipped": 0}
+    for result, element in results:
+        for test_id in sorted(stats.get(result) or []):
+            # go-ftw reports durations in nanoseconds
+            seconds = runtime.get(test_id, 0) / 1e9
+            case = ET.SubElement(suite, "testcase", classname="ftw", name=test_id, time=f"{seconds:.3f}")
+            counts["tests"] += 1
+            if element == "failure":
+                ET.SubElement(case, "failure", message=f"test {result}")
+                counts["failures"] += 1
+            elif element == "skipped":
+                ET.SubElement(case, "skipped", message=f"test {result}")
+                counts["skipped"] += 1
+
+    for key, count in counts.items():
+        suite.set(key, str(count))
+    suite.set("time", f"{stats.get('TotalTime', 0) / 1e9:.3f}")
+
+    ET.ElementTree(suite).write(output_file, encoding="utf-8", xml_declaration=True)
 
 
 def find_free_port():
@@ -196,22 +390,31 @@ def main():
     parser.add_argument("--rules-directory", required=True, help="Rules directory")
     parser.add_argument("--kubeconfig", required=True, help="Kubeconfig file location")
     parser.add_argument("--output-log", required=False, help="Output for execution log. If empty will output to stdout")
-    parser.add_argument("--output-format", required=False, help="Output format for execution log. If empty will use the default")
+    parser.add_argument("--output-format", required=False, help="Output format for execution log (e.g. quiet, json, junit). If empty will use the default")
+    parser.add_argument("--keep-logs", action="store_true", help="Keep the streamed gateway logs after the run instead of removing them")
+    parser.add_argument("--dest-addr", required=False, help="Address to send test requests to. If empty will be resolved from the gateway's service")
+    parser.add_argument("--port", required=False, type=int, help="Port to send test requests to. If empty will be resolved from the gateway's service")
 
     args = parser.parse_args()
 
     # Initialize Kubernetes helper
     kube = KubeHelper(args.namespace, args.kubeconfig)
 
-    # Get service information
-    ip_or_type, port, service_name = kube.get_gateway_service_info(args.gateway)
+    # Get service information, unless the destination was provided
+    service_name = None
+    service_ip_or_type, service_port = None, None
+    if not args.dest_addr:
+        service_ip_or_type, service_port, service_name = kube.get_gateway_service_info(args.gateway)
 
-    print(f"Service Name: {service_name}")
-    print(f"Service IP/Type: {ip_or_type}")
-    print(f"Service Port: {port}")
+        print(f"Service Name: {service_name}")
+        print(f"Service IP/Type: {service_ip_or_type}")
+        print(f"Service Port: {service_port}")
 
     # Determine target host and port for testing
     port_forward_process = None
+    log_process = None
+    log_file = None
+    ip_or_type, port = resolve_destination(args.dest_addr, args.port, service_ip_or_type, service_port)
     target_host = ip_or_type
     target_port = port
 
@@ -255,18 +458,12 @@ def main():
         print("="*60 + "\n")
 
         # Set up log streaming
-        log_file = tempfile.NamedTemporaryFile(mode='w', prefix='ftw_logs_', suffix='.log', delete=False)
+        log_file = tempfile.NamedTemporaryFile(mode='w', prefix='ftw_logs_', suffix='.log', delete=False, buffering=1)
         log_filename = log_file.name
-        log_file.close()  # Close it so kubectl can write to it
 
-        print(f"Streaming pod logs to: {log_filename}")
-
-        log_thread = threading.Thread(
-            target=kube.stream_pod_logs,
-            args=(f"gateway.networking.k8s.io/gateway-name={args.gateway}", log_filename),
-            daemon=True
-        )
-        log_thread.start()
+        pod_name = kube.get_gateway_pod(args.gateway)
+        print(f"Streaming logs of pod {pod_name} to: {log_filename}")
+        log_process = kube.start_pod_log_stream(pod_name, log_file)
 
         # Wait for log streaming to start with a readiness check and configurable timeout
         log_start_timeout = float(os.getenv("FTW_LOG_START_TIMEOUT_SECONDS", "5"))
@@ -347,11 +544,19 @@ def main():
             "--read-timeout", "10s"
         ]
 
-        if args.output_log:
-            ftw_cmd += ["-f", args.output_log]
-        
-        if args.output_format:
-            ftw_cmd += ["--output", args.output_format]
+        # go-ftw doesn't support JUnit, so convert its JSON results instead
+        junit_filename = None
+        if args.output_format == "junit":
+            junit_filename = args.output_log or "ftw-results.xml"
+            json_file = tempfile.NamedTemporaryFile(mode='w', prefix='ftw_results_', suffix='.json', delete=False)
+            json_file.close()
+            ftw_cmd += ["-f", json_file.name, "--output", "json"]
+        else:
+            if args.output_log:
+                ftw_cmd += ["-f", args.output_log]
+
+            if args.output_format:
+                ftw_cmd += ["--output", args.output_format]
 
         print(f"Configuration:")
         print(f"  Target: {target_host}:{target_port}")
@@ -360,10 +565,26 @@ def main():
         print(f"Executing: {' '.join(ftw_cmd)}\n")
 
         ftw_result = subprocess.run(ftw_cmd)
+        exit_code = ftw_result.returncode
+
+        exit_code = check_log_stream(log_process, exit_code)
+
+        if junit_filename:
+            try:
+                with open(json_file.name, 'r') as f:
+                    stats = parse_ftw_stats(f.read())
+                write_junit(stats, junit_filename)
+                print(f"JUnit results written to: {junit_filename}")
+            except (OSError, ValueError) as e:
+                print(f"ERROR: Failed to write JUnit results: {e}", file=sys.stderr)
+                sys.exit(1)
+            finally:
+                os.unlink(json_file.name)
 
         print(f"\n" + "="*60)
         print(f"FTW tests completed with exit code: {ftw_result.returncode}")
-        print(f"Logs saved to: {log_filename}")
+        if args.keep_logs:
+            print(f"Logs saved to: {log_filename}")
         print("="*60)
 
         # Clean up modified config file
@@ -372,19 +593,23 @@ def main():
         except Exception:
             pass
 
-        sys.exit(ftw_result.returncode)
+        sys.exit(exit_code)
 
     finally:
+        # Cleanup: stop log streaming and remove the logs unless kept
+        if log_process:
+            stop_process(log_process, "log streaming")
+        if log_file:
+            log_file.close()
+            if not args.keep_logs:
+                try:
+                    os.unlink(log_file.name)
+                except OSError:
+                    pass
+
         # Cleanup: stop port-forward if it was started
         if port_forward_process:
-            print("\nStopping port-forward...")
-            port_forward_process.terminate()
-            try:
-                port_forward_process.wait(timeout=5)
-            except subprocess.TimeoutExpired:
-                print("Port-forward did not stop gracefully, killing...")
-                port_forward_process.kill()
-                port_forward_process.wait()
+            stop_process(port_forward_process, "port-forward")
 
 
 if __name__ == "__main__":
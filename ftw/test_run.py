@@ -0,0 +1,209 @@
+#!/usr/bin/env python3
+"""Unit tests for the FTW runner, run with: python3 -m unittest discover -s ftw"""
+import contextlib
+import io
+import json
+import os
+import subprocess
+import sys
+import tempfile
+import unittest
+import xml.etree.ElementTree as ET
+from unittest import mock
+
+import run
+
+
+# Run statistics as printed by go-ftw with "--output json", durations in
+# nanoseconds.
+FTW_STATS = {
+    "run": 6,
+    "success": ["920100-1", "920100-2"],
+    "failed": ["932100-1"],
+    "skipped": ["941100-1"],
+    "ignored": ["942100-1"],
+    "forced-pass": [],
+    "forced-fail": ["949110-1"],
+    "runtime": {
+        "920100-1": 1500000000,
+        "920100-2": 250000000,
+        "932100-1": 2000000000,
+    },
+    "TotalTime": 3750000000,
+}
+
+
+class ParseFtwStatsTest(unittest.TestCase):
+    def test_parses_last_stats_line(self):
+        output = "\n".join([
+            "Running go-ftw!",
+            '{"level":"info","message":"loading tests"}',
+            json.dumps(FTW_STATS),
+            "",
+        ])
+        self.assertEqual(run.parse_ftw_stats(output), FTW_STATS)
+
+    def test_missing_stats(self):
+        with self.assertRaises(ValueError):
+            run.parse_ftw_stats('Running go-ftw!\n{"level":"info"}\n')
+
+
+class WriteJunitTest(unittest.TestCase):
+    def write_report(self, stats):
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "ftw-results.xml")
+            run.write_junit(stats, path)
+            return ET.parse(path).getroot()
+
+    def test_counts(self):
+        suite = self.write_report(FTW_STATS)
+        self.assertEqual(suite.tag, "testsuite")
+        self.assertEqual(suite.get("name"), "ftw")
+        self.assertEqual(suite.get("tests"), "6")
+        self.assertEqual(suite.get("failures"), "2")
+        self.assertEqual(suite.get("skipped"), "2")
+        self.assertEqual(suite.get("time"), "3.750")
+
+    def test_testcases(self):
+        suite = self.write_report(FTW_STATS)
+        cases = {case.get("name"): case for case in suite.findall("testcase")}
+        self.assertEqual(sorted(cases), sorted([
+            "920100-1", "920100-2", "932100-1", "941100-1", "942100-1", "949110-1",
+        ]))
+
+        passed = cases["920100-1"]
+        self.assertEqual(passed.get("classname"), "ftw")
+        self.assertEqual(passed.get("time"), "1.500")
+        self.assertEqual(list(passed), [])
+
+        for test_id, result in [("932100-1", "failed"), ("949110-1", "forced-fail")]:
+            failure = cases[test_id].find("failure")
+            self.assertIsNotNone(failure, test_id)
+            self.assertEqual(failure.get("message"), f"test {result}")
+
+        for test_id, result in [("941100-1", "skipped"), ("942100-1", "ignored")]:
+            skipped = cases[test_id].find("skipped")
+            self.assertIsNotNone(skipped, test_id)
+            self.assertEqual(skipped.get("message"), f"test {result}")
+            self.assertEqual(cases[test_id].get("time"), "0.000")
+
+    def test_empty_run(self):
+        suite = self.write_report({"run": 0, "success": None})
+        self.assertEqual(suite.get("tests"), "0")
+        self.assertEqual(suite.get("failures"), "0")
+        self.assertEqual(suite.get("skipped"), "0")
+        self.assertEqual(suite.findall("testcase"), [])
+
+
+def pod(name, phase, terminating=False):
+    """Build a pod as listed by kubectl in JSON."""
+    metadata = {"name": name}
+    if terminating:
+        metadata["deletionTimestamp"] = "2026-01-01T00:00:00Z"
+    return {"metadata": metadata, "status": {"phase": phase}}
+
+
+class ResolveDestinationTest(unittest.TestCase):
+    def test_dest_addr_overrides_service(self):
+        self.assertEqual(run.resolve_destination("10.0.0.5", None, None, None), ("10.0.0.5", 80))
+        self.assertEqual(run.resolve_destination("10.0.0.5", None, "ClusterIP", 8080), ("10.0.0.5", 8080))
+
+    def test_port_overrides_service_port(self):
+        self.assertEqual(run.resolve_destination(None, 9090, "192.0.2.10", 80), ("192.0.2.10", 9090))
+        self.assertEqual(run.resolve_destination("10.0.0.5", 9090, None, None), ("10.0.0.5", 9090))
+
+    def test_falls_back_to_service(self):
+        self.assertEqual(run.resolve_destination(None, None, "192.0.2.10", 8080), ("192.0.2.10", 8080))
+        self.assertEqual(run.resolve_destination(None, None, "ClusterIP", 80), ("ClusterIP", 80))
+
+
+class SelectRunningPodTest(unittest.TestCase):
+    def setUp(self):
+        self.kube = run.KubeHelper("ftw-test", "/tmp/kubeconfig")
+
+    def stub_pods(self, *pods):
+        """Stub kubectl to list the given pods."""
+        output = json.dumps({"kind": "List", "items": list(pods)})
+        completed = subprocess.CompletedProcess(args=["kubectl"], returncode=0, stdout=output, stderr="")
+        patcher = mock.patch.object(self.kube, "kubectl", return_value=completed)
+        kubectl = patcher.start()
+        self.addCleanup(patcher.stop)
+        return kubectl
+
+    def test_selects_running_pod(self):
+        kubectl = self.stub_pods(pod("gateway-a", "Running"), pod("gateway-b", "Running"))
+        self.assertEqual(self.kube.get_gateway_pod("gateway"), "gateway-a")
+        kubectl.assert_called_once_with(
+            "get", "pods", "-l", "gateway.networking.k8s.io/gateway-name=gateway", "-o", "json")
+
+    def test_skips_pods_not_running(self):
+        self.stub_pods(
+            pod("gateway-pending", "Pending"),
+            pod("gateway-failed", "Failed"),
+            pod("gateway-succeeded", "Succeeded"),
+            pod("gateway-running", "Running"),
+        )
+        self.assertEqual(self.kube.get_gateway_pod("gateway"), "gateway-running")
+
+    def test_skips_terminating_pods(self):
+        self.stub_pods(
+            pod("gateway-old", "Running", terminating=True),
+            pod("gateway-new", "Running"),
+        )
+        self.assertEqual(self.kube.get_gateway_pod("gateway"), "gateway-new")
+
+    def test_no_running_pod(self):
+        for pods in [
+            [],
+            [pod("gateway-pending", "Pending")],
+            [pod("gateway-old", "Running", terminating=True)],
+        ]:
+            with self.subTest(pods=pods):
+                self.stub_pods(*pods)
+                stderr = io.StringIO()
+                with contextlib.redirect_stderr(stderr), self.assertRaises(SystemExit) as raised:
+                    self.kube.get_gateway_pod("gateway")
+                self.assertEqual(raised.exception.code, 1)
+                self.assertIn(
+                    "No Running pod found with label gateway.networking.k8s.io/gateway-name=gateway in namespace ftw-test",
+                    stderr.getvalue())
+
+
+class LogStreamErrorTest(unittest.TestCase):
+    def start_stream(self, code):
+        """Start a process standing in for kubectl logs, running the given code."""
+        process = subprocess.Popen([sys.executable, "-c", code])
+        self.addCleanup(run.stop_process, process, "log stream")
+        return process
+
+    def test_running_stream(self):
+        process = self.start_stream("import time; time.sleep(60)")
+        self.assertIsNone(run.log_stream_error(process))
+
+        stderr = io.StringIO()
+        with contextlib.redirect_stderr(stderr):
+            self.assertEqual(run.check_log_stream(process, 0), 0)
+        self.assertEqual(stderr.getvalue(), "")
+
+    def test_stream_exited(self):
+        process = self.start_stream("import sys; sys.exit(3)")
+        process.wait(timeout=10)
+        self.assertEqual(
+            run.log_stream_error(process),
+            "log stream ended before the FTW run completed (kubectl exit code: 3)")
+
+        stderr = io.StringIO()
+        with contextlib.redirect_stderr(stderr):
+            self.assertEqual(run.check_log_stream(process, 0), 1)
+        self.assertIn("ERROR: log stream ended before the FTW run completed", stderr.getvalue())
+        self.assertIn("results may be unreliable", stderr.getvalue())
+
+    def test_stream_exited_keeps_ftw_failure(self):
+        process = self.start_stream("pass")
+        process.wait(timeout=10)
+        with contextlib.redirect_stderr(io.StringIO()):
+            self.assertEqual(run.check_log_stream(process, 2), 2)
+
+
+if __name__ == "__main__":
+    unittest.main()
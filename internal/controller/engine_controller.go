@@ -19,24 +19,29 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets/cache"
 )
 
 // -----------------------------------------------------------------------------
@@ -51,6 +56,10 @@ import (
 // Engine Controller
 // -----------------------------------------------------------------------------
 
+// engineFinalizer ensures the resources provisioned for a deleted Engine are
+// cleaned up by its driver before the Engine is removed.
+const engineFinalizer = "waf.k8s.coraza.io/engine-finalizer"
+
 // EngineReconciler reconciles an Engine object
 type EngineReconciler struct {
 	Scheme   *runtime.Scheme
@@ -58,36 +67,105 @@ type EngineReconciler struct {
 
 	client.Client
 	ruleSetCacheServerCluster string
+	rateLimiter               *RateLimiterConfig
+	matchStats                RuleMatchStatsSource
+	servedRules               ServedRulesSource
+	ruleSetCache              *cache.RuleSetCache
+	fieldManager              string
+
+	// cacheAuthToken, when set, is the token the cache server requires,
+	// from which the token of each Engine's data plane is derived.
+	cacheAuthToken string
+
+	// aggregateValidation, when set, compiles the aggregated rules of
+	// Engines with multiple RuleSets with Coraza before they're cached.
+	aggregateValidation *validationCache
+
+	// provisioningFailures counts the consecutive failures of each Engine's
+	// driver to apply its resources, to back off retries.
+	provisioningFailures failureCounter
+
+	// pinnedVersionWarnings and noEffectiveRulesWarnings track the pinned
+	// rules version and NoEffectiveRules warnings last emitted for each
+	// Engine, to only emit them on change.
+	pinnedVersionWarnings    warningTracker
+	noEffectiveRulesWarnings warningTracker
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *EngineReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	wasmPlugin := &unstructured.Unstructured{}
-	wasmPlugin.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "extensions.istio.io",
-		Version: "v1alpha1",
-		Kind:    "WasmPlugin",
-	})
-
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&wafv1alpha1.Engine{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
-		Owns(wasmPlugin).
-		WithOptions(controller.Options{
-			RateLimiter: workqueue.NewTypedItemExponentialFailureRateLimiter[ctrl.Request](
-				1*time.Second,
-				1*time.Minute,
-			),
-		}).
+	wasmPlugin.SetGroupVersionKind(wasmPluginGVK)
+
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(), &wafv1alpha1.Engine{}, engineRuleSetIndexKey, indexEngineRuleSets,
+	); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&wafv1alpha1.Engine{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.AnnotationChangedPredicate{},
+		))).
+		// WasmPlugins enqueue every Engine in their namespace (including the
+		// WasmPlugin's owner), and spec changes to Engines the other Engines
+		// whose selectors overlap theirs, so that selector conflicts are
+		// detected regardless of the order Engines are reconciled in.
+		Watches(wasmPlugin, handler.EnqueueRequestsFromMapFunc(r.findEnginesForWasmPlugin)).
+		Watches(
+			&wafv1alpha1.Engine{},
+			r.engineSelectorHandler(),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(
+			&wafv1alpha1.RuleSet{},
+			handler.EnqueueRequestsFromMapFunc(r.findEnginesForRuleSet),
+			builder.WithPredicates(ruleSetReadinessChangedPredicate()),
+		)
+
+	if gatewayWatchAvailable(mgr.GetRESTMapper()) {
+		gateway := &unstructured.Unstructured{}
+		gateway.SetGroupVersionKind(gatewayGVK)
+		b = b.Watches(gateway, handler.EnqueueRequestsFromMapFunc(r.findEnginesForGateway))
+	}
+
+	if envoyExtensionPolicyWatchAvailable(mgr.GetRESTMapper()) {
+		policy := &unstructured.Unstructured{}
+		policy.SetGroupVersionKind(envoyExtensionPolicyGVK)
+		b = b.Owns(policy)
+	}
+
+	return b.WithOptions(r.controllerOptions()).
 		Named("engine").
 		Complete(r)
 }
 
+// controllerOptions returns the controller options for the Engine controller,
+// including an exponential failure rate limiter so that repeatedly failing
+// Engines (e.g. a bad image) don't hammer the API server. Successful
+// reconciliations reset the backoff for the Engine.
+func (r *EngineReconciler) controllerOptions() controller.Options {
+	limits := r.rateLimiter
+	if limits == nil {
+		limits = DefaultRateLimiter()
+	}
+
+	return controller.Options{
+		RateLimiter: workqueue.NewTypedItemExponentialFailureRateLimiter[ctrl.Request](
+			limits.BaseDelay,
+			limits.MaxDelay,
+		),
+	}
+}
+
 // -----------------------------------------------------------------------------
 // Engine Controller - Reconciler
 // -----------------------------------------------------------------------------
 
 // Reconcile handles reconciliation of Engine resources
-func (r *EngineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *EngineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer observeReconcile("engine", time.Now(), &result, &err)
 	log := logf.FromContext(ctx)
 
 	logDebug(log, req, "Engine", "Starting reconciliation")
@@ -95,6 +173,10 @@ func (r *EngineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	if err := r.Get(ctx, req.NamespacedName, &engine); err != nil {
 		if apierrors.IsNotFound(err) {
 			logDebug(log, req, "Engine", "Resource not found")
+			if r.ruleSetCache != nil {
+				r.ruleSetCache.Delete(engineAggregateCacheKey(req.Namespace, req.Name))
+				r.ruleSetCache.SetPinned(req.String(), "")
+			}
 			return ctrl.Result{Requeue: false}, nil
 		}
 
@@ -102,6 +184,27 @@ func (r *EngineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{Requeue: true}, err
 	}
 
+	if !engine.DeletionTimestamp.IsZero() {
+		return r.finalize(ctx, log, req, &engine)
+	}
+
+	if isPaused(&engine) {
+		return pauseReconciliation(ctx, r.Client, r.Recorder, log, req, "Engine", &engine, &engine.Status.Conditions)
+	}
+	if err := resumeReconciliation(ctx, r.Client, r.Recorder, log, req, "Engine", &engine, &engine.Status.Conditions); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !controllerutil.ContainsFinalizer(&engine, engineFinalizer) {
+		logDebug(log, req, "Engine", "Adding finalizer")
+		patch := client.MergeFromWithOptions(engine.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		controllerutil.AddFinalizer(&engine, engineFinalizer)
+		if err := r.Patch(ctx, &engine, patch); err != nil {
+			logError(log, req, "Engine", err, "Failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
 	logDebug(log, req, "Engine", "Applying conditions")
 	if apimeta.FindStatusCondition(engine.Status.Conditions, "Ready") == nil {
 		patch := client.MergeFrom(engine.DeepCopy())
@@ -112,42 +215,169 @@ func (r *EngineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		}
 	}
 
+	if result, err := r.aggregateRuleSets(ctx, log, req, &engine); err != nil || !result.IsZero() {
+		return result, err
+	}
+	r.updatePinnedRulesVersion(log, req, &engine)
+
+	ruleSetReady, err := r.updateRuleSetReadiness(ctx, log, req, &engine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if ruleSetReady != metav1.ConditionTrue && requiresReadyRuleSets(&engine) {
+		return r.blockOnRuleSetReadiness(ctx, log, req, &engine)
+	}
+
+	if result, err := r.gateOnRuleValidation(ctx, log, req, &engine); err != nil || !result.IsZero() {
+		return result, err
+	}
+
 	logInfo(log, req, "Engine", "Selecting driver and provisioning")
-	return r.selectDriver(ctx, log, req, engine)
+	result, err = r.selectDriver(ctx, log, req, engine)
+	if err != nil || !result.IsZero() {
+		return result, err
+	}
+
+	// Provisioning updated the status of a copy of the Engine, so re-read it
+	// before updating its status further.
+	if err := r.Get(ctx, req.NamespacedName, &engine); err != nil {
+		logError(log, req, "Engine", err, "Failed to get")
+		return ctrl.Result{}, err
+	}
+
+	r.warnOnNoEffectiveRules(ctx, log, req, &engine)
+	servedRulesRequeue := r.updateServedRules(ctx, log, req, &engine)
+
+	result, err = r.updateRuleMatchStatistics(ctx, log, req, &engine)
+	if err != nil {
+		return result, err
+	}
+
+	if servedRulesRequeue > 0 && (result.RequeueAfter == 0 || servedRulesRequeue < result.RequeueAfter) {
+		result.RequeueAfter = servedRulesRequeue
+	}
+
+	if ruleSetReady == metav1.ConditionFalse && (result.RequeueAfter == 0 || RuleSetReadinessRecheckInterval < result.RequeueAfter) {
+		result.RequeueAfter = RuleSetReadinessRecheckInterval
+	}
+
+	return result, nil
 }
 
 // -----------------------------------------------------------------------------
 // Engine Controller - Driver Provisioning
 // -----------------------------------------------------------------------------
 
+// fieldManagerName returns the server-side apply field manager used for the
+// resources provisioned for Engines.
+func (r *EngineReconciler) fieldManagerName() string {
+	if r.fieldManager == "" {
+		return DefaultFieldManager
+	}
+	return r.fieldManager
+}
+
+// selectDriver looks up the registered Driver for the Engine's driver
+// configuration and uses it to provision the Engine.
 func (r *EngineReconciler) selectDriver(ctx context.Context, log logr.Logger, req ctrl.Request, engine wafv1alpha1.Engine) (ctrl.Result, error) {
-	switch {
-	case engine.Spec.Driver.Istio != nil:
-		switch {
-		case engine.Spec.Driver.Istio.Wasm != nil:
-			logDebug(log, req, "Engine", "Using Istio driver with WASM mode")
-			return r.provisionIstioEngineWithWasm(ctx, log, req, engine)
-		default:
-			return ctrl.Result{}, r.handleInvalidDriverConfiguration(ctx, log, req, &engine)
-		}
-	default:
+	driver, err := r.lookupDriver(&engine)
+	if err != nil {
+		logDebug(log, req, "Engine", "No driver registered for configuration", "reason", err.Error())
 		return ctrl.Result{}, r.handleInvalidDriverConfiguration(ctx, log, req, &engine)
 	}
+
+	return driver.Provision(ctx, log, req, engine)
+}
+
+// finalize cleans up the resources provisioned for a deleted Engine with its
+// driver, and then removes its finalizer, allowing the deletion to complete.
+// Engines without a supported driver have nothing to clean up.
+func (r *EngineReconciler) finalize(ctx context.Context, log logr.Logger, req ctrl.Request, engine *wafv1alpha1.Engine) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(engine, engineFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	if driver, err := r.lookupDriver(engine); err == nil {
+		logInfo(log, req, "Engine", "Cleaning up provisioned resources")
+		if result, err := driver.Cleanup(ctx, log, req, *engine); err != nil || !result.IsZero() {
+			return result, err
+		}
+	}
+
+	if r.ruleSetCache != nil {
+		r.ruleSetCache.Delete(engineAggregateCacheKey(engine.Namespace, engine.Name))
+		r.ruleSetCache.SetPinned(req.String(), "")
+	}
+	r.aggregateValidation.forget(engineAggregateValidationKey(engine))
+	r.provisioningFailures.reset(req.NamespacedName)
+	r.pinnedVersionWarnings.transition(req.NamespacedName, "")
+	r.noEffectiveRulesWarnings.transition(req.NamespacedName, "")
+
+	logDebug(log, req, "Engine", "Removing finalizer")
+	patch := client.MergeFromWithOptions(engine.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	controllerutil.RemoveFinalizer(engine, engineFinalizer)
+	if err := r.Patch(ctx, engine, patch); err != nil {
+		logError(log, req, "Engine", err, "Failed to remove finalizer")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	return ctrl.Result{}, nil
 }
 
 // -----------------------------------------------------------------------------
 // Engine Controller - Configuration Issue Handling
 // -----------------------------------------------------------------------------
 
+// warnOnNoEffectiveRules emits a NoEffectiveRules Warning event on the Engine
+// once its RuleSets are found to have been cached without any rules, as the
+// Engine would otherwise report Ready while enforcing nothing. RuleSets which
+// have not yet been cached are ignored. The warning is emitted again only
+// after the RuleSets gained rules, or the Engine's RuleSets changed.
+func (r *EngineReconciler) warnOnNoEffectiveRules(ctx context.Context, log logr.Logger, req ctrl.Request, engine *wafv1alpha1.Engine) {
+	names := engineRuleSetNames(engine)
+	for _, name := range names {
+		var ruleSet wafv1alpha1.RuleSet
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: engine.Namespace}, &ruleSet); err != nil {
+			if !apierrors.IsNotFound(err) {
+				logError(log, req, "Engine", err, "Failed to get RuleSet", "ruleSetName", name)
+				return
+			}
+			r.noEffectiveRulesWarnings.transition(req.NamespacedName, "")
+			return
+		}
+
+		if ruleSet.Status.RuleCount == nil || *ruleSet.Status.RuleCount > 0 {
+			r.noEffectiveRulesWarnings.transition(req.NamespacedName, "")
+			return
+		}
+	}
+
+	ruleSetNames := strings.Join(names, ", ")
+	if !r.noEffectiveRulesWarnings.transition(req.NamespacedName, ruleSetNames) {
+		return
+	}
+
+	logInfo(log, req, "Engine", "Referenced RuleSets contain no rules", "ruleSetNames", ruleSetNames)
+	if len(names) == 1 {
+		r.Recorder.Eventf(engine, nil, "Warning", wafv1alpha1.EventReasonNoEffectiveRules, "Reconcile",
+			"RuleSet %s contains no rules, so no requests will be blocked", ruleSetNames)
+		return
+	}
+	r.Recorder.Eventf(engine, nil, "Warning", wafv1alpha1.EventReasonNoEffectiveRules, "Reconcile",
+		"RuleSets %s contain no rules, so no requests will be blocked", ruleSetNames)
+}
+
 // handleInvalidDriverConfiguration marks the engine as degraded due to invalid
-// driver configuration. Currently, only Istio driver with Wasm mode is supported.
+// driver configuration, i.e. one without a registered Driver. Currently, the
+// Istio driver with Wasm mode and the Envoy driver with ext_proc mode are
+// supported.
 func (r *EngineReconciler) handleInvalidDriverConfiguration(ctx context.Context, log logr.Logger, req ctrl.Request, engine *wafv1alpha1.Engine) error {
-	err := fmt.Errorf("invalid driver configuration: only Istio driver with Wasm mode is currently supported")
+	err := fmt.Errorf("invalid driver configuration: only the Istio driver with Wasm mode and the Envoy driver with ext_proc mode are currently supported")
 	logError(log, req, "Engine", err, "Invalid driver configuration")
 
-	r.Recorder.Eventf(engine, nil, "Warning", "InvalidConfiguration", "Reconcile", err.Error())
+	r.Recorder.Eventf(engine, nil, "Warning", wafv1alpha1.ReasonInvalidConfiguration, "Reconcile", err.Error())
 	patch := client.MergeFrom(engine.DeepCopy())
-	setStatusConditionDegraded(log, req, "Engine", &engine.Status.Conditions, engine.Generation, "InvalidConfiguration", err.Error())
+	setStatusConditionDegraded(log, req, "Engine", &engine.Status.Conditions, engine.Generation, wafv1alpha1.ReasonInvalidConfiguration, err.Error())
 	if updateErr := r.Status().Patch(ctx, engine, patch); updateErr != nil {
 		logError(log, req, "Engine", updateErr, "Failed to patch status after validation error")
 		return fmt.Errorf("validation failed: %w (status patch also failed: %v)", err, updateErr)
@@ -0,0 +1,48 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets/cache"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Cache Auth Consts
+// -----------------------------------------------------------------------------
+
+// CacheAuthTokenEnvVar is the environment variable of the WASM plugin's VM
+// which the Engine's cache auth token is passed in, as named by the
+// cache_server_auth_token_env plugin config key.
+const CacheAuthTokenEnvVar = "CORAZA_CACHE_AUTH_TOKEN"
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Cache Auth Token
+// -----------------------------------------------------------------------------
+
+// engineCacheAuthToken returns the token the Engine's data plane loads its
+// rules from the cache server with, or "" if the cache server doesn't
+// require one. The token is derived from the cache server's auth token for
+// the Engine's cache key (see cache.InstanceToken), so that it only grants
+// access to the Engine's own rules, and not to those of other Engines, e.g.
+// of other tenants.
+func (r *EngineReconciler) engineCacheAuthToken(engine *wafv1alpha1.Engine) string {
+	if r.cacheAuthToken == "" {
+		return ""
+	}
+	return cache.InstanceToken(r.cacheAuthToken, engineCacheKey(engine))
+}
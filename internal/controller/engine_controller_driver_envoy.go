@@ -0,0 +1,213 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Envoy RBAC
+// -----------------------------------------------------------------------------
+
+// +kubebuilder:rbac:groups=gateway.envoyproxy.io,resources=envoyextensionpolicies,verbs=get;list;watch;create;update;patch;delete
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Envoy Consts
+// -----------------------------------------------------------------------------
+
+const (
+	// EnvoyExtensionPolicyNamePrefix is the prefix used for all created
+	// EnvoyExtensionPolicy resources
+	EnvoyExtensionPolicyNamePrefix = "coraza-engine-"
+
+	// CacheServerInstanceAnnotation is set on EnvoyExtensionPolicies to the
+	// RuleSet cache key of the Engine's rules, which the ext_proc service
+	// loads from the cache server, as the WasmPlugin's cache_server_instance.
+	CacheServerInstanceAnnotation = "waf.k8s.coraza.io/cache-server-instance"
+
+	// CacheServerClusterAnnotation is set on EnvoyExtensionPolicies to the
+	// address of the RuleSet cache server, as the WasmPlugin's
+	// cache_server_cluster.
+	CacheServerClusterAnnotation = "waf.k8s.coraza.io/cache-server-cluster"
+
+	// CacheServerAuthTokenAnnotation is set on EnvoyExtensionPolicies to the
+	// token the ext_proc service loads the Engine's rules from the cache
+	// server with, if the cache server requires one. It only grants access
+	// to the Engine's own rules.
+	CacheServerAuthTokenAnnotation = "waf.k8s.coraza.io/cache-server-auth-token"
+)
+
+// envoyExtensionPolicyGVK is the GroupVersionKind of Envoy Gateway's
+// EnvoyExtensionPolicy.
+var envoyExtensionPolicyGVK = schema.GroupVersionKind{
+	Group:   "gateway.envoyproxy.io",
+	Version: "v1alpha1",
+	Kind:    "EnvoyExtensionPolicy",
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Envoy Driver
+// -----------------------------------------------------------------------------
+
+// envoyExtensionPolicyWatchAvailable reports whether Envoy Gateway's
+// EnvoyExtensionPolicy CRD is installed, so that the Engine controller only
+// watches the policies it owns in clusters with Envoy Gateway.
+func envoyExtensionPolicyWatchAvailable(mapper apimeta.RESTMapper) bool {
+	_, err := mapper.RESTMapping(envoyExtensionPolicyGVK.GroupKind(), envoyExtensionPolicyGVK.Version)
+	return err == nil
+}
+
+// envoyExtProcDriver is the Driver implementation for the Envoy driver using
+// ext_proc mode.
+type envoyExtProcDriver struct {
+	r *EngineReconciler
+}
+
+// Provision implements Driver.
+func (d *envoyExtProcDriver) Provision(ctx context.Context, log logr.Logger, req ctrl.Request, engine wafv1alpha1.Engine) (ctrl.Result, error) {
+	logDebug(log, req, "Engine", "Using Envoy driver with ext_proc mode")
+	return d.r.provisionEnvoyEngineWithExtProc(ctx, log, req, engine)
+}
+
+// Cleanup implements Driver.
+func (d *envoyExtProcDriver) Cleanup(ctx context.Context, log logr.Logger, req ctrl.Request, engine wafv1alpha1.Engine) (ctrl.Result, error) {
+	logDebug(log, req, "Engine", "Cleaning up Envoy driver with ext_proc mode")
+	return d.r.cleanupEnvoyEngineWithExtProc(ctx, log, req, engine)
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Envoy Driver - Provisioning
+// -----------------------------------------------------------------------------
+
+// provisionEnvoyEngineWithExtProc provisions the Envoy Gateway
+// EnvoyExtensionPolicy which sends the Gateway's traffic to the Engine's
+// ext_proc service.
+func (r *EngineReconciler) provisionEnvoyEngineWithExtProc(ctx context.Context, log logr.Logger, req ctrl.Request, engine wafv1alpha1.Engine) (ctrl.Result, error) {
+	logDebug(log, req, "Engine", "Building EnvoyExtensionPolicy resource")
+	policy := r.buildEnvoyExtensionPolicy(&engine)
+
+	logDebug(log, req, "Engine", "Setting controller reference on EnvoyExtensionPolicy")
+	if err := controllerutil.SetControllerReference(&engine, policy, r.Scheme); err != nil {
+		logError(log, req, "Engine", err, "Failed to set owner reference on EnvoyExtensionPolicy")
+		return ctrl.Result{}, err
+	}
+
+	logDebug(log, req, "Engine", "Applying EnvoyExtensionPolicy", "policyName", policy.GetName())
+	if err := serverSideApply(ctx, r.Client, r.fieldManagerName(), policy); err != nil {
+		return r.handleProvisioningFailure(ctx, log, req, &engine, "EnvoyExtensionPolicy", err)
+	}
+	r.provisioningFailures.reset(req.NamespacedName)
+	logInfo(log, req, "Engine", "EnvoyExtensionPolicy provisioned", "policyNamespace", policy.GetNamespace(), "policyName", policy.GetName())
+
+	logDebug(log, req, "Engine", "Updating status after successful provisioning")
+	patch := client.MergeFrom(engine.DeepCopy())
+	upsertOwnedResource(&engine, ownedResourceRef(policy))
+	r.setStatusProvisioned(log, req, &engine, "Configured", "EnvoyExtensionPolicy successfully created/updated")
+	if err := r.Status().Patch(ctx, &engine, patch); err != nil {
+		logError(log, req, "Engine", err, "Failed to patch status")
+		return ctrl.Result{}, err
+	}
+	r.Recorder.Eventf(&engine, nil, "Normal", wafv1alpha1.EventReasonEnvoyExtensionPolicyCreated, "Provision", "Created EnvoyExtensionPolicy %s/%s", policy.GetNamespace(), policy.GetName())
+
+	return ctrl.Result{}, nil
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Envoy Driver - Cleanup
+// -----------------------------------------------------------------------------
+
+// cleanupEnvoyEngineWithExtProc deletes the EnvoyExtensionPolicy for the
+// Engine, along with any other resources it owns. A policy that is already
+// gone is not considered an error.
+func (r *EngineReconciler) cleanupEnvoyEngineWithExtProc(ctx context.Context, log logr.Logger, req ctrl.Request, engine wafv1alpha1.Engine) (ctrl.Result, error) {
+	policy := &unstructured.Unstructured{}
+	policy.SetGroupVersionKind(envoyExtensionPolicyGVK)
+	policy.SetName(fmt.Sprintf("%s%s", EnvoyExtensionPolicyNamePrefix, engine.Name))
+	policy.SetNamespace(engine.Namespace)
+
+	if err := r.deleteOwnedResources(ctx, log, req, engine, policy); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Envoy Driver - EnvoyExtensionPolicy Builder
+// -----------------------------------------------------------------------------
+
+// buildEnvoyExtensionPolicy builds an EnvoyExtensionPolicy attaching the
+// Engine's ext_proc service to its Gateway. The policy is annotated with the
+// RuleSet cache key and cache server address the ext_proc service loads the
+// Engine's rules from, as Envoy Gateway can't pass static configuration to
+// ext_proc services. If the cache server requires an auth token, the policy
+// is also annotated with the Engine's token.
+func (r *EngineReconciler) buildEnvoyExtensionPolicy(engine *wafv1alpha1.Engine) *unstructured.Unstructured {
+	extProc := engine.Spec.Driver.Envoy.ExtProc
+
+	annotations := map[string]any{
+		CacheServerInstanceAnnotation: engineCacheKey(engine),
+		CacheServerClusterAnnotation:  r.ruleSetCacheServerCluster,
+	}
+	if cacheAuthToken := r.engineCacheAuthToken(engine); cacheAuthToken != "" {
+		annotations[CacheServerAuthTokenAnnotation] = cacheAuthToken
+	}
+
+	policy := &unstructured.Unstructured{
+		Object: map[string]any{
+			"metadata": map[string]any{
+				"name":        fmt.Sprintf("%s%s", EnvoyExtensionPolicyNamePrefix, engine.Name),
+				"namespace":   engine.Namespace,
+				"annotations": annotations,
+			},
+			"spec": map[string]any{
+				"targetRefs": []any{
+					map[string]any{
+						"group": "gateway.networking.k8s.io",
+						"kind":  "Gateway",
+						"name":  extProc.GatewayName,
+					},
+				},
+				"extProc": []any{
+					map[string]any{
+						"backendRefs": []any{
+							map[string]any{
+								"name": extProc.Service.Name,
+								"port": int64(extProc.Service.Port),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	policy.SetGroupVersionKind(envoyExtensionPolicyGVK)
+
+	return policy
+}
@@ -18,14 +18,26 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
 )
@@ -40,8 +52,39 @@ import (
 // Engine Controller - Istio Consts
 // -----------------------------------------------------------------------------
 
-// WasmPluginNamePrefix is the prefix used for all created WasmPlugin resources
-const WasmPluginNamePrefix = "coraza-engine-"
+const (
+	// WasmPluginNamePrefix is the prefix used for all created WasmPlugin resources
+	WasmPluginNamePrefix = "coraza-engine-"
+)
+
+// wasmPluginGVK is the GroupVersionKind of Istio's WasmPlugin.
+var wasmPluginGVK = schema.GroupVersionKind{
+	Group:   "extensions.istio.io",
+	Version: "v1alpha1",
+	Kind:    "WasmPlugin",
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Istio Driver
+// -----------------------------------------------------------------------------
+
+// istioWasmDriver is the Driver implementation for the Istio driver using
+// WASM mode.
+type istioWasmDriver struct {
+	r *EngineReconciler
+}
+
+// Provision implements Driver.
+func (d *istioWasmDriver) Provision(ctx context.Context, log logr.Logger, req ctrl.Request, engine wafv1alpha1.Engine) (ctrl.Result, error) {
+	logDebug(log, req, "Engine", "Using Istio driver with WASM mode")
+	return d.r.provisionIstioEngineWithWasm(ctx, log, req, engine)
+}
+
+// Cleanup implements Driver.
+func (d *istioWasmDriver) Cleanup(ctx context.Context, log logr.Logger, req ctrl.Request, engine wafv1alpha1.Engine) (ctrl.Result, error) {
+	logDebug(log, req, "Engine", "Cleaning up Istio driver with WASM mode")
+	return d.r.cleanupIstioEngineWithWasm(ctx, log, req, engine)
+}
 
 // -----------------------------------------------------------------------------
 // Engine Controller - Istio Driver - Provisioning
@@ -50,38 +93,354 @@ const WasmPluginNamePrefix = "coraza-engine-"
 // provisionIstioEngineWithWasm provisions the Istio WasmPlugin resource for
 // the Engine.
 func (r *EngineReconciler) provisionIstioEngineWithWasm(ctx context.Context, log logr.Logger, req ctrl.Request, engine wafv1alpha1.Engine) (ctrl.Result, error) {
-	logDebug(log, req, "Engine", "Building WasmPlugin resource")
-	wasmPlugin := r.buildWasmPlugin(&engine)
+	if _, pinned := imageDigest(engine.Spec.Driver.Istio.Wasm.Image); engine.Spec.Driver.Istio.Wasm.RequireDigest && !pinned {
+		msg := fmt.Sprintf("Image %s is not pinned by a sha256 digest, which is required by requireDigest", engine.Spec.Driver.Istio.Wasm.Image)
+		logInfo(log, req, "Engine", "Image is not pinned by digest", "image", engine.Spec.Driver.Istio.Wasm.Image)
+		r.Recorder.Eventf(&engine, nil, "Warning", wafv1alpha1.ReasonImageNotPinned, "Provision", msg)
 
-	logDebug(log, req, "Engine", "Setting controller reference on WasmPlugin")
-	if err := controllerutil.SetControllerReference(&engine, wasmPlugin, r.Scheme); err != nil {
-		logError(log, req, "Engine", err, "Failed to set owner reference on WasmPlugin")
+		patch := client.MergeFrom(engine.DeepCopy())
+		setStatusConditionDegraded(log, req, "Engine", &engine.Status.Conditions, engine.Generation, wafv1alpha1.ReasonImageNotPinned, msg)
+		if updateErr := r.Status().Patch(ctx, &engine, patch); updateErr != nil {
+			logError(log, req, "Engine", updateErr, "Failed to patch status after image digest check")
+			return ctrl.Result{}, updateErr
+		}
+
+		// Only a change to the Engine's spec can resolve this, which
+		// triggers a reconcile.
+		return ctrl.Result{}, nil
+	}
+
+	if engine.Spec.Driver.Istio.Wasm.Mode == wafv1alpha1.IstioIntegrationModeGateway {
+		logDebug(log, req, "Engine", "Checking for a Gateway matching the workload selector")
+		found, err := r.gatewayExistsForEngine(ctx, &engine)
+		if err != nil {
+			logError(log, req, "Engine", err, "Failed to list Gateways")
+			return ctrl.Result{}, err
+		}
+		if !found {
+			msg := "No Gateway in the namespace matches the workload selector"
+			logInfo(log, req, "Engine", msg)
+			r.Recorder.Eventf(&engine, nil, "Warning", wafv1alpha1.ReasonGatewayNotFound, "Provision", msg)
+
+			patch := client.MergeFrom(engine.DeepCopy())
+			setStatusConditionDegraded(log, req, "Engine", &engine.Status.Conditions, engine.Generation, wafv1alpha1.ReasonGatewayNotFound, msg)
+			if updateErr := r.Status().Patch(ctx, &engine, patch); updateErr != nil {
+				logError(log, req, "Engine", updateErr, "Failed to patch status after Gateway lookup")
+				return ctrl.Result{}, updateErr
+			}
+
+			// Gateways are watched, so the Engine is reconciled again once a
+			// matching Gateway appears.
+			return ctrl.Result{}, nil
+		}
+	}
+
+	logDebug(log, req, "Engine", "Checking for WasmPlugins with overlapping workload selectors")
+	conflict, err := r.findWasmPluginSelectorConflict(ctx, &engine)
+	if err != nil {
+		logError(log, req, "Engine", err, "Failed to check for WasmPlugin selector conflicts")
 		return ctrl.Result{}, err
 	}
+	if conflict != "" {
+		msg := fmt.Sprintf("WasmPlugin %s of an existing Engine selects workloads overlapping this Engine's workload selector, rule precedence between them would be undefined unless both Engines set distinct priorities", conflict)
+		logInfo(log, req, "Engine", "Workload selector conflicts with an existing WasmPlugin", "wasmPluginName", conflict)
+		r.Recorder.Eventf(&engine, nil, "Warning", wafv1alpha1.ReasonSelectorConflict, "Provision", msg)
 
-	logDebug(log, req, "Engine", "Applying WasmPlugin", "wasmPluginName", wasmPlugin.GetName())
-	if err := serverSideApply(ctx, r.Client, wasmPlugin); err != nil {
-		logError(log, req, "Engine", err, "Failed to create or update WasmPlugin")
-		r.Recorder.Eventf(&engine, nil, "Warning", "ProvisioningFailed", "Provision", "Failed to create WasmPlugin: %v", err)
+		// This Engine may already have a WasmPlugin, e.g. if an older
+		// Engine's selector changed to overlap it, which would otherwise stay
+		// attached to the same workloads.
+		if err := r.Delete(ctx, wasmPluginRef(&engine)); client.IgnoreNotFound(err) != nil && !apimeta.IsNoMatchError(err) {
+			logError(log, req, "Engine", err, "Failed to delete WasmPlugin after selector conflict")
+			return ctrl.Result{}, err
+		}
 
 		patch := client.MergeFrom(engine.DeepCopy())
-		setStatusConditionDegraded(log, req, "Engine", &engine.Status.Conditions, engine.Generation, "ProvisioningFailed", fmt.Sprintf("Failed to create or update WasmPlugin: %v", err))
+		engine.Status.WasmPluginName = ""
+		setStatusConditionDegraded(log, req, "Engine", &engine.Status.Conditions, engine.Generation, wafv1alpha1.ReasonSelectorConflict, msg)
 		if updateErr := r.Status().Patch(ctx, &engine, patch); updateErr != nil {
-			logError(log, req, "Engine", updateErr, "Failed to patch status after provisioning failure")
+			logError(log, req, "Engine", updateErr, "Failed to patch status after selector conflict")
 		}
 
+		// Changes to the namespace's WasmPlugins, and to Engines whose
+		// selectors overlap this one's, enqueue it, so the Engine is
+		// reconciled again once the conflict is resolved.
+		return ctrl.Result{}, nil
+	}
+
+	logDebug(log, req, "Engine", "Building WasmPlugin resource")
+	wasmPlugin, err := r.buildWasmPlugin(&engine)
+	if err != nil {
+		msg := fmt.Sprintf("Invalid WasmPlugin configuration: %v", err)
+		logInfo(log, req, "Engine", "Refusing to build WasmPlugin", "reason", err.Error())
+		r.Recorder.Eventf(&engine, nil, "Warning", wafv1alpha1.ReasonInvalidConfiguration, "Provision", msg)
+
+		patch := client.MergeFrom(engine.DeepCopy())
+		setStatusConditionDegraded(log, req, "Engine", &engine.Status.Conditions, engine.Generation, wafv1alpha1.ReasonInvalidConfiguration, msg)
+		if updateErr := r.Status().Patch(ctx, &engine, patch); updateErr != nil {
+			logError(log, req, "Engine", updateErr, "Failed to patch status after WasmPlugin configuration check")
+			return ctrl.Result{}, updateErr
+		}
+
+		// Only a change to the Engine's spec can resolve this, which
+		// triggers a reconcile.
+		return ctrl.Result{}, nil
+	}
+
+	logDebug(log, req, "Engine", "Setting controller reference on WasmPlugin")
+	if err := controllerutil.SetControllerReference(&engine, wasmPlugin, r.Scheme); err != nil {
+		logError(log, req, "Engine", err, "Failed to set owner reference on WasmPlugin")
 		return ctrl.Result{}, err
 	}
+
+	logDebug(log, req, "Engine", "Applying WasmPlugin", "wasmPluginName", wasmPlugin.GetName())
+	if err := serverSideApply(ctx, r.Client, r.fieldManagerName(), wasmPlugin); err != nil {
+		return r.handleProvisioningFailure(ctx, log, req, &engine, "WasmPlugin", err)
+	}
+	r.provisioningFailures.reset(req.NamespacedName)
 	logInfo(log, req, "Engine", "WasmPlugin provisioned", "wasmNamespace", wasmPlugin.GetNamespace(), "wasmName", wasmPlugin.GetName())
 
 	logDebug(log, req, "Engine", "Updating status after successful provisioning")
 	patch := client.MergeFrom(engine.DeepCopy())
-	setStatusReady(log, req, "Engine", &engine.Status.Conditions, engine.Generation, "Configured", "WasmPlugin successfully created/updated")
+	engine.Status.WasmPluginName = wasmPlugin.GetName()
+	upsertOwnedResource(&engine, ownedResourceRef(wasmPlugin))
+	r.setStatusProvisioned(log, req, &engine, "Configured", "WasmPlugin successfully created/updated")
 	if err := r.Status().Patch(ctx, &engine, patch); err != nil {
 		logError(log, req, "Engine", err, "Failed to patch status")
 		return ctrl.Result{}, err
 	}
-	r.Recorder.Eventf(&engine, nil, "Normal", "WasmPluginCreated", "Provision", "Created WasmPlugin %s/%s", wasmPlugin.GetNamespace(), wasmPlugin.GetName())
+	r.Recorder.Eventf(&engine, nil, "Normal", wafv1alpha1.EventReasonWasmPluginCreated, "Provision", "Created WasmPlugin %s/%s", wasmPlugin.GetNamespace(), wasmPlugin.GetName())
+
+	return ctrl.Result{}, nil
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Istio Driver - Selector Conflicts
+// -----------------------------------------------------------------------------
+
+// findWasmPluginSelectorConflict returns the name of a WasmPlugin provisioned
+// for another Engine in the same namespace whose workload selector overlaps
+// the Engine's, or "" if there is none (or the WasmPlugin CRD isn't
+// installed). Only Engines created before this one
+// (by creation time, then name) are considered, so that the first Engine
+// keeps its WasmPlugin and later ones are rejected. WasmPlugins whose Engine
+// is gone or being deleted are awaiting garbage collection and are ignored,
+// as are those of Engines ordered against this one by a distinct priority.
+func (r *EngineReconciler) findWasmPluginSelectorConflict(ctx context.Context, engine *wafv1alpha1.Engine) (string, error) {
+	plugins := &unstructured.UnstructuredList{}
+	plugins.SetGroupVersionKind(wasmPluginGVK.GroupVersion().WithKind("WasmPluginList"))
+	if err := r.List(ctx, plugins, client.InNamespace(engine.Namespace)); err != nil {
+		if apimeta.IsNoMatchError(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	ownName := fmt.Sprintf("%s%s", WasmPluginNamePrefix, engine.Name)
+	for i := range plugins.Items {
+		plugin := &plugins.Items[i]
+		if plugin.GetName() == ownName || !strings.HasPrefix(plugin.GetName(), WasmPluginNamePrefix) {
+			continue
+		}
+
+		matchLabels, _, _ := unstructured.NestedStringMap(plugin.Object, "spec", "selector", "matchLabels")
+		if !matchLabelsOverlap(wasmWorkloadSelector(engine).MatchLabels, matchLabels) {
+			continue
+		}
+
+		owner := metav1.GetControllerOf(plugin)
+		if owner == nil || owner.Kind != "Engine" {
+			continue
+		}
+		var other wafv1alpha1.Engine
+		if err := r.Get(ctx, client.ObjectKey{Namespace: engine.Namespace, Name: owner.Name}, &other); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return "", err
+		}
+		if !other.DeletionTimestamp.IsZero() || !createdBefore(&other, engine) || distinctPriorities(&other, engine) {
+			continue
+		}
+
+		return plugin.GetName(), nil
+	}
+
+	return "", nil
+}
+
+// matchLabelsOverlap reports whether a workload could be selected by both
+// label selectors, i.e. they don't require different values for any label.
+// An empty selector selects every workload.
+func matchLabelsOverlap(a, b map[string]string) bool {
+	for key, value := range a {
+		if other, ok := b[key]; ok && other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// distinctPriorities reports whether both Engines set different explicit
+// priorities, which define the order their WasmPlugins run in.
+func distinctPriorities(a, b *wafv1alpha1.Engine) bool {
+	return a.Spec.Priority != nil && b.Spec.Priority != nil && *a.Spec.Priority != *b.Spec.Priority
+}
+
+// createdBefore reports whether Engine a was created before Engine b, using
+// the name to break ties between Engines created within the same second.
+func createdBefore(a, b *wafv1alpha1.Engine) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Istio Driver - Selector Conflict Watch
+// -----------------------------------------------------------------------------
+
+// findEnginesForWasmPlugin maps a WasmPlugin provisioned for an Engine to
+// every Engine in its namespace, as creating, changing or deleting it may
+// cause or resolve a selector conflict with any of them.
+func (r *EngineReconciler) findEnginesForWasmPlugin(ctx context.Context, obj client.Object) []reconcile.Request {
+	if !strings.HasPrefix(obj.GetName(), WasmPluginNamePrefix) {
+		return nil
+	}
+	return r.findEnginesInNamespace(ctx, obj.GetNamespace(), "WasmPlugin", obj.GetName())
+}
+
+// engineSelectorHandler enqueues, for a change to an Engine (or its
+// creation or deletion), the other Engines in its namespace whose workload
+// selectors overlap the Engine's before or after the change, as it may cause
+// or resolve a selector conflict with any of them, regardless of which of
+// them was reconciled first.
+func (r *EngineReconciler) engineSelectorHandler() handler.EventHandler {
+	enqueue := func(q workqueue.TypedRateLimitingInterface[reconcile.Request], requests []reconcile.Request) {
+		for _, req := range requests {
+			q.Add(req)
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(q, r.findOverlappingEngines(ctx, e.Object))
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(q, r.findOverlappingEngines(ctx, e.ObjectOld, e.ObjectNew))
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(q, r.findOverlappingEngines(ctx, e.Object))
+		},
+		GenericFunc: func(ctx context.Context, e event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(q, r.findOverlappingEngines(ctx, e.Object))
+		},
+	}
+}
+
+// findOverlappingEngines maps versions of an Engine (e.g. before and after an
+// update) to the other Engines in its namespace whose workload
+// selectors overlap the selector of any of them. Overlapping selectors may
+// set different labels, so candidates are filtered from the namespace's
+// Engines rather than looked up by their labels.
+func (r *EngineReconciler) findOverlappingEngines(ctx context.Context, objs ...client.Object) []reconcile.Request {
+	log := logf.FromContext(ctx)
+
+	var selectors []map[string]string
+	for _, obj := range objs {
+		if engine, ok := obj.(*wafv1alpha1.Engine); ok && hasWasmWorkloadSelector(engine) {
+			selectors = append(selectors, wasmWorkloadSelector(engine).MatchLabels)
+		}
+	}
+	if len(selectors) == 0 {
+		return nil
+	}
+	changed := objs[len(objs)-1]
+
+	var engines wafv1alpha1.EngineList
+	if err := r.List(ctx, &engines, client.InNamespace(changed.GetNamespace())); err != nil {
+		log.Error(err, "Engine: Failed to list Engines for selector conflicts", "kind", "Engine", "name", changed.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range engines.Items {
+		engine := &engines.Items[i]
+		if engine.Name == changed.GetName() || !hasWasmWorkloadSelector(engine) {
+			continue
+		}
+		matchLabels := wasmWorkloadSelector(engine).MatchLabels
+		if !slices.ContainsFunc(selectors, func(selector map[string]string) bool {
+			return matchLabelsOverlap(selector, matchLabels)
+		}) {
+			continue
+		}
+
+		req := ctrl.Request{NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: engine.Namespace}}
+		requests = append(requests, req)
+
+		logDebug(log, req, "Engine", "Enqueuing for reconciliation due to a possible selector conflict", "kind", "Engine", "name", changed.GetName())
+	}
+
+	return requests
+}
+
+// hasWasmWorkloadSelector reports whether the Engine uses the Istio driver in
+// Wasm mode with a workload selector, as only such Engines can have
+// conflicting selectors.
+func hasWasmWorkloadSelector(engine *wafv1alpha1.Engine) bool {
+	istio := engine.Spec.Driver.Istio
+	return istio != nil && istio.Wasm != nil && wasmWorkloadSelector(engine) != nil
+}
+
+// findEnginesInNamespace returns a request for every Engine in the
+// namespace, logging the kind and name of the resource whose change caused
+// them to be enqueued.
+func (r *EngineReconciler) findEnginesInNamespace(ctx context.Context, namespace, kind, name string) []reconcile.Request {
+	log := logf.FromContext(ctx)
+
+	var engines wafv1alpha1.EngineList
+	if err := r.List(ctx, &engines, client.InNamespace(namespace)); err != nil {
+		log.Error(err, "Engine: Failed to list Engines for selector conflicts", "kind", kind, "name", name)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(engines.Items))
+	for i := range engines.Items {
+		req := ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      engines.Items[i].Name,
+				Namespace: engines.Items[i].Namespace,
+			},
+		}
+		requests = append(requests, req)
+
+		logDebug(log, req, "Engine", "Enqueuing for reconciliation due to a possible selector conflict", "kind", kind, "name", name)
+	}
+
+	return requests
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Istio Driver - Cleanup
+// -----------------------------------------------------------------------------
+
+// wasmPluginRef returns the Engine's WasmPlugin with only its identity set,
+// e.g. for cleanup.
+func wasmPluginRef(engine *wafv1alpha1.Engine) *unstructured.Unstructured {
+	wasmPlugin := &unstructured.Unstructured{}
+	wasmPlugin.SetGroupVersionKind(wasmPluginGVK)
+	wasmPlugin.SetName(fmt.Sprintf("%s%s", WasmPluginNamePrefix, engine.Name))
+	wasmPlugin.SetNamespace(engine.Namespace)
+	return wasmPlugin
+}
+
+// cleanupIstioEngineWithWasm deletes the Istio WasmPlugin resource for the
+// Engine, along with any other resources it owns. A WasmPlugin that is
+// already gone is not considered an error.
+func (r *EngineReconciler) cleanupIstioEngineWithWasm(ctx context.Context, log logr.Logger, req ctrl.Request, engine wafv1alpha1.Engine) (ctrl.Result, error) {
+	if err := r.deleteOwnedResources(ctx, log, req, engine, wasmPluginRef(&engine)); err != nil {
+		return ctrl.Result{}, err
+	}
 
 	return ctrl.Result{}, nil
 }
@@ -90,17 +449,108 @@ func (r *EngineReconciler) provisionIstioEngineWithWasm(ctx context.Context, log
 // Engine Controller - Istio Driver - WasmPlugin Builder
 // -----------------------------------------------------------------------------
 
-func (r *EngineReconciler) buildWasmPlugin(engine *wafv1alpha1.Engine) *unstructured.Unstructured {
-	rulesetKey := fmt.Sprintf("%s/%s", engine.Namespace, engine.Spec.RuleSet.Name)
+// wasmWorkloadSelector returns the workload selector of the Engine's Istio
+// WASM configuration. When no workloadSelector is set, the Istio driver's
+// gatewayName is expanded into a selector on the Gateway's Pods.
+func wasmWorkloadSelector(engine *wafv1alpha1.Engine) *metav1.LabelSelector {
+	istio := engine.Spec.Driver.Istio
+	if istio.Wasm.WorkloadSelector == nil && istio.GatewayName != "" {
+		return &metav1.LabelSelector{
+			MatchLabels: map[string]string{GatewayNameLabel: istio.GatewayName},
+		}
+	}
+
+	return istio.Wasm.WorkloadSelector
+}
+
+// operatorManagedPluginConfigKeys are the WASM plugin configuration keys
+// set by the operator, which PluginConfigOverrides must not set, whether or
+// not the operator sets them for a given Engine.
+var operatorManagedPluginConfigKeys = []string{
+	"cache_server_instance",
+	"cache_server_cluster",
+	"rule_reload_interval_seconds",
+	"cache_server_auth_token_env",
+}
 
-	pluginConfig := map[string]any{
-		"cache_server_instance": rulesetKey,
-		"cache_server_cluster":  r.ruleSetCacheServerCluster,
+// buildWasmPlugin builds the WasmPlugin attaching the Engine's WASM plugin to
+// the workloads it selects. Returns an error if the workload selector
+// wouldn't restrict the WasmPlugin to specific workloads, i.e. it has no
+// matchLabels, or has matchExpressions, which WasmPlugins don't support, as
+// the WAF would then be attached to every workload in the namespace. Also
+// returns an error if the plugin configuration overrides set an operator
+// managed key.
+func (r *EngineReconciler) buildWasmPlugin(engine *wafv1alpha1.Engine) (*unstructured.Unstructured, error) {
+	selector := wasmWorkloadSelector(engine)
+	switch {
+	case selector == nil || len(selector.MatchLabels) == 0:
+		return nil, fmt.Errorf("the workload selector has no matchLabels, which would select every workload in the namespace")
+	case len(selector.MatchExpressions) > 0:
+		return nil, fmt.Errorf("the workload selector has matchExpressions, which WasmPlugins don't support")
 	}
 
+	rulesetKey := engineCacheKey(engine)
+
+	overrides := engine.Spec.Driver.Istio.Wasm.PluginConfigOverrides
+	pluginConfig := make(map[string]any, len(overrides)+len(operatorManagedPluginConfigKeys))
+	for key, value := range overrides {
+		if slices.Contains(operatorManagedPluginConfigKeys, key) {
+			return nil, fmt.Errorf("pluginConfigOverrides sets %s, which is managed by the operator", key)
+		}
+		var decoded any
+		if err := json.Unmarshal(value.Raw, &decoded); err != nil {
+			return nil, fmt.Errorf("pluginConfigOverrides %s is not valid JSON: %w", key, err)
+		}
+		pluginConfig[key] = decoded
+	}
+	pluginConfig["cache_server_instance"] = rulesetKey
+	pluginConfig["cache_server_cluster"] = r.ruleSetCacheServerCluster
+
 	if engine.Spec.Driver.Istio.Wasm.RuleSetCacheServer != nil {
 		pluginConfig["rule_reload_interval_seconds"] = engine.Spec.Driver.Istio.Wasm.RuleSetCacheServer.PollIntervalSeconds
 	}
+	cacheAuthToken := r.engineCacheAuthToken(engine)
+	if cacheAuthToken != "" {
+		pluginConfig["cache_server_auth_token_env"] = CacheAuthTokenEnvVar
+	}
+
+	spec := map[string]any{
+		"url":          engine.Spec.Driver.Istio.Wasm.Image,
+		"pluginConfig": pluginConfig,
+		"selector": map[string]any{
+			"matchLabels": selector.MatchLabels,
+		},
+		"type":         "HTTP",
+		"match":        wasmPluginMatch(engine.Spec.Driver.Istio.Wasm),
+		"failStrategy": wasmPluginFailStrategy(engine.Spec.FailurePolicy),
+	}
+
+	// The token only grants access to the Engine's own rules, so it's
+	// passed to the plugin inline rather than through the proxy's
+	// environment, which the operator doesn't manage.
+	if cacheAuthToken != "" {
+		spec["vmConfig"] = map[string]any{
+			"env": []any{
+				map[string]any{"name": CacheAuthTokenEnvVar, "valueFrom": "INLINE", "value": cacheAuthToken},
+			},
+		}
+	}
+
+	if engine.Spec.Priority != nil {
+		spec["priority"] = int64(*engine.Spec.Priority)
+	}
+
+	if phase := engine.Spec.Driver.Istio.Wasm.Phase; phase != "" {
+		spec["phase"] = string(phase)
+	}
+
+	if secret := engine.Spec.Driver.Istio.Wasm.ImagePullSecret; secret != "" {
+		spec["imagePullSecret"] = secret
+	}
+
+	if digest, pinned := imageDigest(engine.Spec.Driver.Istio.Wasm.Image); pinned {
+		spec["sha256"] = digest
+	}
 
 	wasmPlugin := &unstructured.Unstructured{
 		Object: map[string]any{
@@ -110,13 +560,7 @@ func (r *EngineReconciler) buildWasmPlugin(engine *wafv1alpha1.Engine) *unstruct
 				"name":      fmt.Sprintf("%s%s", WasmPluginNamePrefix, engine.Name),
 				"namespace": engine.Namespace,
 			},
-			"spec": map[string]any{
-				"url":          engine.Spec.Driver.Istio.Wasm.Image,
-				"pluginConfig": pluginConfig,
-				"selector": map[string]any{
-					"matchLabels": engine.Spec.Driver.Istio.Wasm.WorkloadSelector.MatchLabels,
-				},
-			},
+			"spec": spec,
 		},
 	}
 
@@ -126,5 +570,52 @@ func (r *EngineReconciler) buildWasmPlugin(engine *wafv1alpha1.Engine) *unstruct
 		Kind:    "WasmPlugin",
 	})
 
-	return wasmPlugin
+	return wasmPlugin, nil
+}
+
+// imageDigest returns the hex encoded sha256 digest an OCI image reference
+// is pinned by (e.g. "oci://registry/image@sha256:<digest>"). Returns false
+// if the image isn't pinned by a valid sha256 digest.
+func imageDigest(image string) (string, bool) {
+	_, digest, found := strings.Cut(image, "@sha256:")
+	if !found || len(digest) != 64 {
+		return "", false
+	}
+	for _, c := range digest {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return "", false
+		}
+	}
+	return digest, true
+}
+
+// wasmPluginFailStrategy returns the WasmPlugin failStrategy enforcing the
+// given failure policy when the plugin fails. Anything other than "allow"
+// fails closed, matching the failure policy's default.
+func wasmPluginFailStrategy(policy wafv1alpha1.FailurePolicy) string {
+	if policy == wafv1alpha1.FailurePolicyAllow {
+		return "FAIL_OPEN"
+	}
+	return "FAIL_CLOSE"
+}
+
+// wasmPluginMatch builds the WasmPlugin traffic selectors for the match
+// context of the given Wasm configuration. Istio treats Gateway listeners as
+// server-side traffic, so both the gateway and inbound contexts select SERVER
+// mode. An empty context defaults to the gateway context in gateway mode, and
+// to the inbound context in sidecar mode.
+func wasmPluginMatch(wasm *wafv1alpha1.IstioWasmConfig) []any {
+	matchContext := wasm.MatchContext
+	if matchContext == "" {
+		matchContext = wafv1alpha1.DefaultIstioMatchContext(wasm.Mode)
+	}
+
+	mode := "SERVER"
+	if matchContext == wafv1alpha1.IstioMatchContextOutbound {
+		mode = "CLIENT"
+	}
+
+	return []any{
+		map[string]any{"mode": mode},
+	}
 }
@@ -0,0 +1,103 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Driver Interface
+// -----------------------------------------------------------------------------
+
+// Driver provisions and cleans up the data plane resources for an Engine.
+// Each supported driver type and mode combination has exactly one Driver
+// implementation registered in the driver registry.
+type Driver interface {
+	// Provision creates or updates the resources required to enforce the
+	// Engine's rules on the data plane, and updates the Engine's status.
+	Provision(ctx context.Context, log logr.Logger, req ctrl.Request, engine wafv1alpha1.Engine) (ctrl.Result, error)
+
+	// Cleanup removes any resources previously provisioned for the Engine.
+	Cleanup(ctx context.Context, log logr.Logger, req ctrl.Request, engine wafv1alpha1.Engine) (ctrl.Result, error)
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Driver Registry
+// -----------------------------------------------------------------------------
+
+// driverFactory builds a Driver bound to the given reconciler.
+type driverFactory func(r *EngineReconciler) Driver
+
+// driverRegistration is the registry entry of a Driver implementation.
+type driverRegistration struct {
+	// name identifies the driver by its type and integration mode (e.g.
+	// "istio/wasm") in errors.
+	name string
+
+	// matches reports whether the driver configuration selects the Driver.
+	matches func(config *wafv1alpha1.DriverConfig) bool
+
+	// factory builds the Driver.
+	factory driverFactory
+}
+
+// driverRegistry lists the Driver implementations of the supported driver
+// type and mode combinations. Registering a Driver here is all it takes for
+// Engines whose driver configuration it matches to be provisioned with it.
+var driverRegistry = []driverRegistration{
+	{
+		name:    "istio/wasm",
+		matches: func(config *wafv1alpha1.DriverConfig) bool { return config.Istio != nil && config.Istio.Wasm != nil },
+		factory: func(r *EngineReconciler) Driver { return &istioWasmDriver{r} },
+	},
+	{
+		name:    "envoy/extproc",
+		matches: func(config *wafv1alpha1.DriverConfig) bool { return config.Envoy != nil && config.Envoy.ExtProc != nil },
+		factory: func(r *EngineReconciler) Driver { return &envoyExtProcDriver{r} },
+	},
+}
+
+// lookupDriver returns the registered Driver matching the Engine's driver
+// configuration, or an error if no single registered Driver matches it.
+func (r *EngineReconciler) lookupDriver(engine *wafv1alpha1.Engine) (Driver, error) {
+	var matched []driverRegistration
+	for _, registration := range driverRegistry {
+		if registration.matches(&engine.Spec.Driver) {
+			matched = append(matched, registration)
+		}
+	}
+
+	switch len(matched) {
+	case 0:
+		return nil, fmt.Errorf("no registered driver matches the driver configuration")
+	case 1:
+		return matched[0].factory(r), nil
+	default:
+		names := make([]string, 0, len(matched))
+		for _, registration := range matched {
+			names = append(names, registration.name)
+		}
+		return nil, fmt.Errorf("the driver configuration matches several drivers: %v", names)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Gateway RBAC
+// -----------------------------------------------------------------------------
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways,verbs=get;list;watch
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Gateway Consts
+// -----------------------------------------------------------------------------
+
+// GatewayNameLabel is the label Gateway API implementations set on the Pods
+// they deploy for a Gateway, with the Gateway's name as its value.
+const GatewayNameLabel = wafv1alpha1.GatewayNameLabel
+
+// gatewayGVK is the GroupVersionKind of Gateway API's Gateway.
+var gatewayGVK = schema.GroupVersionKind{
+	Group:   "gateway.networking.k8s.io",
+	Version: "v1",
+	Kind:    "Gateway",
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Gateway Watch
+// -----------------------------------------------------------------------------
+
+// gatewayWatchAvailable reports whether the Gateway API CRDs are installed,
+// so that Gateways can be watched. Without them, Engines are provisioned
+// without checking for a Gateway.
+func gatewayWatchAvailable(mapper apimeta.RESTMapper) bool {
+	_, err := mapper.RESTMapping(gatewayGVK.GroupKind(), gatewayGVK.Version)
+	return err == nil
+}
+
+// findEnginesForGateway maps a Gateway to the gateway mode Engines in its
+// namespace whose workload selector matches the Gateway's Pods, so that
+// they're re-evaluated when the Gateway appears, changes or goes away.
+func (r *EngineReconciler) findEnginesForGateway(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := logf.FromContext(ctx)
+
+	gateway, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	var engines wafv1alpha1.EngineList
+	if err := r.List(ctx, &engines, client.InNamespace(gateway.GetNamespace())); err != nil {
+		log.Error(err, "Engine: Failed to list Engines for Gateway", "gateway", gateway.GetName())
+		return nil
+	}
+
+	podLabels := gatewayPodLabels(gateway)
+	var requests []reconcile.Request
+	for i := range engines.Items {
+		if !engineSelectsGatewayPods(&engines.Items[i], podLabels) {
+			continue
+		}
+		req := ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      engines.Items[i].Name,
+				Namespace: engines.Items[i].Namespace,
+			},
+		}
+		requests = append(requests, req)
+
+		logInfo(log, req, "Engine", "Enqueuing for reconciliation due to Gateway change", "gateway", gateway.GetName())
+	}
+
+	return requests
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Gateway Lookup
+// -----------------------------------------------------------------------------
+
+// gatewayExistsForEngine reports whether a Gateway in the Engine's namespace
+// has Pods matching the Engine's workload selector. If the Gateway CRD isn't
+// installed there's nothing to check, so a Gateway is assumed to exist.
+func (r *EngineReconciler) gatewayExistsForEngine(ctx context.Context, engine *wafv1alpha1.Engine) (bool, error) {
+	gateways := &unstructured.UnstructuredList{}
+	gateways.SetGroupVersionKind(gatewayGVK.GroupVersion().WithKind("GatewayList"))
+	if err := r.List(ctx, gateways, client.InNamespace(engine.Namespace)); err != nil {
+		if apimeta.IsNoMatchError(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	for i := range gateways.Items {
+		if engineSelectsGatewayPods(engine, gatewayPodLabels(&gateways.Items[i])) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// engineSelectsGatewayPods reports whether the Engine is an Istio gateway
+// mode Engine whose workload selector matches Pods with the given labels.
+func engineSelectsGatewayPods(engine *wafv1alpha1.Engine, podLabels map[string]string) bool {
+	if engine.Spec.Driver.Istio == nil || engine.Spec.Driver.Istio.Wasm == nil {
+		return false
+	}
+	workloadSelector := wasmWorkloadSelector(engine)
+	if engine.Spec.Driver.Istio.Wasm.Mode != wafv1alpha1.IstioIntegrationModeGateway || workloadSelector == nil {
+		return false
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(workloadSelector)
+	if err != nil || selector.Empty() {
+		return false
+	}
+
+	return selector.Matches(labels.Set(podLabels))
+}
+
+// gatewayPodLabels returns the labels of the Pods deployed for the Gateway:
+// its infrastructure labels, plus the gateway name label.
+func gatewayPodLabels(gateway *unstructured.Unstructured) map[string]string {
+	podLabels, _, _ := unstructured.NestedStringMap(gateway.Object, "spec", "infrastructure", "labels")
+	if podLabels == nil {
+		podLabels = make(map[string]string, 1)
+	}
+	podLabels[GatewayNameLabel] = gateway.GetName()
+
+	return podLabels
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Rule Match Statistics - Consts
+// -----------------------------------------------------------------------------
+
+const (
+	// DefaultTopMatchedRules is the number of rules reported in an Engine's
+	// TopMatchedRules status.
+	DefaultTopMatchedRules = 10
+
+	// MatchStatisticsRefreshInterval is how often rule match statistics are
+	// refreshed for an Engine when a statistics source is configured.
+	MatchStatisticsRefreshInterval = 1 * time.Minute
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Rule Match Statistics - Source
+// -----------------------------------------------------------------------------
+
+// RuleMatchStatsSource provides per-rule match counts observed on the data
+// plane for an Engine. Implementations may be backed by the WAF's logs, a
+// metrics endpoint, or any other source of match data.
+type RuleMatchStatsSource interface {
+	// RuleMatches returns the number of matches for each rule ID observed for
+	// the Engine. Rules with no matches may be omitted.
+	RuleMatches(ctx context.Context, engine *wafv1alpha1.Engine) (map[int64]int64, error)
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Rule Match Statistics - Status
+// -----------------------------------------------------------------------------
+
+// updateRuleMatchStatistics refreshes the Engine's TopMatchedRules status from
+// the configured statistics source, and requeues so the statistics are
+// periodically refreshed. Failures to gather statistics are logged but never
+// fail the reconciliation, as they don't affect enforcement.
+func (r *EngineReconciler) updateRuleMatchStatistics(ctx context.Context, log logr.Logger, req ctrl.Request, engine *wafv1alpha1.Engine) (ctrl.Result, error) {
+	if r.matchStats == nil {
+		return ctrl.Result{}, nil
+	}
+
+	logDebug(log, req, "Engine", "Gathering rule match statistics")
+	matches, err := r.matchStats.RuleMatches(ctx, engine)
+	if err != nil {
+		logError(log, req, "Engine", err, "Failed to gather rule match statistics")
+		return ctrl.Result{RequeueAfter: MatchStatisticsRefreshInterval}, nil
+	}
+
+	patch := client.MergeFrom(engine.DeepCopy())
+	now := metav1.Now()
+	engine.Status.TopMatchedRules = topRuleMatches(matches, DefaultTopMatchedRules)
+	engine.Status.MatchStatisticsUpdated = &now
+	if err := r.Status().Patch(ctx, engine, patch); err != nil {
+		logError(log, req, "Engine", err, "Failed to patch rule match statistics")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: MatchStatisticsRefreshInterval}, nil
+}
+
+// topRuleMatches returns up to n rules with the most matches, ordered from
+// most to least matches. Ties are ordered by ascending rule ID so the result
+// is deterministic.
+func topRuleMatches(matches map[int64]int64, n int) []wafv1alpha1.RuleMatchCount {
+	counts := make([]wafv1alpha1.RuleMatchCount, 0, len(matches))
+	for id, count := range matches {
+		if count <= 0 {
+			continue
+		}
+		counts = append(counts, wafv1alpha1.RuleMatchCount{RuleID: id, Matches: count})
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Matches != counts[j].Matches {
+			return counts[i].Matches > counts[j].Matches
+		}
+		return counts[i].RuleID < counts[j].RuleID
+	})
+
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+
+	return counts
+}
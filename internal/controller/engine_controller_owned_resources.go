@@ -0,0 +1,89 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Owned Resources
+// -----------------------------------------------------------------------------
+
+// ownedResourceRef returns a reference to a resource provisioned for an
+// Engine, for recording in its status.ownedResources.
+func ownedResourceRef(obj client.Object) corev1.ObjectReference {
+	apiVersion, kind := obj.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
+	return corev1.ObjectReference{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+// upsertOwnedResource records the resource in the Engine's owned resources,
+// replacing an existing entry with the same kind, API version and name, so
+// that resources of different kinds coexist.
+func upsertOwnedResource(engine *wafv1alpha1.Engine, ref corev1.ObjectReference) {
+	for i, owned := range engine.Status.OwnedResources {
+		if owned.Kind == ref.Kind && owned.APIVersion == ref.APIVersion && owned.Name == ref.Name {
+			engine.Status.OwnedResources[i] = ref
+			return
+		}
+	}
+	engine.Status.OwnedResources = append(engine.Status.OwnedResources, ref)
+}
+
+// deleteOwnedResources deletes the resources recorded in the Engine's owned
+// resources, along with the given resources provisioned for it, which are
+// included in case they were created before the Engine's status recorded
+// them. Resources that are already gone, or whose kind isn't installed, are
+// not considered an error.
+func (r *EngineReconciler) deleteOwnedResources(ctx context.Context, log logr.Logger, req ctrl.Request, engine wafv1alpha1.Engine, provisioned ...client.Object) error {
+	engine.Status.OwnedResources = slices.Clone(engine.Status.OwnedResources)
+	for _, obj := range provisioned {
+		upsertOwnedResource(&engine, ownedResourceRef(obj))
+	}
+
+	for _, ref := range engine.Status.OwnedResources {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+		obj.SetNamespace(ref.Namespace)
+		obj.SetName(ref.Name)
+
+		logDebug(log, req, "Engine", "Deleting owned resource", "kind", ref.Kind, "name", ref.Name)
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil && !apimeta.IsNoMatchError(err) {
+			logError(log, req, "Engine", err, "Failed to delete owned resource", "kind", ref.Kind, "name", ref.Name)
+			return err
+		}
+		logInfo(log, req, "Engine", "Owned resource removed", "kind", ref.Kind, "namespace", ref.Namespace, "name", ref.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Pinned Rules - Consts
+// -----------------------------------------------------------------------------
+
+// PinnedRulesVersionAnnotation, when set on an Engine to the UUID of a
+// version of its rules in the RuleSet cache, retains that version through
+// cache garbage collection, e.g. while its data plane is rolled back to it.
+const PinnedRulesVersionAnnotation = "waf.k8s.coraza.io/pinned-rules-version"
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Pinned Rules
+// -----------------------------------------------------------------------------
+
+// updatePinnedRulesVersion pins the version of the Engine's rules named by its
+// PinnedRulesVersionAnnotation in the RuleSet cache, or unpins the Engine's
+// versions if it has no such annotation. It emits a Warning event when the
+// pinned version is found not to be cached, or to be outdated by a newer
+// version, once per pinned (and latest) version.
+func (r *EngineReconciler) updatePinnedRulesVersion(log logr.Logger, req ctrl.Request, engine *wafv1alpha1.Engine) {
+	if r.ruleSetCache == nil {
+		return
+	}
+
+	owner := req.String()
+	pinned := engine.Annotations[PinnedRulesVersionAnnotation]
+	if pinned == "" {
+		r.ruleSetCache.SetPinned(owner, "")
+		r.pinnedVersionWarnings.transition(req.NamespacedName, "")
+		return
+	}
+
+	cacheKey := engineCacheKey(engine)
+	r.ruleSetCache.SetPinned(owner, cacheKey, pinned)
+
+	found := false
+	for _, entry := range r.ruleSetCache.ListEntries(cacheKey) {
+		if entry.UUID == pinned {
+			found = true
+			break
+		}
+	}
+	if !found {
+		if r.pinnedVersionWarnings.transition(req.NamespacedName, wafv1alpha1.EventReasonPinnedVersionNotFound+"/"+pinned) {
+			logInfo(log, req, "Engine", "Pinned rules version not found in cache", "cacheKey", cacheKey, "version", pinned)
+			r.Recorder.Eventf(engine, nil, "Warning", wafv1alpha1.EventReasonPinnedVersionNotFound, "Reconcile",
+				"Pinned rules version %s was not found in the cache for %s", pinned, cacheKey)
+		}
+		return
+	}
+
+	if _, latest, ok := r.ruleSetCache.ServedVersion(cacheKey); ok && latest != pinned {
+		if r.pinnedVersionWarnings.transition(req.NamespacedName, wafv1alpha1.EventReasonPinnedVersionOutdated+"/"+pinned+"/"+latest) {
+			logInfo(log, req, "Engine", "Pinned rules version is outdated", "cacheKey", cacheKey, "version", pinned, "latest", latest)
+			r.Recorder.Eventf(engine, nil, "Warning", wafv1alpha1.EventReasonPinnedVersionOutdated, "Reconcile",
+				"Pinned rules version %s for %s is outdated by version %s", pinned, cacheKey, latest)
+		}
+		return
+	}
+	r.pinnedVersionWarnings.transition(req.NamespacedName, "")
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Provisioning Failures
+// -----------------------------------------------------------------------------
+
+// failureCounter tracks the number of consecutive provisioning failures of
+// each Engine. The zero value is ready to use.
+type failureCounter struct {
+	mu     sync.Mutex
+	counts map[types.NamespacedName]int
+}
+
+// increment records a failure for the Engine, returning the number of
+// consecutive failures including it.
+func (c *failureCounter) increment(key types.NamespacedName) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[types.NamespacedName]int)
+	}
+	c.counts[key]++
+	return c.counts[key]
+}
+
+// reset forgets the failures of the Engine.
+func (c *failureCounter) reset(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.counts, key)
+}
+
+// backoff returns the delay before retrying after the given number of
+// consecutive failures: BaseDelay, doubling with each further failure up to
+// MaxDelay.
+func (c *RateLimiterConfig) backoff(failures int) time.Duration {
+	delay := c.BaseDelay
+	for i := 1; i < failures && delay < c.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, c.MaxDelay)
+}
+
+// handleProvisioningFailure marks the Engine Degraded after its driver failed
+// to apply the named resource, and requeues it with an exponential backoff
+// so that a persistently rejected resource (e.g. its CRD isn't installed)
+// doesn't hot-loop. The ProvisioningFailed event is only emitted for the
+// first of consecutive failures.
+func (r *EngineReconciler) handleProvisioningFailure(ctx context.Context, log logr.Logger, req ctrl.Request, engine *wafv1alpha1.Engine, kind string, err error) (ctrl.Result, error) {
+	limits := r.rateLimiter
+	if limits == nil {
+		limits = DefaultRateLimiter()
+	}
+	failures := r.provisioningFailures.increment(req.NamespacedName)
+	delay := limits.backoff(failures)
+	logError(log, req, "Engine", err, fmt.Sprintf("Failed to create or update %s", kind), "failures", failures, "retryAfter", delay)
+
+	if failures == 1 {
+		r.Recorder.Eventf(engine, nil, "Warning", wafv1alpha1.ReasonProvisioningFailed, "Provision", "Failed to create %s: %v", kind, err)
+	}
+
+	patch := client.MergeFrom(engine.DeepCopy())
+	setStatusConditionDegraded(log, req, "Engine", &engine.Status.Conditions, engine.Generation, wafv1alpha1.ReasonProvisioningFailed, fmt.Sprintf("Failed to create or update %s: %v", kind, err))
+	if updateErr := r.Status().Patch(ctx, engine, patch); updateErr != nil {
+		logError(log, req, "Engine", updateErr, "Failed to patch status after provisioning failure")
+	}
+
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
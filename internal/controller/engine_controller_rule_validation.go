@@ -0,0 +1,144 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Rule Validation Gate - Consts
+// -----------------------------------------------------------------------------
+
+const (
+	// RequireValidRulesAnnotation, when set to "true" on an Engine, blocks
+	// provisioning the Engine until its rules are cached and none of its
+	// RuleSets failed rule validation.
+	RequireValidRulesAnnotation = "waf.k8s.coraza.io/require-valid-rules"
+
+	// RulesValidationRecheckInterval is how often an Engine blocked by the
+	// rule validation gate is requeued to check its rules again, as a
+	// fallback to the reconciliation triggered by changes to its RuleSets.
+	RulesValidationRecheckInterval = 10 * time.Second
+)
+
+// ruleValidationReasons are the reasons a RuleSet is Degraded for when its
+// rules failed the RuleSet controller's validation.
+var ruleValidationReasons = []string{
+	wafv1alpha1.ReasonInvalidConfigMap,
+	wafv1alpha1.ReasonInvalidSecret,
+	wafv1alpha1.ReasonInvalidRuleSource,
+	wafv1alpha1.ReasonInvalidURLSource,
+	wafv1alpha1.ReasonInvalidRules,
+	wafv1alpha1.ReasonInvalidAggregatedRules,
+	wafv1alpha1.ReasonDuplicateRuleID,
+	wafv1alpha1.ReasonInvalidPluginConfig,
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Rule Validation Gate
+// -----------------------------------------------------------------------------
+
+// requiresValidRules reports whether the Engine opted into the rule
+// validation gate with the RequireValidRulesAnnotation.
+func requiresValidRules(engine *wafv1alpha1.Engine) bool {
+	return engine.Annotations[RequireValidRulesAnnotation] == "true"
+}
+
+// gateOnRuleValidation blocks provisioning the Engine on the validation
+// verdict of its RuleSets, if the Engine requires valid rules. The rules
+// aren't compiled again: the RuleSet controller already validated them
+// (honoring sources which opted out of validation) before caching them. A
+// non-zero result means provisioning must not proceed: the Engine is
+// Progressing while its rules aren't cached yet, and Degraded with reason
+// RulesInvalid while one of its RuleSets is Degraded for failing validation.
+// Resources provisioned for earlier, valid rules are left as is.
+func (r *EngineReconciler) gateOnRuleValidation(ctx context.Context, log logr.Logger, req ctrl.Request, engine *wafv1alpha1.Engine) (ctrl.Result, error) {
+	if !requiresValidRules(engine) {
+		return ctrl.Result{}, nil
+	}
+
+	cacheKey := engineCacheKey(engine)
+	cached := false
+	if r.ruleSetCache != nil {
+		_, cached = r.ruleSetCache.Peek(cacheKey)
+	}
+
+	patch := client.MergeFrom(engine.DeepCopy())
+	if !cached {
+		logInfo(log, req, "Engine", "Waiting for rules to be cached before provisioning", "cacheKey", cacheKey)
+		setStatusProgressing(log, req, "Engine", &engine.Status.Conditions, engine.Generation, "RulesNotCached",
+			fmt.Sprintf("Waiting for the rules of %s to be cached", cacheKey))
+		if err := r.Status().Patch(ctx, engine, patch); err != nil {
+			logError(log, req, "Engine", err, "Failed to patch status while waiting for rules")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: RulesValidationRecheckInterval}, nil
+	}
+
+	for _, name := range engineRuleSetNames(engine) {
+		var ruleSet wafv1alpha1.RuleSet
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: engine.Namespace}, &ruleSet); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			logError(log, req, "Engine", err, "Failed to get RuleSet", "ruleSetName", name)
+			return ctrl.Result{}, err
+		}
+
+		degraded := ruleValidationFailure(&ruleSet)
+		if degraded == nil {
+			continue
+		}
+
+		logInfo(log, req, "Engine", "RuleSet failed rule validation, not provisioning", "ruleSetName", name, "reason", degraded.Reason)
+		msg := fmt.Sprintf("RuleSet %s failed rule validation (%s): %s", name, degraded.Reason, degraded.Message)
+		r.Recorder.Eventf(engine, nil, "Warning", wafv1alpha1.ReasonRulesInvalid, "Reconcile", "Not provisioning as %s", msg)
+		setStatusConditionDegraded(log, req, "Engine", &engine.Status.Conditions, engine.Generation, wafv1alpha1.ReasonRulesInvalid, msg)
+		if err := r.Status().Patch(ctx, engine, patch); err != nil {
+			logError(log, req, "Engine", err, "Failed to patch status after rule validation failure")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: RulesValidationRecheckInterval}, nil
+	}
+
+	logDebug(log, req, "Engine", "RuleSets passed rule validation", "cacheKey", cacheKey)
+	return ctrl.Result{}, nil
+}
+
+// ruleValidationFailure returns the RuleSet's Degraded condition if it is
+// Degraded because its rules failed validation, or nil otherwise.
+func ruleValidationFailure(ruleSet *wafv1alpha1.RuleSet) *metav1.Condition {
+	degraded := apimeta.FindStatusCondition(ruleSet.Status.Conditions, "Degraded")
+	if degraded == nil || degraded.Status != metav1.ConditionTrue || !slices.Contains(ruleValidationReasons, degraded.Reason) {
+		return nil
+	}
+	return degraded
+}
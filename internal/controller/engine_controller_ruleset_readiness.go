@@ -0,0 +1,166 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - RuleSet Readiness - Consts
+// -----------------------------------------------------------------------------
+
+const (
+	// RequireReadyRuleSetsAnnotation, when set to "true" on an Engine, blocks
+	// provisioning the Engine until all of its RuleSets are Ready.
+	RequireReadyRuleSetsAnnotation = "waf.k8s.coraza.io/require-ready-rulesets"
+
+	// RuleSetReadinessRecheckInterval is how often an Engine whose RuleSets
+	// aren't Ready is requeued to check them again, as a fallback to the
+	// reconciliation triggered by changes to its RuleSets.
+	RuleSetReadinessRecheckInterval = 10 * time.Second
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - RuleSet Readiness
+// -----------------------------------------------------------------------------
+
+// requiresReadyRuleSets reports whether the Engine opted into blocking its
+// provisioning on RuleSet readiness with the RequireReadyRuleSetsAnnotation.
+func requiresReadyRuleSets(engine *wafv1alpha1.Engine) bool {
+	return engine.Annotations[RequireReadyRuleSetsAnnotation] == "true"
+}
+
+// updateRuleSetReadiness sets the Engine's RuleSetReady condition from the
+// Ready conditions of its RuleSets, and returns its status: True if all the
+// RuleSets are Ready, False if any of them isn't (e.g. it's Degraded by a
+// missing ConfigMap), and Unknown if any of them doesn't exist or wasn't
+// reconciled yet.
+func (r *EngineReconciler) updateRuleSetReadiness(ctx context.Context, log logr.Logger, req ctrl.Request, engine *wafv1alpha1.Engine) (metav1.ConditionStatus, error) {
+	names := engineRuleSetNames(engine)
+	ruleSets := make(map[string]*wafv1alpha1.RuleSet, len(names))
+	for _, name := range names {
+		var ruleSet wafv1alpha1.RuleSet
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: engine.Namespace}, &ruleSet); err != nil {
+			if !apierrors.IsNotFound(err) {
+				logError(log, req, "Engine", err, "Failed to get RuleSet", "ruleSetName", name)
+				return metav1.ConditionUnknown, err
+			}
+			continue
+		}
+		ruleSets[name] = &ruleSet
+	}
+
+	patch := client.MergeFrom(engine.DeepCopy())
+	status := setStatusRuleSetReady(&engine.Status.Conditions, engine.Generation, names, ruleSets)
+	if err := r.Status().Patch(ctx, engine, patch); err != nil {
+		logError(log, req, "Engine", err, "Failed to patch RuleSet readiness status")
+		return status, err
+	}
+
+	logDebug(log, req, "Engine", "Checked RuleSet readiness", "status", status)
+	return status, nil
+}
+
+// setStatusRuleSetReady sets the RuleSetReady condition for the named
+// RuleSets, given those of them which exist, and returns its status. A
+// RuleSet which isn't Ready takes precedence over one which is missing or
+// pending, as it is the more actionable problem.
+func setStatusRuleSetReady(conditions *[]metav1.Condition, generation int64, names []string, ruleSets map[string]*wafv1alpha1.RuleSet) metav1.ConditionStatus {
+	var unknownReason, unknownMessage string
+	for _, name := range names {
+		ruleSet, ok := ruleSets[name]
+		if !ok {
+			if unknownReason == "" {
+				unknownReason, unknownMessage = "RuleSetNotFound", fmt.Sprintf("RuleSet %s does not exist", name)
+			}
+			continue
+		}
+
+		ready := apimeta.FindStatusCondition(ruleSet.Status.Conditions, "Ready")
+		switch {
+		case ready == nil || ready.Status == metav1.ConditionUnknown:
+			if unknownReason == "" {
+				unknownReason, unknownMessage = "RuleSetPending", fmt.Sprintf("RuleSet %s has not been reconciled yet", name)
+			}
+		case ready.Status == metav1.ConditionFalse:
+			setConditionFalse(conditions, generation, "RuleSetReady", wafv1alpha1.ReasonRuleSetNotReady,
+				fmt.Sprintf("RuleSet %s is not Ready (%s): %s", name, ready.Reason, ready.Message))
+			return metav1.ConditionFalse
+		}
+	}
+
+	if unknownReason != "" {
+		setConditionUnknown(conditions, generation, "RuleSetReady", unknownReason, unknownMessage)
+		return metav1.ConditionUnknown
+	}
+
+	setConditionTrue(conditions, generation, "RuleSetReady", "RuleSetsReady", "All RuleSets are Ready")
+	return metav1.ConditionTrue
+}
+
+// blockOnRuleSetReadiness marks the Engine as Progressing while provisioning
+// is blocked on its RuleSets becoming Ready, and requeues it to check them
+// again. Resources provisioned earlier are left as is.
+func (r *EngineReconciler) blockOnRuleSetReadiness(ctx context.Context, log logr.Logger, req ctrl.Request, engine *wafv1alpha1.Engine) (ctrl.Result, error) {
+	condition := apimeta.FindStatusCondition(engine.Status.Conditions, "RuleSetReady")
+	logInfo(log, req, "Engine", "Waiting for RuleSets to be Ready before provisioning", "reason", condition.Reason)
+
+	patch := client.MergeFrom(engine.DeepCopy())
+	setStatusProgressing(log, req, "Engine", &engine.Status.Conditions, engine.Generation, condition.Reason,
+		fmt.Sprintf("Waiting for RuleSets to be Ready: %s", condition.Message))
+	if err := r.Status().Patch(ctx, engine, patch); err != nil {
+		logError(log, req, "Engine", err, "Failed to patch status while waiting for RuleSets")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: RuleSetReadinessRecheckInterval}, nil
+}
+
+// setStatusProvisioned marks a provisioned Engine as Ready, or as Degraded
+// if one of its RuleSets isn't Ready, so it doesn't report Ready while its
+// data plane may be serving outdated or incomplete rules. As the condition is
+// decided before it's written, Ready doesn't flap while the Engine is
+// rechecked, and the Warning event is only emitted when the Engine becomes
+// Degraded for it.
+func (r *EngineReconciler) setStatusProvisioned(log logr.Logger, req ctrl.Request, engine *wafv1alpha1.Engine, reason, message string) {
+	ruleSetReady := apimeta.FindStatusCondition(engine.Status.Conditions, "RuleSetReady")
+	if ruleSetReady == nil || ruleSetReady.Status != metav1.ConditionFalse {
+		setStatusReady(log, req, "Engine", &engine.Status.Conditions, engine.Generation, reason, message)
+		return
+	}
+
+	degraded := apimeta.FindStatusCondition(engine.Status.Conditions, "Degraded")
+	if degraded == nil || degraded.Status != metav1.ConditionTrue ||
+		degraded.Reason != wafv1alpha1.ReasonRuleSetNotReady || degraded.Message != ruleSetReady.Message {
+		logInfo(log, req, "Engine", "Provisioned, but a RuleSet is not Ready")
+		r.Recorder.Eventf(engine, nil, "Warning", wafv1alpha1.ReasonRuleSetNotReady, "Reconcile", ruleSetReady.Message)
+	}
+	setStatusConditionDegraded(log, req, "Engine", &engine.Status.Conditions, engine.Generation, wafv1alpha1.ReasonRuleSetNotReady, ruleSetReady.Message)
+}
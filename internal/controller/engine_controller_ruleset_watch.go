@@ -0,0 +1,113 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - RuleSet Watch - Consts
+// -----------------------------------------------------------------------------
+
+// engineRuleSetIndexKey is the field index mapping Engines to the names of
+// the RuleSets they load rules from.
+const engineRuleSetIndexKey = "spec.ruleSetNames"
+
+// -----------------------------------------------------------------------------
+// Engine Controller - RuleSet Watch
+// -----------------------------------------------------------------------------
+
+// indexEngineRuleSets returns the names of the RuleSets referenced by an
+// Engine, for use with engineRuleSetIndexKey.
+func indexEngineRuleSets(obj client.Object) []string {
+	engine, ok := obj.(*wafv1alpha1.Engine)
+	if !ok {
+		return nil
+	}
+	return engineRuleSetNames(engine)
+}
+
+// ruleSetReadinessChangedPredicate filters RuleSet updates down to those
+// which change its spec or its Ready condition, as nothing else about a
+// RuleSet affects the Engines referencing it.
+func ruleSetReadinessChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldRuleSet, ok := e.ObjectOld.(*wafv1alpha1.RuleSet)
+			if !ok {
+				return false
+			}
+			newRuleSet, ok := e.ObjectNew.(*wafv1alpha1.RuleSet)
+			if !ok {
+				return false
+			}
+			if oldRuleSet.Generation != newRuleSet.Generation {
+				return true
+			}
+
+			oldReady := apimeta.FindStatusCondition(oldRuleSet.Status.Conditions, "Ready")
+			newReady := apimeta.FindStatusCondition(newRuleSet.Status.Conditions, "Ready")
+			if oldReady == nil || newReady == nil {
+				return oldReady != newReady
+			}
+			return oldReady.Status != newReady.Status || oldReady.Reason != newReady.Reason
+		},
+	}
+}
+
+// findEnginesForRuleSet maps a RuleSet to the Engines in its namespace which
+// load rules from it, so that their RuleSetReady condition follows the
+// RuleSet's readiness promptly.
+func (r *EngineReconciler) findEnginesForRuleSet(ctx context.Context, ruleSet client.Object) []reconcile.Request {
+	log := logf.FromContext(ctx)
+
+	var engines wafv1alpha1.EngineList
+	if err := r.List(ctx, &engines,
+		client.InNamespace(ruleSet.GetNamespace()),
+		client.MatchingFields{engineRuleSetIndexKey: ruleSet.GetName()},
+	); err != nil {
+		log.Error(err, "Engine: Failed to list Engines for RuleSet", "ruleSet", ruleSet.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(engines.Items))
+	for _, engine := range engines.Items {
+		req := ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      engine.Name,
+				Namespace: engine.Namespace,
+			},
+		}
+		requests = append(requests, req)
+
+		logInfo(log, req, "Engine", "Enqueuing for reconciliation due to RuleSet change", "ruleSet", ruleSet.GetName())
+	}
+
+	return requests
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets"
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets/cache"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - RuleSets - Consts
+// -----------------------------------------------------------------------------
+
+// EngineAggregateInstancePrefix prefixes the name in the RuleSet cache
+// instance holding the aggregated rules of an Engine with multiple RuleSets.
+// RuleSet names can't contain a colon, so these never collide with the
+// instances of RuleSets.
+const EngineAggregateInstancePrefix = "engine:"
+
+// -----------------------------------------------------------------------------
+// Engine Controller - RuleSets
+// -----------------------------------------------------------------------------
+
+// engineRuleSetNames returns the names of the RuleSets the Engine loads rules
+// from, in order of precedence: the RuleSet referenced by ruleSet first,
+// followed by ruleSets in the order listed. RuleSets referenced more than
+// once are only returned the first time.
+func engineRuleSetNames(engine *wafv1alpha1.Engine) []string {
+	refs := make([]wafv1alpha1.RuleSetReference, 0, len(engine.Spec.RuleSets)+1)
+	if engine.Spec.RuleSet.Name != "" {
+		refs = append(refs, engine.Spec.RuleSet)
+	}
+	refs = append(refs, engine.Spec.RuleSets...)
+
+	seen := make(map[string]bool, len(refs))
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if seen[ref.Name] {
+			continue
+		}
+		seen[ref.Name] = true
+		names = append(names, ref.Name)
+	}
+
+	return names
+}
+
+// engineCacheKey returns the RuleSet cache instance the Engine's data plane
+// loads rules from: the instance of its RuleSet if it only has one, or else
+// the instance aggregating the rules of all its RuleSets.
+func engineCacheKey(engine *wafv1alpha1.Engine) string {
+	names := engineRuleSetNames(engine)
+	if len(names) == 1 {
+		return cache.KeyFor(engine.Namespace, names[0])
+	}
+	return engineAggregateCacheKey(engine.Namespace, engine.Name)
+}
+
+// engineAggregateCacheKey returns the RuleSet cache instance aggregating the
+// rules of the named Engine's RuleSets.
+func engineAggregateCacheKey(namespace, name string) string {
+	return cache.KeyFor(namespace, EngineAggregateInstancePrefix+name)
+}
+
+// aggregateEngineRules stores the concatenated latest rules of the Engine's
+// RuleSets, in order of precedence, in the Engine's aggregate cache
+// instance, once they pass validation: RuleSets defining the same rule ids
+// are rejected, and the aggregated rules are compiled with validate unless it
+// is nil. It is a no-op for Engines with a single RuleSet, and while any of
+// the RuleSets isn't cached yet, in which case the RuleSet controller
+// aggregates the rules once it is. It returns whether a new version of the
+// aggregated rules was cached, or why they are invalid.
+func aggregateEngineRules(rulesetCache *cache.RuleSetCache, engine *wafv1alpha1.Engine, validate func(rules string) error) (bool, error) {
+	names := engineRuleSetNames(engine)
+	if len(names) < 2 {
+		return false, nil
+	}
+
+	sources := make([]rulesets.Source, 0, len(names))
+	for _, name := range names {
+		entry, ok := rulesetCache.Peek(cache.KeyFor(engine.Namespace, name))
+		if !ok {
+			return false, nil
+		}
+		sources = append(sources, rulesets.Source{Name: "RuleSet " + name, Rules: entry.Rules})
+	}
+
+	if duplicates := rulesets.FindSourceDuplicateRuleIDs(sources); len(duplicates) > 0 {
+		return false, &rulesets.SourceDuplicateRuleIDsError{Duplicates: duplicates}
+	}
+
+	cacheKey := engineAggregateCacheKey(engine.Namespace, engine.Name)
+	rules := rulesets.JoinSources(sources, "\n")
+	if current, ok := rulesetCache.Peek(cacheKey); ok && current.Rules == rules {
+		return false, nil
+	}
+	if validate != nil {
+		if err := validate(rules); err != nil {
+			return false, err
+		}
+	}
+	rulesetCache.Put(cacheKey, rules)
+
+	return true, nil
+}
+
+// engineAggregateValidation returns the function compiling the aggregated
+// rules of the Engine's RuleSets with validation, or nil if validation is nil
+// (aggregated rules validation is disabled) or a source of any of the
+// RuleSets opted out of validation, as their aggregate can't be compiled
+// either. If any of the RuleSets has Secret sources, its errors are redacted.
+func engineAggregateValidation(validation *validationCache, engine *wafv1alpha1.Engine, ruleSets []*wafv1alpha1.RuleSet) func(rules string) error {
+	if validation == nil {
+		return nil
+	}
+	redact := false
+	for _, ruleSet := range ruleSets {
+		for _, source := range ruleSet.Status.ResolvedSources {
+			if source.ValidationSkipped {
+				return nil
+			}
+			if source.Kind == wafv1alpha1.RuleSourceKindSecret {
+				redact = true
+			}
+		}
+	}
+
+	key := engineAggregateValidationKey(engine)
+	return func(rules string) error {
+		err := validation.validate(key, rules)
+		if redact {
+			return rulesets.Redact(rules, err)
+		}
+		return err
+	}
+}
+
+// engineAggregateValidationKey returns the key of the Engine's aggregated
+// rules in the validation cache, which never collides with those of RuleSets.
+func engineAggregateValidationKey(engine *wafv1alpha1.Engine) types.NamespacedName {
+	return types.NamespacedName{Namespace: engine.Namespace, Name: EngineAggregateInstancePrefix + engine.Name}
+}
+
+// engineRuleSets returns those of the Engine's RuleSets which exist, using
+// known in place of fetching the RuleSet of the same name, if not nil.
+func engineRuleSets(ctx context.Context, c client.Reader, engine *wafv1alpha1.Engine, known *wafv1alpha1.RuleSet) ([]*wafv1alpha1.RuleSet, error) {
+	names := engineRuleSetNames(engine)
+	ruleSets := make([]*wafv1alpha1.RuleSet, 0, len(names))
+	for _, name := range names {
+		if known != nil && known.Name == name {
+			ruleSets = append(ruleSets, known)
+			continue
+		}
+
+		var ruleSet wafv1alpha1.RuleSet
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: engine.Namespace}, &ruleSet); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		ruleSets = append(ruleSets, &ruleSet)
+	}
+
+	return ruleSets, nil
+}
+
+// aggregateRuleSets aggregates the rules of the Engine's RuleSets in the
+// RuleSet cache, if it has multiple RuleSets. If their aggregate is invalid,
+// the Engine is Degraded and requeued to check them again, while its data
+// plane keeps the last valid aggregate.
+func (r *EngineReconciler) aggregateRuleSets(ctx context.Context, log logr.Logger, req ctrl.Request, engine *wafv1alpha1.Engine) (ctrl.Result, error) {
+	if r.ruleSetCache == nil || len(engineRuleSetNames(engine)) < 2 {
+		return ctrl.Result{}, nil
+	}
+
+	ruleSets, err := engineRuleSets(ctx, r.Client, engine, nil)
+	if err != nil {
+		logError(log, req, "Engine", err, "Failed to get RuleSets")
+		return ctrl.Result{}, err
+	}
+
+	cached, err := aggregateEngineRules(r.ruleSetCache, engine, engineAggregateValidation(r.aggregateValidation, engine, ruleSets))
+	if err != nil {
+		reason := invalidRulesReason(err, wafv1alpha1.ReasonInvalidAggregatedRules)
+		var dupErr *rulesets.SourceDuplicateRuleIDsError
+		if errors.As(err, &dupErr) {
+			reason = wafv1alpha1.ReasonDuplicateRuleID
+		}
+
+		logInfo(log, req, "Engine", "Aggregated rules of RuleSets are invalid", "reason", reason)
+		patch := client.MergeFrom(engine.DeepCopy())
+		msg := fmt.Sprintf("Aggregated rules of RuleSets %v are invalid:\n%v", engineRuleSetNames(engine), err)
+		r.Recorder.Eventf(engine, nil, "Warning", reason, "Reconcile", msg)
+		setStatusConditionDegraded(log, req, "Engine", &engine.Status.Conditions, engine.Generation, reason, msg)
+		if err := r.Status().Patch(ctx, engine, patch); err != nil {
+			logError(log, req, "Engine", err, "Failed to patch status")
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: RulesValidationRecheckInterval}, nil
+	}
+	if cached {
+		logInfo(log, req, "Engine", "Stored aggregated rules of RuleSets in cache", "cacheKey", engineCacheKey(engine))
+	}
+
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Served Rules - Consts
+// -----------------------------------------------------------------------------
+
+const (
+	// ServedRulesPendingInterval is how often an Engine is requeued to check
+	// whether the latest rules were served to the data plane, while they
+	// weren't yet.
+	ServedRulesPendingInterval = 10 * time.Second
+
+	// ServedRulesRefreshInterval is how often an Engine is requeued once the
+	// latest rules were served to the data plane, so that later rule changes
+	// which don't trigger a reconciliation of the Engine are noticed.
+	ServedRulesRefreshInterval = 1 * time.Minute
+)
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Served Rules - Source
+// -----------------------------------------------------------------------------
+
+// ServedRulesSource reports which version of a RuleSet's rules was served to
+// the data plane. It is implemented by the RuleSet cache, whose server
+// records the versions it serves.
+type ServedRulesSource interface {
+	// ServedVersion returns the UUID of the rules most recently served for
+	// the cache instance, which is empty if none were served yet, and the
+	// UUID of the latest rules. It returns false if the instance isn't
+	// cached.
+	ServedVersion(instance string) (served, latest string, ok bool)
+}
+
+// -----------------------------------------------------------------------------
+// Engine Controller - Served Rules - Status
+// -----------------------------------------------------------------------------
+
+// updateServedRules refreshes the Engine's ServedRuleSetUUID status and
+// RulesServed condition from the configured source, and returns how long to
+// wait before checking them again (zero if no source is configured).
+func (r *EngineReconciler) updateServedRules(ctx context.Context, log logr.Logger, req ctrl.Request, engine *wafv1alpha1.Engine) time.Duration {
+	if r.servedRules == nil {
+		return 0
+	}
+
+	served, latest, ok := r.servedRules.ServedVersion(engineCacheKey(engine))
+
+	original := engine.DeepCopy()
+	setStatusRulesServed(&engine.Status, engine.Generation, served, latest, ok)
+	// The Engine is requeued periodically, so avoid patching (and triggering
+	// watches with) a status which didn't change.
+	if !equality.Semantic.DeepEqual(original.Status, engine.Status) {
+		if err := r.Status().Patch(ctx, engine, client.MergeFrom(original)); err != nil {
+			logError(log, req, "Engine", err, "Failed to patch served rules status")
+		}
+	}
+
+	if apimeta.IsStatusConditionTrue(engine.Status.Conditions, "RulesServed") {
+		return ServedRulesRefreshInterval
+	}
+	return ServedRulesPendingInterval
+}
+
+// setStatusRulesServed sets the Engine's ServedRuleSetUUID to the served
+// version of its rules, and the RulesServed condition to whether it is the
+// latest version. found reports whether the RuleSet's rules are cached at
+// all. A previously served UUID is retained until another version is served,
+// as nothing having been served yet (e.g. after an operator restart) doesn't
+// mean the data plane unloaded the rules.
+//
+// Rules being served doesn't confirm that the data plane loaded them: it may
+// fail to compile them, and with multiple data plane instances, a single
+// instance fetching the rules marks them as served for all of them.
+func setStatusRulesServed(status *wafv1alpha1.EngineStatus, generation int64, served, latest string, found bool) {
+	if served != "" {
+		status.ServedRuleSetUUID = served
+	}
+
+	switch {
+	case !found:
+		setConditionUnknown(&status.Conditions, generation, "RulesServed", "RulesNotCached",
+			"The RuleSet's rules are not cached")
+	case served == "":
+		setConditionUnknown(&status.Conditions, generation, "RulesServed", "AwaitingDataPlane",
+			"The data plane has not fetched the RuleSet's rules yet")
+	case served == latest:
+		setConditionTrue(&status.Conditions, generation, "RulesServed", "RulesServed",
+			fmt.Sprintf("The latest rules (uuid: %s) were served to the data plane", latest))
+	default:
+		setConditionFalse(&status.Conditions, generation, "RulesServed", "RulesOutdated",
+			fmt.Sprintf("Rules %s were served to the data plane, but the latest rules are %s", served, latest))
+	}
+}
@@ -18,15 +18,42 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets"
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets/cache"
 	"github.com/networking-incubator/coraza-kubernetes-operator/test/utils"
 )
 
@@ -133,24 +160,38 @@ func TestEngineReconciler_ReconcileIstioDriver(t *testing.T) {
 		Namespace: engine.Namespace,
 	}, &updated)
 	require.NoError(t, err)
-	assert.Len(t, updated.Status.Conditions, 1)
-	condition := updated.Status.Conditions[0]
-	assert.Equal(t, "Ready", condition.Type)
+	assert.Len(t, updated.Status.Conditions, 2)
+	condition := apimeta.FindStatusCondition(updated.Status.Conditions, "Ready")
+	require.NotNil(t, condition)
 	assert.Equal(t, metav1.ConditionTrue, condition.Status)
 	assert.Equal(t, "Configured", condition.Reason)
+	ruleSetReady := apimeta.FindStatusCondition(updated.Status.Conditions, "RuleSetReady")
+	require.NotNil(t, ruleSetReady)
+	assert.Equal(t, metav1.ConditionUnknown, ruleSetReady.Status)
+	assert.Equal(t, "RuleSetNotFound", ruleSetReady.Reason)
+	assert.Equal(t, WasmPluginNamePrefix+engine.Name, updated.Status.WasmPluginName)
+	assert.Equal(t, []corev1.ObjectReference{{
+		APIVersion: "extensions.istio.io/v1alpha1",
+		Kind:       "WasmPlugin",
+		Namespace:  engine.Namespace,
+		Name:       WasmPluginNamePrefix + engine.Name,
+	}}, updated.Status.OwnedResources)
 
-	assert.True(t, recorder.HasEvent("Normal", "WasmPluginCreated"),
+	assert.True(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonWasmPluginCreated),
 		"expected Normal/WasmPluginCreated event; got: %v", recorder.Events)
 }
 
-func TestEngineReconciler_StatusUpdateHandling(t *testing.T) {
+func TestEngineReconciler_ReconcileIstioSidecarMode(t *testing.T) {
 	ctx := context.Background()
 
-	t.Log("Creating test engine for status update testing")
+	t.Log("Creating test engine in sidecar mode")
 	engine := utils.NewTestEngine(utils.EngineOptions{
-		Name:      "status-test",
-		Namespace: "default",
+		Name:                 "sidecar-engine",
+		Namespace:            "default",
+		WorkloadLabels:       map[string]string{"app": "sidecar-workload"},
+		IstioIntegrationMode: wafv1alpha1.IstioIntegrationModeSidecar,
 	})
+	engine.Spec.Driver.Istio.Wasm.MatchContext = wafv1alpha1.IstioMatchContextOutbound
 	require.NoError(t, k8sClient.Create(ctx, engine))
 	t.Cleanup(func() {
 		if err := k8sClient.Delete(ctx, engine); err != nil {
@@ -158,122 +199,2796 @@ func TestEngineReconciler_StatusUpdateHandling(t *testing.T) {
 		}
 	})
 
-	t.Log("Reconciling engine to verify status update")
+	t.Log("Reconciling sidecar Engine")
+	recorder := utils.NewFakeRecorder()
 	reconciler := &EngineReconciler{
 		Client:                    k8sClient,
 		Scheme:                    scheme,
-		Recorder:                  utils.NewTestRecorder(),
+		Recorder:                  recorder,
 		ruleSetCacheServerCluster: "test-cluster",
 	}
 	_, err := reconciler.Reconcile(ctx, ctrl.Request{
-		NamespacedName: types.NamespacedName{
-			Name:      engine.Name,
-			Namespace: engine.Namespace,
-		},
+		NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: engine.Namespace},
 	})
 	require.NoError(t, err)
+	assert.True(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonWasmPluginCreated),
+		"expected Normal/WasmPluginCreated event; got: %v", recorder.Events)
+
+	t.Log("Verifying the WasmPlugin targets the sidecar workload's outbound traffic")
+	wasmPlugin := &unstructured.Unstructured{}
+	wasmPlugin.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "extensions.istio.io",
+		Version: "v1alpha1",
+		Kind:    "WasmPlugin",
+	})
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: WasmPluginNamePrefix + engine.Name, Namespace: engine.Namespace}, wasmPlugin))
+	matchLabels, _, err := unstructured.NestedStringMap(wasmPlugin.Object, "spec", "selector", "matchLabels")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": "sidecar-workload"}, matchLabels)
+	match, _, err := unstructured.NestedSlice(wasmPlugin.Object, "spec", "match")
+	require.NoError(t, err)
+	assert.Equal(t, []any{map[string]any{"mode": "CLIENT"}}, match)
 
-	t.Log("Verifying status conditions were set")
 	var updated wafv1alpha1.Engine
-	err = k8sClient.Get(ctx, types.NamespacedName{
-		Name:      engine.Name,
-		Namespace: engine.Namespace,
-	}, &updated)
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: engine.Name, Namespace: engine.Namespace}, &updated))
+	assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Ready"))
+}
+
+func TestEngineReconciler_Paused(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating a paused Engine")
+	engine := utils.NewTestEngine(utils.EngineOptions{
+		Name:                 "paused-engine",
+		Namespace:            "default",
+		WorkloadLabels:       map[string]string{"app": "paused-workload"},
+		IstioIntegrationMode: wafv1alpha1.IstioIntegrationModeSidecar,
+	})
+	engine.Annotations = map[string]string{PausedAnnotation: "true"}
+	require.NoError(t, k8sClient.Create(ctx, engine))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, engine); err != nil {
+			t.Logf("Failed to delete engine: %v", err)
+		}
+	})
+
+	recorder := utils.NewFakeRecorder()
+	reconciler := &EngineReconciler{
+		Client:                    k8sClient,
+		Scheme:                    scheme,
+		Recorder:                  recorder,
+		ruleSetCacheServerCluster: "test-cluster",
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: engine.Namespace}}
+	wasmPluginKey := types.NamespacedName{Name: WasmPluginNamePrefix + engine.Name, Namespace: engine.Namespace}
+	wasmPlugin := &unstructured.Unstructured{}
+	wasmPlugin.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "extensions.istio.io",
+		Version: "v1alpha1",
+		Kind:    "WasmPlugin",
+	})
+
+	t.Log("Verifying no WasmPlugin is created while the Engine is paused")
+	for range 2 {
+		result, err := reconciler.Reconcile(ctx, req)
+		require.NoError(t, err)
+		assert.True(t, result.IsZero())
+	}
+	err := k8sClient.Get(ctx, wasmPluginKey, wasmPlugin)
+	assert.True(t, apierrors.IsNotFound(err), "WasmPlugin should not exist, got: %v", err)
+
+	var updated wafv1alpha1.Engine
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Paused"))
+	assert.Nil(t, apimeta.FindStatusCondition(updated.Status.Conditions, "Ready"))
+	assert.NotContains(t, updated.Finalizers, engineFinalizer)
+	paused := 0
+	for _, e := range recorder.Events {
+		if e.Reason == wafv1alpha1.EventReasonReconciliationPaused {
+			paused++
+		}
+	}
+	assert.Equal(t, 1, paused, "the paused event should only be emitted once")
+
+	t.Log("Resuming the Engine")
+	delete(updated.Annotations, PausedAnnotation)
+	require.NoError(t, k8sClient.Update(ctx, &updated))
+	_, err = reconciler.Reconcile(ctx, req)
 	require.NoError(t, err)
-	if len(updated.Status.Conditions) > 0 {
-		condition := updated.Status.Conditions[0]
-		assert.NotEmpty(t, condition.Type)
-		assert.NotEmpty(t, condition.Status)
-		assert.NotEmpty(t, condition.Reason)
+
+	t.Log("Verifying the WasmPlugin is created once the Engine is resumed")
+	require.NoError(t, k8sClient.Get(ctx, wasmPluginKey, wasmPlugin))
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	assert.Nil(t, apimeta.FindStatusCondition(updated.Status.Conditions, "Paused"))
+	assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Ready"))
+	assert.True(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonReconciliationResumed),
+		"expected Normal/ReconciliationResumed event; got: %v", recorder.Events)
+}
+
+func TestEngineReconciler_Finalizer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating and reconciling an Istio Engine")
+	engine := utils.NewTestEngine(utils.EngineOptions{
+		Name:      "finalizer-engine",
+		Namespace: "default",
+	})
+	require.NoError(t, k8sClient.Create(ctx, engine))
+	reconciler := &EngineReconciler{
+		Client:                    k8sClient,
+		Scheme:                    scheme,
+		Recorder:                  utils.NewFakeRecorder(),
+		ruleSetCacheServerCluster: "test-cluster",
 	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: engine.Namespace}}
+	_, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+
+	var updated wafv1alpha1.Engine
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	assert.Contains(t, updated.Finalizers, engineFinalizer)
+	wasmPlugin := &unstructured.Unstructured{}
+	wasmPlugin.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "extensions.istio.io",
+		Version: "v1alpha1",
+		Kind:    "WasmPlugin",
+	})
+	wasmPluginKey := types.NamespacedName{Name: WasmPluginNamePrefix + engine.Name, Namespace: engine.Namespace}
+	require.NoError(t, k8sClient.Get(ctx, wasmPluginKey, wasmPlugin))
+
+	t.Log("Deleting the Engine and reconciling the deletion")
+	require.NoError(t, k8sClient.Delete(ctx, engine))
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+
+	t.Log("Verifying the WasmPlugin was cleaned up and the Engine is gone")
+	err = k8sClient.Get(ctx, wasmPluginKey, wasmPlugin)
+	assert.True(t, apierrors.IsNotFound(err), "WasmPlugin should be deleted, got: %v", err)
+	err = k8sClient.Get(ctx, req.NamespacedName, &updated)
+	assert.True(t, apierrors.IsNotFound(err), "Engine should be deleted, got: %v", err)
 }
 
-func TestEngineReconciler_ValidationRejection(t *testing.T) {
+func TestEngineReconciler_ReconcileMultipleRuleSets(t *testing.T) {
+	ctx := context.Background()
+	ns := "default"
+
+	t.Log("Creating an Engine with a base RuleSet and an overlay RuleSet")
+	engine := utils.NewTestEngine(utils.EngineOptions{
+		Name:        "multi-ruleset-engine",
+		Namespace:   ns,
+		RuleSetName: "base-ruleset",
+	})
+	engine.Spec.RuleSets = []wafv1alpha1.RuleSetReference{{Name: "overlay-ruleset"}}
+	require.NoError(t, k8sClient.Create(ctx, engine))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, engine); err != nil {
+			t.Logf("Failed to delete engine: %v", err)
+		}
+	})
+
+	t.Log("Caching the rules of both RuleSets")
+	rulesetCache := cache.NewRuleSetCache()
+	rulesetCache.Put(ns+"/base-ruleset", "SecRuleEngine On")
+	rulesetCache.Put(ns+"/overlay-ruleset", `SecRule ARGS "@contains attack" "id:1,deny"`)
+
+	t.Log("Reconciling the Engine")
+	reconciler := &EngineReconciler{
+		Client:                    k8sClient,
+		Scheme:                    scheme,
+		Recorder:                  utils.NewFakeRecorder(),
+		ruleSetCacheServerCluster: "test-cluster",
+		ruleSetCache:              rulesetCache,
+	}
+	_, err := reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: ns},
+	})
+	require.NoError(t, err)
+
+	t.Log("Verifying the aggregated rules are cached in order")
+	entry, ok := rulesetCache.Get(ns + "/engine:" + engine.Name)
+	require.True(t, ok, "expected the aggregated rules to be cached")
+	assert.Equal(t, "SecRuleEngine On\nSecRule ARGS \"@contains attack\" \"id:1,deny\"", entry.Rules)
+
+	t.Log("Verifying the WasmPlugin loads the aggregated rules")
+	wasmPlugin := &unstructured.Unstructured{}
+	wasmPlugin.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "extensions.istio.io",
+		Version: "v1alpha1",
+		Kind:    "WasmPlugin",
+	})
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: WasmPluginNamePrefix + engine.Name, Namespace: ns}, wasmPlugin))
+	instance, _, err := unstructured.NestedString(wasmPlugin.Object, "spec", "pluginConfig", "cache_server_instance")
+	require.NoError(t, err)
+	assert.Equal(t, ns+"/engine:"+engine.Name, instance)
+
+	t.Log("Caching overlay rules reusing a rule id of the base RuleSet")
+	rulesetCache.Put(ns+"/base-ruleset", `SecRule ARGS "@contains base" "id:1,deny"`)
+	result, err := reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: ns},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, RulesValidationRecheckInterval, result.RequeueAfter)
+
+	t.Log("Verifying the Engine is Degraded and keeps the last valid aggregate")
+	var updated wafv1alpha1.Engine
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: engine.Name, Namespace: ns}, &updated))
+	degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+	assert.Equal(t, wafv1alpha1.ReasonDuplicateRuleID, degraded.Reason)
+	latest, ok := rulesetCache.Get(ns + "/engine:" + engine.Name)
+	require.True(t, ok)
+	assert.Equal(t, entry.UUID, latest.UUID)
+}
+
+func TestEngineReconciler_RequireValidRules(t *testing.T) {
 	ctx := context.Background()
+	ns := "default"
 
 	tests := []struct {
 		name          string
-		engineFunc    func() *wafv1alpha1.Engine
-		expectedError string
+		rules         string
+		ruleSetReason string
+		expectReady   bool
+		expectReason  string
 	}{
 		{
-			name: "ruleset with empty name",
-			engineFunc: func() *wafv1alpha1.Engine {
-				engine := utils.NewTestEngine(utils.EngineOptions{})
-				engine.Spec.RuleSet = wafv1alpha1.RuleSetReference{
-					Name: "",
-				}
-				return engine
-			},
-			expectedError: "spec.ruleSet.name in body should be at least 1 chars long",
+			name:         "valid rules are provisioned",
+			rules:        `SecRule REQUEST_URI "@contains /admin" "id:1,phase:1,deny,status:403"`,
+			expectReady:  true,
+			expectReason: "Configured",
 		},
 		{
-			name: "no driver specified",
-			engineFunc: func() *wafv1alpha1.Engine {
-				engine := utils.NewTestEngine(utils.EngineOptions{})
-				engine.Spec.Driver = wafv1alpha1.DriverConfig{}
-				return engine
-			},
-			expectedError: "exactly one driver must be specified",
+			name:          "rules of a RuleSet which failed validation are not provisioned",
+			rules:         `SecRule REQUEST_URI "@contains /admin" "id:1,phase:1,deny,status:403"`,
+			ruleSetReason: wafv1alpha1.ReasonInvalidRules,
+			expectReason:  wafv1alpha1.ReasonRulesInvalid,
 		},
 		{
-			name: "no istio integration mode specified",
-			engineFunc: func() *wafv1alpha1.Engine {
-				engine := utils.NewTestEngine(utils.EngineOptions{})
-				engine.Spec.Driver.Istio = &wafv1alpha1.IstioDriverConfig{}
-				return engine
-			},
-			expectedError: "exactly one integration mechanism (Wasm, etc) must be specified",
+			name:         "rules opted out of validation are provisioned",
+			rules:        `SecRule REMOTE_ADDR "@ipMatchFromFile /etc/coraza/blocklist.txt" "id:1,phase:1,deny,status:403"`,
+			expectReady:  true,
+			expectReason: "Configured",
 		},
 		{
-			name: "image doesn't start with oci://",
-			engineFunc: func() *wafv1alpha1.Engine {
-				engine := utils.NewTestEngine(utils.EngineOptions{})
-				engine.Spec.Driver.Istio.Wasm.Image = "docker://invalid-image"
-				return engine
-			},
-			expectedError: "spec.driver.istio.wasm.image in body should match '^oci://'",
+			name:         "uncached rules are not provisioned",
+			expectReason: "RulesNotCached",
 		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := fmt.Sprintf("require-valid-rules-%d", i)
+			engine := utils.NewTestEngine(utils.EngineOptions{
+				Name:           name,
+				Namespace:      ns,
+				RuleSetName:    name,
+				WorkloadLabels: map[string]string{"app": name},
+			})
+			engine.Annotations = map[string]string{RequireValidRulesAnnotation: "true"}
+			require.NoError(t, k8sClient.Create(ctx, engine))
+			t.Cleanup(func() {
+				if err := k8sClient.Delete(ctx, engine); err != nil {
+					t.Logf("Failed to delete engine: %v", err)
+				}
+			})
+
+			rulesetCache := cache.NewRuleSetCache()
+			if tt.rules != "" {
+				rulesetCache.Put(ns+"/"+name, tt.rules)
+			}
+
+			if tt.ruleSetReason != "" {
+				t.Log("Creating a RuleSet degraded by failed rule validation")
+				ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+					Name:      name,
+					Namespace: ns,
+					Rules: []wafv1alpha1.RuleSourceReference{
+						{Kind: wafv1alpha1.RuleSourceKindInline, Rules: "SecRuleEngine On"},
+					},
+				})
+				require.NoError(t, k8sClient.Create(ctx, ruleSet))
+				t.Cleanup(func() {
+					if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+						t.Logf("Failed to delete RuleSet: %v", err)
+					}
+				})
+				apimeta.SetStatusCondition(&ruleSet.Status.Conditions, metav1.Condition{
+					Type: "Ready", Status: metav1.ConditionFalse, Reason: tt.ruleSetReason, Message: "rules are invalid",
+				})
+				apimeta.SetStatusCondition(&ruleSet.Status.Conditions, metav1.Condition{
+					Type: "Degraded", Status: metav1.ConditionTrue, Reason: tt.ruleSetReason, Message: "rules are invalid",
+				})
+				require.NoError(t, k8sClient.Status().Update(ctx, ruleSet))
+			}
+
+			recorder := utils.NewFakeRecorder()
+			reconciler := &EngineReconciler{
+				Client:                    k8sClient,
+				Scheme:                    scheme,
+				Recorder:                  recorder,
+				ruleSetCacheServerCluster: "test-cluster",
+				ruleSetCache:              rulesetCache,
+			}
+			result, err := reconciler.Reconcile(ctx, ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: name, Namespace: ns},
+			})
+			require.NoError(t, err)
+			if !tt.expectReady {
+				assert.Equal(t, RulesValidationRecheckInterval, result.RequeueAfter, "blocked Engines should be rechecked")
+			}
+
+			var updated wafv1alpha1.Engine
+			require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: ns}, &updated))
+			ready := apimeta.FindStatusCondition(updated.Status.Conditions, "Ready")
+			require.NotNil(t, ready)
+			assert.Equal(t, tt.expectReady, ready.Status == metav1.ConditionTrue)
+			assert.Equal(t, tt.expectReason, ready.Reason)
+			assert.Equal(t, tt.expectReason == wafv1alpha1.ReasonRulesInvalid, recorder.HasEvent("Warning", wafv1alpha1.ReasonRulesInvalid),
+				"unexpected Warning/RulesInvalid events: %v", recorder.Events)
+
+			wasmPlugin := &unstructured.Unstructured{}
+			wasmPlugin.SetGroupVersionKind(schema.GroupVersionKind{
+				Group:   "extensions.istio.io",
+				Version: "v1alpha1",
+				Kind:    "WasmPlugin",
+			})
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: WasmPluginNamePrefix + name, Namespace: ns}, wasmPlugin)
+			if tt.expectReady {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, apierrors.IsNotFound(err), "expected no WasmPlugin; got: %v", err)
+			}
+		})
+	}
+}
+
+func TestEngineReconciler_RuleSetReadiness(t *testing.T) {
+	ctx := context.Background()
+	ns := "default"
+
+	tests := []struct {
+		name         string
+		requireReady bool
+		expectReason string
+		expectPlugin bool
+		expectEvent  bool
+	}{
 		{
-			name: "image too short",
-			engineFunc: func() *wafv1alpha1.Engine {
-				engine := utils.NewTestEngine(utils.EngineOptions{})
-				engine.Spec.Driver.Istio.Wasm.Image = ""
-				return engine
-			},
-			expectedError: "spec.driver.istio.wasm.image in body should be at least 1 chars long",
+			name:         "provisioned but not Ready",
+			expectReason: wafv1alpha1.ReasonRuleSetNotReady,
+			expectPlugin: true,
+			expectEvent:  true,
 		},
 		{
-			name: "image too long",
-			engineFunc: func() *wafv1alpha1.Engine {
-				engine := utils.NewTestEngine(utils.EngineOptions{})
-				engine.Spec.Driver.Istio.Wasm.Image = "oci://" + string(make([]byte, 1100))
-				return engine
-			},
-			expectedError: "spec.driver.istio.wasm.image: Too long",
+			name:         "provisioning blocked",
+			requireReady: true,
+			expectReason: wafv1alpha1.ReasonRuleSetNotReady,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := fmt.Sprintf("ruleset-readiness-%d", i)
+
+			t.Log("Creating a RuleSet referencing a missing ConfigMap")
+			ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+				Name:      name,
+				Namespace: ns,
+				Rules:     []wafv1alpha1.RuleSourceReference{{Name: name}},
+			})
+			require.NoError(t, k8sClient.Create(ctx, ruleSet))
+			t.Cleanup(func() {
+				if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+					t.Logf("Failed to delete RuleSet: %v", err)
+				}
+			})
+			ruleSetReq := ctrl.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: ns}}
+			ruleSetReconciler := &RuleSetReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: utils.NewTestRecorder(),
+				Cache:    cache.NewRuleSetCache(),
+			}
+			_, err := ruleSetReconciler.Reconcile(ctx, ruleSetReq)
+			require.NoError(t, err)
+
+			engine := utils.NewTestEngine(utils.EngineOptions{
+				Name:           name,
+				Namespace:      ns,
+				RuleSetName:    name,
+				WorkloadLabels: map[string]string{"app": name},
+			})
+			if tt.requireReady {
+				engine.Annotations = map[string]string{RequireReadyRuleSetsAnnotation: "true"}
+			}
+			require.NoError(t, k8sClient.Create(ctx, engine))
+			t.Cleanup(func() {
+				if err := k8sClient.Delete(ctx, engine); err != nil {
+					t.Logf("Failed to delete engine: %v", err)
+				}
+			})
+
+			t.Log("Verifying the Engine doesn't report Ready while its RuleSet is Degraded")
+			recorder := utils.NewFakeRecorder()
+			reconciler := &EngineReconciler{
+				Client:                    k8sClient,
+				Scheme:                    scheme,
+				Recorder:                  recorder,
+				ruleSetCacheServerCluster: "test-cluster",
+			}
+			engineReq := ctrl.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: ns}}
+			result, err := reconciler.Reconcile(ctx, engineReq)
+			require.NoError(t, err)
+			assert.Equal(t, RuleSetReadinessRecheckInterval, result.RequeueAfter)
+
+			var updated wafv1alpha1.Engine
+			require.NoError(t, k8sClient.Get(ctx, engineReq.NamespacedName, &updated))
+			ruleSetReady := apimeta.FindStatusCondition(updated.Status.Conditions, "RuleSetReady")
+			require.NotNil(t, ruleSetReady)
+			assert.Equal(t, metav1.ConditionFalse, ruleSetReady.Status)
+			assert.Equal(t, wafv1alpha1.ReasonRuleSetNotReady, ruleSetReady.Reason)
+			assert.Contains(t, ruleSetReady.Message, wafv1alpha1.ReasonConfigMapNotFound)
+			ready := apimeta.FindStatusCondition(updated.Status.Conditions, "Ready")
+			require.NotNil(t, ready)
+			assert.Equal(t, metav1.ConditionFalse, ready.Status)
+			assert.Equal(t, tt.expectReason, ready.Reason)
+			assert.Equal(t, tt.expectEvent, recorder.HasEvent("Warning", wafv1alpha1.ReasonRuleSetNotReady),
+				"unexpected Warning/RuleSetNotReady events: %v", recorder.Events)
+
+			t.Log("Verifying rechecking the Engine doesn't flap its Ready condition")
+			readyTransition := metav1.NewTime(ready.LastTransitionTime.Add(-time.Hour))
+			ready.LastTransitionTime = readyTransition
+			require.NoError(t, k8sClient.Status().Update(ctx, &updated))
+			_, err = reconciler.Reconcile(ctx, engineReq)
+			require.NoError(t, err)
+			require.NoError(t, k8sClient.Get(ctx, engineReq.NamespacedName, &updated))
+			ready = apimeta.FindStatusCondition(updated.Status.Conditions, "Ready")
+			require.NotNil(t, ready)
+			assert.Equal(t, metav1.ConditionFalse, ready.Status)
+			assert.Equal(t, tt.expectReason, ready.Reason)
+			assert.Equal(t, readyTransition, ready.LastTransitionTime)
+			notReadyEvents := 0
+			for _, event := range recorder.Events {
+				if event.Reason == wafv1alpha1.ReasonRuleSetNotReady {
+					notReadyEvents++
+				}
+			}
+			if tt.expectEvent {
+				assert.Equal(t, 1, notReadyEvents, "RuleSetNotReady should only be emitted once: %v", recorder.Events)
+			}
+
+			wasmPlugin := &unstructured.Unstructured{}
+			wasmPlugin.SetGroupVersionKind(schema.GroupVersionKind{
+				Group:   "extensions.istio.io",
+				Version: "v1alpha1",
+				Kind:    "WasmPlugin",
+			})
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: WasmPluginNamePrefix + name, Namespace: ns}, wasmPlugin)
+			if tt.expectPlugin {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, apierrors.IsNotFound(err), "expected no WasmPlugin; got: %v", err)
+			}
+
+			t.Log("Creating the ConfigMap and verifying the Engine becomes Ready")
+			configMap := utils.NewTestConfigMap(name, ns, `SecRule REQUEST_URI "@contains /admin" "id:1,phase:1,deny,status:403"`)
+			require.NoError(t, k8sClient.Create(ctx, configMap))
+			t.Cleanup(func() {
+				if err := k8sClient.Delete(ctx, configMap); err != nil {
+					t.Logf("Failed to delete ConfigMap: %v", err)
+				}
+			})
+			_, err = ruleSetReconciler.Reconcile(ctx, ruleSetReq)
+			require.NoError(t, err)
+			_, err = reconciler.Reconcile(ctx, engineReq)
+			require.NoError(t, err)
+
+			require.NoError(t, k8sClient.Get(ctx, engineReq.NamespacedName, &updated))
+			assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "RuleSetReady"))
+			assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Ready"))
+			assert.Nil(t, apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded"))
+		})
+	}
+}
+
+func TestSetStatusRuleSetReady(t *testing.T) {
+	ruleSetWithReady := func(status metav1.ConditionStatus, reason string) *wafv1alpha1.RuleSet {
+		ruleSet := &wafv1alpha1.RuleSet{}
+		apimeta.SetStatusCondition(&ruleSet.Status.Conditions, metav1.Condition{
+			Type:    "Ready",
+			Status:  status,
+			Reason:  reason,
+			Message: "message",
+		})
+		return ruleSet
+	}
+
+	tests := []struct {
+		name           string
+		names          []string
+		ruleSets       map[string]*wafv1alpha1.RuleSet
+		expectedStatus metav1.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name:           "all RuleSets Ready",
+			names:          []string{"base", "overlay"},
+			ruleSets:       map[string]*wafv1alpha1.RuleSet{"base": ruleSetWithReady(metav1.ConditionTrue, wafv1alpha1.EventReasonRulesCached), "overlay": ruleSetWithReady(metav1.ConditionTrue, wafv1alpha1.EventReasonRulesCached)},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: "RuleSetsReady",
 		},
 		{
-			name: "gateway mode without workloadSelector",
-			engineFunc: func() *wafv1alpha1.Engine {
-				engine := utils.NewTestEngine(utils.EngineOptions{})
-				engine.Spec.Driver.Istio.Wasm.Mode = wafv1alpha1.IstioIntegrationModeGateway
-				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = nil
-				return engine
-			},
-			expectedError: "workloadSelector is required when mode is gateway",
+			name:           "missing RuleSet",
+			names:          []string{"base"},
+			expectedStatus: metav1.ConditionUnknown,
+			expectedReason: "RuleSetNotFound",
+		},
+		{
+			name:           "RuleSet not reconciled yet",
+			names:          []string{"base"},
+			ruleSets:       map[string]*wafv1alpha1.RuleSet{"base": {}},
+			expectedStatus: metav1.ConditionUnknown,
+			expectedReason: "RuleSetPending",
+		},
+		{
+			name:           "Degraded RuleSet takes precedence over a missing one",
+			names:          []string{"base", "overlay"},
+			ruleSets:       map[string]*wafv1alpha1.RuleSet{"overlay": ruleSetWithReady(metav1.ConditionFalse, wafv1alpha1.ReasonConfigMapNotFound)},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: wafv1alpha1.ReasonRuleSetNotReady,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			t.Logf("Attempting to create Engine with invalid configuration: %s", tt.name)
-			engine := tt.engineFunc()
-			engine.Name = "validation-test-" + t.Name()
-			engine.Namespace = "default"
+			var conditions []metav1.Condition
+			status := setStatusRuleSetReady(&conditions, 1, tt.names, tt.ruleSets)
+			assert.Equal(t, tt.expectedStatus, status)
+			condition := apimeta.FindStatusCondition(conditions, "RuleSetReady")
+			require.NotNil(t, condition)
+			assert.Equal(t, tt.expectedStatus, condition.Status)
+			assert.Equal(t, tt.expectedReason, condition.Reason)
+		})
+	}
+}
 
-			err := k8sClient.Create(ctx, engine)
-			require.Error(t, err)
-			assert.Contains(t, err.Error(), tt.expectedError)
+func TestAggregateEngineRules(t *testing.T) {
+	engine := utils.NewTestEngine(utils.EngineOptions{
+		Name:        "aggregate",
+		RuleSetName: "base",
+	})
+	engine.Spec.RuleSets = []wafv1alpha1.RuleSetReference{{Name: "overlay"}, {Name: "base"}}
+	rulesetCache := cache.NewRuleSetCache()
+
+	assert.Equal(t, []string{"base", "overlay"}, engineRuleSetNames(engine))
+	assert.Equal(t, "default/engine:aggregate", engineCacheKey(engine))
+
+	t.Log("Verifying nothing is aggregated until every RuleSet is cached")
+	rulesetCache.Put("default/base", "base rules")
+	cached, err := aggregateEngineRules(rulesetCache, engine, nil)
+	require.NoError(t, err)
+	assert.False(t, cached)
+	_, ok := rulesetCache.Get("default/engine:aggregate")
+	assert.False(t, ok)
+
+	t.Log("Verifying the rules are aggregated in order of precedence")
+	rulesetCache.Put("default/overlay", "overlay rules")
+	cached, err = aggregateEngineRules(rulesetCache, engine, nil)
+	require.NoError(t, err)
+	assert.True(t, cached)
+	entry, ok := rulesetCache.Get("default/engine:aggregate")
+	require.True(t, ok)
+	assert.Equal(t, "base rules\noverlay rules", entry.Rules)
+
+	t.Log("Verifying unchanged rules aren't cached again")
+	cached, err = aggregateEngineRules(rulesetCache, engine, nil)
+	require.NoError(t, err)
+	assert.False(t, cached)
+
+	t.Log("Verifying RuleSets defining the same rule ids are rejected")
+	rulesetCache.Put("default/base", `SecRule ARGS "@contains a" "id:1,deny"`)
+	rulesetCache.Put("default/overlay", `SecRule ARGS "@contains b" "id:1,deny"`)
+	cached, err = aggregateEngineRules(rulesetCache, engine, nil)
+	var dupErr *rulesets.SourceDuplicateRuleIDsError
+	require.ErrorAs(t, err, &dupErr)
+	assert.Contains(t, err.Error(), "RuleSet overlay")
+	assert.False(t, cached)
+
+	t.Log("Verifying aggregated rules failing validation aren't cached")
+	rulesetCache.Put("default/overlay", `SecRule ARGS "@contains b" "id:2,deny"`)
+	validationErr := errors.New("invalid")
+	cached, err = aggregateEngineRules(rulesetCache, engine, func(string) error { return validationErr })
+	require.ErrorIs(t, err, validationErr)
+	assert.False(t, cached)
+	entry, ok = rulesetCache.Get("default/engine:aggregate")
+	require.True(t, ok)
+	assert.Equal(t, "base rules\noverlay rules", entry.Rules)
+
+	t.Log("Verifying aggregated rules passing validation are cached")
+	cached, err = aggregateEngineRules(rulesetCache, engine, func(string) error { return nil })
+	require.NoError(t, err)
+	assert.True(t, cached)
+
+	t.Log("Verifying an Engine with a single RuleSet uses the RuleSet's instance")
+	engine.Spec.RuleSets = nil
+	assert.Equal(t, "default/base", engineCacheKey(engine))
+	cached, err = aggregateEngineRules(rulesetCache, engine, nil)
+	require.NoError(t, err)
+	assert.False(t, cached)
+}
+
+func TestEngineAggregateValidation(t *testing.T) {
+	engine := utils.NewTestEngine(utils.EngineOptions{Name: "aggregate", RuleSetName: "base"})
+	validation := newValidationCache(func(string) error { return errors.New("invalid") })
+	validated := &wafv1alpha1.RuleSet{Status: wafv1alpha1.RuleSetStatus{
+		ResolvedSources: []wafv1alpha1.ResolvedSource{{Name: "validated"}},
+	}}
+	optedOut := &wafv1alpha1.RuleSet{Status: wafv1alpha1.RuleSetStatus{
+		ResolvedSources: []wafv1alpha1.ResolvedSource{{Name: "validated"}, {Name: "opted-out", ValidationSkipped: true}},
+	}}
+
+	assert.Nil(t, engineAggregateValidation(nil, engine, []*wafv1alpha1.RuleSet{validated}))
+	assert.Nil(t, engineAggregateValidation(validation, engine, []*wafv1alpha1.RuleSet{validated, optedOut}))
+	validate := engineAggregateValidation(validation, engine, []*wafv1alpha1.RuleSet{validated})
+	require.NotNil(t, validate)
+	err := validate("rules")
+	require.Error(t, err)
+	var redacted *rulesets.RedactedError
+	assert.False(t, errors.As(err, &redacted), "errors of RuleSets without Secret sources shouldn't be redacted")
+
+	t.Log("Verifying errors are redacted if a RuleSet has Secret sources")
+	secret := &wafv1alpha1.RuleSet{Status: wafv1alpha1.RuleSetStatus{
+		ResolvedSources: []wafv1alpha1.ResolvedSource{{Name: "secret", Kind: wafv1alpha1.RuleSourceKindSecret}},
+	}}
+	validate = engineAggregateValidation(validation, engine, []*wafv1alpha1.RuleSet{validated, secret})
+	require.NotNil(t, validate)
+	err = validate("rules")
+	require.ErrorAs(t, err, &redacted)
+	assert.NotContains(t, err.Error(), "invalid")
+}
+
+func TestCacheKeyConsistency(t *testing.T) {
+	ctx := context.Background()
+
+	cm := utils.NewTestConfigMap("key-rules", "default", `SecRule ARGS "@contains attack" "id:1,deny"`)
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "key-ruleset",
+		Namespace: "default",
+		Rules:     []wafv1alpha1.RuleSourceReference{{Name: cm.Name}},
+	})
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(cm, ruleSet).
+		WithStatusSubresource(ruleSet).
+		Build()
+
+	t.Log("Caching the RuleSet's rules with the RuleSet controller")
+	rulesetCache := cache.NewRuleSetCache()
+	ruleSetReconciler := &RuleSetReconciler{
+		Client:   c,
+		Scheme:   scheme,
+		Recorder: utils.NewTestRecorder(),
+		Cache:    rulesetCache,
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}}
+	_, err := ruleSetReconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, []string{cache.KeyFor(ruleSet.Namespace, ruleSet.Name)}, rulesetCache.ListKeys())
+
+	t.Log("Verifying the WasmPlugin of an Engine using the RuleSet polls the key it was cached under")
+	engine := utils.NewTestEngine(utils.EngineOptions{Name: "key-engine", Namespace: ruleSet.Namespace, RuleSetName: ruleSet.Name})
+	engineReconciler := &EngineReconciler{ruleSetCacheServerCluster: "test-cluster"}
+	wasmPlugin, err := engineReconciler.buildWasmPlugin(engine)
+	require.NoError(t, err)
+	instance, _, err := unstructured.NestedString(wasmPlugin.Object, "spec", "pluginConfig", "cache_server_instance")
+	require.NoError(t, err)
+	_, ok := rulesetCache.Get(instance)
+	assert.True(t, ok, "WasmPlugin polls %q, but the cache holds %v", instance, rulesetCache.ListKeys())
+}
+
+func TestEngineReconciler_SelectorConflict(t *testing.T) {
+	ctx := context.Background()
+	ns := "default"
+
+	reconcileEngine := func(t *testing.T, name string, labels map[string]string, priority *int32) (*utils.FakeRecorder, ctrl.Result) {
+		t.Helper()
+		engine := utils.NewTestEngine(utils.EngineOptions{
+			Name:           name,
+			Namespace:      ns,
+			WorkloadLabels: labels,
+		})
+		engine.Spec.Priority = priority
+		require.NoError(t, k8sClient.Create(ctx, engine))
+		t.Cleanup(func() {
+			if err := k8sClient.Delete(ctx, engine); err != nil {
+				t.Logf("Failed to delete engine: %v", err)
+			}
+		})
+
+		recorder := utils.NewFakeRecorder()
+		reconciler := &EngineReconciler{
+			Client:                    k8sClient,
+			Scheme:                    scheme,
+			Recorder:                  recorder,
+			ruleSetCacheServerCluster: "test-cluster",
+		}
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: ns},
+		})
+		require.NoError(t, err)
+		return recorder, result
+	}
+	wasmPluginExists := func(engineName string) bool {
+		wasmPlugin := &unstructured.Unstructured{}
+		wasmPlugin.SetGroupVersionKind(schema.GroupVersionKind{
+			Group:   "extensions.istio.io",
+			Version: "v1alpha1",
+			Kind:    "WasmPlugin",
+		})
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: WasmPluginNamePrefix + engineName, Namespace: ns}, wasmPlugin)
+		return err == nil
+	}
+
+	high, low := int32(10), int32(-10)
+
+	t.Log("Provisioning the first Engine")
+	recorder, _ := reconcileEngine(t, "selector-a", map[string]string{"app": "selector-gateway"}, &high)
+	assert.True(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonWasmPluginCreated),
+		"expected Normal/WasmPluginCreated event; got: %v", recorder.Events)
+	require.True(t, wasmPluginExists("selector-a"))
+
+	t.Log("Verifying an Engine with an overlapping selector is rejected")
+	recorder, result := reconcileEngine(t, "selector-b", map[string]string{"app": "selector-gateway", "tier": "edge"}, nil)
+	assert.True(t, result.IsZero())
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonSelectorConflict),
+		"expected Warning/SelectorConflict event; got: %v", recorder.Events)
+	assert.False(t, wasmPluginExists("selector-b"))
+	var conflicted wafv1alpha1.Engine
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: "selector-b", Namespace: ns}, &conflicted))
+	degraded := apimeta.FindStatusCondition(conflicted.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, wafv1alpha1.ReasonSelectorConflict, degraded.Reason)
+	assert.Contains(t, degraded.Message, WasmPluginNamePrefix+"selector-a")
+
+	t.Log("Verifying an Engine with a disjoint selector is provisioned")
+	recorder, _ = reconcileEngine(t, "selector-c", map[string]string{"app": "other-gateway"}, nil)
+	assert.True(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonWasmPluginCreated),
+		"expected Normal/WasmPluginCreated event; got: %v", recorder.Events)
+	assert.False(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonSelectorConflict),
+		"unexpected Warning/SelectorConflict event; got: %v", recorder.Events)
+	assert.True(t, wasmPluginExists("selector-c"))
+
+	t.Log("Verifying an Engine with an overlapping selector and the same priority is rejected")
+	recorder, _ = reconcileEngine(t, "selector-d", map[string]string{"app": "selector-gateway"}, &high)
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonSelectorConflict),
+		"expected Warning/SelectorConflict event; got: %v", recorder.Events)
+	assert.False(t, wasmPluginExists("selector-d"))
+
+	t.Log("Verifying an Engine with an overlapping selector and a distinct priority is provisioned")
+	recorder, _ = reconcileEngine(t, "selector-e", map[string]string{"app": "selector-gateway"}, &low)
+	assert.True(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonWasmPluginCreated),
+		"expected Normal/WasmPluginCreated event; got: %v", recorder.Events)
+	assert.False(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonSelectorConflict),
+		"unexpected Warning/SelectorConflict event; got: %v", recorder.Events)
+	assert.True(t, wasmPluginExists("selector-e"))
+}
+
+func TestEngineReconciler_SelectorConflictAfterSelectorChange(t *testing.T) {
+	ctx := context.Background()
+	ns := "default"
+
+	recorder := utils.NewFakeRecorder()
+	reconciler := &EngineReconciler{
+		Client:                    k8sClient,
+		Scheme:                    scheme,
+		Recorder:                  recorder,
+		ruleSetCacheServerCluster: "test-cluster",
+	}
+	reconcileEngine := func(t *testing.T, name string) {
+		t.Helper()
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: name, Namespace: ns},
+		})
+		require.NoError(t, err)
+	}
+	wasmPluginExists := func(engineName string) bool {
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: WasmPluginNamePrefix + engineName, Namespace: ns}, wasmPluginRef(&wafv1alpha1.Engine{}))
+		return err == nil
+	}
+
+	t.Log("Provisioning an older and a newer Engine with disjoint selectors")
+	older := utils.NewTestEngine(utils.EngineOptions{
+		Name:           "change-selector-a",
+		Namespace:      ns,
+		WorkloadLabels: map[string]string{"app": "change-selector-a"},
+	})
+	newer := utils.NewTestEngine(utils.EngineOptions{
+		Name:           "change-selector-b",
+		Namespace:      ns,
+		WorkloadLabels: map[string]string{"app": "change-selector-b"},
+	})
+	for _, engine := range []*wafv1alpha1.Engine{older, newer} {
+		require.NoError(t, k8sClient.Create(ctx, engine))
+		t.Cleanup(func() {
+			if err := k8sClient.Delete(ctx, engine); err != nil {
+				t.Logf("Failed to delete engine: %v", err)
+			}
+		})
+		reconcileEngine(t, engine.Name)
+		require.True(t, wasmPluginExists(engine.Name))
+	}
+
+	t.Log("Changing the older Engine's selector to overlap the newer Engine's")
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: older.Name, Namespace: ns}, older))
+	older.Spec.Driver.Istio.Wasm.WorkloadSelector.MatchLabels = map[string]string{"app": "change-selector-b"}
+	require.NoError(t, k8sClient.Update(ctx, older))
+
+	t.Log("Verifying the change enqueues the newer Engine")
+	assert.Contains(t, reconciler.findOverlappingEngines(ctx, older),
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: newer.Name, Namespace: ns}})
+
+	t.Log("Verifying the older Engine keeps its WasmPlugin, which enqueues the newer Engine")
+	recorder.Events = nil
+	reconcileEngine(t, older.Name)
+	assert.False(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonSelectorConflict),
+		"unexpected Warning/SelectorConflict event; got: %v", recorder.Events)
+	require.True(t, wasmPluginExists(older.Name))
+	plugin := wasmPluginRef(older)
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(plugin), plugin))
+	assert.Contains(t, reconciler.findEnginesForWasmPlugin(ctx, plugin),
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: newer.Name, Namespace: ns}})
+
+	t.Log("Verifying the newer Engine is rejected and its WasmPlugin deleted")
+	recorder.Events = nil
+	reconcileEngine(t, newer.Name)
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonSelectorConflict),
+		"expected Warning/SelectorConflict event; got: %v", recorder.Events)
+	assert.False(t, wasmPluginExists(newer.Name))
+	var conflicted wafv1alpha1.Engine
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: newer.Name, Namespace: ns}, &conflicted))
+	assert.Empty(t, conflicted.Status.WasmPluginName)
+	degraded := apimeta.FindStatusCondition(conflicted.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, wafv1alpha1.ReasonSelectorConflict, degraded.Reason)
+}
+
+func TestMatchLabelsOverlap(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    map[string]string
+		overlap bool
+	}{
+		{name: "identical", a: map[string]string{"app": "gw"}, b: map[string]string{"app": "gw"}, overlap: true},
+		{name: "subset", a: map[string]string{"app": "gw"}, b: map[string]string{"app": "gw", "tier": "edge"}, overlap: true},
+		{name: "disjoint keys", a: map[string]string{"app": "gw"}, b: map[string]string{"tier": "edge"}, overlap: true},
+		{name: "empty selects everything", a: nil, b: map[string]string{"app": "gw"}, overlap: true},
+		{name: "conflicting values", a: map[string]string{"app": "gw"}, b: map[string]string{"app": "other"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.overlap, matchLabelsOverlap(tt.a, tt.b))
+			assert.Equal(t, tt.overlap, matchLabelsOverlap(tt.b, tt.a))
+		})
+	}
+}
+
+func TestEngineReconciler_FindOverlappingEngines(t *testing.T) {
+	ctx := context.Background()
+	ns := "default"
+	newEngine := func(name string, labels map[string]string) *wafv1alpha1.Engine {
+		return utils.NewTestEngine(utils.EngineOptions{Name: name, Namespace: ns, WorkloadLabels: labels})
+	}
+	changed := newEngine("changed", map[string]string{"app": "a"})
+	sameLabels := newEngine("same-labels", map[string]string{"app": "a"})
+	otherLabels := newEngine("other-labels", map[string]string{"tier": "edge"})
+	disjoint := newEngine("disjoint", map[string]string{"app": "b"})
+	envoy := newEngine("envoy", nil)
+	envoy.Spec.Driver = wafv1alpha1.DriverConfig{Envoy: &wafv1alpha1.EnvoyDriverConfig{ExtProc: &wafv1alpha1.EnvoyExtProcConfig{}}}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(changed, sameLabels, otherLabels, disjoint, envoy).
+		Build()
+	reconciler := &EngineReconciler{Client: c}
+	request := func(name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: ns}}
+	}
+
+	t.Log("Verifying only Engines whose selectors overlap are enqueued")
+	assert.ElementsMatch(t, []reconcile.Request{request(sameLabels.Name), request(otherLabels.Name)},
+		reconciler.findOverlappingEngines(ctx, changed))
+
+	t.Log("Verifying a selector change enqueues the Engines overlapping either selector")
+	updated := changed.DeepCopy()
+	updated.Spec.Driver.Istio.Wasm.WorkloadSelector.MatchLabels = map[string]string{"app": "b"}
+	assert.ElementsMatch(t, []reconcile.Request{request(sameLabels.Name), request(otherLabels.Name), request(disjoint.Name)},
+		reconciler.findOverlappingEngines(ctx, changed, updated))
+
+	t.Log("Verifying Engines of other drivers enqueue nothing")
+	assert.Empty(t, reconciler.findOverlappingEngines(ctx, envoy))
+}
+
+func TestEngineReconciler_GatewayAppearsLater(t *testing.T) {
+	ctx := context.Background()
+
+	engine := utils.NewTestEngine(utils.EngineOptions{
+		Name:           "late-gateway-engine",
+		WorkloadLabels: map[string]string{GatewayNameLabel: "late-gateway"},
+	})
+
+	t.Log("Building a client which knows the Gateway API")
+	mapper := apimeta.NewDefaultRESTMapper(nil)
+	mapper.Add(wafv1alpha1.GroupVersion.WithKind("Engine"), apimeta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "extensions.istio.io", Version: "v1alpha1", Kind: "WasmPlugin"}, apimeta.RESTScopeNamespace)
+	mapper.Add(gatewayGVK, apimeta.RESTScopeNamespace)
+	var applied []string
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRESTMapper(mapper).
+		WithObjects(engine).
+		WithStatusSubresource(engine).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if patch.Type() != types.ApplyPatchType {
+					return c.Patch(ctx, obj, patch, opts...)
+				}
+				applied = append(applied, obj.GetName())
+				return nil
+			},
+		}).
+		Build()
+
+	recorder := utils.NewFakeRecorder()
+	reconciler := &EngineReconciler{
+		Client:                    c,
+		Scheme:                    scheme,
+		Recorder:                  recorder,
+		ruleSetCacheServerCluster: "test-cluster",
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: engine.Namespace}}
+
+	t.Log("Verifying the Engine is degraded while no Gateway matches its selector")
+	result, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.True(t, result.IsZero())
+	assert.Empty(t, applied)
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonGatewayNotFound),
+		"expected Warning/GatewayNotFound event; got: %v", recorder.Events)
+	var updated wafv1alpha1.Engine
+	require.NoError(t, c.Get(ctx, req.NamespacedName, &updated))
+	degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, wafv1alpha1.ReasonGatewayNotFound, degraded.Reason)
+
+	t.Log("Verifying only a Gateway matching the selector enqueues the Engine")
+	other := newTestGateway(engine.Namespace, "other-gateway", nil)
+	assert.Empty(t, reconciler.findEnginesForGateway(ctx, other))
+
+	gateway := newTestGateway(engine.Namespace, "late-gateway", nil)
+	require.NoError(t, c.Create(ctx, gateway))
+	assert.Equal(t, []reconcile.Request{req}, reconciler.findEnginesForGateway(ctx, gateway))
+
+	t.Log("Verifying the Engine is provisioned once the Gateway exists")
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{WasmPluginNamePrefix + engine.Name}, applied)
+	require.NoError(t, c.Get(ctx, req.NamespacedName, &updated))
+	assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Ready"))
+	assert.Nil(t, apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded"))
+}
+
+func TestEngineReconciler_FindEnginesForRuleSet(t *testing.T) {
+	ctx := context.Background()
+
+	base := utils.NewTestEngine(utils.EngineOptions{Name: "base-engine", RuleSetName: "base"})
+	overlay := utils.NewTestEngine(utils.EngineOptions{Name: "overlay-engine", RuleSetName: "other"})
+	overlay.Spec.RuleSets = []wafv1alpha1.RuleSetReference{{Name: "base"}}
+	unrelated := utils.NewTestEngine(utils.EngineOptions{Name: "unrelated-engine", RuleSetName: "other"})
+	elsewhere := utils.NewTestEngine(utils.EngineOptions{Name: "elsewhere-engine", Namespace: "elsewhere", RuleSetName: "base"})
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(base, overlay, unrelated, elsewhere).
+		WithIndex(&wafv1alpha1.Engine{}, engineRuleSetIndexKey, indexEngineRuleSets).
+		Build()
+	reconciler := &EngineReconciler{Client: c, Scheme: scheme}
+
+	t.Log("Verifying only Engines loading rules from the RuleSet in its namespace are enqueued")
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{Name: "base", Namespace: base.Namespace})
+	assert.ElementsMatch(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: base.Name, Namespace: base.Namespace}},
+		{NamespacedName: types.NamespacedName{Name: overlay.Name, Namespace: overlay.Namespace}},
+	}, reconciler.findEnginesForRuleSet(ctx, ruleSet))
+
+	t.Log("Verifying a RuleSet referenced by no Engine enqueues nothing")
+	assert.Empty(t, reconciler.findEnginesForRuleSet(ctx, utils.NewTestRuleSet(utils.RuleSetOptions{Name: "unused", Namespace: base.Namespace})))
+}
+
+func TestRuleSetReadinessChangedPredicate(t *testing.T) {
+	withReady := func(generation int64, status metav1.ConditionStatus, reason string) *wafv1alpha1.RuleSet {
+		ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{Name: "ruleset"})
+		ruleSet.Generation = generation
+		if status != "" {
+			ruleSet.Status.Conditions = []metav1.Condition{{Type: "Ready", Status: status, Reason: reason}}
+		}
+		return ruleSet
+	}
+
+	tests := []struct {
+		name     string
+		old, new *wafv1alpha1.RuleSet
+		expected bool
+	}{
+		{
+			name:     "unchanged",
+			old:      withReady(1, metav1.ConditionTrue, wafv1alpha1.EventReasonRulesCached),
+			new:      withReady(1, metav1.ConditionTrue, wafv1alpha1.EventReasonRulesCached),
+			expected: false,
+		},
+		{
+			name:     "generation changed",
+			old:      withReady(1, metav1.ConditionTrue, wafv1alpha1.EventReasonRulesCached),
+			new:      withReady(2, metav1.ConditionTrue, wafv1alpha1.EventReasonRulesCached),
+			expected: true,
+		},
+		{
+			name:     "became ready",
+			old:      withReady(1, metav1.ConditionFalse, wafv1alpha1.ReasonConfigMapNotFound),
+			new:      withReady(1, metav1.ConditionTrue, wafv1alpha1.EventReasonRulesCached),
+			expected: true,
+		},
+		{
+			name:     "reason changed",
+			old:      withReady(1, metav1.ConditionFalse, wafv1alpha1.ReasonConfigMapNotFound),
+			new:      withReady(1, metav1.ConditionFalse, "InvalidRuleSet"),
+			expected: true,
+		},
+		{
+			name:     "first status",
+			old:      withReady(1, "", ""),
+			new:      withReady(1, metav1.ConditionTrue, wafv1alpha1.EventReasonRulesCached),
+			expected: true,
+		},
+	}
+
+	p := ruleSetReadinessChangedPredicate()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, p.Update(event.UpdateEvent{ObjectOld: tt.old, ObjectNew: tt.new}))
+		})
+	}
+}
+
+func TestEngineReconciler_ImageNotPinned(t *testing.T) {
+	ctx := context.Background()
+
+	engine := utils.NewTestEngine(utils.EngineOptions{
+		Name:           "unpinned-engine",
+		WorkloadLabels: map[string]string{GatewayNameLabel: "pinned-gateway"},
+	})
+	engine.Spec.Driver.Istio.Wasm.Image = "oci://ghcr.io/example/coraza-wasm:latest"
+	engine.Spec.Driver.Istio.Wasm.RequireDigest = true
+
+	t.Log("Building a client which doesn't enforce the CRD's digest validation")
+	mapper := apimeta.NewDefaultRESTMapper(nil)
+	mapper.Add(wafv1alpha1.GroupVersion.WithKind("Engine"), apimeta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "extensions.istio.io", Version: "v1alpha1", Kind: "WasmPlugin"}, apimeta.RESTScopeNamespace)
+	mapper.Add(gatewayGVK, apimeta.RESTScopeNamespace)
+	var applied []string
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRESTMapper(mapper).
+		WithObjects(engine, newTestGateway(engine.Namespace, "pinned-gateway", nil)).
+		WithStatusSubresource(engine).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if patch.Type() != types.ApplyPatchType {
+					return c.Patch(ctx, obj, patch, opts...)
+				}
+				applied = append(applied, obj.GetName())
+				return nil
+			},
+		}).
+		Build()
+
+	recorder := utils.NewFakeRecorder()
+	reconciler := &EngineReconciler{
+		Client:                    c,
+		Scheme:                    scheme,
+		Recorder:                  recorder,
+		ruleSetCacheServerCluster: "test-cluster",
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: engine.Namespace}}
+
+	t.Log("Verifying an Engine requiring a digest isn't provisioned with a tag-only image")
+	_, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonImageNotPinned),
+		"expected Warning/ImageNotPinned event; got: %v", recorder.Events)
+	var updated wafv1alpha1.Engine
+	require.NoError(t, c.Get(ctx, req.NamespacedName, &updated))
+	degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, wafv1alpha1.ReasonImageNotPinned, degraded.Reason)
+
+	t.Log("Verifying the Engine is provisioned once the image is pinned")
+	updated.Spec.Driver.Istio.Wasm.Image = "oci://ghcr.io/example/coraza-wasm@sha256:" + strings.Repeat("ab", 32)
+	require.NoError(t, c.Update(ctx, &updated))
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{WasmPluginNamePrefix + engine.Name}, applied)
+	require.NoError(t, c.Get(ctx, req.NamespacedName, &updated))
+	assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Ready"))
+}
+
+func TestImageDigest(t *testing.T) {
+	digest := strings.Repeat("0123456789abcdef", 4)
+	tests := []struct {
+		image    string
+		expected string
+		pinned   bool
+	}{
+		{image: "oci://ghcr.io/example/coraza-wasm:latest"},
+		{image: "oci://ghcr.io/example/coraza-wasm"},
+		{image: "oci://ghcr.io/example/coraza-wasm@sha256:" + digest, expected: digest, pinned: true},
+		{image: "oci://ghcr.io/example/coraza-wasm:v1@sha256:" + digest, expected: digest, pinned: true},
+		{image: "oci://ghcr.io/example/coraza-wasm@sha256:" + digest[:63]},
+		{image: "oci://ghcr.io/example/coraza-wasm@sha256:" + strings.ToUpper(digest)},
+		{image: "oci://ghcr.io/example/coraza-wasm@sha512:" + digest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			actual, pinned := imageDigest(tt.image)
+			assert.Equal(t, tt.pinned, pinned)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestGatewayPodLabels(t *testing.T) {
+	gateway := newTestGateway("default", "gw", nil)
+	assert.Equal(t, map[string]string{GatewayNameLabel: "gw"}, gatewayPodLabels(gateway))
+
+	gateway = newTestGateway("default", "gw", map[string]string{"tier": "edge"})
+	assert.Equal(t, map[string]string{GatewayNameLabel: "gw", "tier": "edge"}, gatewayPodLabels(gateway))
+}
+
+// newTestGateway builds a Gateway whose Pods carry the given infrastructure
+// labels in addition to the gateway name label.
+func newTestGateway(namespace, name string, infrastructureLabels map[string]string) *unstructured.Unstructured {
+	gateway := &unstructured.Unstructured{}
+	gateway.SetGroupVersionKind(gatewayGVK)
+	gateway.SetName(name)
+	gateway.SetNamespace(namespace)
+	if infrastructureLabels != nil {
+		_ = unstructured.SetNestedStringMap(gateway.Object, infrastructureLabels, "spec", "infrastructure", "labels")
+	}
+	return gateway
+}
+
+func TestEngineReconciler_NoEffectiveRules(t *testing.T) {
+	ctx := context.Background()
+	ns := "default"
+
+	t.Log("Creating a comment-only ConfigMap and a RuleSet referencing it")
+	cm := utils.NewTestConfigMap("no-effective-rules", ns, `# SecRule ARGS "@contains a" "id:1,deny"
+SecRuleEngine On`)
+	require.NoError(t, k8sClient.Create(ctx, cm))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, cm); err != nil {
+			t.Logf("Failed to delete ConfigMap: %v", err)
+		}
+	})
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "no-effective-rules",
+		Namespace: ns,
+		Rules:     []wafv1alpha1.RuleSourceReference{{Name: cm.Name}},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+
+	t.Log("Reconciling the RuleSet to record its rule count")
+	ruleSetReconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: utils.NewTestRecorder(),
+		Cache:    cache.NewRuleSetCache(),
+	}
+	_, err := ruleSetReconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ns},
+	})
+	require.NoError(t, err)
+	var updatedRuleSet wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: ruleSet.Name, Namespace: ns}, &updatedRuleSet))
+	require.NotNil(t, updatedRuleSet.Status.RuleCount)
+	assert.Equal(t, int32(0), *updatedRuleSet.Status.RuleCount)
+
+	t.Log("Creating and reconciling an Engine referencing the RuleSet")
+	engine := utils.NewTestEngine(utils.EngineOptions{
+		Name:        "test-engine-no-effective-rules",
+		Namespace:   ns,
+		RuleSetName: ruleSet.Name,
+	})
+	require.NoError(t, k8sClient.Create(ctx, engine))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, engine); err != nil {
+			t.Logf("Failed to delete engine: %v", err)
+		}
+	})
+	recorder := utils.NewFakeRecorder()
+	reconciler := &EngineReconciler{
+		Client:                    k8sClient,
+		Scheme:                    scheme,
+		Recorder:                  recorder,
+		ruleSetCacheServerCluster: "test-cluster",
+	}
+	_, err = reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: ns},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.EventReasonNoEffectiveRules),
+		"expected Warning/NoEffectiveRules event; got: %v", recorder.Events)
+
+	t.Log("Verifying a second reconcile does not repeat the warning")
+	_, err = reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: ns},
+	})
+	require.NoError(t, err)
+	count := 0
+	for _, event := range recorder.Events {
+		if event.Reason == wafv1alpha1.EventReasonNoEffectiveRules {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count, "expected a single NoEffectiveRules event; got: %v", recorder.Events)
+}
+
+func TestEngineReconciler_NoEffectiveRulesOnTransition(t *testing.T) {
+	ctx := context.Background()
+	ns := "default"
+	empty, populated := int32(0), int32(3)
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{Name: "empty-rules", Namespace: ns})
+	ruleSet.Status.RuleCount = &empty
+	engine := utils.NewTestEngine(utils.EngineOptions{Name: "no-effective-rules", Namespace: ns, RuleSetName: ruleSet.Name})
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ruleSet).Build()
+	recorder := utils.NewFakeRecorder()
+	reconciler := &EngineReconciler{Client: c, Recorder: recorder}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: ns}}
+	log := utils.NewTestLogger(t)
+	countEvents := func() int {
+		count := 0
+		for _, event := range recorder.Events {
+			if event.Reason == wafv1alpha1.EventReasonNoEffectiveRules {
+				count++
+			}
+		}
+		return count
+	}
+
+	t.Log("Verifying the warning is emitted once across reconciles")
+	reconciler.warnOnNoEffectiveRules(ctx, log, req, engine)
+	reconciler.warnOnNoEffectiveRules(ctx, log, req, engine)
+	assert.Equal(t, 1, countEvents())
+
+	t.Log("Verifying the warning is emitted again once the RuleSet is emptied again after gaining rules")
+	ruleSet.Status.RuleCount = &populated
+	require.NoError(t, c.Update(ctx, ruleSet))
+	reconciler.warnOnNoEffectiveRules(ctx, log, req, engine)
+	assert.Equal(t, 1, countEvents())
+	ruleSet.Status.RuleCount = &empty
+	require.NoError(t, c.Update(ctx, ruleSet))
+	reconciler.warnOnNoEffectiveRules(ctx, log, req, engine)
+	reconciler.warnOnNoEffectiveRules(ctx, log, req, engine)
+	assert.Equal(t, 2, countEvents())
+}
+
+func TestEngineReconciler_PinnedRulesVersion(t *testing.T) {
+	ctx := context.Background()
+	ns := "default"
+
+	t.Log("Caching two versions of the rules of a RuleSet")
+	ruleSetCache := cache.NewRuleSetCache()
+	cacheKey := ns + "/pinned-rules"
+	ruleSetCache.Put(cacheKey, `SecRule ARGS "@contains a" "id:1,deny"`)
+	entry, ok := ruleSetCache.Get(cacheKey)
+	require.True(t, ok)
+	pinned := entry.UUID
+	ruleSetCache.Put(cacheKey, `SecRule ARGS "@contains b" "id:1,deny"`)
+
+	t.Log("Creating an Engine pinning the outdated version")
+	engine := utils.NewTestEngine(utils.EngineOptions{
+		Name:        "test-engine-pinned-rules",
+		Namespace:   ns,
+		RuleSetName: "pinned-rules",
+	})
+	engine.Annotations = map[string]string{PinnedRulesVersionAnnotation: pinned}
+	require.NoError(t, k8sClient.Create(ctx, engine))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, engine); err != nil {
+			t.Logf("Failed to delete engine: %v", err)
+		}
+	})
+
+	recorder := utils.NewFakeRecorder()
+	reconciler := &EngineReconciler{
+		Client:                    k8sClient,
+		Scheme:                    scheme,
+		Recorder:                  recorder,
+		ruleSetCache:              ruleSetCache,
+		ruleSetCacheServerCluster: "test-cluster",
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: ns}}
+	_, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+
+	t.Log("Verifying the outdated pinned version is reported and retained")
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.EventReasonPinnedVersionOutdated),
+		"expected Warning/PinnedVersionOutdated event; got: %v", recorder.Events)
+	ruleSetCache.Prune(0)
+	_, ok = ruleSetCache.GetByUUID(cacheKey, pinned)
+	assert.True(t, ok, "pinned version should survive pruning")
+
+	t.Log("Removing the annotation and verifying the version is pruned")
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, engine))
+	patch := client.MergeFrom(engine.DeepCopy())
+	delete(engine.Annotations, PinnedRulesVersionAnnotation)
+	require.NoError(t, k8sClient.Patch(ctx, engine, patch))
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	ruleSetCache.Prune(0)
+	_, ok = ruleSetCache.GetByUUID(cacheKey, pinned)
+	assert.False(t, ok, "unpinned version should be pruned")
+	assert.Equal(t, 1, ruleSetCache.CountEntries(cacheKey))
+
+	t.Log("Pinning a version which isn't cached")
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, engine))
+	patch = client.MergeFrom(engine.DeepCopy())
+	engine.Annotations = map[string]string{PinnedRulesVersionAnnotation: pinned}
+	require.NoError(t, k8sClient.Patch(ctx, engine, patch))
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.EventReasonPinnedVersionNotFound),
+		"expected Warning/PinnedVersionNotFound event; got: %v", recorder.Events)
+}
+
+func TestEngineReconciler_PinnedVersionWarningsOnTransition(t *testing.T) {
+	ns := "default"
+	ruleSetCache := cache.NewRuleSetCache()
+	cacheKey := ns + "/pinned-rules"
+	ruleSetCache.Put(cacheKey, `SecRule ARGS "@contains a" "id:1,deny"`)
+	entry, ok := ruleSetCache.Get(cacheKey)
+	require.True(t, ok)
+	pinned := entry.UUID
+
+	engine := utils.NewTestEngine(utils.EngineOptions{Name: "pinned-warnings", Namespace: ns, RuleSetName: "pinned-rules"})
+	engine.Annotations = map[string]string{PinnedRulesVersionAnnotation: pinned}
+	recorder := utils.NewFakeRecorder()
+	reconciler := &EngineReconciler{Recorder: recorder, ruleSetCache: ruleSetCache}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: ns}}
+	log := utils.NewTestLogger(t)
+	countEvents := func(reason string) int {
+		count := 0
+		for _, event := range recorder.Events {
+			if event.Reason == reason {
+				count++
+			}
+		}
+		return count
+	}
+
+	t.Log("Verifying no warning is emitted while the pinned version is the latest")
+	reconciler.updatePinnedRulesVersion(log, req, engine)
+	assert.Empty(t, recorder.Events)
+
+	t.Log("Verifying the outdated warning is emitted once across reconciles")
+	ruleSetCache.Put(cacheKey, `SecRule ARGS "@contains b" "id:1,deny"`)
+	reconciler.updatePinnedRulesVersion(log, req, engine)
+	reconciler.updatePinnedRulesVersion(log, req, engine)
+	assert.Equal(t, 1, countEvents(wafv1alpha1.EventReasonPinnedVersionOutdated))
+
+	t.Log("Verifying the outdated warning is emitted again when a newer version is cached")
+	ruleSetCache.Put(cacheKey, `SecRule ARGS "@contains c" "id:1,deny"`)
+	reconciler.updatePinnedRulesVersion(log, req, engine)
+	reconciler.updatePinnedRulesVersion(log, req, engine)
+	assert.Equal(t, 2, countEvents(wafv1alpha1.EventReasonPinnedVersionOutdated))
+
+	t.Log("Verifying the not found warning is emitted once across reconciles")
+	engine.Annotations[PinnedRulesVersionAnnotation] = "missing"
+	reconciler.updatePinnedRulesVersion(log, req, engine)
+	reconciler.updatePinnedRulesVersion(log, req, engine)
+	assert.Equal(t, 1, countEvents(wafv1alpha1.EventReasonPinnedVersionNotFound))
+
+	t.Log("Verifying the warning is emitted again after the Engine is unpinned and pinned again")
+	delete(engine.Annotations, PinnedRulesVersionAnnotation)
+	reconciler.updatePinnedRulesVersion(log, req, engine)
+	engine.Annotations[PinnedRulesVersionAnnotation] = "missing"
+	reconciler.updatePinnedRulesVersion(log, req, engine)
+	assert.Equal(t, 2, countEvents(wafv1alpha1.EventReasonPinnedVersionNotFound))
+}
+
+func TestEngineReconciler_StatusUpdateHandling(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating test engine for status update testing")
+	engine := utils.NewTestEngine(utils.EngineOptions{
+		Name:      "status-test",
+		Namespace: "default",
+	})
+	require.NoError(t, k8sClient.Create(ctx, engine))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, engine); err != nil {
+			t.Logf("Failed to delete engine: %v", err)
+		}
+	})
+
+	t.Log("Reconciling engine to verify status update")
+	reconciler := &EngineReconciler{
+		Client:                    k8sClient,
+		Scheme:                    scheme,
+		Recorder:                  utils.NewTestRecorder(),
+		ruleSetCacheServerCluster: "test-cluster",
+	}
+	_, err := reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      engine.Name,
+			Namespace: engine.Namespace,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Log("Verifying status conditions were set")
+	var updated wafv1alpha1.Engine
+	err = k8sClient.Get(ctx, types.NamespacedName{
+		Name:      engine.Name,
+		Namespace: engine.Namespace,
+	}, &updated)
+	require.NoError(t, err)
+	if len(updated.Status.Conditions) > 0 {
+		condition := updated.Status.Conditions[0]
+		assert.NotEmpty(t, condition.Type)
+		assert.NotEmpty(t, condition.Status)
+		assert.NotEmpty(t, condition.Reason)
+	}
+}
+
+func TestEngineReconciler_ValidationRejection(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		engineFunc    func() *wafv1alpha1.Engine
+		expectedError string
+	}{
+		{
+			name: "no ruleset specified",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.RuleSet = wafv1alpha1.RuleSetReference{
+					Name: "",
+				}
+				return engine
+			},
+			expectedError: "at least one of ruleSet or ruleSets must be specified",
+		},
+		{
+			name: "ruleSets entry with empty name",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.RuleSets = []wafv1alpha1.RuleSetReference{{Name: ""}}
+				return engine
+			},
+			expectedError: "spec.ruleSets[0].name in body should be at least 1 chars long",
+		},
+		{
+			name: "no driver specified",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver = wafv1alpha1.DriverConfig{}
+				return engine
+			},
+			expectedError: "exactly one driver must be specified",
+		},
+		{
+			name: "no istio integration mode specified",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio = &wafv1alpha1.IstioDriverConfig{}
+				return engine
+			},
+			expectedError: "exactly one integration mechanism (Wasm, etc) must be specified",
+		},
+		{
+			name: "image doesn't start with oci://",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.Wasm.Image = "docker://invalid-image"
+				return engine
+			},
+			expectedError: "spec.driver.istio.wasm.image in body should match '^oci://'",
+		},
+		{
+			name: "image too short",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.Wasm.Image = ""
+				return engine
+			},
+			expectedError: "spec.driver.istio.wasm.image in body should be at least 1 chars long",
+		},
+		{
+			name: "image too long",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.Wasm.Image = "oci://" + string(make([]byte, 1100))
+				return engine
+			},
+			expectedError: "spec.driver.istio.wasm.image: Too long",
+		},
+		{
+			name: "invalid imagePullSecret name",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.Wasm.ImagePullSecret = "Registry_Credentials"
+				return engine
+			},
+			expectedError: "spec.driver.istio.wasm.imagePullSecret in body should match",
+		},
+		{
+			name: "requireDigest with an image referenced by tag",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.Wasm.Image = "oci://ghcr.io/example/coraza-wasm:latest"
+				engine.Spec.Driver.Istio.Wasm.RequireDigest = true
+				return engine
+			},
+			expectedError: "image must be pinned by a sha256 digest when requireDigest is set",
+		},
+		{
+			name: "requireDigest with a truncated digest",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.Wasm.Image = "oci://ghcr.io/example/coraza-wasm@sha256:abcdef"
+				engine.Spec.Driver.Istio.Wasm.RequireDigest = true
+				return engine
+			},
+			expectedError: "image must be pinned by a sha256 digest when requireDigest is set",
+		},
+		{
+			name: "gateway mode without workloadSelector",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.Wasm.Mode = wafv1alpha1.IstioIntegrationModeGateway
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = nil
+				return engine
+			},
+			expectedError: "workloadSelector is required",
+		},
+		{
+			name: "sidecar mode without workloadSelector",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.Wasm.Mode = wafv1alpha1.IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.MatchContext = wafv1alpha1.IstioMatchContextInbound
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = nil
+				return engine
+			},
+			expectedError: "workloadSelector is required",
+		},
+		{
+			name: "gatewayName with another workloadSelector",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.GatewayName = "my-gateway"
+				return engine
+			},
+			expectedError: "workloadSelector must only match the gatewayName's gateway.networking.k8s.io/gateway-name label when both are set",
+		},
+		{
+			name: "gatewayName in sidecar mode",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.GatewayName = "my-gateway"
+				engine.Spec.Driver.Istio.Wasm.Mode = wafv1alpha1.IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.MatchContext = wafv1alpha1.IstioMatchContextInbound
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = nil
+				return engine
+			},
+			expectedError: "gatewayName is only supported when mode is gateway",
+		},
+		{
+			name: "sidecar mode with gateway match context",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.Wasm.Mode = wafv1alpha1.IstioIntegrationModeSidecar
+				engine.Spec.Driver.Istio.Wasm.MatchContext = wafv1alpha1.IstioMatchContextGateway
+				return engine
+			},
+			expectedError: "matchContext must be inbound or outbound when mode is sidecar",
+		},
+		{
+			name: "gateway mode with sidecar match context",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.Wasm.MatchContext = wafv1alpha1.IstioMatchContextInbound
+				return engine
+			},
+			expectedError: "matchContext must be gateway when mode is gateway",
+		},
+		{
+			name: "unknown match context",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.Wasm.MatchContext = "mesh"
+				return engine
+			},
+			expectedError: "spec.driver.istio.wasm.matchContext: Unsupported value",
+		},
+		{
+			name: "empty workload selector",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{IstioIntegrationMode: wafv1alpha1.IstioIntegrationModeSidecar})
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = &metav1.LabelSelector{}
+				return engine
+			},
+			expectedError: "workloadSelector matchLabels must not be empty",
+		},
+		{
+			name: "workload selector with only matchExpressions",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{IstioIntegrationMode: wafv1alpha1.IstioIntegrationModeSidecar})
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"reviews"}},
+					},
+				}
+				return engine
+			},
+			expectedError: "workloadSelector matchExpressions are not supported, use matchLabels",
+		},
+		{
+			name: "pluginConfigOverrides sets an operator managed key",
+			engineFunc: func() *wafv1alpha1.Engine {
+				engine := utils.NewTestEngine(utils.EngineOptions{})
+				engine.Spec.Driver.Istio.Wasm.PluginConfigOverrides = map[string]apiextensionsv1.JSON{
+					"rule_reload_interval_seconds": {Raw: []byte(`1`)},
+				}
+				return engine
+			},
+			expectedError: "pluginConfigOverrides must not set the operator managed keys",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Logf("Attempting to create Engine with invalid configuration: %s", tt.name)
+			engine := tt.engineFunc()
+			engine.Name = "validation-test-" + t.Name()
+			engine.Namespace = "default"
+
+			err := k8sClient.Create(ctx, engine)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedError)
+		})
+	}
+}
+
+func TestEngineReconciler_BuildWasmPluginMatchContext(t *testing.T) {
+	tests := []struct {
+		matchContext wafv1alpha1.IstioMatchContext
+		expectedMode string
+	}{
+		{matchContext: "", expectedMode: "SERVER"},
+		{matchContext: wafv1alpha1.IstioMatchContextGateway, expectedMode: "SERVER"},
+		{matchContext: wafv1alpha1.IstioMatchContextInbound, expectedMode: "SERVER"},
+		{matchContext: wafv1alpha1.IstioMatchContextOutbound, expectedMode: "CLIENT"},
+	}
+
+	reconciler := &EngineReconciler{ruleSetCacheServerCluster: "test-cluster"}
+	for _, tt := range tests {
+		t.Run(string(tt.matchContext), func(t *testing.T) {
+			engine := utils.NewTestEngine(utils.EngineOptions{})
+			engine.Spec.Driver.Istio.Wasm.MatchContext = tt.matchContext
+
+			wasmPlugin, err := reconciler.buildWasmPlugin(engine)
+			require.NoError(t, err)
+
+			pluginType, _, err := unstructured.NestedString(wasmPlugin.Object, "spec", "type")
+			require.NoError(t, err)
+			assert.Equal(t, "HTTP", pluginType)
+
+			match, found, err := unstructured.NestedSlice(wasmPlugin.Object, "spec", "match")
+			require.NoError(t, err)
+			require.True(t, found)
+			assert.Equal(t, []any{map[string]any{"mode": tt.expectedMode}}, match)
+		})
+	}
+}
+
+func TestEngineReconciler_BuildWasmPluginSidecarMode(t *testing.T) {
+	tests := []struct {
+		matchContext wafv1alpha1.IstioMatchContext
+		expectedMode string
+	}{
+		{matchContext: "", expectedMode: "SERVER"},
+		{matchContext: wafv1alpha1.IstioMatchContextInbound, expectedMode: "SERVER"},
+		{matchContext: wafv1alpha1.IstioMatchContextOutbound, expectedMode: "CLIENT"},
+	}
+
+	reconciler := &EngineReconciler{ruleSetCacheServerCluster: "test-cluster"}
+	for _, tt := range tests {
+		t.Run(string(tt.matchContext), func(t *testing.T) {
+			engine := utils.NewTestEngine(utils.EngineOptions{
+				WorkloadLabels:       map[string]string{"app": "reviews"},
+				IstioIntegrationMode: wafv1alpha1.IstioIntegrationModeSidecar,
+			})
+			engine.Spec.Driver.Istio.Wasm.MatchContext = tt.matchContext
+
+			wasmPlugin, err := reconciler.buildWasmPlugin(engine)
+			require.NoError(t, err)
+
+			pluginType, _, err := unstructured.NestedString(wasmPlugin.Object, "spec", "type")
+			require.NoError(t, err)
+			assert.Equal(t, "HTTP", pluginType)
+
+			matchLabels, found, err := unstructured.NestedFieldNoCopy(wasmPlugin.Object, "spec", "selector", "matchLabels")
+			require.NoError(t, err)
+			require.True(t, found)
+			assert.Equal(t, map[string]string{"app": "reviews"}, matchLabels)
+
+			match, found, err := unstructured.NestedSlice(wasmPlugin.Object, "spec", "match")
+			require.NoError(t, err)
+			require.True(t, found)
+			assert.Equal(t, []any{map[string]any{"mode": tt.expectedMode}}, match)
+		})
+	}
+}
+
+func TestEngineReconciler_BuildWasmPluginGatewayName(t *testing.T) {
+	tests := []struct {
+		name                string
+		gatewayName         string
+		workloadLabels      map[string]string
+		expectedMatchLabels map[string]string
+	}{
+		{
+			name:                "workloadSelector",
+			workloadLabels:      map[string]string{"app": "gateway"},
+			expectedMatchLabels: map[string]string{"app": "gateway"},
+		},
+		{
+			name:                "gatewayName",
+			gatewayName:         "my-gateway",
+			expectedMatchLabels: map[string]string{GatewayNameLabel: "my-gateway"},
+		},
+		{
+			name:                "gatewayName with derived workloadSelector",
+			gatewayName:         "my-gateway",
+			workloadLabels:      map[string]string{GatewayNameLabel: "my-gateway"},
+			expectedMatchLabels: map[string]string{GatewayNameLabel: "my-gateway"},
+		},
+	}
+
+	reconciler := &EngineReconciler{ruleSetCacheServerCluster: "test-cluster"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := utils.NewTestEngine(utils.EngineOptions{})
+			engine.Spec.Driver.Istio.GatewayName = tt.gatewayName
+			engine.Spec.Driver.Istio.Wasm.WorkloadSelector = nil
+			if tt.workloadLabels != nil {
+				engine.Spec.Driver.Istio.Wasm.WorkloadSelector = &metav1.LabelSelector{MatchLabels: tt.workloadLabels}
+			}
+
+			wasmPlugin, err := reconciler.buildWasmPlugin(engine)
+			require.NoError(t, err)
+
+			matchLabels, found, err := unstructured.NestedFieldNoCopy(wasmPlugin.Object, "spec", "selector", "matchLabels")
+			require.NoError(t, err)
+			require.True(t, found)
+			assert.Equal(t, tt.expectedMatchLabels, matchLabels)
+			assert.True(t, engineSelectsGatewayPods(engine, tt.expectedMatchLabels))
+		})
+	}
+}
+
+func TestEngineReconciler_BuildWasmPluginRejectsUnrestrictedSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector *metav1.LabelSelector
+	}{
+		{name: "no workload selector"},
+		{name: "empty workload selector", selector: &metav1.LabelSelector{}},
+		{name: "empty matchLabels", selector: &metav1.LabelSelector{MatchLabels: map[string]string{}}},
+		{
+			name: "only matchExpressions",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"reviews"}},
+			}},
+		},
+		{
+			name: "matchLabels and matchExpressions",
+			selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "reviews"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "version", Operator: metav1.LabelSelectorOpExists},
+				},
+			},
+		},
+	}
+
+	reconciler := &EngineReconciler{ruleSetCacheServerCluster: "test-cluster"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := utils.NewTestEngine(utils.EngineOptions{IstioIntegrationMode: wafv1alpha1.IstioIntegrationModeSidecar})
+			engine.Spec.Driver.Istio.Wasm.WorkloadSelector = tt.selector
+
+			wasmPlugin, err := reconciler.buildWasmPlugin(engine)
+			require.Error(t, err)
+			assert.Nil(t, wasmPlugin)
+		})
+	}
+}
+
+func TestEngineReconciler_BuildWasmPluginFailStrategy(t *testing.T) {
+	tests := []struct {
+		failurePolicy        wafv1alpha1.FailurePolicy
+		expectedFailStrategy string
+	}{
+		{failurePolicy: "", expectedFailStrategy: "FAIL_CLOSE"},
+		{failurePolicy: wafv1alpha1.FailurePolicyFail, expectedFailStrategy: "FAIL_CLOSE"},
+		{failurePolicy: wafv1alpha1.FailurePolicyAllow, expectedFailStrategy: "FAIL_OPEN"},
+	}
+
+	reconciler := &EngineReconciler{ruleSetCacheServerCluster: "test-cluster"}
+	for _, tt := range tests {
+		t.Run(string(tt.failurePolicy), func(t *testing.T) {
+			engine := utils.NewTestEngine(utils.EngineOptions{})
+			engine.Spec.FailurePolicy = tt.failurePolicy
+
+			wasmPlugin, err := reconciler.buildWasmPlugin(engine)
+			require.NoError(t, err)
+
+			failStrategy, found, err := unstructured.NestedString(wasmPlugin.Object, "spec", "failStrategy")
+			require.NoError(t, err)
+			require.True(t, found)
+			assert.Equal(t, tt.expectedFailStrategy, failStrategy)
+		})
+	}
+}
+
+func TestEngineReconciler_BuildWasmPluginPriority(t *testing.T) {
+	reconciler := &EngineReconciler{ruleSetCacheServerCluster: "test-cluster"}
+
+	t.Log("Verifying the priority is omitted when unset")
+	engine := utils.NewTestEngine(utils.EngineOptions{})
+	wasmPlugin, err := reconciler.buildWasmPlugin(engine)
+	require.NoError(t, err)
+	_, found, err := unstructured.NestedInt64(wasmPlugin.Object, "spec", "priority")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	for _, priority := range []int32{0, 10, -10} {
+		t.Logf("Verifying priority %d is emitted", priority)
+		engine.Spec.Priority = &priority
+		wasmPlugin, err = reconciler.buildWasmPlugin(engine)
+		require.NoError(t, err)
+		actual, found, err := unstructured.NestedInt64(wasmPlugin.Object, "spec", "priority")
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, int64(priority), actual)
+	}
+}
+
+func TestEngineReconciler_BuildWasmPluginPhase(t *testing.T) {
+	tests := []struct {
+		phase wafv1alpha1.IstioWasmPhase
+	}{
+		{phase: ""},
+		{phase: wafv1alpha1.IstioWasmPhaseAuthn},
+		{phase: wafv1alpha1.IstioWasmPhaseAuthz},
+		{phase: wafv1alpha1.IstioWasmPhaseStats},
+	}
+
+	reconciler := &EngineReconciler{ruleSetCacheServerCluster: "test-cluster"}
+	for _, tt := range tests {
+		t.Run(string(tt.phase), func(t *testing.T) {
+			engine := utils.NewTestEngine(utils.EngineOptions{})
+			engine.Spec.Driver.Istio.Wasm.Phase = tt.phase
+
+			wasmPlugin, err := reconciler.buildWasmPlugin(engine)
+			require.NoError(t, err)
+
+			phase, found, err := unstructured.NestedString(wasmPlugin.Object, "spec", "phase")
+			require.NoError(t, err)
+			if tt.phase == "" {
+				assert.False(t, found, "phase should be omitted when unset")
+				return
+			}
+			require.True(t, found)
+			assert.Equal(t, string(tt.phase), phase)
+		})
+	}
+}
+
+func TestEngineReconciler_BuildWasmPluginImagePullSecret(t *testing.T) {
+	reconciler := &EngineReconciler{ruleSetCacheServerCluster: "test-cluster"}
+
+	t.Log("Verifying the imagePullSecret is omitted when unset")
+	engine := utils.NewTestEngine(utils.EngineOptions{})
+	wasmPlugin, err := reconciler.buildWasmPlugin(engine)
+	require.NoError(t, err)
+	_, found, err := unstructured.NestedString(wasmPlugin.Object, "spec", "imagePullSecret")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	t.Log("Verifying the imagePullSecret is emitted when set")
+	engine.Spec.Driver.Istio.Wasm.ImagePullSecret = "registry-credentials"
+	wasmPlugin, err = reconciler.buildWasmPlugin(engine)
+	require.NoError(t, err)
+	secret, found, err := unstructured.NestedString(wasmPlugin.Object, "spec", "imagePullSecret")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "registry-credentials", secret)
+}
+
+func TestEngineReconciler_BuildWasmPluginDigest(t *testing.T) {
+	reconciler := &EngineReconciler{ruleSetCacheServerCluster: "test-cluster"}
+
+	t.Log("Verifying the sha256 is omitted for images referenced by tag")
+	engine := utils.NewTestEngine(utils.EngineOptions{})
+	engine.Spec.Driver.Istio.Wasm.Image = "oci://ghcr.io/example/coraza-wasm:latest"
+	wasmPlugin, err := reconciler.buildWasmPlugin(engine)
+	require.NoError(t, err)
+	_, found, err := unstructured.NestedString(wasmPlugin.Object, "spec", "sha256")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	t.Log("Verifying the digest of a pinned image is emitted as the sha256")
+	digest := strings.Repeat("ab", 32)
+	engine.Spec.Driver.Istio.Wasm.Image = "oci://ghcr.io/example/coraza-wasm@sha256:" + digest
+	wasmPlugin, err = reconciler.buildWasmPlugin(engine)
+	require.NoError(t, err)
+	checksum, found, err := unstructured.NestedString(wasmPlugin.Object, "spec", "sha256")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, digest, checksum)
+	url, _, err := unstructured.NestedString(wasmPlugin.Object, "spec", "url")
+	require.NoError(t, err)
+	assert.Equal(t, engine.Spec.Driver.Istio.Wasm.Image, url)
+}
+
+func TestEngineReconciler_BuildWasmPluginConfigOverrides(t *testing.T) {
+	reconciler := &EngineReconciler{ruleSetCacheServerCluster: "test-cluster"}
+	engine := utils.NewTestEngine(utils.EngineOptions{
+		Name:                "overrides",
+		Namespace:           "default",
+		RuleSetName:         "ruleset",
+		PollIntervalSeconds: 15,
+	})
+	engine.Spec.Driver.Istio.Wasm.PluginConfigOverrides = map[string]apiextensionsv1.JSON{
+		"request_body_limit": {Raw: []byte(`1048576`)},
+		"log_level":          {Raw: []byte(`"debug"`)},
+		"allowed_methods":    {Raw: []byte(`["GET","POST"]`)},
+	}
+
+	t.Log("Verifying overrides of any JSON type are merged with the operator managed keys")
+	wasmPlugin, err := reconciler.buildWasmPlugin(engine)
+	require.NoError(t, err)
+	pluginConfig, found, err := unstructured.NestedFieldNoCopy(wasmPlugin.Object, "spec", "pluginConfig")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, map[string]any{
+		"request_body_limit":           float64(1048576),
+		"log_level":                    "debug",
+		"allowed_methods":              []any{"GET", "POST"},
+		"cache_server_instance":        "default/ruleset",
+		"cache_server_cluster":         "test-cluster",
+		"rule_reload_interval_seconds": int32(15),
+	}, pluginConfig)
+
+	t.Log("Verifying every operator managed key is rejected, whether or not the operator sets it")
+	for _, key := range operatorManagedPluginConfigKeys {
+		for _, cacheServer := range []*wafv1alpha1.RuleSetCacheServerConfig{engine.Spec.Driver.Istio.Wasm.RuleSetCacheServer, nil} {
+			overridden := engine.DeepCopy()
+			overridden.Spec.Driver.Istio.Wasm.RuleSetCacheServer = cacheServer
+			overridden.Spec.Driver.Istio.Wasm.PluginConfigOverrides = map[string]apiextensionsv1.JSON{
+				key: {Raw: []byte(`"override"`)},
+			}
+			_, err := reconciler.buildWasmPlugin(overridden)
+			require.Error(t, err, "override of %s should be rejected (cache server set: %t)", key, cacheServer != nil)
+			assert.Contains(t, err.Error(), key)
+		}
+	}
+}
+
+func TestEngineReconciler_BuildEnvoyExtensionPolicy(t *testing.T) {
+	engine := utils.NewTestEngine(utils.EngineOptions{Name: "envoy-engine", RuleSetName: "envoy-rules"})
+	engine.Spec.Driver = wafv1alpha1.DriverConfig{Envoy: &wafv1alpha1.EnvoyDriverConfig{ExtProc: &wafv1alpha1.EnvoyExtProcConfig{
+		GatewayName: "my-gateway",
+		Service:     wafv1alpha1.EnvoyExtProcService{Name: "coraza-ext-proc", Port: 9002},
+	}}}
+
+	reconciler := &EngineReconciler{ruleSetCacheServerCluster: "test-cluster"}
+	policy := reconciler.buildEnvoyExtensionPolicy(engine)
+
+	assert.Equal(t, "coraza-engine-envoy-engine", policy.GetName())
+	assert.Equal(t, map[string]string{
+		CacheServerInstanceAnnotation: engineCacheKey(engine),
+		CacheServerClusterAnnotation:  "test-cluster",
+	}, policy.GetAnnotations())
+
+	backendRefs, found, err := unstructured.NestedSlice(policy.Object, "spec", "extProc")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, []any{map[string]any{"backendRefs": []any{
+		map[string]any{"name": "coraza-ext-proc", "port": int64(9002)},
+	}}}, backendRefs)
+}
+
+func TestEngineReconciler_BuildWithCacheAuthToken(t *testing.T) {
+	engine := utils.NewTestEngine(utils.EngineOptions{Name: "auth-engine", Namespace: "default"})
+
+	t.Log("Verifying no token is passed to the data plane when the cache server doesn't require one")
+	reconciler := &EngineReconciler{ruleSetCacheServerCluster: "test-cluster"}
+	wasmPlugin, err := reconciler.buildWasmPlugin(engine)
+	require.NoError(t, err)
+	_, found, err := unstructured.NestedString(wasmPlugin.Object, "spec", "pluginConfig", "cache_server_auth_token_env")
+	require.NoError(t, err)
+	assert.False(t, found)
+	_, found, err = unstructured.NestedFieldNoCopy(wasmPlugin.Object, "spec", "vmConfig")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	t.Log("Verifying the WasmPlugin passes the Engine's token to the plugin")
+	reconciler.cacheAuthToken = "s3cr3t"
+	engineToken := cache.InstanceToken("s3cr3t", engineCacheKey(engine))
+	wasmPlugin, err = reconciler.buildWasmPlugin(engine)
+	require.NoError(t, err)
+	tokenEnv, found, err := unstructured.NestedString(wasmPlugin.Object, "spec", "pluginConfig", "cache_server_auth_token_env")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, CacheAuthTokenEnvVar, tokenEnv)
+	env, found, err := unstructured.NestedSlice(wasmPlugin.Object, "spec", "vmConfig", "env")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, []any{map[string]any{"name": CacheAuthTokenEnvVar, "valueFrom": "INLINE", "value": engineToken}}, env)
+	assert.NotContains(t, fmt.Sprint(wasmPlugin.Object), "s3cr3t", "the cache server's token must not appear in the WasmPlugin")
+
+	t.Log("Verifying the token differs between Engines")
+	other := utils.NewTestEngine(utils.EngineOptions{Name: "other-engine", Namespace: "other", RuleSetName: "other-rules"})
+	assert.NotEqual(t, engineToken, reconciler.engineCacheAuthToken(other))
+
+	t.Log("Verifying the EnvoyExtensionPolicy is annotated with the Engine's token")
+	engine.Spec.Driver = wafv1alpha1.DriverConfig{Envoy: &wafv1alpha1.EnvoyDriverConfig{ExtProc: &wafv1alpha1.EnvoyExtProcConfig{
+		GatewayName: "my-gateway",
+		Service:     wafv1alpha1.EnvoyExtProcService{Name: "coraza-ext-proc", Port: 9002},
+	}}}
+	policy := reconciler.buildEnvoyExtensionPolicy(engine)
+	assert.Equal(t, engineToken, policy.GetAnnotations()[CacheServerAuthTokenAnnotation])
+	assert.NotContains(t, fmt.Sprint(policy.Object), "s3cr3t", "the cache server's token must not appear in the EnvoyExtensionPolicy")
+}
+
+func TestEngineReconciler_ServeSecretSources(t *testing.T) {
+	ctx := context.Background()
+	ruleSetCache := cache.NewRuleSetCache()
+	const token = "secret-sources-token"
+
+	t.Log("Serving the cache with an auth token")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	server := cache.NewServer(ruleSetCache, addr, utils.NewTestLogger(t), nil).WithAuthToken(token)
+	go func() {
+		if err := server.Start(t.Context()); err != nil {
+			t.Logf("Cache server stopped: %v", err)
+		}
+	}()
+
+	t.Log("Creating a RuleSet loading its rules from a Secret, and an Engine using it")
+	secret := utils.NewTestSecret("served-secret-rules", "default", `SecRule ARGS "@contains blocked" "id:1,phase:2,deny"`)
+	require.NoError(t, k8sClient.Create(ctx, secret))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, secret); err != nil {
+			t.Logf("Failed to delete Secret: %v", err)
+		}
+	})
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "served-secret-ruleset",
+		Namespace: "default",
+		Rules:     []wafv1alpha1.RuleSourceReference{{Kind: wafv1alpha1.RuleSourceKindSecret, Name: secret.Name}},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+	engine := utils.NewTestEngine(utils.EngineOptions{Name: "served-secret-engine", RuleSetName: ruleSet.Name})
+	require.NoError(t, k8sClient.Create(ctx, engine))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, engine); err != nil {
+			t.Logf("Failed to delete Engine: %v", err)
+		}
+	})
+
+	t.Log("Reconciling the RuleSet and the Engine with Secret sources enabled by the auth token")
+	ruleSetReconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: utils.NewTestRecorder(),
+		Cache:    ruleSetCache,
+
+		secretSources: true,
+	}
+	_, err = ruleSetReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}})
+	require.NoError(t, err)
+	engineReconciler := &EngineReconciler{
+		Client:                    k8sClient,
+		Scheme:                    scheme,
+		Recorder:                  utils.NewTestRecorder(),
+		ruleSetCacheServerCluster: "test-cluster",
+		ruleSetCache:              ruleSetCache,
+		cacheAuthToken:            token,
+	}
+	_, err = engineReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: engine.Namespace}})
+	require.NoError(t, err)
+
+	t.Log("Verifying the Engine's data plane is given its token through the WasmPlugin")
+	wasmPlugin := &unstructured.Unstructured{}
+	wasmPlugin.SetGroupVersionKind(wasmPluginGVK)
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: WasmPluginNamePrefix + engine.Name, Namespace: engine.Namespace}, wasmPlugin))
+	env, found, err := unstructured.NestedSlice(wasmPlugin.Object, "spec", "vmConfig", "env")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Len(t, env, 1)
+	engineToken, _, err := unstructured.NestedString(env[0].(map[string]any), "value")
+	require.NoError(t, err)
+	require.NotEmpty(t, engineToken)
+
+	getRules := func(key, token string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s/rules/%s", addr, key), nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Log("Verifying the cache server serves the Engine's rules to a client bearing its token")
+	require.Eventually(t, func() bool {
+		resp := getRules(engineCacheKey(engine), engineToken)
+		defer func() { _ = resp.Body.Close() }()
+		var entry cache.RuleSetEntry
+		return resp.StatusCode == http.StatusOK &&
+			json.NewDecoder(resp.Body).Decode(&entry) == nil &&
+			entry.Rules == string(secret.Data["rules"])
+	}, 5*time.Second, 100*time.Millisecond)
+
+	t.Log("Verifying the Engine's token doesn't grant access to other rules")
+	ruleSetCache.Put("other/ruleset", `SecRule ARGS "@contains other" "id:2,phase:2,deny"`)
+	resp := getRules("other/ruleset", engineToken)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "the Engine's token must not grant access to other instances")
+
+	resp = getRules(engineCacheKey(engine), "")
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "rules must not be served without the token")
+}
+
+func TestEngineReconciler_FieldManager(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		fieldManager string
+		expected     string
+	}{
+		{name: "default", expected: DefaultFieldManager},
+		{name: "configured", fieldManager: "coraza-canary", expected: "coraza-canary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := utils.NewTestEngine(utils.EngineOptions{Name: "field-manager-engine"})
+			gateway := newTestGateway(engine.Namespace, "gateway", map[string]string{"app": "gateway"})
+
+			var applied []string
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(engine, gateway).
+				WithStatusSubresource(engine).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+						if patch.Type() != types.ApplyPatchType {
+							return c.Patch(ctx, obj, patch, opts...)
+						}
+						patchOpts := &client.PatchOptions{}
+						patchOpts.ApplyOptions(opts)
+						applied = append(applied, patchOpts.FieldManager)
+						return nil
+					},
+				}).
+				Build()
+
+			reconciler := &EngineReconciler{
+				Client:                    c,
+				Scheme:                    scheme,
+				Recorder:                  utils.NewTestRecorder(),
+				ruleSetCacheServerCluster: "test-cluster",
+				fieldManager:              tt.fieldManager,
+			}
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: engine.Namespace},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []string{tt.expected}, applied)
+		})
+	}
+}
+
+func TestEngineReconciler_ProvisioningFailureBackoff(t *testing.T) {
+	ctx := context.Background()
+
+	engine := utils.NewTestEngine(utils.EngineOptions{Name: "apply-failure-engine"})
+	gateway := newTestGateway(engine.Namespace, "gateway", map[string]string{"app": "gateway"})
+
+	applyErr := errors.New("no matches for kind \"WasmPlugin\"")
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(engine, gateway).
+		WithStatusSubresource(engine).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if patch.Type() != types.ApplyPatchType {
+					return c.Patch(ctx, obj, patch, opts...)
+				}
+				return applyErr
+			},
+		}).
+		Build()
+
+	recorder := utils.NewFakeRecorder()
+	reconciler := &EngineReconciler{
+		Client:                    c,
+		Scheme:                    scheme,
+		Recorder:                  recorder,
+		ruleSetCacheServerCluster: "test-cluster",
+		rateLimiter:               &RateLimiterConfig{BaseDelay: time.Second, MaxDelay: 3 * time.Second},
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: engine.Namespace}}
+
+	t.Log("Verifying repeated apply failures are retried with a capped exponential backoff")
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		result, err := reconciler.Reconcile(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, expected, result.RequeueAfter)
+	}
+
+	var updated wafv1alpha1.Engine
+	require.NoError(t, c.Get(ctx, req.NamespacedName, &updated))
+	degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, wafv1alpha1.ReasonProvisioningFailed, degraded.Reason)
+
+	t.Log("Verifying the ProvisioningFailed event is only emitted for the first failure")
+	failedEvents := 0
+	for _, e := range recorder.Events {
+		if e.Reason == wafv1alpha1.ReasonProvisioningFailed {
+			failedEvents++
+		}
+	}
+	assert.Equal(t, 1, failedEvents)
+
+	t.Log("Verifying a successful apply resets the backoff")
+	applyErr = nil
+	result, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+	require.NoError(t, c.Get(ctx, req.NamespacedName, &updated))
+	assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Ready"))
+
+	applyErr = errors.New("apply rejected")
+	result, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, result.RequeueAfter)
+}
+
+func TestEngineReconciler_DriverRegistry(t *testing.T) {
+	reconciler := &EngineReconciler{
+		Client:                    k8sClient,
+		Scheme:                    scheme,
+		Recorder:                  utils.NewTestRecorder(),
+		ruleSetCacheServerCluster: "test-cluster",
+	}
+
+	t.Log("Looking up driver for Istio with WASM mode")
+	driver, err := reconciler.lookupDriver(utils.NewTestEngine(utils.EngineOptions{}))
+	require.NoError(t, err)
+	assert.IsType(t, &istioWasmDriver{}, driver)
+
+	t.Log("Looking up driver for Istio without an integration mode")
+	engine := utils.NewTestEngine(utils.EngineOptions{})
+	engine.Spec.Driver.Istio.Wasm = nil
+	_, err = reconciler.lookupDriver(engine)
+	require.Error(t, err)
+
+	t.Log("Looking up driver for Envoy with ext_proc mode")
+	engine = utils.NewTestEngine(utils.EngineOptions{})
+	engine.Spec.Driver = wafv1alpha1.DriverConfig{
+		Envoy: &wafv1alpha1.EnvoyDriverConfig{
+			ExtProc: &wafv1alpha1.EnvoyExtProcConfig{
+				GatewayName: "test-gateway",
+				Service:     wafv1alpha1.EnvoyExtProcService{Name: "coraza-ext-proc", Port: 9002},
+			},
+		},
+	}
+	driver, err = reconciler.lookupDriver(engine)
+	require.NoError(t, err)
+	assert.IsType(t, &envoyExtProcDriver{}, driver)
+
+	t.Log("Looking up driver for Envoy without an integration mode")
+	engine.Spec.Driver.Envoy.ExtProc = nil
+	_, err = reconciler.lookupDriver(engine)
+	require.Error(t, err)
+
+	t.Log("Looking up driver when no driver is configured")
+	engine = utils.NewTestEngine(utils.EngineOptions{})
+	engine.Spec.Driver = wafv1alpha1.DriverConfig{}
+	_, err = reconciler.lookupDriver(engine)
+	require.Error(t, err)
+
+	t.Log("Dispatching to a registered driver")
+	original := driverRegistry
+	t.Cleanup(func() { driverRegistry = original })
+	registration := func(name string) driverRegistration {
+		i := slices.IndexFunc(original, func(d driverRegistration) bool { return d.name == name })
+		require.GreaterOrEqual(t, i, 0, "driver %s is not registered", name)
+		return original[i]
+	}
+	istioWasm, envoyExtProc := registration("istio/wasm"), registration("envoy/extproc")
+	fake := &fakeDriver{}
+	driverRegistry = []driverRegistration{
+		{name: istioWasm.name, matches: istioWasm.matches, factory: func(*EngineReconciler) Driver { return fake }},
+		envoyExtProc,
+	}
+	_, err = reconciler.selectDriver(context.Background(), ctrl.Log, ctrl.Request{}, *utils.NewTestEngine(utils.EngineOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, fake.provisioned)
+
+	t.Log("Looking up a driver combination that is not registered")
+	driverRegistry = []driverRegistration{envoyExtProc}
+	_, err = reconciler.lookupDriver(utils.NewTestEngine(utils.EngineOptions{}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no registered driver matches")
+
+	t.Log("Looking up a driver added by registration alone")
+	added := driverRegistration{
+		name:    "test/fake",
+		matches: func(config *wafv1alpha1.DriverConfig) bool { return config.Istio != nil },
+		factory: func(*EngineReconciler) Driver { return fake },
+	}
+	driverRegistry = []driverRegistration{envoyExtProc, added}
+	driver, err = reconciler.lookupDriver(utils.NewTestEngine(utils.EngineOptions{}))
+	require.NoError(t, err)
+	assert.Same(t, fake, driver)
+
+	t.Log("Looking up a driver configuration matching several drivers")
+	driverRegistry = append(slices.Clone(original), added)
+	_, err = reconciler.lookupDriver(utils.NewTestEngine(utils.EngineOptions{}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "matches several drivers: [istio/wasm test/fake]")
+}
+
+// fakeDriver is a Driver which records calls without touching the cluster.
+type fakeDriver struct {
+	provisioned int
+	cleanedUp   int
+}
+
+func (d *fakeDriver) Provision(context.Context, logr.Logger, ctrl.Request, wafv1alpha1.Engine) (ctrl.Result, error) {
+	d.provisioned++
+	return ctrl.Result{}, nil
+}
+
+func (d *fakeDriver) Cleanup(context.Context, logr.Logger, ctrl.Request, wafv1alpha1.Engine) (ctrl.Result, error) {
+	d.cleanedUp++
+	return ctrl.Result{}, nil
+}
+
+func TestEngineReconciler_ControllerOptionsRateLimiter(t *testing.T) {
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "rate-limited", Namespace: "default"}}
+
+	t.Log("Verifying default rate limiter bounds are used when none are configured")
+	reconciler := &EngineReconciler{}
+	limiter := reconciler.controllerOptions().RateLimiter
+	require.NotNil(t, limiter)
+	assert.Equal(t, DefaultRateLimiterBaseDelay, limiter.When(req))
+	assert.Equal(t, 2*DefaultRateLimiterBaseDelay, limiter.When(req))
+
+	t.Log("Verifying configured rate limiter bounds are honored")
+	reconciler = &EngineReconciler{rateLimiter: &RateLimiterConfig{
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  300 * time.Millisecond,
+	}}
+	limiter = reconciler.controllerOptions().RateLimiter
+	require.NotNil(t, limiter)
+	assert.Equal(t, 100*time.Millisecond, limiter.When(req))
+	assert.Equal(t, 200*time.Millisecond, limiter.When(req))
+	assert.Equal(t, 300*time.Millisecond, limiter.When(req), "delay should be capped at MaxDelay")
+	assert.Equal(t, 3, limiter.NumRequeues(req))
+
+	t.Log("Verifying a successful reconcile resets the backoff")
+	limiter.Forget(req)
+	assert.Equal(t, 0, limiter.NumRequeues(req))
+	assert.Equal(t, 100*time.Millisecond, limiter.When(req))
+}
+
+func TestEngineReconciler_RuleMatchStatistics(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating test engine for rule match statistics")
+	engine := utils.NewTestEngine(utils.EngineOptions{
+		Name:      "match-stats-test",
+		Namespace: "default",
+	})
+	require.NoError(t, k8sClient.Create(ctx, engine))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, engine); err != nil {
+			t.Logf("Failed to delete engine: %v", err)
+		}
+	})
+
+	t.Log("Reconciling engine with a fake rule match statistics source")
+	stats := &fakeRuleMatchStatsSource{matches: map[int64]int64{
+		1001: 5,
+		1002: 42,
+		1003: 0,
+		1004: 42,
+	}}
+	reconciler := &EngineReconciler{
+		Client:                    k8sClient,
+		Scheme:                    scheme,
+		Recorder:                  utils.NewTestRecorder(),
+		ruleSetCacheServerCluster: "test-cluster",
+		matchStats:                stats,
+	}
+	result, err := reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      engine.Name,
+			Namespace: engine.Namespace,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, MatchStatisticsRefreshInterval, result.RequeueAfter)
+
+	t.Log("Verifying top matched rules were written to status")
+	var updated wafv1alpha1.Engine
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: engine.Name, Namespace: engine.Namespace}, &updated))
+	assert.Equal(t, []wafv1alpha1.RuleMatchCount{
+		{RuleID: 1002, Matches: 42},
+		{RuleID: 1004, Matches: 42},
+		{RuleID: 1001, Matches: 5},
+	}, updated.Status.TopMatchedRules)
+	assert.NotNil(t, updated.Status.MatchStatisticsUpdated)
+	assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Ready"))
+
+	t.Log("Verifying a failing statistics source doesn't fail reconciliation")
+	stats.err = errors.New("stats unavailable")
+	result, err = reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      engine.Name,
+			Namespace: engine.Namespace,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, MatchStatisticsRefreshInterval, result.RequeueAfter)
+}
+
+func TestTopRuleMatches(t *testing.T) {
+	matches := map[int64]int64{}
+	for id := int64(1); id <= 20; id++ {
+		matches[id] = id * 10
+	}
+
+	top := topRuleMatches(matches, 3)
+	assert.Equal(t, []wafv1alpha1.RuleMatchCount{
+		{RuleID: 20, Matches: 200},
+		{RuleID: 19, Matches: 190},
+		{RuleID: 18, Matches: 180},
+	}, top)
+
+	assert.Empty(t, topRuleMatches(nil, 3))
+	assert.Empty(t, topRuleMatches(map[int64]int64{1: 0}, 3))
+}
+
+func TestSetStatusRulesServed(t *testing.T) {
+	tests := []struct {
+		name       string
+		served     string
+		latest     string
+		found      bool
+		wantStatus metav1.ConditionStatus
+		wantReason string
+		wantUUID   string
+	}{
+		{
+			name:       "rules not cached",
+			wantStatus: metav1.ConditionUnknown,
+			wantReason: "RulesNotCached",
+			wantUUID:   "previous",
+		},
+		{
+			name:       "nothing served yet",
+			latest:     "uuid-2",
+			found:      true,
+			wantStatus: metav1.ConditionUnknown,
+			wantReason: "AwaitingDataPlane",
+			wantUUID:   "previous",
+		},
+		{
+			name:       "latest rules served",
+			served:     "uuid-2",
+			latest:     "uuid-2",
+			found:      true,
+			wantStatus: metav1.ConditionTrue,
+			wantReason: "RulesServed",
+			wantUUID:   "uuid-2",
+		},
+		{
+			name:       "older rules served",
+			served:     "uuid-1",
+			latest:     "uuid-2",
+			found:      true,
+			wantStatus: metav1.ConditionFalse,
+			wantReason: "RulesOutdated",
+			wantUUID:   "uuid-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := wafv1alpha1.EngineStatus{ServedRuleSetUUID: "previous"}
+			setStatusRulesServed(&status, 3, tt.served, tt.latest, tt.found)
+
+			assert.Equal(t, tt.wantUUID, status.ServedRuleSetUUID)
+			cond := apimeta.FindStatusCondition(status.Conditions, "RulesServed")
+			require.NotNil(t, cond)
+			assert.Equal(t, tt.wantStatus, cond.Status)
+			assert.Equal(t, tt.wantReason, cond.Reason)
+			assert.Equal(t, int64(3), cond.ObservedGeneration)
+		})
+	}
+}
+
+func TestEngineReconciler_UpdateServedRulesSkipsUnchangedStatus(t *testing.T) {
+	ctx := context.Background()
+	engine := utils.NewTestEngine(utils.EngineOptions{Name: "served-rules-engine"})
+
+	var statusPatches int
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(engine).
+		WithStatusSubresource(engine).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				statusPatches++
+				return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+
+	source := &fakeServedRulesSource{served: "uuid-1", latest: "uuid-1", found: true}
+	reconciler := &EngineReconciler{
+		Client:      c,
+		Scheme:      scheme,
+		Recorder:    utils.NewTestRecorder(),
+		servedRules: source,
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: engine.Name, Namespace: engine.Namespace}}
+
+	t.Log("Verifying the first check patches the status")
+	require.NoError(t, c.Get(ctx, req.NamespacedName, engine))
+	assert.Equal(t, ServedRulesRefreshInterval, reconciler.updateServedRules(ctx, utils.NewTestLogger(t), req, engine))
+	assert.Equal(t, 1, statusPatches)
+
+	t.Log("Verifying later checks of the same version don't patch it again")
+	for range 3 {
+		require.NoError(t, c.Get(ctx, req.NamespacedName, engine))
+		reconciler.updateServedRules(ctx, utils.NewTestLogger(t), req, engine)
+	}
+	assert.Equal(t, 1, statusPatches)
+
+	t.Log("Verifying a new latest version patches the status")
+	source.latest = "uuid-2"
+	require.NoError(t, c.Get(ctx, req.NamespacedName, engine))
+	assert.Equal(t, ServedRulesPendingInterval, reconciler.updateServedRules(ctx, utils.NewTestLogger(t), req, engine))
+	assert.Equal(t, 2, statusPatches)
+}
+
+// fakeServedRulesSource is a ServedRulesSource returning fixed versions.
+type fakeServedRulesSource struct {
+	served, latest string
+	found          bool
+}
+
+func (f *fakeServedRulesSource) ServedVersion(string) (served, latest string, ok bool) {
+	return f.served, f.latest, f.found
+}
+
+// fakeRuleMatchStatsSource is a RuleMatchStatsSource returning fixed data.
+type fakeRuleMatchStatsSource struct {
+	matches map[int64]int64
+	err     error
+}
+
+func (f *fakeRuleMatchStatsSource) RuleMatches(context.Context, *wafv1alpha1.Engine) (map[int64]int64, error) {
+	return f.matches, f.err
+}
+
+func TestUpsertOwnedResource(t *testing.T) {
+	engine := utils.NewTestEngine(utils.EngineOptions{Name: "owner-engine"})
+	wasmPlugin := corev1.ObjectReference{APIVersion: "extensions.istio.io/v1alpha1", Kind: "WasmPlugin", Namespace: "default", Name: "coraza-engine-owner-engine"}
+	policy := corev1.ObjectReference{APIVersion: "gateway.envoyproxy.io/v1alpha1", Kind: "EnvoyExtensionPolicy", Namespace: "default", Name: "coraza-engine-owner-engine"}
+
+	t.Log("Verifying owned resources of different kinds with the same name coexist")
+	upsertOwnedResource(engine, wasmPlugin)
+	upsertOwnedResource(engine, policy)
+	assert.Equal(t, []corev1.ObjectReference{wasmPlugin, policy}, engine.Status.OwnedResources)
+
+	t.Log("Verifying recording them again is idempotent")
+	upsertOwnedResource(engine, policy)
+	upsertOwnedResource(engine, wasmPlugin)
+	assert.Equal(t, []corev1.ObjectReference{wasmPlugin, policy}, engine.Status.OwnedResources)
+
+	t.Log("Verifying an updated reference replaces the existing entry")
+	updated := wasmPlugin
+	updated.UID = "uid-1"
+	upsertOwnedResource(engine, updated)
+	assert.Equal(t, []corev1.ObjectReference{updated, policy}, engine.Status.OwnedResources)
+}
+
+func TestDegradedReasons(t *testing.T) {
+	defined := make(map[string]bool)
+	for _, reason := range wafv1alpha1.DegradedReasons() {
+		defined[reason] = true
+	}
+
+	t.Log("Verifying every reason constant is part of the defined set")
+	fset := token.NewFileSet()
+	reasonsFile, err := parser.ParseFile(fset, "../../api/v1alpha1/condition_reasons.go", nil, 0)
+	require.NoError(t, err)
+	constants := make(map[string]string)
+	ast.Inspect(reasonsFile, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.ValueSpec); ok && strings.HasPrefix(spec.Names[0].Name, "Reason") {
+			value, err := strconv.Unquote(spec.Values[0].(*ast.BasicLit).Value)
+			require.NoError(t, err)
+			constants[spec.Names[0].Name] = value
+			assert.True(t, defined[value], "%s is missing from DegradedReasons", spec.Names[0].Name)
+		}
+		return true
+	})
+	assert.Len(t, constants, len(defined))
+
+	t.Log("Verifying every Degraded reason set by the controllers is a reason constant")
+	sources, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+	calls := 0
+	for _, source := range sources {
+		if strings.HasSuffix(source, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, source, nil, 0)
+		require.NoError(t, err)
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			fn, ok := call.Fun.(*ast.Ident)
+			if !ok || fn.Name != "setStatusConditionDegraded" {
+				return true
+			}
+			calls++
+			switch reason := call.Args[5].(type) {
+			case *ast.SelectorExpr:
+				_, ok := constants[reason.Sel.Name]
+				assert.True(t, ok, "%s: unknown reason %s", fset.Position(call.Pos()), reason.Sel.Name)
+			case *ast.Ident:
+				// reasons computed by invalidRulesReason or reasonsForRuleSource,
+				// or passed through by a helper, are verified below
+			default:
+				assert.Fail(t, "reason is not a constant", "%s", fset.Position(call.Pos()))
+			}
+			return true
+		})
+	}
+	assert.NotZero(t, calls)
+
+	t.Log("Verifying computed reasons are part of the defined set")
+	for _, kind := range []wafv1alpha1.RuleSourceKind{wafv1alpha1.RuleSourceKindConfigMap, wafv1alpha1.RuleSourceKindSecret} {
+		reasons := reasonsForRuleSource(kind)
+		for _, reason := range []string{reasons.notFound, reasons.accessError, reasons.invalid} {
+			assert.True(t, defined[reason], "unknown reason %s for %s sources", reason, kind)
+		}
+	}
+	assert.True(t, defined[invalidRulesReason(&rulesets.DuplicateRuleIDsError{}, wafv1alpha1.ReasonInvalidRuleSource)])
+}
+
+func TestEventReasons(t *testing.T) {
+	defined := make(map[string]bool)
+	for _, reason := range wafv1alpha1.EventReasons() {
+		defined[reason] = true
+	}
+	degraded := make(map[string]bool)
+	for _, reason := range wafv1alpha1.DegradedReasons() {
+		degraded[reason] = true
+	}
+
+	t.Log("Verifying every event reason constant is part of the defined set, and not a Degraded reason")
+	fset := token.NewFileSet()
+	reasonsFile, err := parser.ParseFile(fset, "../../api/v1alpha1/condition_reasons.go", nil, 0)
+	require.NoError(t, err)
+	constants := make(map[string]string)
+	eventConstants := 0
+	ast.Inspect(reasonsFile, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+		name := spec.Names[0].Name
+		value, err := strconv.Unquote(spec.Values[0].(*ast.BasicLit).Value)
+		require.NoError(t, err)
+		constants[name] = value
+		if strings.HasPrefix(name, "EventReason") {
+			eventConstants++
+			assert.True(t, defined[value], "%s is missing from EventReasons", name)
+			assert.False(t, degraded[value], "%s is also a Degraded reason", name)
+		}
+		return true
+	})
+	assert.Equal(t, len(defined), eventConstants)
+
+	t.Log("Verifying every event emitted by the controllers has a reason constant")
+	sources, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+	calls := 0
+	for _, source := range sources {
+		if strings.HasSuffix(source, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, source, nil, 0)
+		require.NoError(t, err)
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			fn, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || fn.Sel.Name != "Eventf" {
+				return true
+			}
+			calls++
+			switch reason := call.Args[3].(type) {
+			case *ast.SelectorExpr:
+				_, ok := constants[reason.Sel.Name]
+				assert.True(t, ok, "%s: unknown reason %s", fset.Position(call.Pos()), reason.Sel.Name)
+			case *ast.Ident:
+				// Degraded reasons passed through by a helper, or computed
+				// (see TestDegradedReasons)
+			default:
+				assert.Fail(t, "reason is not a constant", "%s", fset.Position(call.Pos()))
+			}
+			return true
 		})
 	}
+	assert.NotZero(t, calls)
 }
@@ -18,10 +18,17 @@ limitations under the License.
 package controller
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
 	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets/cache"
 )
 
@@ -39,20 +46,148 @@ import (
 
 // DefaultRuleSetCacheServerPort is the default port number for the RuleSet
 // cache server.
-const DefaultRuleSetCacheServerPort = 18080
+const DefaultRuleSetCacheServerPort = cache.DefaultPort
+
+// DefaultFieldManager is the default server-side apply field manager name
+// used when applying resources such as WasmPlugins.
+const DefaultFieldManager = "coraza-kubernetes-operator"
+
+// DefaultMaxRuleSetSize is the default limit on the size in bytes of the
+// aggregated rules of a RuleSet.
+const DefaultMaxRuleSetSize = 10 * 1024 * 1024
+
+const (
+	// DefaultRateLimiterBaseDelay is the default initial delay before retrying
+	// a failed reconciliation.
+	DefaultRateLimiterBaseDelay = 1 * time.Second
+
+	// DefaultRateLimiterMaxDelay is the default upper bound on the delay
+	// between retries of a failed reconciliation.
+	DefaultRateLimiterMaxDelay = 1 * time.Minute
+)
+
+// RateLimiterConfig holds the bounds for the exponential failure rate limiter
+// used by a controller. Delays double after each consecutive failure for the
+// same object, starting at BaseDelay and capped at MaxDelay. A successful
+// reconciliation resets the delay back to BaseDelay.
+type RateLimiterConfig struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRateLimiter returns the default rate limiter configuration.
+func DefaultRateLimiter() *RateLimiterConfig {
+	return &RateLimiterConfig{
+		BaseDelay: DefaultRateLimiterBaseDelay,
+		MaxDelay:  DefaultRateLimiterMaxDelay,
+	}
+}
 
 // -----------------------------------------------------------------------------
 // Manager - Setup
 // -----------------------------------------------------------------------------
 
-// SetupControllers initializes all controllers
-func SetupControllers(mgr ctrl.Manager, rulesetCache *cache.RuleSetCache, envoyClusterName string) error {
-	if err := (&RuleSetReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorder("ruleset-controller"),
-		Cache:    rulesetCache,
-	}).SetupWithManager(mgr); err != nil {
+// ControllerOptions configures the controllers set up by SetupControllers.
+type ControllerOptions struct {
+	// RuleSetCache stores the rules of RuleSets and Engines for the cache
+	// server to serve to the data plane.
+	RuleSetCache *cache.RuleSetCache
+
+	// EnvoyClusterName is the name of the Envoy cluster pointing to the
+	// RuleSet cache server, which the data plane loads rules from.
+	EnvoyClusterName string
+
+	// EngineRateLimiter configures the Engine controller's failure rate
+	// limiter. If nil, DefaultRateLimiter is used.
+	EngineRateLimiter *RateLimiterConfig
+
+	// CacheReadiness, if not nil, is marked ready once all RuleSets that exist
+	// at startup have been reconciled at least once (or
+	// InitialReconcileTimeout elapses).
+	CacheReadiness *cache.ReadinessGate
+
+	// ValidateAggregatedRules, if set, compiles the aggregated rules of each
+	// RuleSet, and of each Engine with multiple RuleSets, with Coraza before
+	// they are cached (unless a source opted out of validation), skipping
+	// compilation when they are unchanged since the last reconcile.
+	ValidateAggregatedRules bool
+
+	// StrictRuleValidation, if set, treats rule validation warnings (e.g.
+	// multiple disruptive actions on a rule or an unsupported operator) as
+	// errors, otherwise they're only reported as events.
+	StrictRuleValidation bool
+
+	// ProvenanceLabelKeys are the keys of the ConfigMap labels recorded in
+	// each RuleSet's status.
+	ProvenanceLabelKeys []string
+
+	// FieldManager is the server-side apply field manager resources are
+	// applied with. If empty, DefaultFieldManager is used.
+	FieldManager string
+
+	// URLRuleSources configures fetching URL rule sources. If nil, they
+	// aren't fetched and RuleSets using them are Degraded.
+	URLRuleSources *URLRuleSourceConfig
+
+	// CacheAuthToken is the token the cache server requires requests to
+	// bear, if any. The data plane of each Engine is given a token derived
+	// from it which only grants access to the Engine's own rules. As the
+	// cache server serves rules in plaintext, Secret rule sources are only
+	// enabled if it is set; otherwise RuleSets using them are Degraded.
+	CacheAuthToken string
+
+	// MaxRuleSetSize is the limit in bytes on the aggregated rules of a
+	// RuleSet, beyond which it is Degraded instead of being cached. Zero
+	// leaves their size unlimited.
+	MaxRuleSetSize int
+}
+
+// SetupControllers initializes all controllers with the given options. Cache
+// instances restored from a snapshot whose RuleSet no longer exists are
+// dropped once the informers have synced.
+func SetupControllers(mgr ctrl.Manager, opts ControllerOptions) error {
+	ruleSetReconciler := &RuleSetReconciler{
+		Client:              mgr.GetClient(),
+		Scheme:              mgr.GetScheme(),
+		Recorder:            mgr.GetEventRecorder("ruleset-controller"),
+		Cache:               opts.RuleSetCache,
+		provenanceLabelKeys: opts.ProvenanceLabelKeys,
+		strictValidation:    opts.StrictRuleValidation,
+		maxRuleSetSize:      opts.MaxRuleSetSize,
+		secretSources:       opts.CacheAuthToken != "",
+		apiReader:           mgr.GetAPIReader(),
+	}
+	if opts.ValidateAggregatedRules {
+		ruleSetReconciler.aggregateValidation = newValidationCache(func(rules string) error {
+			return ruleSetReconciler.validateRules(rules).Err()
+		})
+	}
+	if opts.URLRuleSources != nil {
+		ruleSetReconciler.urlSources = newURLFetcher(opts.URLRuleSources)
+	}
+
+	if opts.CacheReadiness != nil {
+		ruleSetReconciler.reconciled = newReconciledSet()
+		if err := mgr.Add(&initialReconcileGate{
+			client:     mgr.GetClient(),
+			gate:       opts.CacheReadiness,
+			reconciled: ruleSetReconciler.reconciled,
+			timeout:    InitialReconcileTimeout,
+			logger:     ctrl.Log.WithName("cache-readiness"),
+		}); err != nil {
+			return fmt.Errorf("unable to add cache readiness gate: %w", err)
+		}
+	}
+
+	if err := mgr.Add(&restoredEntriesPruner{
+		client: mgr.GetClient(),
+		cache:  opts.RuleSetCache,
+		logger: ctrl.Log.WithName("cache-restore"),
+	}); err != nil {
+		return fmt.Errorf("unable to add restored cache entries pruner: %w", err)
+	}
+
+	if err := ruleSetReconciler.SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to create controller RuleSet: %w", err)
 	}
 
@@ -60,10 +195,48 @@ func SetupControllers(mgr ctrl.Manager, rulesetCache *cache.RuleSetCache, envoyC
 		Client:                    mgr.GetClient(),
 		Scheme:                    mgr.GetScheme(),
 		Recorder:                  mgr.GetEventRecorder("engine-controller"),
-		ruleSetCacheServerCluster: envoyClusterName,
+		ruleSetCacheServerCluster: opts.EnvoyClusterName,
+		rateLimiter:               opts.EngineRateLimiter,
+		servedRules:               opts.RuleSetCache,
+		ruleSetCache:              opts.RuleSetCache,
+		fieldManager:              opts.FieldManager,
+		cacheAuthToken:            opts.CacheAuthToken,
+		aggregateValidation:       ruleSetReconciler.aggregateValidation,
 	}).SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to create controller Engine: %w", err)
 	}
 
 	return nil
 }
+
+// -----------------------------------------------------------------------------
+// Manager - Cache Server Events
+// -----------------------------------------------------------------------------
+
+// CacheOverCapacityEvents returns a cache.OverCapacityFunc which emits a
+// Warning CacheOverCapacity event on the resource retaining the most data in
+// the cache, so that an over capacity cache is visible with kubectl. The
+// instance is resolved to its RuleSet, or to its Engine for the aggregated
+// rules of an Engine, which is fetched with c so that the event refers to the
+// existing object. No event is emitted if it can't be fetched (e.g. it was
+// deleted meanwhile).
+func CacheOverCapacityEvents(c client.Reader, recorder events.EventRecorder) cache.OverCapacityFunc {
+	return func(instance string, size, maxSize int) {
+		namespace, name, ok := strings.Cut(instance, "/")
+		if !ok {
+			return
+		}
+
+		var obj client.Object = &wafv1alpha1.RuleSet{}
+		if engineName, isEngine := strings.CutPrefix(name, EngineAggregateInstancePrefix); isEngine {
+			obj, name = &wafv1alpha1.Engine{}, engineName
+		}
+		if err := c.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+			logf.Log.WithName("ruleset-cache").Error(err, "Failed to get the resource of an over capacity cache instance", "instance", instance)
+			return
+		}
+
+		recorder.Eventf(obj, nil, "Warning", wafv1alpha1.EventReasonCacheOverCapacity, "GarbageCollect",
+			"RuleSet cache size %d bytes exceeds its max size of %d bytes after pruning, %s retains the most data", size, maxSize, instance)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// -----------------------------------------------------------------------------
+// Metrics - Consts
+// -----------------------------------------------------------------------------
+
+// Values of the "result" label of the reconcile duration histogram.
+const (
+	// reconcileResultSuccess is a reconciliation which completed.
+	reconcileResultSuccess = "success"
+
+	// reconcileResultRequeue is a reconciliation which completed, but
+	// requested to be retried (e.g. with a backoff after a failure).
+	reconcileResultRequeue = "requeue"
+
+	// reconcileResultError is a reconciliation which returned an error.
+	reconcileResultError = "error"
+)
+
+// -----------------------------------------------------------------------------
+// Metrics
+// -----------------------------------------------------------------------------
+
+var (
+	// reconcileDuration observes how long reconciliations take, by
+	// controller and result.
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "coraza_reconcile_duration_seconds",
+		Help:    "Duration of reconciliations in seconds, by controller and result.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"controller", "result"})
+
+	// reconcileErrors counts reconciliations which left the reconciled
+	// resource Degraded, by controller and the reason of the Degraded
+	// condition.
+	reconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "coraza_reconcile_errors_total",
+		Help: "Total number of reconciliations which left a resource Degraded, by controller and reason.",
+	}, []string{"controller", "reason"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(reconcileDuration, reconcileErrors)
+}
+
+// observeReconcile records the duration and result of a reconciliation by
+// the given controller which started at the given time. It is meant to be
+// deferred at the start of Reconcile, with its named return values.
+func observeReconcile(controller string, start time.Time, result *ctrl.Result, err *error) {
+	outcome := reconcileResultSuccess
+	switch {
+	case *err != nil:
+		outcome = reconcileResultError
+	case !result.IsZero():
+		outcome = reconcileResultRequeue
+	}
+	reconcileDuration.WithLabelValues(controller, outcome).Observe(time.Since(start).Seconds())
+}
+
+// observeDegraded counts a reconciliation of a resource of the given kind
+// (e.g. "Engine") which left it Degraded with the given reason.
+func observeDegraded(kind, reason string) {
+	reconcileErrors.WithLabelValues(strings.ToLower(kind), reason).Inc()
+}
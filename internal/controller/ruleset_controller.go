@@ -17,15 +17,17 @@ limitations under the License.
 package controller
 
 import (
+	"cmp"
 	"context"
 	"fmt"
-	"strings"
+	"slices"
 	"time"
 
-	"github.com/corazawaf/coraza/v3"
+	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/events"
@@ -34,11 +36,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets"
 	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets/cache"
 )
 
@@ -48,53 +52,170 @@ import (
 
 // +kubebuilder:rbac:groups=waf.k8s.coraza.io,resources=rulesets,verbs=get;list;watch;patch;update
 // +kubebuilder:rbac:groups=waf.k8s.coraza.io,resources=rulesets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=waf.k8s.coraza.io,resources=rulesets/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
 // -----------------------------------------------------------------------------
 // RuleSet Controller
 // -----------------------------------------------------------------------------
 
+// ruleSetFinalizer ensures a deleted RuleSet's rules are evicted from the
+// cache before the RuleSet is removed.
+const ruleSetFinalizer = "waf.k8s.coraza.io/ruleset-finalizer"
+
+const (
+	// missingSourceBaseDelay is the delay before retrying a RuleSet after
+	// the first consecutive reconciliation failing on a missing rule source.
+	// It doubles with each further failure, up to missingSourceMaxDelay.
+	missingSourceBaseDelay = 5 * time.Second
+
+	// missingSourceMaxDelay is the upper bound on the delay between retries
+	// of a RuleSet whose rule source remains missing.
+	missingSourceMaxDelay = 5 * time.Minute
+)
+
+// missingSourceBackoff returns the delay before retrying a RuleSet after the
+// given number of consecutive failures on a missing rule source.
+func missingSourceBackoff(failures int32) time.Duration {
+	delay := missingSourceBaseDelay
+	for i := int32(1); i < failures && delay < missingSourceMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, missingSourceMaxDelay)
+}
+
 // RuleSetReconciler reconciles a RuleSet object
 type RuleSetReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder events.EventRecorder
 	Cache    *cache.RuleSetCache
+
+	reconciled *reconciledSet
+
+	// aggregateValidation, when set, compiles the aggregated rules with
+	// Coraza before caching them, in addition to validating each source.
+	aggregateValidation *validationCache
+
+	// provenanceLabelKeys lists the ConfigMap label keys recorded in the
+	// RuleSet's status.resolvedSources. When empty, no labels are recorded.
+	provenanceLabelKeys []string
+
+	// urlSources fetches the rules of URL rule sources. When nil, URL rule
+	// sources are disabled.
+	urlSources *urlFetcher
+
+	// secretSources enables Secret rule sources. As the cache server serves
+	// the rules in plaintext, they should only be enabled if it requires an
+	// auth token.
+	secretSources bool
+
+	// apiReader reads Secret rule sources directly from the API server, as
+	// only their metadata is cached. When nil, the Client is used.
+	apiReader client.Reader
+
+	// strictValidation treats rule validation warnings (e.g. multiple
+	// disruptive actions on a rule) as errors.
+	strictValidation bool
+
+	// maxRuleSetSize limits the size in bytes of the aggregated rules of a
+	// RuleSet. When zero, their size is unlimited.
+	maxRuleSetSize int
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *RuleSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&wafv1alpha1.RuleSet{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(), &wafv1alpha1.RuleSet{}, ruleSetConfigMapIndexKey, indexRuleSetConfigMaps,
+	); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(), &wafv1alpha1.RuleSet{}, ruleSetCrossNamespaceConfigMapIndexKey, indexRuleSetCrossNamespaceConfigMaps,
+	); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(), &wafv1alpha1.RuleSet{}, ruleSetCrossNamespaceSourceNamespaceIndexKey, indexRuleSetCrossNamespaceSourceNamespaces,
+	); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(), &wafv1alpha1.RuleSet{}, ruleSetSecretIndexKey, indexRuleSetSecrets,
+	); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&wafv1alpha1.RuleSet{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			pausedChangedPredicate(),
+		))).
 		Watches(
 			&corev1.ConfigMap{},
 			handler.EnqueueRequestsFromMapFunc(r.findRuleSetsForConfigMap),
 		).
-		WithOptions(controller.Options{
-			RateLimiter: workqueue.NewTypedItemExponentialFailureRateLimiter[ctrl.Request](
-				1*time.Second,
-				1*time.Minute,
-			),
-		}).
+		WatchesMetadata(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.findRuleSetsForSecret),
+		)
+
+	if referenceGrantWatchAvailable(mgr.GetRESTMapper()) {
+		grant := &unstructured.Unstructured{}
+		grant.SetGroupVersionKind(referenceGrantGVK)
+		b = b.Watches(grant, handler.EnqueueRequestsFromMapFunc(r.findRuleSetsForReferenceGrant))
+	}
+
+	return b.WithOptions(controller.Options{
+		RateLimiter: workqueue.NewTypedItemExponentialFailureRateLimiter[ctrl.Request](
+			1*time.Second,
+			1*time.Minute,
+		),
+	}).
 		Named("ruleset").
 		Complete(r)
 }
 
 // Reconcile handles reconciliation of RuleSet resources
-func (r *RuleSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *RuleSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer observeReconcile("ruleset", time.Now(), &result, &err)
 	log := logf.FromContext(ctx)
+	defer r.reconciled.mark(req.NamespacedName)
 
 	logDebug(log, req, "RuleSet", "Starting reconciliation")
 	var ruleset wafv1alpha1.RuleSet
 	if err := r.Get(ctx, req.NamespacedName, &ruleset); err != nil {
 		if errors.IsNotFound(err) {
 			logDebug(log, req, "RuleSet", "Resource not found")
+			r.aggregateValidation.forget(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		logError(log, req, "RuleSet", err, "Failed to GET")
 		return ctrl.Result{}, err
 	}
 
+	if !ruleset.DeletionTimestamp.IsZero() {
+		return r.finalize(ctx, log, req, &ruleset)
+	}
+
+	if isPaused(&ruleset) {
+		return pauseReconciliation(ctx, r.Client, r.Recorder, log, req, "RuleSet", &ruleset, &ruleset.Status.Conditions)
+	}
+	if err := resumeReconciliation(ctx, r.Client, r.Recorder, log, req, "RuleSet", &ruleset, &ruleset.Status.Conditions); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !controllerutil.ContainsFinalizer(&ruleset, ruleSetFinalizer) {
+		logDebug(log, req, "RuleSet", "Adding finalizer")
+		patch := client.MergeFromWithOptions(ruleset.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		controllerutil.AddFinalizer(&ruleset, ruleSetFinalizer)
+		if err := r.Patch(ctx, &ruleset, patch); err != nil {
+			logError(log, req, "RuleSet", err, "Failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
 	if apimeta.FindStatusCondition(ruleset.Status.Conditions, "Ready") == nil {
 		patch := client.MergeFrom(ruleset.DeepCopy())
 		setStatusProgressing(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, "Reconciling", "Starting reconciliation")
@@ -105,33 +226,174 @@ func (r *RuleSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	}
 
 	logDebug(log, req, "RuleSet", "Aggregating rules from sources", "ruleCount", len(ruleset.Spec.Rules))
-	var aggregatedRules strings.Builder
-	for i, rule := range ruleset.Spec.Rules {
-		logDebug(log, req, "RuleSet", "Processing rule source", "index", i, "configMapName", rule.Name)
-		logDebug(log, req, "RuleSet", "Fetching ConfigMap", "configMapName", rule.Name, "configMapNamespace", ruleset.Namespace)
-		var cm corev1.ConfigMap
-		if err := r.Get(ctx, types.NamespacedName{
+	sources := make([]rulesets.Source, 0, len(ruleset.Spec.Rules))
+	var resolved []wafv1alpha1.ResolvedSource
+	validationOptOut := false
+	hasURLSources := false
+	hasSecretSources := false
+	for _, i := range ruleSourceOrder(ruleset.Spec.Rules) {
+		rule := ruleset.Spec.Rules[i]
+		if rule.Kind == wafv1alpha1.RuleSourceKindInline {
+			logDebug(log, req, "RuleSet", "Processing inline rule source", "index", i, "sourceName", rule.Name)
+			if err := r.validateRuleSource(&ruleset, fmt.Sprintf("Inline rule source %s", inlineSourceName(i, rule)), rule.Rules, false); err != nil {
+				patch := client.MergeFrom(ruleset.DeepCopy())
+				msg := fmt.Sprintf("Inline rule source %s doesn't contain valid rules:\n%v", inlineSourceName(i, rule), err)
+				reason := invalidRulesReason(err, wafv1alpha1.ReasonInvalidRuleSource)
+				r.Recorder.Eventf(&ruleset, nil, "Warning", reason, "Reconcile", msg)
+				setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, reason, msg)
+				if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
+					logError(log, req, "RuleSet", updateErr, "Failed to patch status")
+				}
+
+				return ctrl.Result{}, err
+			}
+
+			sources = append(sources, rulesets.Source{Name: fmt.Sprintf("Inline rule source %s", inlineSourceName(i, rule)), Rules: rule.Rules})
+			continue
+		}
+
+		if rule.Kind == wafv1alpha1.RuleSourceKindURL {
+			logDebug(log, req, "RuleSet", "Processing URL rule source", "index", i, "url", rule.URL)
+			if r.urlSources == nil {
+				patch := client.MergeFrom(ruleset.DeepCopy())
+				msg := fmt.Sprintf("URL rule source %s can't be fetched, URL rule sources are disabled", rule.URL)
+				r.Recorder.Eventf(&ruleset, nil, "Warning", wafv1alpha1.ReasonURLSourcesDisabled, "Reconcile", msg)
+				setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, wafv1alpha1.ReasonURLSourcesDisabled, msg)
+				if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
+					logError(log, req, "RuleSet", updateErr, "Failed to patch status")
+				}
+
+				return ctrl.Result{}, nil
+			}
+
+			if !r.urlSources.urlHostAllowed(rule.URL) {
+				patch := client.MergeFrom(ruleset.DeepCopy())
+				msg := fmt.Sprintf("URL rule source %s can't be fetched, its host is not allowed", rule.URL)
+				r.Recorder.Eventf(&ruleset, nil, "Warning", wafv1alpha1.ReasonURLHostNotAllowed, "Reconcile", msg)
+				setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, wafv1alpha1.ReasonURLHostNotAllowed, msg)
+				if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
+					logError(log, req, "RuleSet", updateErr, "Failed to patch status")
+				}
+
+				return ctrl.Result{}, nil
+			}
+
+			data, err := r.urlSources.fetch(ctx, rule.URL)
+			if err != nil {
+				logError(log, req, "RuleSet", err, "Failed to fetch URL rule source", "url", rule.URL)
+				patch := client.MergeFrom(ruleset.DeepCopy())
+				msg := fmt.Sprintf("Failed to fetch URL rule source: %v", err)
+				r.Recorder.Eventf(&ruleset, nil, "Warning", wafv1alpha1.ReasonFetchFailed, "Reconcile", msg)
+				setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, wafv1alpha1.ReasonFetchFailed, msg)
+				if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
+					logError(log, req, "RuleSet", updateErr, "Failed to patch status")
+				}
+
+				return ctrl.Result{}, err
+			}
+
+			// Remote rules are redacted like Secret rules, as they may not be
+			// meant to be readable by whoever can read the RuleSet.
+			if err := r.validateRuleSource(&ruleset, fmt.Sprintf("URL rule source %s", rule.URL), data, true); err != nil {
+				patch := client.MergeFrom(ruleset.DeepCopy())
+				msg := fmt.Sprintf("URL rule source %s doesn't contain valid rules:\n%v", rule.URL, err)
+				reason := invalidRulesReason(err, wafv1alpha1.ReasonInvalidURLSource)
+				r.Recorder.Eventf(&ruleset, nil, "Warning", reason, "Reconcile", msg)
+				setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, reason, msg)
+				if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
+					logError(log, req, "RuleSet", updateErr, "Failed to patch status")
+				}
+
+				return ctrl.Result{}, err
+			}
+
+			sources = append(sources, rulesets.Source{Name: fmt.Sprintf("URL rule source %s", rule.URL), Rules: data})
+			hasURLSources = true
+			continue
+		}
+
+		kind := rule.Kind
+		if kind == "" {
+			kind = wafv1alpha1.RuleSourceKindConfigMap
+		}
+		logDebug(log, req, "RuleSet", "Processing rule source", "index", i, "kind", kind, "sourceName", rule.Name)
+		if kind == wafv1alpha1.RuleSourceKindSecret {
+			if !r.secretSources {
+				patch := client.MergeFrom(ruleset.DeepCopy())
+				msg := fmt.Sprintf("Secret %s can't be used as a rule source, Secret rule sources are disabled as the cache server doesn't require an auth token", rule.Name)
+				r.Recorder.Eventf(&ruleset, nil, "Warning", wafv1alpha1.ReasonSecretSourcesDisabled, "Reconcile", msg)
+				setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, wafv1alpha1.ReasonSecretSourcesDisabled, msg)
+				if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
+					logError(log, req, "RuleSet", updateErr, "Failed to patch status")
+				}
+
+				return ctrl.Result{}, nil
+			}
+			hasSecretSources = true
+		}
+		sourceNamespace := ruleset.Namespace
+		if kind == wafv1alpha1.RuleSourceKindConfigMap && rule.Namespace != "" {
+			sourceNamespace = rule.Namespace
+		}
+		if sourceNamespace != ruleset.Namespace {
+			logDebug(log, req, "RuleSet", "Checking ReferenceGrants for cross-namespace rule source", "kind", kind, "sourceName", rule.Name, "sourceNamespace", sourceNamespace)
+			permitted, err := r.configMapReferenceGranted(ctx, &ruleset, sourceNamespace, rule.Name)
+			if err != nil {
+				logError(log, req, "RuleSet", err, "Failed to list ReferenceGrants", "sourceNamespace", sourceNamespace)
+				return ctrl.Result{}, err
+			}
+			if !permitted {
+				logInfo(log, req, "RuleSet", "Cross-namespace rule source not permitted", "kind", kind, "sourceName", rule.Name, "sourceNamespace", sourceNamespace)
+				// Unlike other failures, the last version cached isn't kept
+				// in place: a revoked grant must stop its rules being served.
+				if cacheKey := cache.KeyFor(ruleset.Namespace, ruleset.Name); r.Cache.Delete(cacheKey) {
+					logInfo(log, req, "RuleSet", "Evicted rules of revoked cross-namespace reference from cache", "cacheKey", cacheKey)
+				}
+				patch := client.MergeFrom(ruleset.DeepCopy())
+				msg := fmt.Sprintf("Reference to %s %s/%s is not permitted by any ReferenceGrant in namespace %s", kind, sourceNamespace, rule.Name, sourceNamespace)
+				r.Recorder.Eventf(&ruleset, nil, "Warning", wafv1alpha1.ReasonRefNotPermitted, "Reconcile", msg)
+				setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, wafv1alpha1.ReasonRefNotPermitted, msg)
+				if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
+					logError(log, req, "RuleSet", updateErr, "Failed to patch status")
+				}
+
+				return ctrl.Result{}, nil
+			}
+		}
+
+		logDebug(log, req, "RuleSet", "Fetching rule source", "kind", kind, "sourceName", rule.Name, "sourceNamespace", sourceNamespace)
+		source, data, found, err := r.getRuleSource(ctx, kind, types.NamespacedName{
 			Name:      rule.Name,
-			Namespace: ruleset.Namespace,
-		}, &cm); err != nil {
+			Namespace: sourceNamespace,
+		})
+		if err != nil {
 			if errors.IsNotFound(err) {
-				logInfo(log, req, "RuleSet", "ConfigMap not found", "configMapName", rule.Name)
+				logInfo(log, req, "RuleSet", "Rule source not found", "kind", kind, "sourceName", rule.Name)
 				patch := client.MergeFrom(ruleset.DeepCopy())
-				msg := fmt.Sprintf("Referenced ConfigMap %s does not exist", rule.Name)
-				r.Recorder.Eventf(&ruleset, nil, "Warning", "ConfigMapNotFound", "Reconcile", msg)
-				setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, "ConfigMapNotFound", msg)
+				msg := fmt.Sprintf("Referenced %s %s does not exist", kind, rule.Name)
+				reason := reasonsForRuleSource(kind).notFound
+				r.Recorder.Eventf(&ruleset, nil, "Warning", reason, "Reconcile", msg)
+				setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, reason, msg)
+				ruleset.Status.ObservedFailures++
+				ruleset.Status.LastError = msg
 				if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
 					logError(log, req, "RuleSet", updateErr, "Failed to patch status")
 				}
 
-				return ctrl.Result{Requeue: true}, nil
+				// The source is watched, so it's picked up as soon as it's
+				// created; retries only back off to surface a chronically
+				// missing source without hot-looping.
+				delay := missingSourceBackoff(ruleset.Status.ObservedFailures)
+				logDebug(log, req, "RuleSet", "Retrying after backoff", "failures", ruleset.Status.ObservedFailures, "delay", delay)
+				return ctrl.Result{RequeueAfter: delay}, nil
 			}
-			logError(log, req, "RuleSet", err, "Failed to get ConfigMap", "configMapName", rule.Name)
+			logError(log, req, "RuleSet", err, "Failed to get rule source", "kind", kind, "sourceName", rule.Name)
 
 			patch := client.MergeFrom(ruleset.DeepCopy())
-			msg := fmt.Sprintf("Failed to access ConfigMap %s: %v", rule.Name, err)
-			r.Recorder.Eventf(&ruleset, nil, "Warning", "ConfigMapAccessError", "Reconcile", msg)
-			setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, "ConfigMapAccessError", msg)
+			msg := fmt.Sprintf("Failed to access %s %s: %v", kind, rule.Name, err)
+			reason := reasonsForRuleSource(kind).accessError
+			r.Recorder.Eventf(&ruleset, nil, "Warning", reason, "Reconcile", msg)
+			setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, reason, msg)
 			if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
 				logError(log, req, "RuleSet", updateErr, "Failed to patch status")
 			}
@@ -139,15 +401,15 @@ func (r *RuleSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			return ctrl.Result{}, err
 		}
 
-		data, ok := cm.Data["rules"]
-		if !ok {
-			err := fmt.Errorf("ConfigMap %s missing 'rules' key", rule.Name)
-			logError(log, req, "RuleSet", err, "ConfigMap missing 'rules' key", "configMapName", rule.Name)
+		if !found {
+			err := fmt.Errorf("%s %s missing 'rules' key", kind, rule.Name)
+			logError(log, req, "RuleSet", err, "Rule source missing 'rules' key", "kind", kind, "sourceName", rule.Name)
 
 			patch := client.MergeFrom(ruleset.DeepCopy())
-			msg := fmt.Sprintf("ConfigMap %s is missing required 'rules' key", rule.Name)
-			r.Recorder.Eventf(&ruleset, nil, "Warning", "InvalidConfigMap", "Reconcile", msg)
-			setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, "InvalidConfigMap", msg)
+			msg := fmt.Sprintf("%s %s is missing required 'rules' key", kind, rule.Name)
+			reason := reasonsForRuleSource(kind).invalid
+			r.Recorder.Eventf(&ruleset, nil, "Warning", reason, "Reconcile", msg)
+			setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, reason, msg)
 			if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
 				logError(log, req, "RuleSet", updateErr, "Failed to patch status")
 			}
@@ -155,13 +417,16 @@ func (r *RuleSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			return ctrl.Result{}, err
 		}
 
-		if cm.Annotations["coraza.io/validation"] != "false" {
-			conf := coraza.NewWAFConfig()
-			if _, err := coraza.NewWAF(conf.WithDirectives(data)); err != nil {
+		sourceValidationSkipped := source.GetAnnotations()["coraza.io/validation"] == "false"
+		if sourceValidationSkipped {
+			validationOptOut = true
+		} else {
+			if err := r.validateRuleSource(&ruleset, fmt.Sprintf("%s %s", kind, rule.Name), data, kind == wafv1alpha1.RuleSourceKindSecret); err != nil {
 				patch := client.MergeFrom(ruleset.DeepCopy())
-				msg := fmt.Sprintf("ConfigMap %s doesn't contain valid rules:\n%v", rule.Name, err)
-				r.Recorder.Eventf(&ruleset, nil, "Warning", "InvalidConfigMap", "Reconcile", msg)
-				setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, "InvalidConfigMap", msg)
+				msg := fmt.Sprintf("%s %s doesn't contain valid rules:\n%v", kind, rule.Name, err)
+				reason := invalidRulesReason(err, reasonsForRuleSource(kind).invalid)
+				r.Recorder.Eventf(&ruleset, nil, "Warning", reason, "Reconcile", msg)
+				setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, reason, msg)
 				if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
 					logError(log, req, "RuleSet", updateErr, "Failed to patch status")
 				}
@@ -170,25 +435,267 @@ func (r *RuleSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			}
 		}
 
-		aggregatedRules.WriteString(data)
-		if i < len(ruleset.Spec.Rules)-1 {
-			aggregatedRules.WriteString("\n")
+		sources = append(sources, rulesets.Source{Name: fmt.Sprintf("%s %s", kind, rule.Name), Rules: data})
+		resolvedSource := wafv1alpha1.ResolvedSource{
+			Name:              source.GetName(),
+			Kind:              kind,
+			ResourceVersion:   source.GetResourceVersion(),
+			Bytes:             int64(len(data)),
+			ValidationSkipped: sourceValidationSkipped,
 		}
+		if sourceNamespace != ruleset.Namespace {
+			resolvedSource.Namespace = sourceNamespace
+		}
+		if kind == wafv1alpha1.RuleSourceKindConfigMap {
+			resolvedSource.Labels = provenanceLabels(source.GetLabels(), r.provenanceLabelKeys)
+		}
+		resolved = append(resolved, resolvedSource)
 	}
 
-	logDebug(log, req, "RuleSet", "Storing aggregated rules in cache")
-	cacheKey := fmt.Sprintf("%s/%s", ruleset.Namespace, ruleset.Name)
-	r.Cache.Put(cacheKey, aggregatedRules.String())
-	logInfo(log, req, "RuleSet", "Stored rules in cache", "cacheKey", cacheKey)
+	if ruleset.Spec.InlineRules != "" {
+		logDebug(log, req, "RuleSet", "Processing inline rules")
+		if err := r.validateRuleSource(&ruleset, "RuleSet inlineRules", ruleset.Spec.InlineRules, false); err != nil {
+			patch := client.MergeFrom(ruleset.DeepCopy())
+			msg := fmt.Sprintf("RuleSet inlineRules don't contain valid rules:\n%v", err)
+			reason := invalidRulesReason(err, wafv1alpha1.ReasonInvalidRuleSource)
+			r.Recorder.Eventf(&ruleset, nil, "Warning", reason, "Reconcile", msg)
+			setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, reason, msg)
+			if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
+				logError(log, req, "RuleSet", updateErr, "Failed to patch status")
+			}
+
+			return ctrl.Result{}, err
+		}
+
+		sources = append(sources, rulesets.Source{Name: "RuleSet inlineRules", Rules: ruleset.Spec.InlineRules})
+	}
+
+	if len(ruleset.Spec.Plugins) > 0 {
+		logDebug(log, req, "RuleSet", "Rendering CRS plugin initialization", "pluginCount", len(ruleset.Spec.Plugins))
+		pluginInits := make([]rulesets.Source, 0, len(ruleset.Spec.Plugins))
+		for _, plugin := range ruleset.Spec.Plugins {
+			rendered, err := rulesets.RenderPluginInit(string(plugin.Name), plugin.Settings)
+			if err != nil {
+				patch := client.MergeFrom(ruleset.DeepCopy())
+				msg := fmt.Sprintf("CRS plugin %s can't be configured: %v", plugin.Name, err)
+				r.Recorder.Eventf(&ruleset, nil, "Warning", wafv1alpha1.ReasonInvalidPluginConfig, "Reconcile", msg)
+				setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, wafv1alpha1.ReasonInvalidPluginConfig, msg)
+				if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
+					logError(log, req, "RuleSet", updateErr, "Failed to patch status")
+				}
+
+				return ctrl.Result{}, err
+			}
+			pluginInits = append(pluginInits, rulesets.Source{Name: fmt.Sprintf("CRS plugin %s", plugin.Name), Rules: rendered})
+		}
+		sources = append(pluginInits, sources...)
+	}
+
+	cacheKey := cache.KeyFor(ruleset.Namespace, ruleset.Name)
+	if duplicates := rulesets.FindSourceDuplicateRuleIDs(sources); len(duplicates) > 0 {
+		dupErr := &rulesets.SourceDuplicateRuleIDsError{Duplicates: duplicates}
+		if ruleset.Spec.DuplicateRuleIDs != wafv1alpha1.DuplicateRuleIDPolicyKeepLast {
+			patch := client.MergeFrom(ruleset.DeepCopy())
+			msg := fmt.Sprintf("Rule sources for %s define the same rule ids:\n%v", cacheKey, dupErr)
+			r.Recorder.Eventf(&ruleset, nil, "Warning", wafv1alpha1.ReasonDuplicateRuleID, "Reconcile", msg)
+			setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, wafv1alpha1.ReasonDuplicateRuleID, msg)
+			if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
+				logError(log, req, "RuleSet", updateErr, "Failed to patch status")
+			}
+
+			return ctrl.Result{}, dupErr
+		}
+
+		logInfo(log, req, "RuleSet", "Keeping the last definition of duplicate rule ids", "count", len(duplicates))
+		r.Recorder.Eventf(&ruleset, nil, "Normal", wafv1alpha1.EventReasonDuplicateRuleIDsReplaced, "Reconcile",
+			"Rules for %s replaced by later sources:\n%v", cacheKey, dupErr)
+		sources = rulesets.KeepLastRuleIDs(sources)
+	}
+
+	rules := rulesets.JoinSources(sources, sourceSeparator(ruleset.Spec.SourceSeparator))
+	if r.maxRuleSetSize > 0 && len(rules) > r.maxRuleSetSize {
+		// The last version cached remains in place: an oversized latest
+		// version could never be pruned from the cache.
+		logInfo(log, req, "RuleSet", "Aggregated rules exceed the maximum size", "bytes", len(rules), "maxBytes", r.maxRuleSetSize)
+		patch := client.MergeFrom(ruleset.DeepCopy())
+		msg := fmt.Sprintf("Aggregated rules for %s are %d bytes, exceeding the limit of %d bytes", cacheKey, len(rules), r.maxRuleSetSize)
+		r.Recorder.Eventf(&ruleset, nil, "Warning", wafv1alpha1.ReasonRuleSetTooLarge, "Reconcile", msg)
+		setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, wafv1alpha1.ReasonRuleSetTooLarge, msg)
+		if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
+			logError(log, req, "RuleSet", updateErr, "Failed to patch status")
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	// Sources opted out of validation typically reference resources which
+	// only exist on the data plane (e.g. @pmFromFile), so the aggregate
+	// can't be compiled here either.
+	if r.aggregateValidation != nil && validationOptOut {
+		logDebug(log, req, "RuleSet", "Skipping aggregated rules validation, a source opted out of validation")
+	} else if r.aggregateValidation != nil {
+		logDebug(log, req, "RuleSet", "Validating aggregated rules")
+		if err := r.aggregateValidation.validate(req.NamespacedName, rules); err != nil {
+			if hasSecretSources {
+				err = rulesets.Redact(rules, err)
+			}
+			patch := client.MergeFrom(ruleset.DeepCopy())
+			msg := fmt.Sprintf("Aggregated rules for %s don't compile:\n%v", cacheKey, err)
+			reason := invalidRulesReason(err, wafv1alpha1.ReasonInvalidAggregatedRules)
+			r.Recorder.Eventf(&ruleset, nil, "Warning", reason, "Reconcile", msg)
+			setStatusConditionDegraded(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, reason, msg)
+			if updateErr := r.Status().Patch(ctx, &ruleset, patch); updateErr != nil {
+				logError(log, req, "RuleSet", updateErr, "Failed to patch status")
+			}
+
+			return ctrl.Result{}, err
+		}
+	}
 
-	patch := client.MergeFrom(ruleset.DeepCopy())
 	msg := fmt.Sprintf("Successfully cached rules for %s/%s", ruleset.Namespace, ruleset.Name)
-	r.Recorder.Eventf(&ruleset, nil, "Normal", "RulesCached", "Reconcile", msg)
-	setStatusReady(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, "RulesCached", msg)
+	if current, ok := r.Cache.Peek(cacheKey); ok && current.Rules == rules {
+		logDebug(log, req, "RuleSet", "Rules unchanged, skipping cache rotation", "cacheKey", cacheKey, "uuid", current.UUID)
+		r.Recorder.Eventf(&ruleset, nil, "Normal", wafv1alpha1.EventReasonRulesUnchanged, "Reconcile", "Rules for %s are unchanged (uuid: %s)", cacheKey, current.UUID)
+	} else {
+		logDebug(log, req, "RuleSet", "Storing aggregated rules in cache")
+		r.Cache.Put(cacheKey, rules)
+		entry, _ := r.Cache.Peek(cacheKey)
+		logInfo(log, req, "RuleSet", "Stored rules in cache", "cacheKey", cacheKey, "uuid", entry.UUID)
+		r.Recorder.Eventf(&ruleset, nil, "Normal", wafv1alpha1.EventReasonRulesCached, "Reconcile", "%s (uuid: %s, size: %d bytes)", msg, entry.UUID, len(entry.Rules))
+
+		// Only warn about the version when it's cached, rather than on every
+		// reconcile of the same rules.
+		if missing := rulesets.FindRulesWithoutAction(rules); len(missing) > 0 {
+			descriptions := make([]string, 0, len(missing))
+			for _, m := range missing {
+				descriptions = append(descriptions, m.String())
+			}
+			logInfo(log, req, "RuleSet", "Rules have no disruptive or flow action", "count", len(missing))
+			r.Recorder.Eventf(&ruleset, nil, "Warning", wafv1alpha1.EventReasonRuleMissingAction, "Reconcile",
+				"Rules for %s match without effect:\n%s", cacheKey, rulesets.JoinLimited(descriptions, "\n"))
+		}
+	}
+
+	patch := client.MergeFrom(ruleset.DeepCopy())
+	ruleCount := int32(rulesets.CountRules(rules))
+	ruleset.Status.RuleCount = &ruleCount
+	aggregatedBytes := int64(len(rules))
+	ruleset.Status.AggregatedBytes = &aggregatedBytes
+	ruleset.Status.ResolvedSources = resolved
+	ruleset.Status.ObservedFailures = 0
+	ruleset.Status.LastError = ""
+	setStatusReady(log, req, "RuleSet", &ruleset.Status.Conditions, ruleset.Generation, wafv1alpha1.EventReasonRulesCached, msg)
 	if err := r.Status().Patch(ctx, &ruleset, patch); err != nil {
 		logError(log, req, "RuleSet", err, "Failed to patch status")
 		return ctrl.Result{}, err
 	}
+	r.refreshEngineAggregates(ctx, log, req, &ruleset)
+
+	// URL sources aren't watched, so requeue to pick up remote changes.
+	if hasURLSources {
+		return ctrl.Result{RequeueAfter: r.urlSources.refreshInterval}, nil
+	}
 
 	return ctrl.Result{}, nil
 }
+
+// getRuleSource fetches the ConfigMap or Secret rule source with the given
+// name, returning it along with the contents of its "rules" key. found is
+// false if the source has no "rules" key. Secrets are read from the API
+// server, as only their metadata is cached.
+func (r *RuleSetReconciler) getRuleSource(ctx context.Context, kind wafv1alpha1.RuleSourceKind, key types.NamespacedName) (source client.Object, data string, found bool, err error) {
+	switch kind {
+	case wafv1alpha1.RuleSourceKindSecret:
+		reader := r.apiReader
+		if reader == nil {
+			reader = r.Client
+		}
+		var secret corev1.Secret
+		if err := reader.Get(ctx, key, &secret); err != nil {
+			return nil, "", false, err
+		}
+		rules, ok := secret.Data["rules"]
+		return &secret, string(rules), ok, nil
+	default:
+		var cm corev1.ConfigMap
+		if err := r.Get(ctx, key, &cm); err != nil {
+			return nil, "", false, err
+		}
+		rules, ok := cm.Data["rules"]
+		return &cm, rules, ok, nil
+	}
+}
+
+// finalize evicts a deleted RuleSet's rules from the cache and then removes
+// its finalizer, allowing the deletion to complete. Evicting a key which was
+// never cached is a no-op.
+func (r *RuleSetReconciler) finalize(ctx context.Context, log logr.Logger, req ctrl.Request, ruleset *wafv1alpha1.RuleSet) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(ruleset, ruleSetFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	cacheKey := cache.KeyFor(ruleset.Namespace, ruleset.Name)
+	if r.Cache.Delete(cacheKey) {
+		logInfo(log, req, "RuleSet", "Evicted rules of deleted RuleSet from cache", "cacheKey", cacheKey)
+	}
+	r.aggregateValidation.forget(req.NamespacedName)
+
+	logDebug(log, req, "RuleSet", "Removing finalizer")
+	patch := client.MergeFromWithOptions(ruleset.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	controllerutil.RemoveFinalizer(ruleset, ruleSetFinalizer)
+	if err := r.Patch(ctx, ruleset, patch); err != nil {
+		logError(log, req, "RuleSet", err, "Failed to remove finalizer")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// provenanceLabels returns the subset of labels whose keys are in keys, or
+// nil if there are none.
+func provenanceLabels(labels map[string]string, keys []string) map[string]string {
+	var selected map[string]string
+	for _, key := range keys {
+		value, ok := labels[key]
+		if !ok {
+			continue
+		}
+		if selected == nil {
+			selected = make(map[string]string, len(keys))
+		}
+		selected[key] = value
+	}
+	return selected
+}
+
+// inlineSourceName returns a human readable identifier for an inline rule
+// source, using its name if one was provided.
+func inlineSourceName(index int, rule wafv1alpha1.RuleSourceReference) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// sourceSeparator returns the string separating consecutive rule sources for
+// the given separator policy, defaulting to a newline.
+func sourceSeparator(separator wafv1alpha1.SourceSeparator) string {
+	if separator == wafv1alpha1.SourceSeparatorBlankLine {
+		return "\n\n"
+	}
+	return "\n"
+}
+
+// ruleSourceOrder returns the indices of the given rule sources in the order
+// they are aggregated: by ascending priority, and in listed order for sources
+// with the same priority.
+func ruleSourceOrder(rules []wafv1alpha1.RuleSourceReference) []int {
+	order := make([]int, len(rules))
+	for i := range rules {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(rules[a].Priority, rules[b].Priority)
+	})
+	return order
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// RuleSet Controller - Engine Aggregates
+// -----------------------------------------------------------------------------
+
+// refreshEngineAggregates re-aggregates the rules of the Engines with
+// multiple RuleSets which reference the RuleSet, so that they pick up its
+// newly cached rules. Engines are found with the field index registered by
+// the Engine controller. Failures, including invalid aggregates, are logged,
+// as they don't affect the RuleSet: the Engine controller Degrades Engines
+// whose aggregate is invalid when it next reconciles them.
+func (r *RuleSetReconciler) refreshEngineAggregates(ctx context.Context, log logr.Logger, req ctrl.Request, ruleset *wafv1alpha1.RuleSet) {
+	var engines wafv1alpha1.EngineList
+	if err := r.List(ctx, &engines,
+		client.InNamespace(ruleset.Namespace),
+		client.MatchingFields{engineRuleSetIndexKey: ruleset.Name},
+	); err != nil {
+		logError(log, req, "RuleSet", err, "Failed to list Engines to refresh aggregated rules")
+		return
+	}
+
+	for i := range engines.Items {
+		engine := &engines.Items[i]
+		if len(engineRuleSetNames(engine)) < 2 {
+			continue
+		}
+
+		ruleSets, err := engineRuleSets(ctx, r.Client, engine, ruleset)
+		if err != nil {
+			logError(log, req, "RuleSet", err, "Failed to get RuleSets to refresh aggregated rules", "engine", engine.Name)
+			continue
+		}
+
+		cached, err := aggregateEngineRules(r.Cache, engine, engineAggregateValidation(r.aggregateValidation, engine, ruleSets))
+		if err != nil {
+			logInfo(log, req, "RuleSet", "Aggregated rules of Engine are invalid, keeping the last valid version", "engine", engine.Name, "error", err.Error())
+			continue
+		}
+		if cached {
+			logInfo(log, req, "RuleSet", "Stored aggregated rules of Engine in cache", "engine", engine.Name, "cacheKey", engineCacheKey(engine))
+		}
+	}
+}
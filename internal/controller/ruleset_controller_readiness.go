@@ -0,0 +1,130 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets/cache"
+)
+
+// -----------------------------------------------------------------------------
+// RuleSet Controller - Initial Reconciliation Readiness
+// -----------------------------------------------------------------------------
+
+// InitialReconcileTimeout is the maximum time to wait for all RuleSets that
+// existed at startup to be reconciled before marking the cache ready anyway.
+const InitialReconcileTimeout = 2 * time.Minute
+
+// initialReconcilePollInterval is how often the readiness runnable checks
+// whether all initial RuleSets have been reconciled.
+const initialReconcilePollInterval = 500 * time.Millisecond
+
+// reconciledSet tracks which RuleSets have been reconciled at least once,
+// regardless of the outcome.
+type reconciledSet struct {
+	mu   sync.Mutex
+	keys map[types.NamespacedName]struct{}
+}
+
+func newReconciledSet() *reconciledSet {
+	return &reconciledSet{keys: make(map[types.NamespacedName]struct{})}
+}
+
+// mark records that the RuleSet has been reconciled. It is safe to call on a
+// nil reconciledSet.
+func (s *reconciledSet) mark(key types.NamespacedName) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key] = struct{}{}
+}
+
+// has reports whether the RuleSet has been reconciled.
+func (s *reconciledSet) has(key types.NamespacedName) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.keys[key]
+	return ok
+}
+
+// initialReconcileGate is a manager Runnable which marks the cache readiness
+// gate ready once every RuleSet that existed when it started has been
+// reconciled at least once, or once InitialReconcileTimeout has elapsed. Like
+// the controllers, it only runs on the elected leader.
+type initialReconcileGate struct {
+	client     client.Reader
+	gate       *cache.ReadinessGate
+	reconciled *reconciledSet
+	timeout    time.Duration
+	logger     logr.Logger
+}
+
+// Start implements manager.Runnable.
+func (g *initialReconcileGate) Start(ctx context.Context) error {
+	var ruleSets wafv1alpha1.RuleSetList
+	if err := g.client.List(ctx, &ruleSets); err != nil {
+		g.logger.Error(err, "Failed to list RuleSets for initial reconciliation, marking cache ready")
+		g.gate.SetReady()
+		return nil
+	}
+
+	pending := make([]types.NamespacedName, 0, len(ruleSets.Items))
+	for _, ruleSet := range ruleSets.Items {
+		pending = append(pending, types.NamespacedName{Namespace: ruleSet.Namespace, Name: ruleSet.Name})
+	}
+	g.logger.Info("Waiting for initial RuleSet reconciliation", "count", len(pending))
+
+	deadline := time.After(g.timeout)
+	ticker := time.NewTicker(initialReconcilePollInterval)
+	defer ticker.Stop()
+
+	for {
+		remaining := pending[:0]
+		for _, key := range pending {
+			if !g.reconciled.has(key) {
+				remaining = append(remaining, key)
+			}
+		}
+		pending = remaining
+
+		if len(pending) == 0 {
+			g.logger.Info("Initial RuleSet reconciliation complete, cache is ready")
+			g.gate.SetReady()
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline:
+			g.logger.Info("Timed out waiting for initial RuleSet reconciliation, marking cache ready", "pending", len(pending))
+			g.gate.SetReady()
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
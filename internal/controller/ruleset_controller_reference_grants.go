@@ -0,0 +1,171 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// RuleSet Controller - ReferenceGrant RBAC
+// -----------------------------------------------------------------------------
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=referencegrants,verbs=get;list;watch
+
+// -----------------------------------------------------------------------------
+// RuleSet Controller - ReferenceGrants
+// -----------------------------------------------------------------------------
+
+// referenceGrantGVK is the GroupVersionKind of Gateway API's ReferenceGrant.
+var referenceGrantGVK = schema.GroupVersionKind{
+	Group:   "gateway.networking.k8s.io",
+	Version: "v1beta1",
+	Kind:    "ReferenceGrant",
+}
+
+// referenceGrantListGVK is the GroupVersionKind of Gateway API's
+// ReferenceGrantList.
+var referenceGrantListGVK = referenceGrantGVK.GroupVersion().WithKind("ReferenceGrantList")
+
+// configMapReferenceGranted reports whether a ReferenceGrant in namespace
+// permits RuleSets in the RuleSet's namespace to reference the named
+// ConfigMap. If the ReferenceGrant CRD isn't installed no reference is
+// permitted.
+func (r *RuleSetReconciler) configMapReferenceGranted(ctx context.Context, ruleset *wafv1alpha1.RuleSet, namespace, name string) (bool, error) {
+	grants := &unstructured.UnstructuredList{}
+	grants.SetGroupVersionKind(referenceGrantListGVK)
+	if err := r.List(ctx, grants, client.InNamespace(namespace)); err != nil {
+		if apimeta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for i := range grants.Items {
+		if referenceGrantPermits(&grants.Items[i], ruleset.Namespace, name) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// referenceGrantPermits reports whether the ReferenceGrant permits RuleSets in
+// fromNamespace to reference the named ConfigMap in the grant's namespace. A
+// "to" entry without a name permits every ConfigMap.
+func referenceGrantPermits(grant *unstructured.Unstructured, fromNamespace, name string) bool {
+	if !slices.Contains(referenceGrantRuleSetNamespaces(grant), fromNamespace) {
+		return false
+	}
+
+	to, _, _ := unstructured.NestedSlice(grant.Object, "spec", "to")
+	for _, entry := range to {
+		ref, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		group, _ := ref["group"].(string)
+		toName, _ := ref["name"].(string)
+		if group == "" && ref["kind"] == "ConfigMap" && (toName == "" || toName == name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// referenceGrantRuleSetNamespaces returns the namespaces of the RuleSets the
+// ReferenceGrant permits references from.
+func referenceGrantRuleSetNamespaces(grant *unstructured.Unstructured) []string {
+	from, _, _ := unstructured.NestedSlice(grant.Object, "spec", "from")
+	var namespaces []string
+	for _, entry := range from {
+		ref, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		namespace, _ := ref["namespace"].(string)
+		if ref["group"] == wafv1alpha1.GroupVersion.Group && ref["kind"] == "RuleSet" && namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	return namespaces
+}
+
+// -----------------------------------------------------------------------------
+// RuleSet Controller - ReferenceGrant Watch
+// -----------------------------------------------------------------------------
+
+// referenceGrantWatchAvailable reports whether the ReferenceGrant CRD is
+// installed, so that ReferenceGrants can be watched. Without it, no
+// cross-namespace reference is permitted anyway.
+func referenceGrantWatchAvailable(mapper apimeta.RESTMapper) bool {
+	_, err := mapper.RESTMapping(referenceGrantGVK.GroupKind(), referenceGrantGVK.Version)
+	return err == nil
+}
+
+// findRuleSetsForReferenceGrant maps a ReferenceGrant to the RuleSets in the
+// namespaces it permits references from which reference ConfigMaps in the
+// grant's namespace, so that they're re-evaluated when a grant is created,
+// changed or revoked.
+func (r *RuleSetReconciler) findRuleSetsForReferenceGrant(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := logf.FromContext(ctx)
+
+	grant, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, fromNamespace := range referenceGrantRuleSetNamespaces(grant) {
+		var ruleSetList wafv1alpha1.RuleSetList
+		if err := r.List(ctx, &ruleSetList,
+			client.InNamespace(fromNamespace),
+			client.MatchingFields{ruleSetCrossNamespaceSourceNamespaceIndexKey: grant.GetNamespace()},
+		); err != nil {
+			log.Error(err, "RuleSet: Failed to list RuleSets for ReferenceGrant", "referenceGrant", client.ObjectKeyFromObject(grant).String())
+			continue
+		}
+
+		for _, ruleSet := range ruleSetList.Items {
+			req := ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      ruleSet.Name,
+					Namespace: ruleSet.Namespace,
+				},
+			}
+			requests = append(requests, req)
+
+			logInfo(log, req, "RuleSet", "Enqueuing for reconciliation due to ReferenceGrant change", "referenceGrant", grant.GetName(), "referenceGrantNamespace", grant.GetNamespace())
+		}
+	}
+
+	return requests
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets/cache"
+)
+
+// -----------------------------------------------------------------------------
+// RuleSet Controller - Restored Cache Entries
+// -----------------------------------------------------------------------------
+
+// restoredEntriesPruner is a manager Runnable which drops the instances
+// restored from a cache snapshot whose RuleSet (or Engine, for aggregates) was
+// deleted while the operator was down, as no reconciliation would otherwise
+// ever remove them. The manager starts it once the informers have synced.
+// Like the controllers, it only runs on the elected leader.
+type restoredEntriesPruner struct {
+	client client.Reader
+	cache  *cache.RuleSetCache
+	logger logr.Logger
+}
+
+// Start implements manager.Runnable.
+func (p *restoredEntriesPruner) Start(ctx context.Context) error {
+	var ruleSets wafv1alpha1.RuleSetList
+	if err := p.client.List(ctx, &ruleSets); err != nil {
+		p.logger.Error(err, "Failed to list RuleSets, keeping restored cache entries")
+		return nil
+	}
+	var engines wafv1alpha1.EngineList
+	if err := p.client.List(ctx, &engines); err != nil {
+		p.logger.Error(err, "Failed to list Engines, keeping restored cache entries")
+		return nil
+	}
+
+	wanted := make(map[string]bool, len(ruleSets.Items)+len(engines.Items))
+	for _, ruleSet := range ruleSets.Items {
+		wanted[cache.KeyFor(ruleSet.Namespace, ruleSet.Name)] = true
+	}
+	for i := range engines.Items {
+		wanted[engineCacheKey(&engines.Items[i])] = true
+	}
+
+	pruned := p.cache.PruneRestored(func(instance string) bool {
+		return wanted[instance]
+	})
+	if pruned > 0 {
+		p.logger.Info("Pruned restored cache entries without a RuleSet", "count", pruned)
+	}
+
+	return nil
+}
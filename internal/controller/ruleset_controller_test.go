@@ -18,16 +18,36 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets"
 	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets/cache"
 	"github.com/networking-incubator/coraza-kubernetes-operator/test/utils"
 )
@@ -59,69 +79,1996 @@ func TestRuleSetReconciler_ReconcileNotFound(t *testing.T) {
 	assert.False(t, result.Requeue)
 }
 
+func TestRuleSetReconciler_Finalizer(t *testing.T) {
+	ctx := context.Background()
+	ruleSetCache := cache.NewRuleSetCache()
+
+	t.Log("Serving the cache")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	server := cache.NewServer(ruleSetCache, addr, utils.NewTestLogger(t), nil)
+	go func() {
+		if err := server.Start(t.Context()); err != nil {
+			t.Logf("Cache server stopped: %v", err)
+		}
+	}()
+
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: utils.NewTestRecorder(),
+		Cache:    ruleSetCache,
+	}
+
+	tests := []struct {
+		name   string
+		rules  []wafv1alpha1.RuleSourceReference
+		cached bool
+	}{
+		{
+			name:   "cached-ruleset",
+			rules:  []wafv1alpha1.RuleSourceReference{{Kind: wafv1alpha1.RuleSourceKindInline, Rules: "SecCollectionTimeout 1"}},
+			cached: true,
+		},
+		{
+			name:  "never-cached-ruleset",
+			rules: []wafv1alpha1.RuleSourceReference{{Name: "finalizer-missing-rules"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Log("Creating and reconciling a RuleSet")
+			ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+				Name:      tt.name,
+				Namespace: testNamespace,
+				Rules:     tt.rules,
+			})
+			require.NoError(t, k8sClient.Create(ctx, ruleSet))
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}}
+			_, err := reconciler.Reconcile(ctx, req)
+			require.NoError(t, err)
+
+			var updated wafv1alpha1.RuleSet
+			require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+			assert.Contains(t, updated.Finalizers, ruleSetFinalizer)
+			rulesURL := fmt.Sprintf("http://%s/rules/%s", addr, req.String())
+			if tt.cached {
+				t.Log("Verifying the cache server is serving the rules")
+				require.Eventually(t, func() bool {
+					resp, err := http.Get(rulesURL)
+					if err != nil {
+						return false
+					}
+					defer func() { _ = resp.Body.Close() }()
+					return resp.StatusCode == http.StatusOK
+				}, 5*time.Second, 100*time.Millisecond)
+			}
+
+			t.Log("Deleting the RuleSet and reconciling the deletion")
+			require.NoError(t, k8sClient.Delete(ctx, ruleSet))
+			_, err = reconciler.Reconcile(ctx, req)
+			require.NoError(t, err)
+
+			t.Log("Verifying the rules were evicted and the RuleSet is gone")
+			_, ok := ruleSetCache.Get(req.String())
+			assert.False(t, ok, "Cache entry should be evicted")
+			resp, err := http.Get(rulesURL)
+			require.NoError(t, err)
+			defer func() { _ = resp.Body.Close() }()
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+			err = k8sClient.Get(ctx, req.NamespacedName, &updated)
+			assert.True(t, errors.IsNotFound(err), "RuleSet should be deleted, got: %v", err)
+		})
+	}
+}
+
 func TestRuleSetReconciler_ReconcileConfigMaps(t *testing.T) {
+	tests := []struct {
+		name            string
+		ruleSetName     string
+		configMaps      map[string]string
+		sourceSeparator wafv1alpha1.SourceSeparator
+		expectedRules   string
+	}{
+		{
+			name:        "single ConfigMap",
+			ruleSetName: "single-cm-ruleset",
+			configMaps: map[string]string{
+				"test-rules": "SecRule REQUEST_URI \"@contains /admin\" \"id:1,deny\"",
+			},
+			expectedRules: "SecRule REQUEST_URI \"@contains /admin\" \"id:1,deny\"",
+		},
+		{
+			name:        "multiple ConfigMaps",
+			ruleSetName: "multi-cm-ruleset",
+			configMaps: map[string]string{
+				"rules-1": "SecCollectionTimeout 1",
+				"rules-2": "SecCollectionTimeout 2",
+				"rules-3": "SecCollectionTimeout 3",
+			},
+			expectedRules: "SecCollectionTimeout 1\nSecCollectionTimeout 2\nSecCollectionTimeout 3",
+		},
+		{
+			name:        "ConfigMaps lacking trailing newlines",
+			ruleSetName: "no-newline-cm-ruleset",
+			configMaps: map[string]string{
+				"rules-a": `SecRule ARGS "@contains a" "id:1,phase:1,deny"`,
+				"rules-b": `SecRule ARGS "@contains b" "id:2,phase:1,deny"`,
+			},
+			expectedRules: `SecRule ARGS "@contains a" "id:1,phase:1,deny"` + "\n" +
+				`SecRule ARGS "@contains b" "id:2,phase:1,deny"`,
+		},
+		{
+			name:        "ConfigMaps separated by a blank line",
+			ruleSetName: "blank-line-cm-ruleset",
+			configMaps: map[string]string{
+				"rules-c": `SecRule ARGS "@contains c" "id:3,phase:1,deny"`,
+				"rules-d": `SecRule ARGS "@contains d" "id:4,phase:1,deny"` + "\n",
+			},
+			sourceSeparator: wafv1alpha1.SourceSeparatorBlankLine,
+			expectedRules: `SecRule ARGS "@contains c" "id:3,phase:1,deny"` + "\n\n" +
+				`SecRule ARGS "@contains d" "id:4,phase:1,deny"` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ruleSetCache := cache.NewRuleSetCache()
+
+			t.Logf("Creating %d ConfigMap(s)", len(tt.configMaps))
+			var refs []wafv1alpha1.RuleSourceReference
+			var names []string
+			for name := range tt.configMaps {
+				names = append(names, name)
+			}
+			sort.Strings(names) // need a consistent order for testing
+
+			t.Logf("Creating ConfigMaps: %v", names)
+			for _, name := range names {
+				data := tt.configMaps[name]
+				cm := utils.NewTestConfigMap(name, testNamespace, data)
+				require.NoError(t, k8sClient.Create(ctx, cm))
+
+				t.Cleanup(func() {
+					if err := k8sClient.Delete(ctx, cm); err != nil {
+						t.Logf("Failed to delete ConfigMap %s: %v", name, err)
+					}
+				})
+
+				refs = append(refs, wafv1alpha1.RuleSourceReference{Name: name})
+			}
+
+			t.Log("Creating RuleSet referencing ConfigMaps")
+			ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+				Name:      tt.ruleSetName,
+				Namespace: testNamespace,
+				Rules:     refs,
+			})
+			ruleSet.Spec.SourceSeparator = tt.sourceSeparator
+
+			t.Log("Creating RuleSet in Kubernetes")
+			require.NoError(t, k8sClient.Create(ctx, ruleSet))
+			t.Cleanup(func() {
+				if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+					t.Logf("Failed to delete RuleSet: %v", err)
+				}
+			})
+
+			t.Logf("Reconciling RuleSet %s", tt.ruleSetName)
+			recorder := utils.NewFakeRecorder()
+			reconciler := &RuleSetReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: recorder,
+				Cache:    ruleSetCache,
+			}
+			result, err := reconciler.Reconcile(ctx, ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      ruleSet.Name,
+					Namespace: ruleSet.Namespace,
+				},
+			})
+
+			t.Log("Verifying cache was populated with combined rules")
+			require.NoError(t, err)
+			assert.False(t, result.Requeue)
+			cacheKey := testNamespace + "/" + tt.ruleSetName
+			entry, ok := ruleSetCache.Get(cacheKey)
+			require.True(t, ok, "Cache entry should exist")
+			assert.Equal(t, tt.expectedRules, entry.Rules)
+			assert.NoError(t, rulesets.Validate(entry.Rules), "sources should not run together")
+			assert.NotEmpty(t, entry.UUID)
+
+			assert.True(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonRulesCached),
+				"expected Normal/RulesCached event; got: %v", recorder.Events)
+			for _, e := range recorder.Events {
+				if e.Reason == wafv1alpha1.EventReasonRulesCached {
+					assert.Contains(t, e.Note, entry.UUID, "RulesCached event should include the cache UUID")
+				}
+			}
+
+			t.Log("Reconciling again without changes - cache version should not rotate")
+			_, err = reconciler.Reconcile(ctx, ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      ruleSet.Name,
+					Namespace: ruleSet.Namespace,
+				},
+			})
+			require.NoError(t, err)
+			unchanged, ok := ruleSetCache.Get(cacheKey)
+			require.True(t, ok)
+			assert.Equal(t, entry.UUID, unchanged.UUID)
+			assert.True(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonRulesUnchanged),
+				"expected Normal/RulesUnchanged event; got: %v", recorder.Events)
+		})
+	}
+}
+
+func TestRuleSetReconciler_StatusRuleCountAndSize(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating ConfigMaps with a known number of rules")
+	rulesA := `SecRuleEngine On
+SecRule ARGS "@contains a" "id:401,phase:1,deny"`
+	rulesB := `SecAction "id:402,phase:1,pass,nolog"
+# SecRule ARGS "@contains c" "id:404,phase:1,deny"
+SecRule ARGS "@contains b" "id:403,phase:1,deny"`
+	for name, rules := range map[string]string{"status-rules-a": rulesA, "status-rules-b": rulesB} {
+		cm := utils.NewTestConfigMap(name, testNamespace, rules)
+		require.NoError(t, k8sClient.Create(ctx, cm))
+		t.Cleanup(func() {
+			if err := k8sClient.Delete(ctx, cm); err != nil {
+				t.Logf("Failed to delete ConfigMap: %v", err)
+			}
+		})
+	}
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "status-ruleset",
+		Namespace: testNamespace,
+		Rules:     []wafv1alpha1.RuleSourceReference{{Name: "status-rules-a"}, {Name: "status-rules-b"}},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+
+	t.Log("Reconciling RuleSet")
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: utils.NewTestRecorder(),
+		Cache:    cache.NewRuleSetCache(),
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}}
+	_, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+
+	t.Log("Verifying the status reports the rule count and aggregated size")
+	var updated wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	require.NotNil(t, updated.Status.RuleCount)
+	assert.Equal(t, int32(3), *updated.Status.RuleCount)
+	require.NotNil(t, updated.Status.AggregatedBytes)
+	assert.Equal(t, int64(len(rulesA)+len("\n")+len(rulesB)), *updated.Status.AggregatedBytes)
+}
+
+func TestRuleSetReconciler_InlineSources(t *testing.T) {
+	ctx := context.Background()
+	ruleSetCache := cache.NewRuleSetCache()
+
+	t.Log("Creating ConfigMap for mixed inline and ConfigMap sources")
+	cm := utils.NewTestConfigMap("inline-mixed-rules", testNamespace, "SecCollectionTimeout 2")
+	require.NoError(t, k8sClient.Create(ctx, cm))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, cm); err != nil {
+			t.Logf("Failed to delete ConfigMap: %v", err)
+		}
+	})
+
+	t.Log("Creating RuleSet mixing inline and ConfigMap sources")
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "inline-mixed-ruleset",
+		Namespace: testNamespace,
+		Rules: []wafv1alpha1.RuleSourceReference{
+			{Kind: wafv1alpha1.RuleSourceKindInline, Rules: "SecCollectionTimeout 1"},
+			{Name: "inline-mixed-rules"},
+			{Kind: wafv1alpha1.RuleSourceKindInline, Name: "overlay", Rules: "SecCollectionTimeout 3"},
+		},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+
+	t.Log("Reconciling RuleSet")
+	recorder := utils.NewFakeRecorder()
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Cache:    ruleSetCache,
+	}
+	result, err := reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      ruleSet.Name,
+			Namespace: ruleSet.Namespace,
+		},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.Requeue)
+
+	t.Log("Verifying sources were aggregated in order")
+	entry, ok := ruleSetCache.Get(testNamespace + "/inline-mixed-ruleset")
+	require.True(t, ok, "Cache entry should exist")
+	assert.Equal(t, "SecCollectionTimeout 1\nSecCollectionTimeout 2\nSecCollectionTimeout 3", entry.Rules)
+	assert.True(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonRulesCached),
+		"expected Normal/RulesCached event; got: %v", recorder.Events)
+}
+
+func TestRuleSetReconciler_URLSources(t *testing.T) {
+	ctx := context.Background()
+	ruleSetCache := cache.NewRuleSetCache()
+
+	t.Log("Starting HTTP server hosting rules")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rules.conf", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("SecCollectionTimeout 2"))
+	})
+	mux.HandleFunc("/broken.conf", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/invalid.conf", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("SecNotARealDirective top-secret"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name        string
+		urlSources  *URLRuleSourceConfig
+		url         string
+		wantErr     bool
+		eventType   string
+		eventReason string
+	}{
+		{
+			name:        "fetched and aggregated in order",
+			urlSources:  DefaultURLRuleSourceConfig(),
+			url:         server.URL + "/rules.conf",
+			eventType:   "Normal",
+			eventReason: wafv1alpha1.EventReasonRulesCached,
+		},
+		{
+			name:        "non-200 response",
+			urlSources:  DefaultURLRuleSourceConfig(),
+			url:         server.URL + "/broken.conf",
+			wantErr:     true,
+			eventType:   "Warning",
+			eventReason: wafv1alpha1.ReasonFetchFailed,
+		},
+		{
+			name:        "disabled",
+			url:         server.URL + "/rules.conf",
+			eventType:   "Warning",
+			eventReason: wafv1alpha1.ReasonURLSourcesDisabled,
+		},
+		{
+			name: "host not allowed",
+			urlSources: &URLRuleSourceConfig{
+				Timeout:         DefaultURLRuleSourceTimeout,
+				MaxSize:         DefaultURLRuleSourceMaxSize,
+				RefreshInterval: DefaultURLRuleSourceRefreshInterval,
+				AllowedHosts:    []string{"rules.example.com"},
+			},
+			url:         server.URL + "/rules.conf",
+			eventType:   "Warning",
+			eventReason: wafv1alpha1.ReasonURLHostNotAllowed,
+		},
+		{
+			name:        "invalid rules are redacted",
+			urlSources:  DefaultURLRuleSourceConfig(),
+			url:         server.URL + "/invalid.conf",
+			wantErr:     true,
+			eventType:   "Warning",
+			eventReason: wafv1alpha1.ReasonInvalidURLSource,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Log("Creating RuleSet mixing inline and URL sources")
+			ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+				Name:      fmt.Sprintf("url-ruleset-%d", i),
+				Namespace: testNamespace,
+				Rules: []wafv1alpha1.RuleSourceReference{
+					{Kind: wafv1alpha1.RuleSourceKindInline, Rules: "SecCollectionTimeout 1"},
+					{Kind: wafv1alpha1.RuleSourceKindURL, URL: tt.url},
+					{Kind: wafv1alpha1.RuleSourceKindInline, Rules: "SecCollectionTimeout 3"},
+				},
+			})
+			require.NoError(t, k8sClient.Create(ctx, ruleSet))
+			t.Cleanup(func() {
+				if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+					t.Logf("Failed to delete RuleSet: %v", err)
+				}
+			})
+
+			t.Log("Reconciling RuleSet")
+			recorder := utils.NewFakeRecorder()
+			reconciler := &RuleSetReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: recorder,
+				Cache:    ruleSetCache,
+			}
+			if tt.urlSources != nil {
+				reconciler.urlSources = newURLFetcher(tt.urlSources)
+			}
+			result, err := reconciler.Reconcile(ctx, ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace},
+			})
+			assert.True(t, recorder.HasEvent(tt.eventType, tt.eventReason),
+				"expected %s/%s event; got: %v", tt.eventType, tt.eventReason, recorder.Events)
+
+			cacheKey := testNamespace + "/" + ruleSet.Name
+			if tt.eventReason != wafv1alpha1.EventReasonRulesCached {
+				if tt.wantErr {
+					require.Error(t, err)
+				} else {
+					require.NoError(t, err)
+				}
+				_, ok := ruleSetCache.Get(cacheKey)
+				assert.False(t, ok, "Rules should not be cached")
+
+				var updated wafv1alpha1.RuleSet
+				require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}, &updated))
+				degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+				require.NotNil(t, degraded)
+				assert.Equal(t, tt.eventReason, degraded.Reason)
+				assert.NotContains(t, degraded.Message, "top-secret")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, DefaultURLRuleSourceRefreshInterval, result.RequeueAfter,
+				"RuleSets with URL sources should be requeued to pick up remote changes")
+			entry, ok := ruleSetCache.Get(cacheKey)
+			require.True(t, ok, "Cache entry should exist")
+			assert.Equal(t, "SecCollectionTimeout 1\nSecCollectionTimeout 2\nSecCollectionTimeout 3", entry.Rules)
+		})
+	}
+}
+
+func TestURLFetcher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Starting HTTP server counting requests")
+	var requests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rules.conf", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, "SecCollectionTimeout %d", requests.Add(1))
+	})
+	mux.HandleFunc("/slow.conf", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	t.Log("Verifying fetched rules are reused until the refresh interval elapses")
+	fetcher := newURLFetcher(&URLRuleSourceConfig{
+		Timeout:         DefaultURLRuleSourceTimeout,
+		MaxSize:         DefaultURLRuleSourceMaxSize,
+		RefreshInterval: 100 * time.Millisecond,
+	})
+	rules, err := fetcher.fetch(ctx, server.URL+"/rules.conf")
+	require.NoError(t, err)
+	assert.Equal(t, "SecCollectionTimeout 1", rules)
+	rules, err = fetcher.fetch(ctx, server.URL+"/rules.conf")
+	require.NoError(t, err)
+	assert.Equal(t, "SecCollectionTimeout 1", rules)
+	assert.Equal(t, int32(1), requests.Load())
+
+	time.Sleep(150 * time.Millisecond)
+	rules, err = fetcher.fetch(ctx, server.URL+"/rules.conf")
+	require.NoError(t, err)
+	assert.Equal(t, "SecCollectionTimeout 2", rules)
+
+	t.Log("Verifying responses over the max size are rejected")
+	fetcher = newURLFetcher(&URLRuleSourceConfig{
+		Timeout:         DefaultURLRuleSourceTimeout,
+		MaxSize:         4,
+		RefreshInterval: DefaultURLRuleSourceRefreshInterval,
+	})
+	_, err = fetcher.fetch(ctx, server.URL+"/rules.conf")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceed the max size")
+
+	t.Log("Verifying slow responses time out")
+	fetcher = newURLFetcher(&URLRuleSourceConfig{
+		Timeout:         50 * time.Millisecond,
+		MaxSize:         DefaultURLRuleSourceMaxSize,
+		RefreshInterval: DefaultURLRuleSourceRefreshInterval,
+	})
+	_, err = fetcher.fetch(ctx, server.URL+"/slow.conf")
+	require.Error(t, err)
+
+	t.Log("Verifying redirects to hosts which aren't allowed are refused")
+	other := httptest.NewServer(mux)
+	t.Cleanup(other.Close)
+	mux.HandleFunc("/redirect.conf", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(other.URL, "127.0.0.1", "localhost", 1)+"/rules.conf", http.StatusFound)
+	})
+	fetcher = newURLFetcher(&URLRuleSourceConfig{
+		Timeout:         DefaultURLRuleSourceTimeout,
+		MaxSize:         DefaultURLRuleSourceMaxSize,
+		RefreshInterval: DefaultURLRuleSourceRefreshInterval,
+		AllowedHosts:    []string{"127.0.0.1"},
+	})
+	assert.True(t, fetcher.urlHostAllowed(server.URL+"/rules.conf"))
+	assert.False(t, fetcher.urlHostAllowed("https://rules.example.com/rules.conf"))
+	_, err = fetcher.fetch(ctx, server.URL+"/redirect.conf")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "redirect to host localhost is not allowed")
+
+	t.Log("Verifying rules fetched longer than the refresh interval ago are dropped")
+	fetcher = newURLFetcher(&URLRuleSourceConfig{
+		Timeout:         DefaultURLRuleSourceTimeout,
+		MaxSize:         DefaultURLRuleSourceMaxSize,
+		RefreshInterval: 100 * time.Millisecond,
+	})
+	_, err = fetcher.fetch(ctx, server.URL+"/rules.conf")
+	require.NoError(t, err)
+	time.Sleep(150 * time.Millisecond)
+	_, err = fetcher.fetch(ctx, other.URL+"/rules.conf")
+	require.NoError(t, err)
+	assert.Len(t, fetcher.fetched, 1)
+	assert.Contains(t, fetcher.fetched, other.URL+"/rules.conf")
+}
+
+func TestCacheOverCapacityEvents(t *testing.T) {
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{Name: "over-capacity-ruleset", Namespace: testNamespace})
+	ruleSet.UID = "ruleset-uid"
+	engine := utils.NewTestEngine(utils.EngineOptions{Name: "over-capacity-engine", Namespace: testNamespace})
+	engine.UID = "engine-uid"
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ruleSet, engine).Build()
+
+	recorder := utils.NewFakeRecorder()
+	onOverCapacity := CacheOverCapacityEvents(c, recorder)
+
+	t.Log("Verifying a RuleSet instance emits the event on the fetched RuleSet")
+	onOverCapacity(cache.KeyFor(testNamespace, ruleSet.Name), 2048, 1024)
+	require.Len(t, recorder.Events, 1)
+	regarding, ok := recorder.Events[0].Object.(*wafv1alpha1.RuleSet)
+	require.True(t, ok, "expected a RuleSet, got %T", recorder.Events[0].Object)
+	assert.Equal(t, ruleSet.UID, regarding.UID)
+	assert.Equal(t, wafv1alpha1.EventReasonCacheOverCapacity, recorder.Events[0].Reason)
+
+	t.Log("Verifying an Engine aggregate instance emits the event on the fetched Engine")
+	onOverCapacity(engineAggregateCacheKey(testNamespace, engine.Name), 2048, 1024)
+	require.Len(t, recorder.Events, 2)
+	regardingEngine, ok := recorder.Events[1].Object.(*wafv1alpha1.Engine)
+	require.True(t, ok, "expected an Engine, got %T", recorder.Events[1].Object)
+	assert.Equal(t, engine.UID, regardingEngine.UID)
+
+	t.Log("Verifying instances whose resource is gone emit no event")
+	onOverCapacity(cache.KeyFor(testNamespace, "deleted"), 2048, 1024)
+	onOverCapacity("malformed", 2048, 1024)
+	assert.Len(t, recorder.Events, 2)
+}
+
+func TestRuleSetReconciler_RuleMissingAction(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		rules   string
+		warning bool
+	}{
+		{
+			name:    "missing-action",
+			rules:   `SecRule ARGS "@contains attack" "id:1,phase:1,log"`,
+			warning: true,
+		},
+		{
+			name:  "with-action",
+			rules: `SecRule ARGS "@contains attack" "id:1,phase:1,deny,status:403"`,
+		},
+		{
+			name: "many-missing-actions",
+			rules: func() string {
+				var rules strings.Builder
+				for id := 1; id <= 50; id++ {
+					fmt.Fprintf(&rules, "SecRule ARGS \"@contains attack\" \"id:%d,phase:1,log\"\n", id)
+				}
+				return rules.String()
+			}(),
+			warning: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+				Name:      "rule-" + tt.name,
+				Namespace: testNamespace,
+				Rules: []wafv1alpha1.RuleSourceReference{
+					{Kind: wafv1alpha1.RuleSourceKindInline, Rules: tt.rules},
+				},
+			})
+			require.NoError(t, k8sClient.Create(ctx, ruleSet))
+			t.Cleanup(func() {
+				if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+					t.Logf("Failed to delete RuleSet: %v", err)
+				}
+			})
+
+			recorder := utils.NewFakeRecorder()
+			reconciler := &RuleSetReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: recorder,
+				Cache:    cache.NewRuleSetCache(),
+			}
+			req := ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace},
+			}
+			_, err := reconciler.Reconcile(ctx, req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.warning, recorder.HasEvent("Warning", wafv1alpha1.EventReasonRuleMissingAction),
+				"unexpected Warning/RuleMissingAction state; got: %v", recorder.Events)
+			assert.True(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonRulesCached),
+				"expected Normal/RulesCached event; got: %v", recorder.Events)
+			for _, event := range recorder.Events {
+				if event.Reason == wafv1alpha1.EventReasonRuleMissingAction {
+					assert.Less(t, len(event.Note), 1024)
+				}
+			}
+
+			t.Log("Reconciling the unchanged rules - no warning is emitted again")
+			recorder.Events = nil
+			_, err = reconciler.Reconcile(ctx, req)
+			require.NoError(t, err)
+			assert.False(t, recorder.HasEvent("Warning", wafv1alpha1.EventReasonRuleMissingAction),
+				"unexpected Warning/RuleMissingAction event; got: %v", recorder.Events)
+		})
+	}
+}
+
+func TestRuleSetReconciler_ValidationWarnings(t *testing.T) {
+	ctx := context.Background()
+	const rules = `SecRule ARGS "@contains attack" "id:1,phase:1,deny,pass"`
+
+	tests := []struct {
+		name   string
+		strict bool
+	}{
+		{name: "lenient"},
+		{name: "strict", strict: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+				Name:      "warnings-" + tt.name,
+				Namespace: testNamespace,
+				Rules: []wafv1alpha1.RuleSourceReference{
+					{Kind: wafv1alpha1.RuleSourceKindInline, Name: "questionable", Rules: rules},
+				},
+			})
+			require.NoError(t, k8sClient.Create(ctx, ruleSet))
+			t.Cleanup(func() {
+				if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+					t.Logf("Failed to delete RuleSet: %v", err)
+				}
+			})
+
+			ruleSetCache := cache.NewRuleSetCache()
+			recorder := utils.NewFakeRecorder()
+			reconciler := &RuleSetReconciler{
+				Client:           k8sClient,
+				Scheme:           scheme,
+				Recorder:         recorder,
+				Cache:            ruleSetCache,
+				strictValidation: tt.strict,
+			}
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace},
+			})
+
+			var updated wafv1alpha1.RuleSet
+			require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}, &updated))
+			_, cached := ruleSetCache.Get(testNamespace + "/" + ruleSet.Name)
+			if tt.strict {
+				t.Log("Verifying warnings are promoted to errors")
+				require.Error(t, err)
+				assert.False(t, cached)
+				degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+				require.NotNil(t, degraded)
+				assert.Equal(t, wafv1alpha1.ReasonInvalidRuleSource, degraded.Reason)
+				assert.False(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonRuleValidationWarning),
+					"unexpected Normal/RuleValidationWarning event; got: %v", recorder.Events)
+				return
+			}
+
+			t.Log("Verifying warnings are reported without failing validation")
+			require.NoError(t, err)
+			assert.True(t, cached)
+			assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Ready"))
+			assert.True(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonRuleValidationWarning),
+				"expected Normal/RuleValidationWarning event; got: %v", recorder.Events)
+		})
+	}
+}
+
+func TestRuleSetReconciler_CRSPlugins(t *testing.T) {
+	ctx := context.Background()
+	ruleSetCache := cache.NewRuleSetCache()
+
+	t.Log("Creating RuleSet configuring a CRS plugin")
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "crs-plugins-ruleset",
+		Namespace: testNamespace,
+		Rules: []wafv1alpha1.RuleSourceReference{
+			{Kind: wafv1alpha1.RuleSourceKindInline, Rules: "SecCollectionTimeout 1"},
+		},
+	})
+	ruleSet.Spec.Plugins = []wafv1alpha1.CRSPlugin{{
+		Name:     wafv1alpha1.CRSPluginBodyDecompress,
+		Settings: map[string]string{"body-decompress-plugin_max_size": "1048576"},
+	}}
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+
+	t.Log("Reconciling RuleSet")
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: utils.NewTestRecorder(),
+		Cache:    ruleSetCache,
+	}
+	_, err := reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace},
+	})
+	require.NoError(t, err)
+
+	t.Log("Verifying the plugin initialization precedes the rule sources")
+	entry, ok := ruleSetCache.Get(testNamespace + "/crs-plugins-ruleset")
+	require.True(t, ok, "Cache entry should exist")
+	assert.Equal(t, `SecAction "id:9505010,phase:1,pass,nolog,setvar:'tx.body-decompress-plugin_enabled=1',`+
+		`setvar:'tx.body-decompress-plugin_max_size=1048576'"`+"\nSecCollectionTimeout 1", entry.Rules)
+}
+
+func TestRuleSetReconciler_ConfigMapIndex(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Building a client indexing RuleSets by referenced ConfigMap")
+	ruleSets := []client.Object{
+		utils.NewTestRuleSet(utils.RuleSetOptions{
+			Name:      "both",
+			Namespace: testNamespace,
+			Rules:     []wafv1alpha1.RuleSourceReference{{Name: "base"}, {Name: "extra"}, {Name: "base"}},
+		}),
+		utils.NewTestRuleSet(utils.RuleSetOptions{
+			Name:      "extra-only",
+			Namespace: testNamespace,
+			Rules:     []wafv1alpha1.RuleSourceReference{{Name: "extra"}},
+		}),
+		utils.NewTestRuleSet(utils.RuleSetOptions{
+			Name:      "inline",
+			Namespace: testNamespace,
+			Rules: []wafv1alpha1.RuleSourceReference{
+				{Kind: wafv1alpha1.RuleSourceKindInline, Name: "base", Rules: "SecRuleEngine On"},
+			},
+		}),
+		utils.NewTestRuleSet(utils.RuleSetOptions{
+			Name:      "other-namespace",
+			Namespace: "other",
+			Rules:     []wafv1alpha1.RuleSourceReference{{Name: "base"}},
+		}),
+		utils.NewTestRuleSet(utils.RuleSetOptions{
+			Name:      "secret",
+			Namespace: testNamespace,
+			Rules:     []wafv1alpha1.RuleSourceReference{{Kind: wafv1alpha1.RuleSourceKindSecret, Name: "base"}},
+		}),
+		utils.NewTestRuleSet(utils.RuleSetOptions{
+			Name:      "cross-namespace",
+			Namespace: "other",
+			Rules:     []wafv1alpha1.RuleSourceReference{{Name: "extra", Namespace: testNamespace}},
+		}),
+	}
+	reconciler := &RuleSetReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithIndex(&wafv1alpha1.RuleSet{}, ruleSetConfigMapIndexKey, indexRuleSetConfigMaps).
+			WithIndex(&wafv1alpha1.RuleSet{}, ruleSetCrossNamespaceConfigMapIndexKey, indexRuleSetCrossNamespaceConfigMaps).
+			WithIndex(&wafv1alpha1.RuleSet{}, ruleSetSecretIndexKey, indexRuleSetSecrets).
+			WithObjects(ruleSets...).
+			Build(),
+		Scheme: scheme,
+	}
+
+	t.Log("Verifying the indexer extracts distinct ConfigMap names only")
+	assert.Equal(t, []string{"base", "extra"}, indexRuleSetConfigMaps(ruleSets[0]))
+	assert.Empty(t, indexRuleSetConfigMaps(ruleSets[2]))
+	assert.Empty(t, indexRuleSetConfigMaps(ruleSets[4]))
+	assert.Equal(t, []string{"base"}, indexRuleSetSecrets(ruleSets[4]))
+	assert.Empty(t, indexRuleSetConfigMaps(ruleSets[5]))
+	assert.Equal(t, []string{testNamespace + "/extra"}, indexRuleSetCrossNamespaceConfigMaps(ruleSets[5]))
+	assert.Equal(t, []string{testNamespace}, indexRuleSetCrossNamespaceSourceNamespaces(ruleSets[5]))
+	assert.Empty(t, indexRuleSetCrossNamespaceSourceNamespaces(ruleSets[0]))
+	assert.Empty(t, indexRuleSetCrossNamespaceConfigMaps(ruleSets[0]))
+
+	t.Log("Verifying ConfigMaps map to the RuleSets referencing them")
+	requestNames := func(cmName string) []string {
+		var names []string
+		for _, req := range reconciler.findRuleSetsForConfigMap(ctx, utils.NewTestConfigMap(cmName, testNamespace, "")) {
+			names = append(names, req.String())
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{testNamespace + "/both"}, requestNames("base"))
+	assert.ElementsMatch(t, []string{testNamespace + "/both", testNamespace + "/extra-only", "other/cross-namespace"}, requestNames("extra"))
+	assert.Empty(t, requestNames("unreferenced"))
+
+	t.Log("Verifying Secrets map to the RuleSets referencing them")
+	secretRequests := reconciler.findRuleSetsForSecret(ctx, utils.NewTestSecret("base", testNamespace, ""))
+	require.Len(t, secretRequests, 1)
+	assert.Equal(t, "secret", secretRequests[0].Name)
+}
+
+func TestRuleSetReconciler_CrossNamespaceConfigMap(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating a ConfigMap in another namespace")
+	sourceNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shared-rules"}}
+	require.NoError(t, k8sClient.Create(ctx, sourceNamespace))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, sourceNamespace); err != nil {
+			t.Logf("Failed to delete Namespace: %v", err)
+		}
+	})
+	configMap := utils.NewTestConfigMap("crs", sourceNamespace.Name, `SecRule ARGS "@contains attack" "id:1,phase:1,deny,status:403"`)
+	require.NoError(t, k8sClient.Create(ctx, configMap))
+
+	tests := []struct {
+		name      string
+		grantKind string
+		permitted bool
+	}{
+		{name: "granted", grantKind: "ConfigMap", permitted: true},
+		{name: "missing-grant"},
+		{name: "wrong-kind-grant", grantKind: "Secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.grantKind != "" {
+				grant := newTestReferenceGrant(sourceNamespace.Name, "grant-"+tt.name, testNamespace, tt.grantKind)
+				require.NoError(t, k8sClient.Create(ctx, grant))
+				t.Cleanup(func() {
+					if err := k8sClient.Delete(ctx, grant); err != nil {
+						t.Logf("Failed to delete ReferenceGrant: %v", err)
+					}
+				})
+			}
+
+			ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+				Name:      "cross-namespace-" + tt.name,
+				Namespace: testNamespace,
+				Rules: []wafv1alpha1.RuleSourceReference{
+					{Name: configMap.Name, Namespace: sourceNamespace.Name},
+				},
+			})
+			require.NoError(t, k8sClient.Create(ctx, ruleSet))
+			t.Cleanup(func() {
+				if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+					t.Logf("Failed to delete RuleSet: %v", err)
+				}
+			})
+
+			ruleSetCache := cache.NewRuleSetCache()
+			recorder := utils.NewFakeRecorder()
+			reconciler := &RuleSetReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme,
+				Recorder: recorder,
+				Cache:    ruleSetCache,
+			}
+			result, err := reconciler.Reconcile(ctx, ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace},
+			})
+			require.NoError(t, err)
+
+			var updated wafv1alpha1.RuleSet
+			require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}, &updated))
+			entry, cached := ruleSetCache.Get(testNamespace + "/" + ruleSet.Name)
+			if tt.permitted {
+				t.Log("Verifying the ConfigMap's rules were cached")
+				require.True(t, cached)
+				assert.Equal(t, configMap.Data["rules"], entry.Rules)
+				assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Ready"))
+				assert.False(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonRefNotPermitted),
+					"unexpected Warning/RefNotPermitted event; got: %v", recorder.Events)
+				return
+			}
+
+			t.Log("Verifying the reference was denied")
+			assert.False(t, cached)
+			assert.Equal(t, ctrl.Result{}, result, "ReferenceGrants are watched, so the RuleSet shouldn't be requeued")
+			assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonRefNotPermitted),
+				"expected Warning/RefNotPermitted event; got: %v", recorder.Events)
+			degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+			require.NotNil(t, degraded)
+			assert.Equal(t, wafv1alpha1.ReasonRefNotPermitted, degraded.Reason)
+			assert.Contains(t, degraded.Message, sourceNamespace.Name+"/"+configMap.Name)
+		})
+	}
+}
+
+func TestRuleSetReconciler_ReferenceGrantRevoked(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating a ConfigMap in another namespace and a ReferenceGrant for it")
+	sourceNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "revoked-rules"}}
+	require.NoError(t, k8sClient.Create(ctx, sourceNamespace))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, sourceNamespace); err != nil {
+			t.Logf("Failed to delete Namespace: %v", err)
+		}
+	})
+	configMap := utils.NewTestConfigMap("crs", sourceNamespace.Name, `SecRule ARGS "@contains attack" "id:1,phase:1,deny,status:403"`)
+	require.NoError(t, k8sClient.Create(ctx, configMap))
+	grant := newTestReferenceGrant(sourceNamespace.Name, "grant", testNamespace, "ConfigMap")
+	require.NoError(t, k8sClient.Create(ctx, grant))
+
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "revoked-grant",
+		Namespace: testNamespace,
+		Rules: []wafv1alpha1.RuleSourceReference{
+			{Name: configMap.Name, Namespace: sourceNamespace.Name},
+		},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+
+	t.Log("Reconciling RuleSet while the reference is granted")
+	recorder := utils.NewFakeRecorder()
+	ruleSetCache := cache.NewRuleSetCache()
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Cache:    ruleSetCache,
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}}
+	_, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	var updated wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	require.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Ready"))
+	_, ok := ruleSetCache.Get(cache.KeyFor(ruleSet.Namespace, ruleSet.Name))
+	require.True(t, ok)
+
+	t.Log("Revoking the ReferenceGrant")
+	require.NoError(t, k8sClient.Delete(ctx, grant))
+
+	t.Log("Verifying the revoked ReferenceGrant maps to the RuleSet")
+	indexed := &RuleSetReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithIndex(&wafv1alpha1.RuleSet{}, ruleSetCrossNamespaceSourceNamespaceIndexKey, indexRuleSetCrossNamespaceSourceNamespaces).
+			WithObjects(&updated).
+			Build(),
+		Scheme: scheme,
+	}
+	assert.Equal(t, []reconcile.Request{req}, indexed.findRuleSetsForReferenceGrant(ctx, grant))
+	otherGrant := newTestReferenceGrant(sourceNamespace.Name, "other", "other", "ConfigMap")
+	assert.Empty(t, indexed.findRuleSetsForReferenceGrant(ctx, otherGrant))
+
+	t.Log("Reconciling RuleSet after the ReferenceGrant was revoked")
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+
+	t.Log("Verifying the RuleSet is degraded")
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+	assert.Equal(t, wafv1alpha1.ReasonRefNotPermitted, degraded.Reason)
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonRefNotPermitted),
+		"expected Warning/RefNotPermitted event; got: %v", recorder.Events)
+
+	t.Log("Verifying the rules of the revoked reference are no longer cached")
+	_, ok = ruleSetCache.Get(cache.KeyFor(ruleSet.Namespace, ruleSet.Name))
+	assert.False(t, ok)
+}
+
+func TestRuleSetReconciler_SpecInlineRules(t *testing.T) {
+	ctx := context.Background()
+	ruleSetCache := cache.NewRuleSetCache()
+
+	t.Log("Creating ConfigMap for RuleSet with inline rules")
+	cm := utils.NewTestConfigMap("spec-inline-rules", testNamespace, "SecCollectionTimeout 1")
+	require.NoError(t, k8sClient.Create(ctx, cm))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, cm); err != nil {
+			t.Logf("Failed to delete ConfigMap: %v", err)
+		}
+	})
+
+	t.Log("Creating RuleSet with inline rules and a ConfigMap source")
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "spec-inline-ruleset",
+		Namespace: testNamespace,
+		Rules: []wafv1alpha1.RuleSourceReference{
+			{Name: "spec-inline-rules"},
+			{Kind: wafv1alpha1.RuleSourceKindInline, Rules: "SecCollectionTimeout 2", Priority: 10},
+		},
+	})
+	ruleSet.Spec.InlineRules = "SecCollectionTimeout 3"
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+
+	t.Log("Reconciling RuleSet")
+	recorder := utils.NewFakeRecorder()
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Cache:    ruleSetCache,
+	}
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      ruleSet.Name,
+			Namespace: ruleSet.Namespace,
+		},
+	}
+	_, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+
+	t.Log("Verifying inline rules were aggregated after all sources")
+	entry, ok := ruleSetCache.Get(testNamespace + "/spec-inline-ruleset")
+	require.True(t, ok, "Cache entry should exist")
+	assert.Equal(t, "SecCollectionTimeout 1\nSecCollectionTimeout 2\nSecCollectionTimeout 3", entry.Rules)
+
+	t.Log("Updating RuleSet with invalid inline rules")
+	var current wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &current))
+	current.Spec.InlineRules = "SecNotARealDirective On"
+	require.NoError(t, k8sClient.Update(ctx, &current))
+
+	t.Log("Reconciling RuleSet - should fail validation")
+	_, err = reconciler.Reconcile(ctx, req)
+	require.Error(t, err)
+
+	t.Log("Verifying the RuleSet is degraded and the last good rules are kept")
+	var updated wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, wafv1alpha1.ReasonInvalidRuleSource, degraded.Reason)
+	assert.Contains(t, degraded.Message, "inlineRules")
+	entry, ok = ruleSetCache.Get(testNamespace + "/spec-inline-ruleset")
+	require.True(t, ok, "Cache entry should exist")
+	assert.Contains(t, entry.Rules, "SecCollectionTimeout 3")
+}
+
+func TestRuleSetReconciler_InvalidInlineSource(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating RuleSet with invalid inline rules")
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "invalid-inline-ruleset",
+		Namespace: testNamespace,
+		Rules: []wafv1alpha1.RuleSourceReference{
+			{Kind: wafv1alpha1.RuleSourceKindInline, Name: "broken", Rules: "SecNotARealDirective On"},
+		},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+
+	t.Log("Reconciling RuleSet - should fail validation")
+	ruleSetCache := cache.NewRuleSetCache()
+	recorder := utils.NewFakeRecorder()
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Cache:    ruleSetCache,
+	}
+	_, err := reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      ruleSet.Name,
+			Namespace: ruleSet.Namespace,
+		},
+	})
+	require.Error(t, err)
+
+	t.Log("Verifying nothing was cached and the RuleSet is degraded")
+	_, ok := ruleSetCache.Get(testNamespace + "/invalid-inline-ruleset")
+	assert.False(t, ok)
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonInvalidRuleSource),
+		"expected Warning/InvalidRuleSource event; got: %v", recorder.Events)
+
+	var updated wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}, &updated))
+	degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, wafv1alpha1.ReasonInvalidRuleSource, degraded.Reason)
+	assert.Contains(t, degraded.Message, "broken")
+}
+
+func TestRuleSetReconciler_MissingConfigMap(t *testing.T) {
+	ctx := context.Background()
+
+	ruleSetCache := cache.NewRuleSetCache()
+
+	t.Log("Creating RuleSet referencing non-existent ConfigMap")
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "missing-cm-ruleset",
+		Namespace: testNamespace,
+		Rules: []wafv1alpha1.RuleSourceReference{
+			{Name: "non-existent"},
+		},
+	})
+	err := k8sClient.Create(ctx, ruleSet)
+	require.NoError(t, err)
+	defer func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	}()
+
+	t.Log("Reconciling RuleSet - should requeue due to missing ConfigMap")
+	recorder := utils.NewFakeRecorder()
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Cache:    ruleSetCache,
+	}
+	result, err := reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      ruleSet.Name,
+			Namespace: ruleSet.Namespace,
+		},
+	})
+
+	t.Log("Verifying cache was not populated due to missing ConfigMap")
+	require.NoError(t, err)
+	assert.Equal(t, missingSourceBaseDelay, result.RequeueAfter, "Should requeue after a backoff when ConfigMap is not found")
+	cacheKey := testNamespace + "/missing-cm-ruleset"
+	_, ok := ruleSetCache.Get(cacheKey)
+	assert.False(t, ok)
+
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonConfigMapNotFound),
+		"expected Warning/ConfigMapNotFound event; got: %v", recorder.Events)
+}
+
+func TestRuleSetReconciler_MissingConfigMapBackoff(t *testing.T) {
+	ctx := context.Background()
+
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "backoff-ruleset",
+		Namespace: testNamespace,
+		Rules: []wafv1alpha1.RuleSourceReference{
+			{Name: "backoff-rules"},
+		},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}}
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: utils.NewFakeRecorder(),
+		Cache:    cache.NewRuleSetCache(),
+	}
+
+	t.Log("Reconciling repeatedly while the ConfigMap is missing")
+	for failures, expectedDelay := range []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second} {
+		result, err := reconciler.Reconcile(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, expectedDelay, result.RequeueAfter)
+
+		var updated wafv1alpha1.RuleSet
+		require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+		assert.Equal(t, int32(failures+1), updated.Status.ObservedFailures)
+		assert.Equal(t, "Referenced ConfigMap backoff-rules does not exist", updated.Status.LastError)
+		degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+		require.NotNil(t, degraded)
+		assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+		assert.Equal(t, wafv1alpha1.ReasonConfigMapNotFound, degraded.Reason)
+	}
+
+	t.Log("Creating the ConfigMap and verifying the failures are reset")
+	configMap := utils.NewTestConfigMap("backoff-rules", testNamespace, "SecCollectionTimeout 1")
+	require.NoError(t, k8sClient.Create(ctx, configMap))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, configMap); err != nil {
+			t.Logf("Failed to delete ConfigMap: %v", err)
+		}
+	})
+	result, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+
+	var updated wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	assert.Zero(t, updated.Status.ObservedFailures)
+	assert.Empty(t, updated.Status.LastError)
+	assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Ready"))
+}
+
+func TestMissingSourceBackoff(t *testing.T) {
+	assert.Equal(t, 5*time.Second, missingSourceBackoff(0))
+	assert.Equal(t, 5*time.Second, missingSourceBackoff(1))
+	assert.Equal(t, 10*time.Second, missingSourceBackoff(2))
+	assert.Equal(t, 160*time.Second, missingSourceBackoff(6))
+	assert.Equal(t, missingSourceMaxDelay, missingSourceBackoff(7))
+	assert.Equal(t, missingSourceMaxDelay, missingSourceBackoff(1000))
+}
+
+func TestRuleSetReconciler_DuplicateRuleIDsInConfigMap(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating ConfigMap containing two rules with the same id")
+	cm := utils.NewTestConfigMap("duplicate-id-rules", testNamespace, `SecRule ARGS "@contains a" "id:100,phase:1,deny"
+SecRule ARGS "@contains b" "id:100,phase:1,deny"`)
+	require.NoError(t, k8sClient.Create(ctx, cm))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, cm); err != nil {
+			t.Logf("Failed to delete ConfigMap: %v", err)
+		}
+	})
+
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "duplicate-id-ruleset",
+		Namespace: testNamespace,
+		Rules: []wafv1alpha1.RuleSourceReference{
+			{Name: "duplicate-id-rules"},
+		},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+
+	t.Log("Reconciling RuleSet - should fail with InvalidRules")
+	ruleSetCache := cache.NewRuleSetCache()
+	recorder := utils.NewFakeRecorder()
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Cache:    ruleSetCache,
+	}
+	_, err := reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      ruleSet.Name,
+			Namespace: ruleSet.Namespace,
+		},
+	})
+	require.Error(t, err)
+
+	_, ok := ruleSetCache.Get(testNamespace + "/duplicate-id-ruleset")
+	assert.False(t, ok)
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonInvalidRules),
+		"expected Warning/InvalidRules event; got: %v", recorder.Events)
+
+	var updated wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}, &updated))
+	degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, wafv1alpha1.ReasonInvalidRules, degraded.Reason)
+	assert.Contains(t, degraded.Message, "id 100 on line 2 duplicates line 1")
+}
+
+func TestRuleSetReconciler_ValidateAggregatedRules(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating ConfigMaps which are valid alone but conflict when aggregated")
+	for _, name := range []string{"aggregate-rules-a", "aggregate-rules-b"} {
+		cm := utils.NewTestConfigMap(name, testNamespace, `SecRule ARGS "@contains a" "id:200,phase:1,deny"`)
+		require.NoError(t, k8sClient.Create(ctx, cm))
+		t.Cleanup(func() {
+			if err := k8sClient.Delete(ctx, cm); err != nil {
+				t.Logf("Failed to delete ConfigMap: %v", err)
+			}
+		})
+	}
+
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "aggregate-ruleset",
+		Namespace: testNamespace,
+		Rules: []wafv1alpha1.RuleSourceReference{
+			{Name: "aggregate-rules-a"},
+			{Name: "aggregate-rules-b"},
+		},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      ruleSet.Name,
+			Namespace: ruleSet.Namespace,
+		},
+	}
+
+	t.Log("Reconciling - sources pass individually but their rule ids collide")
+	ruleSetCache := cache.NewRuleSetCache()
+	recorder := utils.NewFakeRecorder()
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Cache:    ruleSetCache,
+	}
+	_, err := reconciler.Reconcile(ctx, req)
+	require.Error(t, err)
+
+	_, ok := ruleSetCache.Get(testNamespace + "/aggregate-ruleset")
+	assert.False(t, ok)
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonDuplicateRuleID),
+		"expected Warning/DuplicateRuleID event; got: %v", recorder.Events)
+
+	var updated wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, wafv1alpha1.ReasonDuplicateRuleID, degraded.Reason)
+	assert.Contains(t, degraded.Message, "id 200 in ConfigMap aggregate-rules-b duplicates ConfigMap aggregate-rules-a")
+
+	t.Log("Keeping the last definition of duplicate rule ids")
+	updated.Spec.DuplicateRuleIDs = wafv1alpha1.DuplicateRuleIDPolicyKeepLast
+	require.NoError(t, k8sClient.Update(ctx, &updated))
+
+	t.Log("Reconciling with aggregated validation - the deduplicated rules compile")
+	recorder = utils.NewFakeRecorder()
+	reconciler = &RuleSetReconciler{
+		Client:              k8sClient,
+		Scheme:              scheme,
+		Recorder:            recorder,
+		Cache:               ruleSetCache,
+		aggregateValidation: newValidationCache(rulesets.Validate),
+	}
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+
+	entry, ok := ruleSetCache.Get(testNamespace + "/aggregate-ruleset")
+	require.True(t, ok)
+	assert.Equal(t, "\n"+`SecRule ARGS "@contains a" "id:200,phase:1,deny"`, entry.Rules)
+	assert.True(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonDuplicateRuleIDsReplaced),
+		"expected Normal/DuplicateRuleIDsReplaced event; got: %v", recorder.Events)
+}
+
+func TestRuleSetReconciler_ManyDuplicateRuleIDs(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating ConfigMaps which define the same 200 rule ids")
+	var rules strings.Builder
+	for id := 1; id <= 200; id++ {
+		fmt.Fprintf(&rules, "SecRule ARGS \"@contains a\" \"id:%d,phase:1,deny\"\n", 1000+id)
+	}
+	for _, name := range []string{"many-duplicates-a", "many-duplicates-b"} {
+		cm := utils.NewTestConfigMap(name, testNamespace, rules.String())
+		require.NoError(t, k8sClient.Create(ctx, cm))
+		t.Cleanup(func() {
+			if err := k8sClient.Delete(ctx, cm); err != nil {
+				t.Logf("Failed to delete ConfigMap: %v", err)
+			}
+		})
+	}
+
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "many-duplicates-ruleset",
+		Namespace: testNamespace,
+		Rules: []wafv1alpha1.RuleSourceReference{
+			{Name: "many-duplicates-a"},
+			{Name: "many-duplicates-b"},
+		},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      ruleSet.Name,
+			Namespace: ruleSet.Namespace,
+		},
+	}
+
+	t.Log("Reconciling - every rule id collides")
+	recorder := utils.NewFakeRecorder()
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Cache:    cache.NewRuleSetCache(),
+	}
+	_, err := reconciler.Reconcile(ctx, req)
+	require.Error(t, err)
+
+	t.Log("Verifying the event and condition only list the first few duplicates")
+	suffix := fmt.Sprintf("...and %d more", 200-rulesets.MaxListedProblems)
+	require.NotEmpty(t, recorder.Events)
+	for _, event := range recorder.Events {
+		if event.Reason == wafv1alpha1.ReasonDuplicateRuleID {
+			assert.Contains(t, event.Note, suffix)
+			assert.Less(t, len(event.Note), 1024)
+		}
+	}
+
+	var updated wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, wafv1alpha1.ReasonDuplicateRuleID, degraded.Reason)
+	assert.Contains(t, degraded.Message, "id 1001 in ConfigMap many-duplicates-b duplicates ConfigMap many-duplicates-a")
+	assert.Contains(t, degraded.Message, suffix)
+	assert.NotContains(t, degraded.Message, "id 1200")
+}
+
+func TestRuleSetReconciler_SourcePriority(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating a ConfigMap and a RuleSet listing its sources out of priority order")
+	cm := utils.NewTestConfigMap("priority-rules", testNamespace, "SecCollectionTimeout 2")
+	require.NoError(t, k8sClient.Create(ctx, cm))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, cm); err != nil {
+			t.Logf("Failed to delete ConfigMap: %v", err)
+		}
+	})
+
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "priority-ruleset",
+		Namespace: testNamespace,
+		Rules: []wafv1alpha1.RuleSourceReference{
+			{Kind: wafv1alpha1.RuleSourceKindInline, Rules: "SecCollectionTimeout 4", Priority: 10},
+			{Name: "priority-rules"},
+			{Kind: wafv1alpha1.RuleSourceKindInline, Rules: "SecCollectionTimeout 1", Priority: -10},
+			{Kind: wafv1alpha1.RuleSourceKindInline, Rules: "SecCollectionTimeout 3"},
+		},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+
+	t.Log("Reconciling the RuleSet")
+	ruleSetCache := cache.NewRuleSetCache()
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: utils.NewFakeRecorder(),
+		Cache:    ruleSetCache,
+	}
+	_, err := reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      ruleSet.Name,
+			Namespace: ruleSet.Namespace,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Log("Verifying sources were aggregated by priority, then in listed order")
+	entry, ok := ruleSetCache.Get(testNamespace + "/priority-ruleset")
+	require.True(t, ok)
+	assert.Equal(t, "SecCollectionTimeout 1\nSecCollectionTimeout 2\nSecCollectionTimeout 3\nSecCollectionTimeout 4", entry.Rules)
+}
+
+func TestRuleSetReconciler_Paused(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating a ConfigMap and a paused RuleSet referencing it")
+	cm := utils.NewTestConfigMap("paused-rules", testNamespace, "SecRuleEngine On")
+	require.NoError(t, k8sClient.Create(ctx, cm))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, cm); err != nil {
+			t.Logf("Failed to delete ConfigMap: %v", err)
+		}
+	})
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "paused-ruleset",
+		Namespace: testNamespace,
+		Rules:     []wafv1alpha1.RuleSourceReference{{Name: cm.Name}},
+	})
+	ruleSet.Annotations = map[string]string{PausedAnnotation: "true"}
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}}
+	cacheKey := testNamespace + "/paused-ruleset"
+
+	ruleSetCache := cache.NewRuleSetCache()
+	recorder := utils.NewFakeRecorder()
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: recorder,
+		Cache:    ruleSetCache,
+	}
+
+	t.Log("Verifying the rules aren't cached while the RuleSet is paused")
+	_, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	_, ok := ruleSetCache.Get(cacheKey)
+	assert.False(t, ok)
+	var updated wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Paused"))
+	assert.True(t, recorder.HasEvent("Normal", wafv1alpha1.EventReasonReconciliationPaused),
+		"expected Normal/ReconciliationPaused event; got: %v", recorder.Events)
+
+	t.Log("Resuming the RuleSet")
+	delete(updated.Annotations, PausedAnnotation)
+	require.NoError(t, k8sClient.Update(ctx, &updated))
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+
+	t.Log("Verifying the rules are cached once the RuleSet is resumed")
+	entry, ok := ruleSetCache.Get(cacheKey)
+	require.True(t, ok)
+	assert.Equal(t, "SecRuleEngine On", entry.Rules)
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	assert.Nil(t, apimeta.FindStatusCondition(updated.Status.Conditions, "Paused"))
+	assert.True(t, apimeta.IsStatusConditionTrue(updated.Status.Conditions, "Ready"))
+}
+
+func TestRuleSourceOrder(t *testing.T) {
+	assert.Empty(t, ruleSourceOrder(nil))
+	assert.Equal(t, []int{0, 1, 2}, ruleSourceOrder([]wafv1alpha1.RuleSourceReference{
+		{Name: "a"}, {Name: "b"}, {Name: "c"},
+	}))
+	assert.Equal(t, []int{2, 0, 3, 1}, ruleSourceOrder([]wafv1alpha1.RuleSourceReference{
+		{Name: "a"}, {Name: "b", Priority: 5}, {Name: "c", Priority: -1}, {Name: "d"},
+	}))
+}
+
+func TestRuleSetReconciler_InvalidRulesKeepLastGood(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating a ConfigMap with valid rules and a RuleSet referencing it")
+	cm := utils.NewTestConfigMap("pmfromfile-rules", testNamespace, `SecRule ARGS "@contains a" "id:300,phase:1,deny"`)
+	require.NoError(t, k8sClient.Create(ctx, cm))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, cm); err != nil {
+			t.Logf("Failed to delete ConfigMap: %v", err)
+		}
+	})
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "pmfromfile-ruleset",
+		Namespace: testNamespace,
+		Rules:     []wafv1alpha1.RuleSourceReference{{Name: cm.Name}},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}}
+	cacheKey := testNamespace + "/pmfromfile-ruleset"
+
+	ruleSetCache := cache.NewRuleSetCache()
+	recorder := utils.NewFakeRecorder()
+	reconciler := &RuleSetReconciler{
+		Client:              k8sClient,
+		Scheme:              scheme,
+		Recorder:            recorder,
+		Cache:               ruleSetCache,
+		aggregateValidation: newValidationCache(rulesets.Validate),
+	}
+	_, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	lastGood, ok := ruleSetCache.Get(cacheKey)
+	require.True(t, ok)
+
+	t.Log("Updating the ConfigMap to use @pmFromFile, which can't be loaded by the operator")
+	pmFromFileRules := `SecRule ARGS "@pmFromFile blocklist.txt" "id:300,phase:1,deny"`
+	cm.Data["rules"] = pmFromFileRules
+	require.NoError(t, k8sClient.Update(ctx, cm))
+	_, err = reconciler.Reconcile(ctx, req)
+	require.Error(t, err)
+
+	t.Log("Verifying the RuleSet is degraded and the last good rules keep serving")
+	var updated wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+	assert.Contains(t, degraded.Message, "ConfigMap pmfromfile-rules doesn't contain valid rules")
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonInvalidConfigMap),
+		"expected Warning/InvalidConfigMap event; got: %v", recorder.Events)
+	entry, ok := ruleSetCache.Get(cacheKey)
+	require.True(t, ok)
+	assert.Equal(t, lastGood.UUID, entry.UUID)
+
+	t.Log("Opting the ConfigMap out of validation - aggregated validation is skipped too")
+	cm.Annotations = map[string]string{"coraza.io/validation": "false"}
+	require.NoError(t, k8sClient.Update(ctx, cm))
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	entry, ok = ruleSetCache.Get(cacheKey)
+	require.True(t, ok)
+	assert.Equal(t, pmFromFileRules, entry.Rules)
+}
+
+func TestRuleSetReconciler_RuleSetTooLarge(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating ConfigMaps which fit the size limit individually and a RuleSet referencing one")
+	small := utils.NewTestConfigMap("size-rules-a", testNamespace, "SecCollectionTimeout 1")
+	large := utils.NewTestConfigMap("size-rules-b", testNamespace, "# "+strings.Repeat("x", 96))
+	for _, cm := range []*corev1.ConfigMap{small, large} {
+		require.NoError(t, k8sClient.Create(ctx, cm))
+		t.Cleanup(func() {
+			if err := k8sClient.Delete(ctx, cm); err != nil {
+				t.Logf("Failed to delete ConfigMap: %v", err)
+			}
+		})
+	}
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "size-ruleset",
+		Namespace: testNamespace,
+		Rules:     []wafv1alpha1.RuleSourceReference{{Name: small.Name}},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}}
+	cacheKey := testNamespace + "/size-ruleset"
+
+	ruleSetCache := cache.NewRuleSetCache()
+	recorder := utils.NewFakeRecorder()
+	reconciler := &RuleSetReconciler{
+		Client:         k8sClient,
+		Scheme:         scheme,
+		Recorder:       recorder,
+		Cache:          ruleSetCache,
+		maxRuleSetSize: 100,
+	}
+	_, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	lastGood, ok := ruleSetCache.Get(cacheKey)
+	require.True(t, ok)
+
+	t.Log("Adding the other ConfigMap, pushing the aggregate past the limit")
+	var updated wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	updated.Spec.Rules = append(updated.Spec.Rules, wafv1alpha1.RuleSourceReference{Name: large.Name})
+	require.NoError(t, k8sClient.Update(ctx, &updated))
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+
+	t.Log("Verifying the RuleSet is degraded and the last good rules keep serving")
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+	assert.Equal(t, wafv1alpha1.ReasonRuleSetTooLarge, degraded.Reason)
+	assert.Contains(t, degraded.Message, "are 121 bytes, exceeding the limit of 100 bytes")
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonRuleSetTooLarge),
+		"expected Warning/RuleSetTooLarge event; got: %v", recorder.Events)
+	entry, ok := ruleSetCache.Get(cacheKey)
+	require.True(t, ok)
+	assert.Equal(t, lastGood.UUID, entry.UUID)
+}
+
+func TestRuleSetReconciler_AggregateValidationCache(t *testing.T) {
+	ctx := context.Background()
+
+	cm := utils.NewTestConfigMap("validation-cache-rules", testNamespace, "SecRuleEngine On")
+	require.NoError(t, k8sClient.Create(ctx, cm))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, cm); err != nil {
+			t.Logf("Failed to delete ConfigMap: %v", err)
+		}
+	})
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "validation-cache-ruleset",
+		Namespace: testNamespace,
+		Rules:     []wafv1alpha1.RuleSourceReference{{Name: cm.Name}},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+
+	compiles := 0
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: utils.NewTestRecorder(),
+		Cache:    cache.NewRuleSetCache(),
+		aggregateValidation: newValidationCache(func(rules string) error {
+			compiles++
+			return rulesets.Validate(rules)
+		}),
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}}
+
+	t.Log("Reconciling twice with unchanged rules - should compile once")
+	_, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 1, compiles)
+
+	t.Log("Changing the rules - should compile again")
+	cm.Data["rules"] = "SecRuleEngine DetectionOnly"
+	require.NoError(t, k8sClient.Update(ctx, cm))
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 2, compiles)
+}
+
+func TestRuleSetReconciler_ResolvedSourcesProvenance(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating a labeled ConfigMap and an unlabeled ConfigMap")
+	labeled := utils.NewTestConfigMap("provenance-crs", testNamespace, "SecRuleEngine On")
+	labeled.Labels = map[string]string{
+		"coraza.io/crs-version":  "v4.7.0",
+		"app.kubernetes.io/name": "crs",
+	}
+	unlabeled := utils.NewTestConfigMap("provenance-custom", testNamespace, "SecRequestBodyAccess On")
+	for _, cm := range []*corev1.ConfigMap{labeled, unlabeled} {
+		require.NoError(t, k8sClient.Create(ctx, cm))
+		t.Cleanup(func() {
+			if err := k8sClient.Delete(ctx, cm); err != nil {
+				t.Logf("Failed to delete ConfigMap: %v", err)
+			}
+		})
+	}
+
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "provenance-ruleset",
+		Namespace: testNamespace,
+		Rules: []wafv1alpha1.RuleSourceReference{
+			{Name: labeled.Name},
+			{Kind: wafv1alpha1.RuleSourceKindInline, Rules: "SecCollectionTimeout 1"},
+			{Name: unlabeled.Name},
+		},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+
+	t.Log("Reconciling RuleSet with a provenance label allowlist")
+	reconciler := &RuleSetReconciler{
+		Client:              k8sClient,
+		Scheme:              scheme,
+		Recorder:            utils.NewFakeRecorder(),
+		Cache:               cache.NewRuleSetCache(),
+		provenanceLabelKeys: []string{"coraza.io/crs-version"},
+	}
+	_, err := reconciler.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace},
+	})
+	require.NoError(t, err)
+
+	t.Log("Verifying only allowlisted labels of ConfigMap sources are recorded")
+	var updated wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}, &updated))
+	assert.Equal(t, []wafv1alpha1.ResolvedSource{
+		{
+			Name:            labeled.Name,
+			Kind:            wafv1alpha1.RuleSourceKindConfigMap,
+			ResourceVersion: labeled.ResourceVersion,
+			Bytes:           16,
+			Labels:          map[string]string{"coraza.io/crs-version": "v4.7.0"},
+		},
+		{
+			Name:            unlabeled.Name,
+			Kind:            wafv1alpha1.RuleSourceKindConfigMap,
+			ResourceVersion: unlabeled.ResourceVersion,
+			Bytes:           23,
+		},
+	}, updated.Status.ResolvedSources)
+}
+
+func TestRuleSetReconciler_ResolvedSources(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating ConfigMap and Secret sources")
+	cm := utils.NewTestConfigMap("resolved-rules", testNamespace, "SecRuleEngine On")
+	require.NoError(t, k8sClient.Create(ctx, cm))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, cm); err != nil {
+			t.Logf("Failed to delete ConfigMap: %v", err)
+		}
+	})
+	secret := utils.NewTestSecret("resolved-secret-rules", testNamespace, "SecRequestBodyAccess On")
+	require.NoError(t, k8sClient.Create(ctx, secret))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, secret); err != nil {
+			t.Logf("Failed to delete Secret: %v", err)
+		}
+	})
+
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "resolved-ruleset",
+		Namespace: testNamespace,
+		Rules: []wafv1alpha1.RuleSourceReference{
+			{Name: cm.Name},
+			{Kind: wafv1alpha1.RuleSourceKindInline, Rules: "SecCollectionTimeout 1"},
+			{Kind: wafv1alpha1.RuleSourceKindSecret, Name: secret.Name},
+		},
+	})
+	require.NoError(t, k8sClient.Create(ctx, ruleSet))
+	t.Cleanup(func() {
+		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
+			t.Logf("Failed to delete RuleSet: %v", err)
+		}
+	})
+
+	t.Log("Reconciling RuleSet")
+	reconciler := &RuleSetReconciler{
+		Client:   k8sClient,
+		Scheme:   scheme,
+		Recorder: utils.NewFakeRecorder(),
+		Cache:    cache.NewRuleSetCache(),
+
+		secretSources: true,
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}}
+	_, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+
+	t.Log("Verifying the resolved sources match the referenced sources")
+	var updated wafv1alpha1.RuleSet
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	assert.Equal(t, []wafv1alpha1.ResolvedSource{
+		{Name: cm.Name, Kind: wafv1alpha1.RuleSourceKindConfigMap, ResourceVersion: cm.ResourceVersion, Bytes: 16},
+		{Name: secret.Name, Kind: wafv1alpha1.RuleSourceKindSecret, ResourceVersion: secret.ResourceVersion, Bytes: 23},
+	}, updated.Status.ResolvedSources)
+
+	t.Log("Updating the ConfigMap")
+	cm.Data["rules"] = "SecRuleEngine DetectionOnly"
+	require.NoError(t, k8sClient.Update(ctx, cm))
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+
+	t.Log("Verifying the resolved sources record the new ConfigMap version")
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+	require.Len(t, updated.Status.ResolvedSources, 2)
+	assert.Equal(t, cm.ResourceVersion, updated.Status.ResolvedSources[0].ResourceVersion)
+	assert.Equal(t, int64(27), updated.Status.ResolvedSources[0].Bytes)
+	assert.Equal(t, secret.ResourceVersion, updated.Status.ResolvedSources[1].ResourceVersion)
+}
+
+func TestRuleSetReconciler_SecretSources(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating a Secret with rules, a Secret with invalid rules and a Secret without them")
+	withRules := utils.NewTestSecret("secret-rules", testNamespace, `SecRule REMOTE_ADDR "@rbl key.dnsbl.example" "id:1,phase:1,deny"`)
+	invalidRules := utils.NewTestSecret("secret-invalid-rules", testNamespace, `SecRule REMOTE_ADDR "@rx (key" "id:1,phase:1,deny"`)
+	withoutRules := utils.NewTestSecret("secret-no-rules", testNamespace, "")
+	withoutRules.Data = map[string][]byte{"other": []byte("SecRuleEngine On")}
+	for _, secret := range []*corev1.Secret{withRules, invalidRules, withoutRules} {
+		require.NoError(t, k8sClient.Create(ctx, secret))
+		t.Cleanup(func() {
+			if err := k8sClient.Delete(ctx, secret); err != nil {
+				t.Logf("Failed to delete Secret: %v", err)
+			}
+		})
+	}
+
 	tests := []struct {
 		name          string
-		ruleSetName   string
-		configMaps    map[string]string
+		secretName    string
+		disabled      bool
 		expectedRules string
+		eventType     string
+		expectedEvent string
+		expectedMsg   string
+		expectRequeue bool
+		expectErr     bool
 	}{
 		{
-			name:        "single ConfigMap",
-			ruleSetName: "single-cm-ruleset",
-			configMaps: map[string]string{
-				"test-rules": "SecRule REQUEST_URI \"@contains /admin\" \"id:1,deny\"",
-			},
-			expectedRules: "SecRule REQUEST_URI \"@contains /admin\" \"id:1,deny\"",
+			name:          "secret-source",
+			secretName:    withRules.Name,
+			expectedRules: "SecCollectionTimeout 1\n" + string(withRules.Data["rules"]),
+			eventType:     "Normal",
+			expectedEvent: wafv1alpha1.EventReasonRulesCached,
 		},
 		{
-			name:        "multiple ConfigMaps",
-			ruleSetName: "multi-cm-ruleset",
-			configMaps: map[string]string{
-				"rules-1": "SecCollectionTimeout 1",
-				"rules-2": "SecCollectionTimeout 2",
-				"rules-3": "SecCollectionTimeout 3",
-			},
-			expectedRules: "SecCollectionTimeout 1\nSecCollectionTimeout 2\nSecCollectionTimeout 3",
+			name:          "secret-sources-disabled",
+			secretName:    withRules.Name,
+			disabled:      true,
+			eventType:     "Warning",
+			expectedEvent: wafv1alpha1.ReasonSecretSourcesDisabled,
+		},
+		{
+			name:          "secret-invalid-rules",
+			secretName:    invalidRules.Name,
+			eventType:     "Warning",
+			expectedEvent: wafv1alpha1.ReasonInvalidSecret,
+			expectedMsg:   "line 1: directive can't be compiled",
+			expectErr:     true,
+		},
+		{
+			name:          "secret-missing-rules-key",
+			secretName:    withoutRules.Name,
+			eventType:     "Warning",
+			expectedEvent: wafv1alpha1.ReasonInvalidSecret,
+			expectErr:     true,
+		},
+		{
+			name:          "secret-not-found",
+			secretName:    "secret-does-not-exist",
+			eventType:     "Warning",
+			expectedEvent: wafv1alpha1.ReasonSecretNotFound,
+			expectRequeue: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ctx := context.Background()
 			ruleSetCache := cache.NewRuleSetCache()
-
-			t.Logf("Creating %d ConfigMap(s)", len(tt.configMaps))
-			var refs []wafv1alpha1.RuleSourceReference
-			var names []string
-			for name := range tt.configMaps {
-				names = append(names, name)
-			}
-			sort.Strings(names) // need a consistent order for testing
-
-			t.Logf("Creating ConfigMaps: %v", names)
-			for _, name := range names {
-				data := tt.configMaps[name]
-				cm := utils.NewTestConfigMap(name, testNamespace, data)
-				require.NoError(t, k8sClient.Create(ctx, cm))
-
-				t.Cleanup(func() {
-					if err := k8sClient.Delete(ctx, cm); err != nil {
-						t.Logf("Failed to delete ConfigMap %s: %v", name, err)
-					}
-				})
-
-				refs = append(refs, wafv1alpha1.RuleSourceReference{Name: name})
-			}
-
-			t.Log("Creating RuleSet referencing ConfigMaps")
 			ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
-				Name:      tt.ruleSetName,
+				Name:      tt.name,
 				Namespace: testNamespace,
-				Rules:     refs,
+				Rules: []wafv1alpha1.RuleSourceReference{
+					{Kind: wafv1alpha1.RuleSourceKindInline, Rules: "SecCollectionTimeout 1"},
+					{Kind: wafv1alpha1.RuleSourceKindSecret, Name: tt.secretName},
+				},
 			})
-
-			t.Log("Creating RuleSet in Kubernetes")
 			require.NoError(t, k8sClient.Create(ctx, ruleSet))
 			t.Cleanup(func() {
 				if err := k8sClient.Delete(ctx, ruleSet); err != nil {
@@ -129,83 +2076,50 @@ func TestRuleSetReconciler_ReconcileConfigMaps(t *testing.T) {
 				}
 			})
 
-			t.Logf("Reconciling RuleSet %s", tt.ruleSetName)
 			recorder := utils.NewFakeRecorder()
 			reconciler := &RuleSetReconciler{
 				Client:   k8sClient,
 				Scheme:   scheme,
 				Recorder: recorder,
 				Cache:    ruleSetCache,
+
+				secretSources: !tt.disabled,
 			}
-			result, err := reconciler.Reconcile(ctx, ctrl.Request{
-				NamespacedName: types.NamespacedName{
-					Name:      ruleSet.Name,
-					Namespace: ruleSet.Namespace,
-				},
-			})
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}}
+			result, err := reconciler.Reconcile(ctx, req)
+			if tt.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectRequeue, result.RequeueAfter > 0)
+			assert.True(t, recorder.HasEvent(tt.eventType, tt.expectedEvent),
+				"expected %s/%s event; got: %v", tt.eventType, tt.expectedEvent, recorder.Events)
 
-			t.Log("Verifying cache was populated with combined rules")
-			require.NoError(t, err)
-			assert.False(t, result.Requeue)
-			cacheKey := testNamespace + "/" + tt.ruleSetName
-			entry, ok := ruleSetCache.Get(cacheKey)
+			if tt.expectedMsg != "" {
+				t.Log("Verifying the error doesn't quote the Secret's rules")
+				var updated wafv1alpha1.RuleSet
+				require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, &updated))
+				degraded := apimeta.FindStatusCondition(updated.Status.Conditions, "Degraded")
+				require.NotNil(t, degraded)
+				assert.Contains(t, degraded.Message, tt.expectedMsg)
+				assert.NotContains(t, degraded.Message, "(key")
+				for _, event := range recorder.Events {
+					assert.NotContains(t, event.Note, "(key")
+				}
+			}
+
+			entry, ok := ruleSetCache.Get(testNamespace + "/" + tt.name)
+			if tt.expectedRules == "" {
+				assert.False(t, ok, "Cache entry should not exist")
+				return
+			}
 			require.True(t, ok, "Cache entry should exist")
 			assert.Equal(t, tt.expectedRules, entry.Rules)
-			assert.NotEmpty(t, entry.UUID)
-
-			assert.True(t, recorder.HasEvent("Normal", "RulesCached"),
-				"expected Normal/RulesCached event; got: %v", recorder.Events)
 		})
 	}
 }
 
-func TestRuleSetReconciler_MissingConfigMap(t *testing.T) {
-	ctx := context.Background()
-
-	ruleSetCache := cache.NewRuleSetCache()
-
-	t.Log("Creating RuleSet referencing non-existent ConfigMap")
-	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
-		Name:      "missing-cm-ruleset",
-		Namespace: testNamespace,
-		Rules: []wafv1alpha1.RuleSourceReference{
-			{Name: "non-existent"},
-		},
-	})
-	err := k8sClient.Create(ctx, ruleSet)
-	require.NoError(t, err)
-	defer func() {
-		if err := k8sClient.Delete(ctx, ruleSet); err != nil {
-			t.Logf("Failed to delete RuleSet: %v", err)
-		}
-	}()
-
-	t.Log("Reconciling RuleSet - should requeue due to missing ConfigMap")
-	recorder := utils.NewFakeRecorder()
-	reconciler := &RuleSetReconciler{
-		Client:   k8sClient,
-		Scheme:   scheme,
-		Recorder: recorder,
-		Cache:    ruleSetCache,
-	}
-	result, err := reconciler.Reconcile(ctx, ctrl.Request{
-		NamespacedName: types.NamespacedName{
-			Name:      ruleSet.Name,
-			Namespace: ruleSet.Namespace,
-		},
-	})
-
-	t.Log("Verifying cache was not populated due to missing ConfigMap")
-	require.NoError(t, err)
-	assert.True(t, result.Requeue, "Should requeue when ConfigMap is not found")
-	cacheKey := testNamespace + "/missing-cm-ruleset"
-	_, ok := ruleSetCache.Get(cacheKey)
-	assert.False(t, ok)
-
-	assert.True(t, recorder.HasEvent("Warning", "ConfigMapNotFound"),
-		"expected Warning/ConfigMapNotFound event; got: %v", recorder.Events)
-}
-
 func TestRuleSetReconciler_ConfigMapMissingRulesKey(t *testing.T) {
 	ctx := context.Background()
 
@@ -260,7 +2174,7 @@ func TestRuleSetReconciler_ConfigMapMissingRulesKey(t *testing.T) {
 	assert.Contains(t, err.Error(), "missing 'rules' key")
 	assert.False(t, result.Requeue)
 
-	assert.True(t, recorder.HasEvent("Warning", "InvalidConfigMap"),
+	assert.True(t, recorder.HasEvent("Warning", wafv1alpha1.ReasonInvalidConfigMap),
 		"expected Warning/InvalidConfigMap event; got: %v", recorder.Events)
 }
 
@@ -295,7 +2209,39 @@ func TestRuleSetReconciler_ValidationRejection(t *testing.T) {
 			rules: []wafv1alpha1.RuleSourceReference{
 				{Name: ""},
 			},
-			expectedError: "spec.rules[0].name in body should be at least 1 chars long",
+			expectedError: "name is required for ConfigMap and Secret sources",
+		},
+		{
+			name:        "inline source without rules",
+			ruleSetName: "inline-no-rules-ruleset",
+			rules: []wafv1alpha1.RuleSourceReference{
+				{Kind: wafv1alpha1.RuleSourceKindInline},
+			},
+			expectedError: "rules is required for Inline sources",
+		},
+		{
+			name:        "configmap source with inline rules",
+			ruleSetName: "configmap-with-rules-ruleset",
+			rules: []wafv1alpha1.RuleSourceReference{
+				{Name: "test", Rules: "SecRuleEngine On"},
+			},
+			expectedError: "rules may only be set for Inline sources",
+		},
+		{
+			name:        "inline rules too long",
+			ruleSetName: "inline-too-long-ruleset",
+			rules: []wafv1alpha1.RuleSourceReference{
+				{Kind: wafv1alpha1.RuleSourceKindInline, Rules: strings.Repeat("#", 65537)},
+			},
+			expectedError: "spec.rules[0].rules: Too long",
+		},
+		{
+			name:        "unknown source kind",
+			ruleSetName: "unknown-kind-ruleset",
+			rules: []wafv1alpha1.RuleSourceReference{
+				{Kind: "Service", Name: "test"},
+			},
+			expectedError: "spec.rules[0].kind: Unsupported value",
 		},
 	}
 
@@ -386,3 +2332,160 @@ func TestRuleSetReconciler_UpdateCache(t *testing.T) {
 	assert.Equal(t, "SecDefaultAction \"phase:2,log,auditlog,pass\"", entry2.Rules)
 	assert.NotEqual(t, uuid1, entry2.UUID, "UUID should change when rules are updated")
 }
+
+// newTestReferenceGrant builds a ReferenceGrant in namespace permitting
+// RuleSets in fromNamespace to reference resources of the given kind.
+func newTestReferenceGrant(namespace, name, fromNamespace, toKind string) *unstructured.Unstructured {
+	grant := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"from": []interface{}{
+				map[string]interface{}{
+					"group":     wafv1alpha1.GroupVersion.Group,
+					"kind":      "RuleSet",
+					"namespace": fromNamespace,
+				},
+			},
+			"to": []interface{}{
+				map[string]interface{}{
+					"group": "",
+					"kind":  toKind,
+				},
+			},
+		},
+	}}
+	grant.SetAPIVersion("gateway.networking.k8s.io/v1beta1")
+	grant.SetKind("ReferenceGrant")
+	grant.SetNamespace(namespace)
+	grant.SetName(name)
+	return grant
+}
+
+func TestRuleSetReconciler_Metrics(t *testing.T) {
+	ctx := context.Background()
+
+	histogramCount := func(result string) uint64 {
+		var m dto.Metric
+		require.NoError(t, reconcileDuration.WithLabelValues("ruleset", result).(prometheus.Metric).Write(&m))
+		return m.GetHistogram().GetSampleCount()
+	}
+	successes := histogramCount(reconcileResultSuccess)
+	requeues := histogramCount(reconcileResultRequeue)
+	notFound := testutil.ToFloat64(reconcileErrors.WithLabelValues("ruleset", wafv1alpha1.ReasonConfigMapNotFound))
+
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{
+		Name:      "metrics-ruleset",
+		Namespace: testNamespace,
+		Rules:     []wafv1alpha1.RuleSourceReference{{Name: "metrics-rules"}},
+	})
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ruleSet).
+		WithStatusSubresource(ruleSet).
+		Build()
+	reconciler := &RuleSetReconciler{
+		Client:   c,
+		Scheme:   scheme,
+		Recorder: utils.NewFakeRecorder(),
+		Cache:    cache.NewRuleSetCache(),
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: ruleSet.Name, Namespace: ruleSet.Namespace}}
+
+	t.Log("Reconciling a RuleSet referencing a missing ConfigMap")
+	_, err := reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, requeues+1, histogramCount(reconcileResultRequeue))
+	assert.Equal(t, notFound+1, testutil.ToFloat64(reconcileErrors.WithLabelValues("ruleset", wafv1alpha1.ReasonConfigMapNotFound)))
+
+	t.Log("Reconciling the RuleSet once the ConfigMap exists")
+	require.NoError(t, c.Create(ctx, utils.NewTestConfigMap("metrics-rules", testNamespace, "SecRuleEngine On")))
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, successes+1, histogramCount(reconcileResultSuccess))
+	assert.Equal(t, notFound+1, testutil.ToFloat64(reconcileErrors.WithLabelValues("ruleset", wafv1alpha1.ReasonConfigMapNotFound)))
+}
+
+func TestRuleSetReconciler_RefreshEngineAggregates(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Creating Engines with multiple RuleSets, only one referencing the RuleSet")
+	base := utils.NewTestRuleSet(utils.RuleSetOptions{Name: "base", Namespace: "default"})
+	referencing := utils.NewTestEngine(utils.EngineOptions{Name: "referencing", RuleSetName: "base"})
+	referencing.Spec.RuleSets = []wafv1alpha1.RuleSetReference{{Name: "overlay"}}
+	unrelated := utils.NewTestEngine(utils.EngineOptions{Name: "unrelated", RuleSetName: "other"})
+	unrelated.Spec.RuleSets = []wafv1alpha1.RuleSetReference{{Name: "overlay"}}
+
+	rulesetCache := cache.NewRuleSetCache()
+	rulesetCache.Put("default/base", `SecRule ARGS "@contains a" "id:1,deny"`)
+	rulesetCache.Put("default/other", `SecRule ARGS "@contains c" "id:3,deny"`)
+	rulesetCache.Put("default/overlay", `SecRule ARGS "@contains b" "id:2,deny"`)
+	reconciler := &RuleSetReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithIndex(&wafv1alpha1.Engine{}, engineRuleSetIndexKey, indexEngineRuleSets).
+			WithObjects(referencing, unrelated).
+			Build(),
+		Scheme: scheme,
+		Cache:  rulesetCache,
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: base.Name, Namespace: base.Namespace}}
+
+	t.Log("Verifying only the Engine referencing the RuleSet is aggregated")
+	reconciler.refreshEngineAggregates(ctx, logr.Discard(), req, base)
+	entry, ok := rulesetCache.Get("default/engine:referencing")
+	require.True(t, ok)
+	assert.Equal(t, "SecRule ARGS \"@contains a\" \"id:1,deny\"\nSecRule ARGS \"@contains b\" \"id:2,deny\"", entry.Rules)
+	_, ok = rulesetCache.Get("default/engine:unrelated")
+	assert.False(t, ok)
+
+	t.Log("Verifying an aggregate defining the same rule id twice isn't cached")
+	rulesetCache.Put("default/base", `SecRule ARGS "@contains a" "id:2,deny"`)
+	reconciler.refreshEngineAggregates(ctx, logr.Discard(), req, base)
+	latest, ok := rulesetCache.Get("default/engine:referencing")
+	require.True(t, ok)
+	assert.Equal(t, entry.UUID, latest.UUID)
+
+	t.Log("Verifying an aggregate failing validation isn't cached")
+	rulesetCache.Put("default/base", `SecRule ARGS "@contains a" "id:1,deny"
+SecRule ARGS "@rx (" "id:4,deny"`)
+	reconciler.aggregateValidation = newValidationCache(func(rules string) error {
+		return reconciler.validateRules(rules).Err()
+	})
+	reconciler.refreshEngineAggregates(ctx, logr.Discard(), req, base)
+	latest, ok = rulesetCache.Get("default/engine:referencing")
+	require.True(t, ok)
+	assert.Equal(t, entry.UUID, latest.UUID)
+
+	t.Log("Verifying the aggregate isn't compiled when a source opted out of validation")
+	base.Status.ResolvedSources = []wafv1alpha1.ResolvedSource{{Name: "base", ValidationSkipped: true}}
+	reconciler.refreshEngineAggregates(ctx, logr.Discard(), req, base)
+	latest, ok = rulesetCache.Get("default/engine:referencing")
+	require.True(t, ok)
+	assert.NotEqual(t, entry.UUID, latest.UUID)
+}
+
+func TestRestoredEntriesPruner(t *testing.T) {
+	ctx := context.Background()
+
+	t.Log("Restoring a snapshot with entries of deleted RuleSets and Engines")
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	snapshot := cache.NewRuleSetCache()
+	for _, instance := range []string{"default/existing", "default/deleted", "default/engine:existing", "default/engine:deleted"} {
+		snapshot.Put(instance, "SecRuleEngine On")
+	}
+	require.NoError(t, snapshot.Snapshot(path))
+	rulesetCache := cache.NewRuleSetCache()
+	require.NoError(t, rulesetCache.Restore(path, utils.NewTestLogger(t)))
+
+	ruleSet := utils.NewTestRuleSet(utils.RuleSetOptions{Name: "existing", Namespace: "default"})
+	engine := utils.NewTestEngine(utils.EngineOptions{Name: "existing", RuleSetName: "existing"})
+	engine.Spec.RuleSets = []wafv1alpha1.RuleSetReference{{Name: "deleted"}}
+	pruner := &restoredEntriesPruner{
+		client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(ruleSet, engine).Build(),
+		cache:  rulesetCache,
+		logger: logr.Discard(),
+	}
+
+	t.Log("Verifying only the entries without a RuleSet or Engine are pruned")
+	require.NoError(t, pruner.Start(ctx))
+	assert.ElementsMatch(t, []string{"default/existing", "default/engine:existing"}, rulesetCache.ListKeys())
+}
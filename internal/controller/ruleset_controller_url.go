@@ -0,0 +1,189 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+// RuleSet Controller - URL Rule Sources - Consts
+// -----------------------------------------------------------------------------
+
+const (
+	// DefaultURLRuleSourceTimeout is the default max time to fetch the rules
+	// of a URL rule source.
+	DefaultURLRuleSourceTimeout = 10 * time.Second
+
+	// DefaultURLRuleSourceMaxSize is the default max size in bytes of the
+	// rules fetched from a URL rule source (4MB).
+	DefaultURLRuleSourceMaxSize = 4 * 1024 * 1024
+
+	// DefaultURLRuleSourceRefreshInterval is the default interval at which
+	// URL rule sources are fetched again, and RuleSets with URL sources are
+	// requeued to pick up changes.
+	DefaultURLRuleSourceRefreshInterval = 5 * time.Minute
+)
+
+// -----------------------------------------------------------------------------
+// RuleSet Controller - URL Rule Sources - Config
+// -----------------------------------------------------------------------------
+
+// URLRuleSourceConfig configures fetching of URL rule sources.
+type URLRuleSourceConfig struct {
+	// Timeout is the max time to fetch the rules of a URL source.
+	Timeout time.Duration
+
+	// MaxSize is the max size in bytes of the rules of a URL source.
+	MaxSize int64
+
+	// RefreshInterval is how long fetched rules are reused before the URL
+	// is fetched again.
+	RefreshInterval time.Duration
+
+	// AllowedHosts are the hosts URL sources may be fetched from, including
+	// when following redirects. When empty, any host is allowed.
+	AllowedHosts []string
+}
+
+// DefaultURLRuleSourceConfig returns the default URL rule source
+// configuration.
+func DefaultURLRuleSourceConfig() *URLRuleSourceConfig {
+	return &URLRuleSourceConfig{
+		Timeout:         DefaultURLRuleSourceTimeout,
+		MaxSize:         DefaultURLRuleSourceMaxSize,
+		RefreshInterval: DefaultURLRuleSourceRefreshInterval,
+	}
+}
+
+// -----------------------------------------------------------------------------
+// RuleSet Controller - URL Rule Sources - Fetcher
+// -----------------------------------------------------------------------------
+
+// fetchedRules are the rules fetched from a URL and when they were fetched.
+type fetchedRules struct {
+	rules     string
+	fetchedAt time.Time
+}
+
+// urlFetcher fetches the rules of URL rule sources, reusing the rules fetched
+// from each URL until the refresh interval elapses so that reconciles don't
+// hit the remote server every time.
+type urlFetcher struct {
+	client          *http.Client
+	maxSize         int64
+	refreshInterval time.Duration
+	allowedHosts    map[string]bool
+
+	mu      sync.Mutex
+	fetched map[string]fetchedRules
+}
+
+func newURLFetcher(config *URLRuleSourceConfig) *urlFetcher {
+	f := &urlFetcher{
+		maxSize:         config.MaxSize,
+		refreshInterval: config.RefreshInterval,
+		fetched:         make(map[string]fetchedRules),
+	}
+	if len(config.AllowedHosts) > 0 {
+		f.allowedHosts = make(map[string]bool, len(config.AllowedHosts))
+		for _, host := range config.AllowedHosts {
+			f.allowedHosts[strings.ToLower(host)] = true
+		}
+	}
+	f.client = &http.Client{
+		Timeout: config.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !f.hostAllowed(req.URL) {
+				return fmt.Errorf("redirect to host %s is not allowed", req.URL.Hostname())
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// hostAllowed reports whether rules may be fetched from the host of u.
+func (f *urlFetcher) hostAllowed(u *url.URL) bool {
+	return f.allowedHosts == nil || f.allowedHosts[strings.ToLower(u.Hostname())]
+}
+
+// urlHostAllowed reports whether rules may be fetched from rawURL, which is
+// false if it can't be parsed.
+func (f *urlFetcher) urlHostAllowed(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && f.hostAllowed(u)
+}
+
+// fetch returns the rules at url, fetching them if they were never fetched or
+// the refresh interval elapsed since they were. Responses other than 200 OK,
+// and bodies over the max size, are errors. Rules fetched longer than the
+// refresh interval ago are dropped meanwhile, so that URLs no longer
+// referenced by any RuleSet don't accumulate.
+func (f *urlFetcher) fetch(ctx context.Context, url string) (string, error) {
+	f.mu.Lock()
+	cached, ok := f.fetched[url]
+	for fetchedURL, rules := range f.fetched {
+		if time.Since(rules.fetchedAt) >= f.refreshInterval {
+			delete(f.fetched, fetchedURL)
+		}
+	}
+	f.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < f.refreshInterval {
+		return cached.rules, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %s: %w", url, err)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch %s: unexpected status %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if int64(len(body)) > f.maxSize {
+		return "", fmt.Errorf("rules at %s exceed the max size of %d bytes", url, f.maxSize)
+	}
+
+	f.mu.Lock()
+	f.fetched[url] = fetchedRules{rules: string(body), fetchedAt: time.Now()}
+	f.mu.Unlock()
+
+	return string(body), nil
+}
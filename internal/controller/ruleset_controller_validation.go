@@ -0,0 +1,127 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets"
+)
+
+// -----------------------------------------------------------------------------
+// RuleSet Controller - Source Validation
+// -----------------------------------------------------------------------------
+
+// validateRules validates rules, treating warnings as errors if strict
+// validation is enabled.
+func (r *RuleSetReconciler) validateRules(rules string) rulesets.ValidationResult {
+	result := rulesets.ValidateDetailed(rules)
+	if r.strictValidation {
+		result = result.PromoteWarnings()
+	}
+	return result
+}
+
+// validateRuleSource validates the rules of a single rule source, described
+// by source (e.g. "ConfigMap my-rules"), returning the validation errors.
+// Warnings don't fail validation, they're surfaced as a Normal event on the
+// RuleSet instead. If redact is set (e.g. for Secret sources), errors and
+// warnings only describe the position and kind of each problem, without
+// quoting the rules.
+func (r *RuleSetReconciler) validateRuleSource(ruleset *wafv1alpha1.RuleSet, source, rules string, redact bool) error {
+	result := r.validateRules(rules)
+	if len(result.Warnings) > 0 {
+		warnings := make([]string, 0, len(result.Warnings))
+		for _, w := range result.Warnings {
+			if redact {
+				w = rulesets.Redact(rules, w)
+			}
+			warnings = append(warnings, w.Error())
+		}
+		r.Recorder.Eventf(ruleset, nil, "Normal", wafv1alpha1.EventReasonRuleValidationWarning, "Reconcile",
+			"%s has questionable rules:\n%s", source, rulesets.JoinLimited(warnings, "\n"))
+	}
+
+	if redact {
+		return rulesets.Redact(rules, result.Err())
+	}
+	return result.Err()
+}
+
+// -----------------------------------------------------------------------------
+// RuleSet Controller - Aggregated Rules Validation
+// -----------------------------------------------------------------------------
+
+// validationVerdict is the outcome of compiling a RuleSet's aggregated rules.
+type validationVerdict struct {
+	hash [sha256.Size]byte
+	err  error
+}
+
+// validationCache compiles the aggregated rules of RuleSets, remembering the
+// verdict for the most recently validated rules of each RuleSet so that
+// reconciles with unchanged rules don't compile them again.
+type validationCache struct {
+	compile func(rules string) error
+
+	mu       sync.Mutex
+	verdicts map[types.NamespacedName]validationVerdict
+}
+
+func newValidationCache(compile func(rules string) error) *validationCache {
+	return &validationCache{
+		compile:  compile,
+		verdicts: make(map[types.NamespacedName]validationVerdict),
+	}
+}
+
+// validate returns the result of compiling rules for the RuleSet, reusing the
+// previous verdict if the rules are unchanged since they were last validated.
+func (c *validationCache) validate(key types.NamespacedName, rules string) error {
+	hash := sha256.Sum256([]byte(rules))
+
+	c.mu.Lock()
+	verdict, ok := c.verdicts[key]
+	c.mu.Unlock()
+	if ok && verdict.hash == hash {
+		return verdict.err
+	}
+
+	err := c.compile(rules)
+
+	c.mu.Lock()
+	c.verdicts[key] = validationVerdict{hash: hash, err: err}
+	c.mu.Unlock()
+
+	return err
+}
+
+// forget drops the verdict for a RuleSet, e.g. once it has been deleted. It
+// is safe to call on a nil validationCache.
+func (c *validationCache) forget(key types.NamespacedName) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.verdicts, key)
+}
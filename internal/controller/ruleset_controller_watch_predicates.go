@@ -18,6 +18,8 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"slices"
 
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -32,32 +34,177 @@ import (
 // RuleSet Controller - Watch Predicates
 // -----------------------------------------------------------------------------
 
-// findRuleSetsForConfigMap maps a ConfigMap to the RuleSets that reference it (if any).
+// ruleSetConfigMapIndexKey is the field index mapping RuleSets to the names of
+// the ConfigMaps they reference.
+const ruleSetConfigMapIndexKey = "spec.rules.configMapName"
+
+// ruleSetCrossNamespaceConfigMapIndexKey is the field index mapping RuleSets
+// to the "namespace/name" keys of the ConfigMaps they reference in other
+// namespaces.
+const ruleSetCrossNamespaceConfigMapIndexKey = "spec.rules.crossNamespaceConfigMap"
+
+// ruleSetCrossNamespaceSourceNamespaceIndexKey is the field index mapping
+// RuleSets to the namespaces of the ConfigMaps they reference in other
+// namespaces.
+const ruleSetCrossNamespaceSourceNamespaceIndexKey = "spec.rules.crossNamespaceSourceNamespace"
+
+// ruleSetSecretIndexKey is the field index mapping RuleSets to the names of
+// the Secrets they reference.
+const ruleSetSecretIndexKey = "spec.rules.secretName"
+
+// indexRuleSetConfigMaps returns the names of the ConfigMaps referenced by a
+// RuleSet, for use with ruleSetConfigMapIndexKey.
+func indexRuleSetConfigMaps(obj client.Object) []string {
+	return ruleSetSourceNames(obj, wafv1alpha1.RuleSourceKindConfigMap)
+}
+
+// indexRuleSetCrossNamespaceConfigMaps returns the "namespace/name" keys of
+// the ConfigMaps a RuleSet references in other namespaces, for use with
+// ruleSetCrossNamespaceConfigMapIndexKey.
+func indexRuleSetCrossNamespaceConfigMaps(obj client.Object) []string {
+	ruleSet, ok := obj.(*wafv1alpha1.RuleSet)
+	if !ok {
+		return nil
+	}
+
+	var keys []string
+	seen := make(map[string]struct{}, len(ruleSet.Spec.Rules))
+	for _, rule := range ruleSet.Spec.Rules {
+		if !isCrossNamespaceSource(ruleSet, rule) {
+			continue
+		}
+		key := types.NamespacedName{Namespace: rule.Namespace, Name: rule.Name}.String()
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// indexRuleSetCrossNamespaceSourceNamespaces returns the namespaces of the
+// ConfigMaps a RuleSet references in other namespaces, for use with
+// ruleSetCrossNamespaceSourceNamespaceIndexKey.
+func indexRuleSetCrossNamespaceSourceNamespaces(obj client.Object) []string {
+	ruleSet, ok := obj.(*wafv1alpha1.RuleSet)
+	if !ok {
+		return nil
+	}
+
+	var namespaces []string
+	for _, rule := range ruleSet.Spec.Rules {
+		if isCrossNamespaceSource(ruleSet, rule) && !slices.Contains(namespaces, rule.Namespace) {
+			namespaces = append(namespaces, rule.Namespace)
+		}
+	}
+
+	return namespaces
+}
+
+// isCrossNamespaceSource reports whether the rule source is a ConfigMap in a
+// namespace other than the RuleSet's.
+func isCrossNamespaceSource(ruleSet *wafv1alpha1.RuleSet, rule wafv1alpha1.RuleSourceReference) bool {
+	kind := rule.Kind
+	if kind == "" {
+		kind = wafv1alpha1.RuleSourceKindConfigMap
+	}
+	return kind == wafv1alpha1.RuleSourceKindConfigMap && rule.Namespace != "" && rule.Namespace != ruleSet.Namespace
+}
+
+// indexRuleSetSecrets returns the names of the Secrets referenced by a
+// RuleSet, for use with ruleSetSecretIndexKey.
+func indexRuleSetSecrets(obj client.Object) []string {
+	return ruleSetSourceNames(obj, wafv1alpha1.RuleSourceKindSecret)
+}
+
+// ruleSetSourceNames returns the unique names of the rule sources of the
+// given kind referenced by a RuleSet in its own namespace. Sources without a
+// kind are ConfigMaps.
+func ruleSetSourceNames(obj client.Object, kind wafv1alpha1.RuleSourceKind) []string {
+	ruleSet, ok := obj.(*wafv1alpha1.RuleSet)
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	seen := make(map[string]struct{}, len(ruleSet.Spec.Rules))
+	for _, rule := range ruleSet.Spec.Rules {
+		ruleKind := rule.Kind
+		if ruleKind == "" {
+			ruleKind = wafv1alpha1.RuleSourceKindConfigMap
+		}
+		if ruleKind != kind || isCrossNamespaceSource(ruleSet, rule) {
+			continue
+		}
+		if _, ok := seen[rule.Name]; ok {
+			continue
+		}
+		seen[rule.Name] = struct{}{}
+		names = append(names, rule.Name)
+	}
+
+	return names
+}
+
+// findRuleSetsForConfigMap maps a ConfigMap to the RuleSets that reference it
+// (if any), including RuleSets in other namespaces.
 func (r *RuleSetReconciler) findRuleSetsForConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	requests := r.findRuleSetsForSource(ctx, configMap, wafv1alpha1.RuleSourceKindConfigMap, ruleSetConfigMapIndexKey)
+
+	log := logf.FromContext(ctx)
+	key := client.ObjectKeyFromObject(configMap).String()
+	var ruleSetList wafv1alpha1.RuleSetList
+	if err := r.List(ctx, &ruleSetList, client.MatchingFields{ruleSetCrossNamespaceConfigMapIndexKey: key}); err != nil {
+		log.Error(err, "RuleSet: Failed to list RuleSets referencing ConfigMap from other namespaces", "configMap", key)
+		return requests
+	}
+	for _, ruleSet := range ruleSetList.Items {
+		req := ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      ruleSet.Name,
+				Namespace: ruleSet.Namespace,
+			},
+		}
+		requests = append(requests, req)
+
+		logInfo(log, req, "RuleSet", "Enqueuing for reconciliation due to cross-namespace ConfigMap change", "sourceName", configMap.GetName(), "sourceNamespace", configMap.GetNamespace())
+	}
+
+	return requests
+}
+
+// findRuleSetsForSecret maps a Secret to the RuleSets that reference it (if any).
+func (r *RuleSetReconciler) findRuleSetsForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	return r.findRuleSetsForSource(ctx, secret, wafv1alpha1.RuleSourceKindSecret, ruleSetSecretIndexKey)
+}
+
+// findRuleSetsForSource maps a rule source of the given kind to the RuleSets
+// that reference it, using the source kind's field index.
+func (r *RuleSetReconciler) findRuleSetsForSource(ctx context.Context, source client.Object, kind wafv1alpha1.RuleSourceKind, indexKey string) []reconcile.Request {
 	log := logf.FromContext(ctx)
 
 	var ruleSetList wafv1alpha1.RuleSetList
-	if err := r.List(ctx, &ruleSetList, client.InNamespace(configMap.GetNamespace())); err != nil {
-		log.Error(err, "RuleSet: Failed to list RuleSets", "namespace", configMap.GetNamespace())
+	if err := r.List(ctx, &ruleSetList,
+		client.InNamespace(source.GetNamespace()),
+		client.MatchingFields{indexKey: source.GetName()},
+	); err != nil {
+		log.Error(err, "RuleSet: Failed to list RuleSets", "namespace", source.GetNamespace())
 		return nil
 	}
 
-	var requests []reconcile.Request
+	requests := make([]reconcile.Request, 0, len(ruleSetList.Items))
 	for _, ruleSet := range ruleSetList.Items {
-		for _, rule := range ruleSet.Spec.Rules {
-			if rule.Name == configMap.GetName() {
-				req := ctrl.Request{
-					NamespacedName: types.NamespacedName{
-						Name:      ruleSet.Name,
-						Namespace: ruleSet.Namespace,
-					},
-				}
-				requests = append(requests, req)
-
-				logInfo(log, req, "RuleSet", "Enqueuing for reconciliation due to ConfigMap change", "configMapName", configMap.GetName())
-				break
-			}
+		req := ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      ruleSet.Name,
+				Namespace: ruleSet.Namespace,
+			},
 		}
+		requests = append(requests, req)
+
+		logInfo(log, req, "RuleSet", fmt.Sprintf("Enqueuing for reconciliation due to %s change", kind), "sourceName", source.GetName())
 	}
 
 	return requests
@@ -82,6 +82,7 @@ func TestMain(m *testing.M) {
 		CRDInstallOptions: envtest.CRDInstallOptions{
 			Paths: []string{
 				filepath.Join("..", "..", "config", "crd", "bases"),
+				filepath.Join("testdata", "crds"),
 				istioCRDDir,
 			},
 			CleanUpAfterUse: true,
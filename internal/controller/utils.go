@@ -20,14 +20,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets"
 )
 
 // -----------------------------------------------------------------------------
@@ -83,9 +91,22 @@ func setConditionFalse(conditions *[]metav1.Condition, generation int64, conditi
 	})
 }
 
+// setConditionUnknown is a helper function to set metav1.Conditions to Unknown.
+func setConditionUnknown(conditions *[]metav1.Condition, generation int64, conditionType, reason, message string) {
+	apimeta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionUnknown,
+		ObservedGeneration: generation,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
 // setStatusConditionDegraded is a helper to mark a resource as degraded.
 func setStatusConditionDegraded(log logr.Logger, req ctrl.Request, kind string, conditions *[]metav1.Condition, generation int64, reason, message string) {
 	logDebug(log, req, kind, fmt.Sprintf("Setting degraded status: %s", reason))
+	observeDegraded(kind, reason)
 	setConditionFalse(conditions, generation, "Ready", reason, message)
 	setConditionTrue(conditions, generation, "Degraded", reason, message)
 	apimeta.RemoveStatusCondition(conditions, "Progressing")
@@ -107,18 +128,156 @@ func setStatusReady(log logr.Logger, req ctrl.Request, kind string, conditions *
 }
 
 // -----------------------------------------------------------------------------
-// Kubernetes Client Operation Utilities
+// Pausing Utilities
 // -----------------------------------------------------------------------------
 
-// fieldManager is the server-side apply field manager name for this operator.
-const fieldManager = "coraza-kubernetes-operator"
+// PausedAnnotation, when set to "true" on an Engine or RuleSet, suspends its
+// reconciliation (e.g. during incident response): it is left as is, without
+// updating its cached rules or provisioned resources, until the annotation is
+// removed. Deleting a paused resource still cleans it up.
+const PausedAnnotation = "waf.k8s.coraza.io/paused"
+
+// isPaused reports whether the object's reconciliation is paused with the
+// PausedAnnotation.
+func isPaused(obj client.Object) bool {
+	return obj.GetAnnotations()[PausedAnnotation] == "true"
+}
+
+// pausedChangedPredicate triggers reconciliation when an object is paused or
+// resumed with the PausedAnnotation, for controllers which otherwise ignore
+// changes to the metadata of the objects they reconcile.
+func pausedChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isPaused(e.ObjectOld) != isPaused(e.ObjectNew)
+		},
+	}
+}
+
+// pauseReconciliation sets the Paused condition on the object, whose
+// conditions are given, emitting an event when its reconciliation becomes
+// paused. Nothing else about the object is changed.
+func pauseReconciliation(ctx context.Context, c client.Client, recorder events.EventRecorder, log logr.Logger, req ctrl.Request, kind string, obj client.Object, conditions *[]metav1.Condition) (ctrl.Result, error) {
+	if apimeta.IsStatusConditionTrue(*conditions, "Paused") {
+		logDebug(log, req, kind, "Reconciliation paused")
+		return ctrl.Result{}, nil
+	}
+
+	logInfo(log, req, kind, "Pausing reconciliation", "annotation", PausedAnnotation)
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	msg := fmt.Sprintf("Reconciliation is paused by the %s annotation", PausedAnnotation)
+	setConditionTrue(conditions, obj.GetGeneration(), "Paused", wafv1alpha1.EventReasonReconciliationPaused, msg)
+	if err := c.Status().Patch(ctx, obj, patch); err != nil {
+		logError(log, req, kind, err, "Failed to patch status")
+		return ctrl.Result{}, err
+	}
+	recorder.Eventf(obj, nil, "Normal", wafv1alpha1.EventReasonReconciliationPaused, "Reconcile", msg)
+
+	return ctrl.Result{}, nil
+}
+
+// resumeReconciliation removes the Paused condition from the object, whose
+// conditions are given, once its reconciliation is no longer paused.
+func resumeReconciliation(ctx context.Context, c client.Client, recorder events.EventRecorder, log logr.Logger, req ctrl.Request, kind string, obj client.Object, conditions *[]metav1.Condition) error {
+	if apimeta.FindStatusCondition(*conditions, "Paused") == nil {
+		return nil
+	}
+
+	logInfo(log, req, kind, "Resuming reconciliation")
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	apimeta.RemoveStatusCondition(conditions, "Paused")
+	if err := c.Status().Patch(ctx, obj, patch); err != nil {
+		logError(log, req, kind, err, "Failed to patch status")
+		return err
+	}
+	recorder.Eventf(obj, nil, "Normal", wafv1alpha1.EventReasonReconciliationResumed, "Reconcile", "Reconciliation resumed")
+
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+// Rule Validation Utilities
+// -----------------------------------------------------------------------------
+
+// invalidRulesReason returns the Degraded condition reason for a rules
+// validation error. Errors in the rules themselves which are independent of
+// the source (e.g. duplicate rule ids) are reported as ReasonInvalidRules,
+// anything else is reported with the given source specific reason.
+func invalidRulesReason(err error, sourceReason string) string {
+	var dupErr *rulesets.DuplicateRuleIDsError
+	if errors.As(err, &dupErr) {
+		return wafv1alpha1.ReasonInvalidRules
+	}
+	return sourceReason
+}
+
+// ruleSourceReasons are the Degraded condition reasons for failures to load
+// a ConfigMap or Secret rule source.
+type ruleSourceReasons struct {
+	notFound    string
+	accessError string
+	invalid     string
+}
+
+// reasonsForRuleSource returns the Degraded condition reasons for failures
+// to load a rule source of the given kind, which is a ConfigMap or Secret.
+func reasonsForRuleSource(kind wafv1alpha1.RuleSourceKind) ruleSourceReasons {
+	if kind == wafv1alpha1.RuleSourceKindSecret {
+		return ruleSourceReasons{
+			notFound:    wafv1alpha1.ReasonSecretNotFound,
+			accessError: wafv1alpha1.ReasonSecretAccessError,
+			invalid:     wafv1alpha1.ReasonInvalidSecret,
+		}
+	}
+	return ruleSourceReasons{
+		notFound:    wafv1alpha1.ReasonConfigMapNotFound,
+		accessError: wafv1alpha1.ReasonConfigMapAccessError,
+		invalid:     wafv1alpha1.ReasonInvalidConfigMap,
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Warning Event Utilities
+// -----------------------------------------------------------------------------
+
+// warningTracker tracks the warning last emitted for each resource about a
+// persistent issue, so that it's emitted once when the issue arises or
+// changes, rather than on every reconcile. The zero value is ready to use.
+type warningTracker struct {
+	mu       sync.Mutex
+	warnings map[types.NamespacedName]string
+}
+
+// transition records the warning currently applying to the resource, or ""
+// if none does, returning whether it differs from the one last recorded.
+func (w *warningTracker) transition(key types.NamespacedName, warning string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.warnings[key] == warning {
+		return false
+	}
+	if warning == "" {
+		delete(w.warnings, key)
+		return true
+	}
+	if w.warnings == nil {
+		w.warnings = make(map[types.NamespacedName]string)
+	}
+	w.warnings[key] = warning
+	return true
+}
+
+// -----------------------------------------------------------------------------
+// Kubernetes Client Operation Utilities
+// -----------------------------------------------------------------------------
 
 // serverSideApply applies an unstructured Kubernetes object using server-side
-// apply. This avoids the optimistic concurrency conflicts inherent in
-// Get-then-Update patterns by using field ownership for conflict detection.
+// apply as the given field manager. This avoids the optimistic concurrency
+// conflicts inherent in Get-then-Update patterns by using field ownership for
+// conflict detection.
 //
 // The desired object must have its GVK and name set.
-func serverSideApply(ctx context.Context, c client.Client, desired *unstructured.Unstructured) error {
+func serverSideApply(ctx context.Context, c client.Client, fieldManager string, desired *unstructured.Unstructured) error {
 	gvk := desired.GetObjectKind().GroupVersionKind()
 	if gvk.Empty() {
 		return errors.New("desired object must have GroupVersionKind set")
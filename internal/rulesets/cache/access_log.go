@@ -0,0 +1,129 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+)
+
+// -----------------------------------------------------------------------------
+// Access Log - Consts
+// -----------------------------------------------------------------------------
+
+// RequestIDHeader is the header carrying the ID correlating a request with
+// the server's log lines. A valid ID provided by the client is propagated,
+// otherwise one is assigned.
+const RequestIDHeader = "X-Request-Id"
+
+// MaxRequestIDLength is the max length of a client provided request ID.
+const MaxRequestIDLength = 128
+
+// AccessLogVerbosity is the verbosity at which requests are logged, i.e.
+// debug level.
+const AccessLogVerbosity = 1
+
+// -----------------------------------------------------------------------------
+// Access Log
+// -----------------------------------------------------------------------------
+
+// withAccessLog wraps the handler to assign each request an ID, which is
+// echoed in the RequestIDHeader of the response, and to log each request's
+// method, path, status, duration and response size. Handlers can log with
+// the request ID through loggerFor.
+func (s *ruleSetCacheServer) withAccessLog(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		logger := s.logger.WithValues("requestID", requestID)
+		rec := &accessLogResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r.WithContext(logr.NewContext(r.Context(), logger)))
+
+		logger.V(AccessLogVerbosity).Info("Handled request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration", time.Since(start),
+			"bytes", rec.bytes,
+		)
+	})
+}
+
+// loggerFor returns the logger for the request, which carries its request
+// ID if the request went through the access log.
+func (s *ruleSetCacheServer) loggerFor(r *http.Request) logr.Logger {
+	if logger, err := logr.FromContext(r.Context()); err == nil {
+		return logger
+	}
+	return s.logger
+}
+
+// validRequestID reports whether a client provided request ID is safe to
+// propagate into logs and responses: non-empty, at most MaxRequestIDLength
+// long, and made of letters, digits and "-", "_", ".", ":" only.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// accessLogResponseWriter records the status and size of a response.
+type accessLogResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *accessLogResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter.
+func (w *accessLogResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for http.ResponseController.
+func (w *accessLogResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
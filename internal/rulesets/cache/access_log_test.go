@@ -0,0 +1,102 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_AccessLog(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+	logger := funcr.NewJSON(func(obj string) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, obj)
+	}, funcr.Options{Verbosity: AccessLogVerbosity})
+	linesWith := func(requestID string) []string {
+		mu.Lock()
+		defer mu.Unlock()
+		var matching []string
+		for _, line := range lines {
+			if strings.Contains(line, `"requestID":"`+requestID+`"`) {
+				matching = append(matching, line)
+			}
+		}
+		return matching
+	}
+
+	cache := NewRuleSetCache()
+	cache.Put("test-instance", "SecRuleEngine On")
+	server := NewServer(cache, testServerAddr, logger, nil)
+
+	t.Log("Verifying a hit is assigned a request ID and logged with its status")
+	req := httptest.NewRequest(http.MethodGet, "/rules/test-instance", nil)
+	w := httptest.NewRecorder()
+	server.srv.Handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	requestID := w.Header().Get(RequestIDHeader)
+	_, err := uuid.Parse(requestID)
+	require.NoError(t, err, "expected a generated request ID")
+	logged := linesWith(requestID)
+	require.Len(t, logged, 2, "expected the handler's and the access log lines")
+	assert.Contains(t, logged[0], `"msg":"Serving rules from cache"`)
+	assert.Contains(t, logged[1], `"msg":"Handled request"`)
+	assert.Contains(t, logged[1], `"status":200`)
+	assert.Contains(t, logged[1], `"method":"GET"`)
+	assert.Contains(t, logged[1], `"path":"/rules/test-instance"`)
+	assert.Contains(t, logged[1], fmt.Sprintf(`"bytes":%d`, w.Body.Len()))
+
+	t.Log("Verifying a client provided request ID is propagated on a 404")
+	req = httptest.NewRequest(http.MethodGet, "/rules/missing-instance", nil)
+	req.Header.Set(RequestIDHeader, "poll-1234")
+	w = httptest.NewRecorder()
+	server.srv.Handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "poll-1234", w.Header().Get(RequestIDHeader))
+	logged = linesWith("poll-1234")
+	require.Len(t, logged, 1)
+	assert.Contains(t, logged[0], `"status":404`)
+
+	t.Log("Verifying an unsafe request ID is replaced")
+	req = httptest.NewRequest(http.MethodGet, "/rules/test-instance", nil)
+	req.Header.Set(RequestIDHeader, "bad id\n")
+	w = httptest.NewRecorder()
+	server.srv.Handler.ServeHTTP(w, req)
+	assert.NotEqual(t, "bad id\n", w.Header().Get(RequestIDHeader))
+	_, err = uuid.Parse(w.Header().Get(RequestIDHeader))
+	assert.NoError(t, err)
+}
+
+func TestValidRequestID(t *testing.T) {
+	assert.True(t, validRequestID("f47ac10b-58cc-4372-a567-0e02b2c3d479"))
+	assert.True(t, validRequestID("envoy:poll_1.2"))
+	assert.False(t, validRequestID(""))
+	assert.False(t, validRequestID("has space"))
+	assert.False(t, validRequestID(`"quoted"`))
+	assert.False(t, validRequestID(strings.Repeat("a", MaxRequestIDLength+1)))
+}
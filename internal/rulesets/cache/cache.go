@@ -18,12 +18,29 @@ limitations under the License.
 package cache
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// -----------------------------------------------------------------------------
+// Keys
+// -----------------------------------------------------------------------------
+
+// KeyFor returns the cache instance key for the named object: its namespace
+// and name joined by a slash (e.g. "default/my-ruleset"). This is the key the
+// RuleSet controller stores rules under, and the instance data planes are
+// configured to poll from the cache server, so both must use it.
+func KeyFor(namespace, name string) string {
+	return namespace + "/" + name
+}
+
 // -----------------------------------------------------------------------------
 // RuleSetEntry
 // -----------------------------------------------------------------------------
@@ -33,6 +50,31 @@ type RuleSetEntry struct {
 	UUID      string    `json:"uuid"`
 	Timestamp time.Time `json:"timestamp"`
 	Rules     string    `json:"rules"`
+
+	// hash identifies the entry's rules, so that putting identical rules
+	// doesn't mint a new version. It is computed lazily for entries restored
+	// from snapshots.
+	hash [sha256.Size]byte
+}
+
+// contentHash returns the hash of the entry's rules, computing it on first
+// use. The caller must hold the write lock.
+func (e *RuleSetEntry) contentHash() [sha256.Size]byte {
+	if e.hash == ([sha256.Size]byte{}) {
+		e.hash = sha256.Sum256([]byte(e.Rules))
+	}
+	return e.hash
+}
+
+// entryOverhead approximates the memory used by a RuleSetEntry beyond its
+// rules and UUID: the struct itself (string headers, timestamp and content
+// hash) and the pointer to it in the instance's entries.
+const entryOverhead = 96
+
+// size returns the approximate memory used by the entry in bytes, used for
+// accounting the cache size against its max size.
+func (e *RuleSetEntry) size() int {
+	return len(e.Rules) + len(e.UUID) + entryOverhead
 }
 
 // RuleSetEntries wraps a list of RuleSetEntry objects for an instance.
@@ -40,6 +82,17 @@ type RuleSetEntry struct {
 type RuleSetEntries struct {
 	Latest  string          `json:"latest"`
 	Entries []*RuleSetEntry `json:"entries"`
+
+	// lastAccessed is when an entry of the instance was last retrieved, in
+	// Unix nanoseconds, used to prune idle instances first. It is atomic so
+	// that retrievals only need the read lock. It is not persisted in
+	// snapshots.
+	lastAccessed atomic.Int64
+
+	// LastServed is the UUID of the entry most recently served to a client
+	// of the instance, i.e. the version the data plane last loaded. It is not
+	// persisted in snapshots.
+	LastServed string `json:"-"`
 }
 
 // -----------------------------------------------------------------------------
@@ -50,16 +103,43 @@ type RuleSetEntries struct {
 type RuleSetCache struct {
 	mu      sync.RWMutex
 	entries map[string]*RuleSetEntries
+
+	// pinned holds, per instance, the UUIDs of versions which clients depend
+	// on and which garbage collection must therefore retain. It is the union
+	// of the pins of all owners.
+	pinned map[string]map[string]bool
+
+	// pins holds the versions pinned by each owner (e.g. an Engine).
+	pins map[string]ownerPins
+
+	// restored holds the instances restored from a snapshot which haven't
+	// been stored again since, see PruneRestored.
+	restored map[string]bool
+
+	// maxVersions is the max number of versions retained per instance when
+	// new versions are Put. Zero means unlimited.
+	maxVersions int
+
+	// subscribers holds, per instance, the channels signaled when a new
+	// version of the instance's rules is cached.
+	subscribers map[string]map[chan struct{}]struct{}
 }
 
 // NewRuleSetCache creates a new RuleSetCache instance
 func NewRuleSetCache() *RuleSetCache {
 	return &RuleSetCache{
-		entries: make(map[string]*RuleSetEntries),
+		entries:     make(map[string]*RuleSetEntries),
+		pinned:      make(map[string]map[string]bool),
+		pins:        make(map[string]ownerPins),
+		restored:    make(map[string]bool),
+		subscribers: make(map[string]map[chan struct{}]struct{}),
 	}
 }
 
-// Get retrieves the latest ruleset entry for the given instance
+// Get retrieves the latest ruleset entry for the given instance, marking the
+// instance as accessed. It is meant for serving clients: other readers, e.g.
+// controllers, should use Peek so that they don't keep idle instances from
+// being pruned first.
 func (c *RuleSetCache) Get(instance string) (*RuleSetEntry, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -70,22 +150,115 @@ func (c *RuleSetCache) Get(instance string) (*RuleSetEntry, bool) {
 	// Find and return the entry matching the Latest UUID.
 	for _, entry := range entries.Entries {
 		if entry.UUID == entries.Latest {
+			entries.lastAccessed.Store(time.Now().UnixNano())
 			return entry, true
 		}
 	}
 	return nil, false
 }
 
+// Peek retrieves the latest ruleset entry for the given instance, without
+// marking the instance as accessed.
+func (c *RuleSetCache) Peek(instance string) (*RuleSetEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry := c.latest(instance)
+	return entry, entry != nil
+}
+
+// GetByUUID retrieves the entry with the given UUID for the given instance,
+// which may be any retained version rather than the latest. The instance is
+// marked as accessed.
+func (c *RuleSetCache) GetByUUID(instance, uuid string) (*RuleSetEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entries, ok := c.entries[instance]
+	if !ok {
+		return nil, false
+	}
+	for _, entry := range entries.Entries {
+		if entry.UUID == uuid {
+			entries.lastAccessed.Store(time.Now().UnixNano())
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// ListEntries returns copies of all retained entries for the given instance,
+// ordered oldest to newest. It returns nil if the instance is not present,
+// and a non-nil (possibly empty) slice otherwise.
+func (c *RuleSetCache) ListEntries(instance string) []*RuleSetEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entries, ok := c.entries[instance]
+	if !ok {
+		return nil
+	}
+
+	list := make([]*RuleSetEntry, 0, len(entries.Entries))
+	for _, entry := range entries.Entries {
+		entryCopy := *entry
+		list = append(list, &entryCopy)
+	}
+	return list
+}
+
+// MarkServed records that the entry with the given UUID was served to a
+// client of the given instance. It is a no-op if the instance is not present.
+// As it's called on every request, it only takes the write lock when the
+// served entry changes.
+func (c *RuleSetCache) MarkServed(instance, uuid string) {
+	c.mu.RLock()
+	entries, ok := c.entries[instance]
+	unchanged := !ok || entries.LastServed == uuid
+	c.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entries, ok := c.entries[instance]; ok {
+		entries.LastServed = uuid
+	}
+}
+
+// ServedVersion returns the UUID of the entry most recently served to a
+// client of the given instance, which is empty if none was served yet, and
+// the UUID of the latest entry. It returns false if the instance is not
+// present. Unlike Get, it doesn't mark the instance as accessed.
+func (c *RuleSetCache) ServedVersion(instance string) (served, latest string, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entries, ok := c.entries[instance]
+	if !ok {
+		return "", "", false
+	}
+	return entries.LastServed, entries.Latest, true
+}
+
 // Put stores rules for the given instance with a new UUID and timestamp.
 // New entries are appended to the end, maintaining oldest-to-newest order.
+// If the rules are identical to the latest entry's, no new version is
+// created and the latest entry keeps its UUID and timestamp, so clients
+// don't reload unchanged rules.
 func (c *RuleSetCache) Put(instance string, rules string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	delete(c.restored, instance)
+
+	hash := sha256.Sum256([]byte(rules))
+	if latest := c.latest(instance); latest != nil && latest.contentHash() == hash {
+		return
+	}
 
 	newEntry := &RuleSetEntry{
 		UUID:      uuid.New().String(),
 		Timestamp: time.Now(),
 		Rules:     rules,
+		hash:      hash,
 	}
 
 	if c.entries[instance] == nil {
@@ -97,6 +270,186 @@ func (c *RuleSetCache) Put(instance string, rules string) {
 		c.entries[instance].Entries = append(c.entries[instance].Entries, newEntry)
 		c.entries[instance].Latest = newEntry.UUID
 	}
+
+	c.capVersions(instance)
+	c.notify(instance)
+}
+
+// latest returns the latest entry of the given instance, or nil if the
+// instance is not present. The caller must hold the lock.
+func (c *RuleSetCache) latest(instance string) *RuleSetEntry {
+	entries, ok := c.entries[instance]
+	if !ok {
+		return nil
+	}
+	for _, entry := range entries.Entries {
+		if entry.UUID == entries.Latest {
+			return entry
+		}
+	}
+	return nil
+}
+
+// SetMaxVersionsPerInstance sets the max number of versions retained per
+// instance. Once Put exceeds it, the oldest versions are dropped, except for
+// the latest and pinned versions. Zero or less means unlimited.
+func (c *RuleSetCache) SetMaxVersionsPerInstance(maxVersions int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxVersions = maxVersions
+}
+
+// capVersions drops the oldest versions of the instance beyond maxVersions,
+// never dropping the latest or a pinned version. The caller must hold the
+// write lock.
+func (c *RuleSetCache) capVersions(instance string) {
+	entries := c.entries[instance]
+	if c.maxVersions <= 0 || len(entries.Entries) <= c.maxVersions {
+		return
+	}
+
+	excess := len(entries.Entries) - c.maxVersions
+	newEntries := make([]*RuleSetEntry, 0, c.maxVersions)
+	for _, entry := range entries.Entries {
+		if excess > 0 && entry.UUID != entries.Latest && !c.pinned[instance][entry.UUID] {
+			excess--
+			continue
+		}
+		newEntries = append(newEntries, entry)
+	}
+	entries.Entries = newEntries
+}
+
+// Replace atomically drops all prior versions for the given instance, except
+// for pinned versions, and stores the rules as a fresh entry, which becomes
+// the latest.
+//
+// Unlike Put, which always appends a new version and retains history for
+// clients that may still reference it, Replace discards history. Use it when
+// rebuilding an instance from scratch (e.g. during a full resync).
+func (c *RuleSetCache) Replace(instance string, rules string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	newEntry := &RuleSetEntry{
+		UUID:      uuid.New().String(),
+		Timestamp: time.Now(),
+		Rules:     rules,
+	}
+
+	var newEntries []*RuleSetEntry
+	if entries, ok := c.entries[instance]; ok {
+		for _, entry := range entries.Entries {
+			if c.pinned[instance][entry.UUID] {
+				newEntries = append(newEntries, entry)
+			}
+		}
+	}
+
+	c.entries[instance] = &RuleSetEntries{
+		Latest:  newEntry.UUID,
+		Entries: append(newEntries, newEntry),
+	}
+	delete(c.restored, instance)
+	c.notify(instance)
+}
+
+// Delete removes all entries for the given instance, along with its pinned
+// versions, and signals its subscribers so that they learn of the eviction.
+// It returns false if the instance was not present.
+func (c *RuleSetCache) Delete(instance string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[instance]; !ok {
+		return false
+	}
+	c.remove(instance)
+	return true
+}
+
+// remove removes all entries and pinned versions of the instance, and signals
+// its subscribers. The caller must hold the write lock.
+func (c *RuleSetCache) remove(instance string) {
+	delete(c.entries, instance)
+	for owner, pins := range c.pins {
+		if pins.instance == instance {
+			delete(c.pins, owner)
+		}
+	}
+	delete(c.pinned, instance)
+	delete(c.restored, instance)
+	c.notify(instance)
+}
+
+// ownerPins are the versions of an instance pinned by an owner.
+type ownerPins struct {
+	instance string
+	uuids    []string
+}
+
+// SetPinned replaces the versions pinned by the given owner (e.g. an Engine
+// whose data plane loads a specific version) with the given versions of the
+// instance, dropping any versions of other instances the owner pinned.
+// Pinned versions are never removed by Prune, PruneBySize or Replace, though
+// Delete still drops them. Passing no UUIDs unpins all versions of the owner.
+func (c *RuleSetCache) SetPinned(owner, instance string, uuids ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(uuids) == 0 {
+		delete(c.pins, owner)
+	} else {
+		c.pins[owner] = ownerPins{instance: instance, uuids: uuids}
+	}
+
+	c.pinned = make(map[string]map[string]bool, len(c.pins))
+	for _, pins := range c.pins {
+		if c.pinned[pins.instance] == nil {
+			c.pinned[pins.instance] = make(map[string]bool, len(pins.uuids))
+		}
+		for _, id := range pins.uuids {
+			c.pinned[pins.instance][id] = true
+		}
+	}
+}
+
+// PinnedSize returns the total size in bytes (see TotalSize) of pinned
+// versions which are not the latest for their instance, i.e. the size garbage
+// collection must retain on top of the latest versions.
+func (c *RuleSetCache) PinnedSize() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	size := 0
+	for instance, entries := range c.entries {
+		for _, entry := range entries.Entries {
+			if entry.UUID != entries.Latest && c.pinned[instance][entry.UUID] {
+				size += entry.size()
+			}
+		}
+	}
+	return size
+}
+
+// LargestInstance returns the instance retaining the most data and its size
+// in bytes (see TotalSize). It returns an empty instance if the cache is
+// empty.
+func (c *RuleSetCache) LargestInstance() (string, int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	largest, largestSize := "", 0
+	for instance, entries := range c.entries {
+		size := 0
+		for _, entry := range entries.Entries {
+			size += entry.size()
+		}
+		if size > largestSize || (size == largestSize && instance < largest) {
+			largest, largestSize = instance, size
+		}
+	}
+	return largest, largestSize
 }
 
 // ListKeys returns all instance names stored in the cache
@@ -110,14 +463,16 @@ func (c *RuleSetCache) ListKeys() []string {
 	return keys
 }
 
-// TotalSize returns the total size of all cached rules in bytes
+// TotalSize returns the approximate total size in bytes of all cached
+// entries, including their UUIDs and a fixed per-entry overhead along with
+// the rules themselves.
 func (c *RuleSetCache) TotalSize() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	size := 0
 	for _, entries := range c.entries {
 		for _, entry := range entries.Entries {
-			size += len(entry.Rules)
+			size += entry.size()
 		}
 	}
 	return size
@@ -146,12 +501,103 @@ func (c *RuleSetCache) CountEntries(instance string) int {
 	return 0
 }
 
+// Stats summarizes the contents of a RuleSetCache.
+type Stats struct {
+	// Instances is the number of cached instances.
+	Instances int
+
+	// TotalEntries is the number of cached versions across all instances.
+	TotalEntries int
+
+	// TotalBytes is the approximate total size in bytes of all cached
+	// entries, as reported by TotalSize.
+	TotalBytes int
+
+	// OldestEntry and NewestEntry are the timestamps of the oldest and newest
+	// cached versions. They are zero if the cache is empty.
+	OldestEntry time.Time
+	NewestEntry time.Time
+
+	// Keys are the keys of the cached instances, sorted.
+	Keys []string
+}
+
+// Stats returns a summary of the cache's contents, computed in a single pass
+// under the read lock so that it is consistent.
+func (c *RuleSetCache) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := Stats{
+		Instances: len(c.entries),
+		Keys:      make([]string, 0, len(c.entries)),
+	}
+	for key, entries := range c.entries {
+		stats.Keys = append(stats.Keys, key)
+		for _, entry := range entries.Entries {
+			stats.TotalEntries++
+			stats.TotalBytes += entry.size()
+			if stats.OldestEntry.IsZero() || entry.Timestamp.Before(stats.OldestEntry) {
+				stats.OldestEntry = entry.Timestamp
+			}
+			if entry.Timestamp.After(stats.NewestEntry) {
+				stats.NewestEntry = entry.Timestamp
+			}
+		}
+	}
+	slices.Sort(stats.Keys)
+	return stats
+}
+
+// -----------------------------------------------------------------------------
+// RuleSetCache - Subscriptions
+// -----------------------------------------------------------------------------
+
+// Subscribe registers for notifications of new versions of the given
+// instance's rules: the returned channel is signaled whenever Put or Replace
+// caches a new latest version, and when Delete evicts the instance.
+// Notifications are coalesced, so that a subscriber which is slow to receive
+// them is signaled once for several versions; subscribers should therefore
+// Get the latest entry when signaled. The returned function unsubscribes, and
+// must be called once the subscriber is done.
+func (c *RuleSetCache) Subscribe(instance string) (<-chan struct{}, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	if c.subscribers[instance] == nil {
+		c.subscribers[instance] = make(map[chan struct{}]struct{})
+	}
+	c.subscribers[instance][ch] = struct{}{}
+
+	return ch, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.subscribers[instance], ch)
+		if len(c.subscribers[instance]) == 0 {
+			delete(c.subscribers, instance)
+		}
+	}
+}
+
+// notify signals the subscribers of the given instance, without blocking on
+// those which haven't received their previous notification yet. The caller
+// must hold the write lock.
+func (c *RuleSetCache) notify(instance string) {
+	for ch := range c.subscribers[instance] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
 // -----------------------------------------------------------------------------
 // RuleSetCache - Cleanup
 // -----------------------------------------------------------------------------
 
 // Prune removes cache entries older than the specified age, but never removes
-// the latest entry for any instance
+// the latest or a pinned entry for any instance
 func (c *RuleSetCache) Prune(maxAge time.Duration) int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -169,6 +615,10 @@ func (c *RuleSetCache) Prune(maxAge time.Duration) int {
 				newEntries = append(newEntries, entry)
 				continue // never prune latest
 			}
+			if c.pinned[instance][entry.UUID] {
+				newEntries = append(newEntries, entry)
+				continue // never prune pinned
+			}
 
 			if now.Sub(entry.Timestamp) <= maxAge {
 				newEntries = append(newEntries, entry)
@@ -182,8 +632,10 @@ func (c *RuleSetCache) Prune(maxAge time.Duration) int {
 	return pruned
 }
 
-// PruneBySize removes oldest entries until cache is under maxSize. Iterates instances,
-// pruning from oldest to newest, but never removes the latest entry for any instance.
+// PruneBySize removes oldest entries until cache is under maxSize. Iterates instances
+// from the least recently accessed, so that actively served instances keep their
+// history under memory pressure, pruning each from oldest to newest, but never
+// removes the latest or a pinned entry for any instance.
 // Will log errors if the cache size cannot be reduced under maxSize.
 func (c *RuleSetCache) PruneBySize(maxSize int) int {
 	c.mu.Lock()
@@ -192,7 +644,7 @@ func (c *RuleSetCache) PruneBySize(maxSize int) int {
 	currentSize := 0
 	for _, entries := range c.entries {
 		for _, entry := range entries.Entries {
-			currentSize += len(entry.Rules)
+			currentSize += entry.size()
 		}
 	}
 
@@ -200,13 +652,26 @@ func (c *RuleSetCache) PruneBySize(maxSize int) int {
 		return 0
 	}
 
-	// Prune oldest entries from each instance until under size limit
+	// Prune oldest entries from each instance until under size limit, least
+	// recently accessed instances first.
 	// Entries are already ordered oldest to newest, so we can prune from the front
+	instances := make([]string, 0, len(c.entries))
+	for instance := range c.entries {
+		instances = append(instances, instance)
+	}
+	slices.SortFunc(instances, func(a, b string) int {
+		if byAccess := cmp.Compare(c.entries[a].lastAccessed.Load(), c.entries[b].lastAccessed.Load()); byAccess != 0 {
+			return byAccess
+		}
+		return strings.Compare(a, b)
+	})
+
 	pruned := 0
-	for instance, entries := range c.entries {
+	for _, instance := range instances {
 		if currentSize <= maxSize {
 			break
 		}
+		entries := c.entries[instance]
 
 		newEntries := make([]*RuleSetEntry, 0, len(entries.Entries))
 		for _, entry := range entries.Entries {
@@ -214,10 +679,14 @@ func (c *RuleSetCache) PruneBySize(maxSize int) int {
 				newEntries = append(newEntries, entry)
 				continue // never prune latest
 			}
+			if c.pinned[instance][entry.UUID] {
+				newEntries = append(newEntries, entry)
+				continue // never prune pinned
+			}
 
 			// If we're still over size, prune.
 			if currentSize > maxSize {
-				currentSize -= len(entry.Rules)
+				currentSize -= entry.size()
 				pruned++
 			} else {
 				// Under size now, keep the remainder.
@@ -17,15 +17,27 @@ limitations under the License.
 package cache
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/test/utils"
 )
 
 const skipCountAssertion = -1
 
+func TestKeyFor(t *testing.T) {
+	assert.Equal(t, "default/my-ruleset", KeyFor("default", "my-ruleset"))
+	assert.Equal(t, "default/engine:my-engine", KeyFor("default", "engine:my-engine"))
+}
+
 func TestRuleSetCache_PutAndGet(t *testing.T) {
 	cache := NewRuleSetCache()
 
@@ -111,10 +123,10 @@ func TestRuleSetCache_Pruning(t *testing.T) {
 				c.SetEntryTimestamp("instance1", 0, time.Now().Add(-2*time.Hour))
 				c.SetEntryTimestamp("instance2", 0, time.Now().Add(-1*time.Hour))
 			},
-			pruneMaxSize:  20,
+			pruneMaxSize:  2*testEntrySize("new1") + testEntrySize("rules3"),
 			expectedCount: skipCountAssertion,
 			verifyLatest: func(t *testing.T, c *RuleSetCache) {
-				assert.LessOrEqual(t, c.TotalSize(), 20)
+				assert.LessOrEqual(t, c.TotalSize(), 2*testEntrySize("new1")+testEntrySize("rules3"))
 				_, ok := c.Get("instance1")
 				assert.True(t, ok)
 				_, ok = c.Get("instance2")
@@ -198,6 +210,100 @@ func TestRuleSetCache_Pruning(t *testing.T) {
 	}
 }
 
+func TestRuleSetCache_MaxVersionsPerInstance(t *testing.T) {
+	cache := NewRuleSetCache()
+	cache.SetMaxVersionsPerInstance(10)
+
+	t.Log("Putting 50 versions of an instance")
+	for i := range 50 {
+		cache.Put("instance", fmt.Sprintf("rules v%d", i))
+	}
+
+	t.Log("Verifying only the newest versions up to the cap were retained")
+	entries := cache.ListEntries("instance")
+	require.Len(t, entries, 10)
+	for i, entry := range entries {
+		assert.Equal(t, fmt.Sprintf("rules v%d", 40+i), entry.Rules)
+	}
+	latest, ok := cache.Get("instance")
+	require.True(t, ok)
+	assert.Equal(t, "rules v49", latest.Rules)
+
+	t.Log("Verifying pinned versions are retained beyond the cap")
+	cache.SetPinned("engine", "instance", entries[0].UUID)
+	cache.Put("instance", "rules v50")
+	entries = cache.ListEntries("instance")
+	require.Len(t, entries, 10)
+	assert.Equal(t, "rules v40", entries[0].Rules)
+	assert.Equal(t, "rules v42", entries[1].Rules)
+	assert.Equal(t, "rules v50", entries[9].Rules)
+
+	t.Log("Verifying deleting the instance drops its pinned versions")
+	require.True(t, cache.Delete("instance"))
+	assert.NotContains(t, cache.pinned, "instance")
+	assert.NotContains(t, cache.pins, "engine")
+	for i := range 10 {
+		cache.Put("instance", fmt.Sprintf("rules v%d", i))
+	}
+
+	t.Log("Verifying no versions are dropped when unlimited")
+	cache.SetMaxVersionsPerInstance(0)
+	for i := range 5 {
+		cache.Put("instance", fmt.Sprintf("rules v%d", 51+i))
+	}
+	assert.Equal(t, 15, cache.CountEntries("instance"))
+}
+
+func TestRuleSetCache_PruneBySizeLeastRecentlyAccessed(t *testing.T) {
+	cache := NewRuleSetCache()
+
+	t.Log("Adding two versions for an active and an idle instance")
+	cache.Put("active", "active v1")
+	cache.Put("active", "active v2")
+	cache.Put("idle", "idle v1..")
+	cache.Put("idle", "idle v2..")
+
+	t.Log("Accessing only the active instance")
+	_, ok := cache.Get("active")
+	require.True(t, ok)
+
+	t.Log("Pruning just enough to drop a single version")
+	require.Equal(t, 1, cache.PruneBySize(cache.TotalSize()-1))
+	assert.Equal(t, 2, cache.CountEntries("active"), "active instance should keep its history")
+	assert.Equal(t, 1, cache.CountEntries("idle"), "idle instance should be pruned first")
+
+	t.Log("Verifying the least recently accessed instance is pruned first")
+	cache.Put("idle", "idle v3..")
+	_, ok = cache.Get("idle")
+	require.True(t, ok)
+	time.Sleep(10 * time.Millisecond)
+	_, ok = cache.Get("active")
+	require.True(t, ok)
+	require.Equal(t, 1, cache.PruneBySize(cache.TotalSize()-1))
+	assert.Equal(t, 2, cache.CountEntries("active"))
+	assert.Equal(t, 1, cache.CountEntries("idle"))
+
+	time.Sleep(10 * time.Millisecond)
+	_, ok = cache.Get("idle")
+	require.True(t, ok)
+	require.Equal(t, 1, cache.PruneBySize(cache.TotalSize()-1))
+	assert.Equal(t, 1, cache.CountEntries("active"))
+	assert.Equal(t, 1, cache.CountEntries("idle"))
+
+	t.Log("Verifying peeking doesn't count as an access")
+	cache.Put("active", "active v3")
+	cache.Put("idle", "idle v4..")
+	_, ok = cache.Get("active")
+	require.True(t, ok)
+	time.Sleep(10 * time.Millisecond)
+	entry, ok := cache.Peek("idle")
+	require.True(t, ok)
+	assert.Equal(t, "idle v4..", entry.Rules)
+	require.Equal(t, 1, cache.PruneBySize(cache.TotalSize()-1))
+	assert.Equal(t, 2, cache.CountEntries("active"), "peeked instance should still be pruned first")
+	assert.Equal(t, 1, cache.CountEntries("idle"))
+}
+
 func TestRuleSetCache_ListKeys(t *testing.T) {
 	cache := NewRuleSetCache()
 	keys := cache.ListKeys()
@@ -210,14 +316,77 @@ func TestRuleSetCache_ListKeys(t *testing.T) {
 	assert.ElementsMatch(t, []string{"instance1", "instance2", "instance3"}, keys)
 }
 
+func TestRuleSetCache_ListEntries(t *testing.T) {
+	cache := NewRuleSetCache()
+	assert.Nil(t, cache.ListEntries("non-existent"))
+
+	cache.Put("instance", "rules v1")
+	cache.Put("instance", "rules v2")
+	entries := cache.ListEntries("instance")
+	require.Len(t, entries, 2)
+	assert.Equal(t, "rules v1", entries[0].Rules)
+	assert.Equal(t, "rules v2", entries[1].Rules)
+
+	t.Log("Verifying returned entries are copies")
+	entries[0].Rules = "modified"
+	assert.Equal(t, "rules v1", cache.ListEntries("instance")[0].Rules)
+}
+
+func TestRuleSetCache_ServedVersion(t *testing.T) {
+	cache := NewRuleSetCache()
+	_, _, ok := cache.ServedVersion("non-existent")
+	assert.False(t, ok)
+
+	t.Log("Verifying nothing is served for a new instance")
+	cache.Put("instance", "rules v1")
+	v1, ok := cache.Get("instance")
+	require.True(t, ok)
+	served, latest, ok := cache.ServedVersion("instance")
+	require.True(t, ok)
+	assert.Empty(t, served)
+	assert.Equal(t, v1.UUID, latest)
+
+	t.Log("Verifying the served version trails the latest until served")
+	cache.MarkServed("instance", v1.UUID)
+	cache.Put("instance", "rules v2")
+	v2, ok := cache.Get("instance")
+	require.True(t, ok)
+	served, latest, ok = cache.ServedVersion("instance")
+	require.True(t, ok)
+	assert.Equal(t, v1.UUID, served)
+	assert.Equal(t, v2.UUID, latest)
+
+	cache.MarkServed("instance", v2.UUID)
+	served, _, _ = cache.ServedVersion("instance")
+	assert.Equal(t, v2.UUID, served)
+
+	t.Log("Verifying marking an unknown instance is a no-op")
+	cache.MarkServed("non-existent", v2.UUID)
+	_, _, ok = cache.ServedVersion("non-existent")
+	assert.False(t, ok)
+}
+
 func TestRuleSetCache_TotalSize(t *testing.T) {
 	cache := NewRuleSetCache()
 	assert.Equal(t, 0, cache.TotalSize())
 	cache.Put("instance1", "12345")
 	cache.Put("instance2", "1234567890")
-	assert.Equal(t, 15, cache.TotalSize())
+	assert.Equal(t, testEntrySize("12345")+testEntrySize("1234567890"), cache.TotalSize())
 	cache.Put("instance1", "123")
-	assert.Equal(t, 18, cache.TotalSize())
+	assert.Equal(t, testEntrySize("12345")+testEntrySize("1234567890")+testEntrySize("123"), cache.TotalSize())
+
+	t.Log("Verifying per-entry overhead dominates for many tiny versions")
+	cache = NewRuleSetCache()
+	for i := range 1000 {
+		cache.Put("instance", []string{"x", "y"}[i%2])
+	}
+	assert.Equal(t, 1000*testEntrySize("x"), cache.TotalSize())
+	assert.Greater(t, cache.TotalSize(), 100*1000, "UUIDs and overhead should be accounted for")
+}
+
+// testEntrySize returns the size accounted for an entry with the given rules.
+func testEntrySize(rules string) int {
+	return len(rules) + len(uuid.New().String()) + entryOverhead
 }
 
 func TestRuleSetCache_PutUpdatesUUID(t *testing.T) {
@@ -231,6 +400,64 @@ func TestRuleSetCache_PutUpdatesUUID(t *testing.T) {
 	assert.NotEqual(t, entry1.UUID, entry2.UUID, "UUID should change on update")
 	assert.NotEqual(t, entry1.Timestamp, entry2.Timestamp, "Timestamp should change on update")
 	assert.Equal(t, "rules v2", entry2.Rules)
+
+	t.Log("Verifying putting the same content keeps the version stable")
+	time.Sleep(10 * time.Millisecond)
+	cache.Put(instance, "rules v2")
+	entry3, _ := cache.Get(instance)
+	assert.Equal(t, entry2.UUID, entry3.UUID, "UUID should not change for identical rules")
+	assert.Equal(t, entry2.Timestamp, entry3.Timestamp, "Timestamp should not change for identical rules")
+	assert.Equal(t, 2, cache.CountEntries(instance))
+
+	t.Log("Verifying changed content rotates the version")
+	cache.Put(instance, "rules v3")
+	entry4, _ := cache.Get(instance)
+	assert.NotEqual(t, entry2.UUID, entry4.UUID, "UUID should change when rules change")
+	assert.Equal(t, "rules v3", entry4.Rules)
+	assert.Equal(t, 3, cache.CountEntries(instance))
+
+	t.Log("Verifying content matching an older version still creates a new version")
+	cache.Put(instance, "rules v1")
+	entry5, _ := cache.Get(instance)
+	assert.NotEqual(t, entry1.UUID, entry5.UUID, "only the latest version is reused")
+	assert.Equal(t, 4, cache.CountEntries(instance))
+}
+
+func TestRuleSetCache_Subscribe(t *testing.T) {
+	cache := NewRuleSetCache()
+	updates, unsubscribe := cache.Subscribe("test-instance")
+
+	notified := func() bool {
+		select {
+		case <-updates:
+			return true
+		default:
+			return false
+		}
+	}
+
+	t.Log("Verifying new versions of the instance notify subscribers")
+	cache.Put("test-instance", "rules v1")
+	assert.True(t, notified())
+	assert.False(t, notified())
+
+	t.Log("Verifying unchanged rules and other instances don't notify subscribers")
+	cache.Put("test-instance", "rules v1")
+	cache.Put("other-instance", "rules v1")
+	assert.False(t, notified())
+
+	t.Log("Verifying notifications are coalesced without blocking Put")
+	cache.Put("test-instance", "rules v2")
+	cache.Put("test-instance", "rules v3")
+	cache.Replace("test-instance", "rules v4")
+	assert.True(t, notified())
+	assert.False(t, notified())
+
+	t.Log("Verifying unsubscribed subscribers aren't notified")
+	unsubscribe()
+	cache.Put("test-instance", "rules v5")
+	assert.False(t, notified())
+	assert.Empty(t, cache.subscribers)
 }
 
 func TestRuleSetCache_GetNonExistent(t *testing.T) {
@@ -239,3 +466,136 @@ func TestRuleSetCache_GetNonExistent(t *testing.T) {
 	assert.False(t, ok)
 	assert.Nil(t, entry)
 }
+
+func TestRuleSetCache_Replace(t *testing.T) {
+	cache := NewRuleSetCache()
+	instance := "test-instance"
+
+	t.Log("Building up version history with Put")
+	cache.Put(instance, "rules v1")
+	cache.Put(instance, "rules v2")
+	cache.Put(instance, "rules v3")
+	require.Equal(t, 3, cache.CountEntries(instance))
+	previous, ok := cache.Get(instance)
+	require.True(t, ok)
+
+	t.Log("Replacing all versions with a single fresh entry")
+	cache.Replace(instance, "rules v4")
+
+	t.Log("Verifying prior versions are gone and the new entry is latest")
+	assert.Equal(t, 1, cache.CountEntries(instance))
+	entry, ok := cache.Get(instance)
+	require.True(t, ok)
+	assert.Equal(t, "rules v4", entry.Rules)
+	assert.NotEqual(t, previous.UUID, entry.UUID)
+	assert.Equal(t, testEntrySize("rules v4"), cache.TotalSize())
+
+	t.Log("Replacing an unknown instance creates it")
+	cache.Replace("new-instance", "fresh rules")
+	assert.Equal(t, 1, cache.CountEntries("new-instance"))
+	entry, ok = cache.Get("new-instance")
+	require.True(t, ok)
+	assert.Equal(t, "fresh rules", entry.Rules)
+}
+
+func TestRuleSetCache_ReplaceKeepsPinnedVersions(t *testing.T) {
+	cache := NewRuleSetCache()
+	instance := "test-instance"
+
+	t.Log("Building up version history and pinning the oldest version")
+	cache.Put(instance, "rules v1")
+	pinned, ok := cache.Get(instance)
+	require.True(t, ok)
+	cache.Put(instance, "rules v2")
+	cache.SetPinned("engine", instance, pinned.UUID)
+
+	t.Log("Replacing the versions with a fresh entry")
+	cache.Replace(instance, "rules v3")
+
+	t.Log("Verifying the pinned version is kept alongside the new latest")
+	entries := cache.ListEntries(instance)
+	require.Len(t, entries, 2)
+	assert.Equal(t, pinned.UUID, entries[0].UUID)
+	assert.Equal(t, "rules v3", entries[1].Rules)
+	entry, ok := cache.GetByUUID(instance, pinned.UUID)
+	require.True(t, ok)
+	assert.Equal(t, "rules v1", entry.Rules)
+	latest, ok := cache.Get(instance)
+	require.True(t, ok)
+	assert.Equal(t, "rules v3", latest.Rules)
+}
+
+func TestRuleSetCache_RestoreMissingSnapshot(t *testing.T) {
+	cache := NewRuleSetCache()
+	cache.Put("test-instance", "rules v1")
+
+	t.Log("Restoring from a missing snapshot leaves the cache unchanged")
+	err := cache.Restore(filepath.Join(t.TempDir(), "missing.json"), utils.NewTestLogger(t))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, 1, cache.CountEntries("test-instance"))
+}
+
+func TestRuleSetCache_RestoreCorruptSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	snapshot := NewRuleSetCache()
+	snapshot.Put("ns/valid", "valid rules")
+	require.NoError(t, snapshot.Snapshot(path))
+	valid, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	t.Log("Adding malformed instances to the snapshot")
+	corrupt := strings.TrimSuffix(string(valid), "}") + `,` +
+		`"ns/null":null,` +
+		`"ns/empty":{"latest":"","entries":[]},` +
+		`"ns/null-entry":{"latest":"a","entries":[null]},` +
+		`"ns/dangling":{"latest":"missing","entries":[{"uuid":"a","rules":"rules"}]}}`
+	require.NoError(t, os.WriteFile(path, []byte(corrupt), 0o600))
+
+	t.Log("Verifying only the valid instance is restored")
+	cache := NewRuleSetCache()
+	require.NoError(t, cache.Restore(path, utils.NewTestLogger(t)))
+	assert.Equal(t, []string{"ns/valid"}, cache.ListKeys())
+	entry, ok := cache.Get("ns/valid")
+	require.True(t, ok)
+	assert.Equal(t, "valid rules", entry.Rules)
+	assert.Equal(t, 0, cache.PruneRestored(func(instance string) bool { return instance == "ns/valid" }))
+
+	t.Log("Verifying a null snapshot leaves an empty, usable cache")
+	require.NoError(t, os.WriteFile(path, []byte("null"), 0o600))
+	require.NoError(t, cache.Restore(path, utils.NewTestLogger(t)))
+	assert.Empty(t, cache.ListKeys())
+	cache.Put("ns/new", "new rules")
+	assert.Equal(t, 1, cache.CountEntries("ns/new"))
+}
+
+func TestRuleSetCache_PruneRestored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	snapshot := NewRuleSetCache()
+	snapshot.Put("ns/deleted", "deleted rules")
+	snapshot.Put("ns/existing", "existing rules")
+	snapshot.Put("ns/recreated", "recreated rules")
+	require.NoError(t, snapshot.Snapshot(path))
+
+	t.Log("Restoring the snapshot and storing one instance again")
+	cache := NewRuleSetCache()
+	require.NoError(t, cache.Restore(path, utils.NewTestLogger(t)))
+	cache.Put("ns/recreated", "recreated rules")
+	updates, cancel := cache.Subscribe("ns/deleted")
+	defer cancel()
+
+	t.Log("Pruning restored instances which are no longer wanted")
+	pruned := cache.PruneRestored(func(instance string) bool { return instance == "ns/existing" })
+	assert.Equal(t, 1, pruned)
+	assert.Equal(t, 0, cache.CountEntries("ns/deleted"))
+	assert.Equal(t, 1, cache.CountEntries("ns/existing"))
+	assert.Equal(t, 1, cache.CountEntries("ns/recreated"))
+	select {
+	case <-updates:
+	default:
+		t.Fatal("expected subscribers of the pruned instance to be signaled")
+	}
+
+	t.Log("Verifying restored instances are only pruned once")
+	assert.Equal(t, 0, cache.PruneRestored(func(string) bool { return false }))
+	assert.Equal(t, 1, cache.CountEntries("ns/existing"))
+}
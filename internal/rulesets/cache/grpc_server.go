@@ -0,0 +1,248 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets/cache/cachepb"
+)
+
+// -----------------------------------------------------------------------------
+// RuleSetCacheServer - gRPC
+// -----------------------------------------------------------------------------
+
+// WithGRPC additionally serves the RuleSetCache gRPC service (see
+// cachepb/cache.proto) on the given address, for data planes which prefer
+// to have new versions of their rules pushed to them over polling the HTTP
+// endpoints. The service shares the server's readiness gate, auth token and
+// TLS configuration, and counts its requests in the same metrics.
+func (s *ruleSetCacheServer) WithGRPC(addr string) *ruleSetCacheServer {
+	s.grpcAddr = addr
+	return s
+}
+
+// newGRPCServer creates a gRPC server serving the RuleSetCache service, whose
+// streaming RPCs end once done is closed.
+func (s *ruleSetCacheServer) newGRPCServer(done <-chan struct{}) (*grpc.Server, error) {
+	var opts []grpc.ServerOption
+	if s.tlsCertFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(s.tlsCertFile, s.tlsKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	srv := grpc.NewServer(opts...)
+	cachepb.RegisterRuleSetCacheServer(srv, &grpcService{s: s, done: done})
+	return srv, nil
+}
+
+// serveGRPC listens on the gRPC address and serves the gRPC service in the
+// background, sending any error serving it to errChan.
+func (s *ruleSetCacheServer) serveGRPC(errChan chan<- error) error {
+	s.grpcDone = make(chan struct{})
+	srv, err := s.newGRPCServer(s.grpcDone)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", s.grpcAddr)
+	if err != nil {
+		return err
+	}
+	s.grpcSrv = srv
+
+	go func() {
+		s.logger.Info("Starting ruleset cache gRPC server", "addr", s.grpcAddr)
+		if err := srv.Serve(listener); err != nil {
+			errChan <- err
+		}
+	}()
+
+	return nil
+}
+
+// stopGRPC ends the streaming RPCs and gracefully stops the gRPC server,
+// forcing it to stop if in-flight RPCs don't complete before ctx is done.
+func (s *ruleSetCacheServer) stopGRPC(ctx context.Context) {
+	if s.grpcSrv == nil {
+		return
+	}
+
+	close(s.grpcDone)
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcSrv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.logger.Error(ctx.Err(), "Error during graceful gRPC shutdown, forcing stop")
+		s.grpcSrv.Stop()
+	}
+}
+
+// grpcAuthorize checks that the RPC bears the auth token, or the token of the
+// requested instance (see InstanceToken), in its "authorization" metadata,
+// as "Bearer <token>", if an auth token is required.
+func (s *ruleSetCacheServer) grpcAuthorize(ctx context.Context, instance string) error {
+	if s.authToken == "" {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, authorization := range md.Get("authorization") {
+		if s.authorizes(authorization, instance) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "Unauthorized")
+}
+
+// -----------------------------------------------------------------------------
+// RuleSetCacheServer - gRPC Service
+// -----------------------------------------------------------------------------
+
+// grpcService implements the RuleSetCache gRPC service on the server's cache.
+type grpcService struct {
+	cachepb.UnimplementedRuleSetCacheServer
+
+	s *ruleSetCacheServer
+
+	// done is closed when the server shuts down.
+	done <-chan struct{}
+}
+
+// GetRules implements cachepb.RuleSetCacheServer.
+func (g *grpcService) GetRules(ctx context.Context, req *cachepb.GetRulesRequest) (*cachepb.RuleSetEntry, error) {
+	if err := g.checkRequest(ctx, req.GetInstance()); err != nil {
+		return nil, err
+	}
+
+	var entry *RuleSetEntry
+	var ok bool
+	if req.GetUuid() != "" {
+		entry, ok = g.s.cache.GetByUUID(req.GetInstance(), req.GetUuid())
+	} else {
+		entry, ok = g.s.cache.Get(req.GetInstance())
+	}
+	if !ok {
+		g.s.metrics.observeRequest(requestResultNotFound)
+		return nil, status.Error(codes.NotFound, "RuleSet not found")
+	}
+
+	g.s.metrics.observeRequest(requestResultHit)
+	g.s.cache.MarkServed(req.GetInstance(), entry.UUID)
+	return entryMessage(entry), nil
+}
+
+// GetLatest implements cachepb.RuleSetCacheServer.
+func (g *grpcService) GetLatest(ctx context.Context, req *cachepb.GetLatestRequest) (*cachepb.LatestResponse, error) {
+	if err := g.checkRequest(ctx, req.GetInstance()); err != nil {
+		return nil, err
+	}
+
+	entry, ok := g.s.cache.Get(req.GetInstance())
+	if !ok {
+		g.s.metrics.observeRequest(requestResultNotFound)
+		return nil, status.Error(codes.NotFound, "RuleSet not found")
+	}
+
+	g.s.metrics.observeRequest(requestResultHit)
+	return &cachepb.LatestResponse{
+		Uuid:      entry.UUID,
+		Timestamp: entry.Timestamp.Format(TimestampFormat),
+	}, nil
+}
+
+// WatchRules implements cachepb.RuleSetCacheServer. The latest rules are
+// sent when the watch starts, unless the client already holds them, and
+// whenever a new version is cached thereafter. The watch ends with NotFound
+// once an instance the client holds rules of is evicted.
+func (g *grpcService) WatchRules(req *cachepb.WatchRulesRequest, stream grpc.ServerStreamingServer[cachepb.RuleSetEntry]) error {
+	if err := g.checkRequest(stream.Context(), req.GetInstance()); err != nil {
+		return err
+	}
+
+	// Subscribe before getting the latest rules, so that no version cached
+	// in between is missed.
+	updates, unsubscribe := g.s.cache.Subscribe(req.GetInstance())
+	defer unsubscribe()
+
+	sent := req.GetSince()
+	for {
+		entry, ok := g.s.cache.Get(req.GetInstance())
+		switch {
+		case ok && entry.UUID != sent:
+			g.s.metrics.observeRequest(requestResultHit)
+			g.s.cache.MarkServed(req.GetInstance(), entry.UUID)
+			if err := stream.Send(entryMessage(entry)); err != nil {
+				return err
+			}
+			sent = entry.UUID
+		case !ok && sent != "":
+			g.s.metrics.observeRequest(requestResultNotFound)
+			return status.Error(codes.NotFound, "RuleSet not found")
+		}
+
+		select {
+		case <-updates:
+		case <-g.done:
+			return status.Error(codes.Unavailable, "RuleSet cache server shutting down")
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+	}
+}
+
+// checkRequest validates the instance of a request, authorizes it for the
+// instance, and fails it while the cache isn't ready yet. Authorization is
+// checked here rather than in an interceptor, as instance tokens only grant
+// access to the requested instance.
+func (g *grpcService) checkRequest(ctx context.Context, instance string) error {
+	if instance == "" {
+		return status.Error(codes.InvalidArgument, "RuleSet key required")
+	}
+	if err := g.s.grpcAuthorize(ctx, instance); err != nil {
+		return err
+	}
+	if g.s.ready != nil && !g.s.ready.Ready() {
+		g.s.metrics.observeRequest(requestResultMiss)
+		return status.Error(codes.Unavailable, "RuleSet cache not ready")
+	}
+	return nil
+}
+
+// entryMessage converts a cache entry to its gRPC message.
+func entryMessage(entry *RuleSetEntry) *cachepb.RuleSetEntry {
+	return &cachepb.RuleSetEntry{
+		Uuid:      entry.UUID,
+		Timestamp: entry.Timestamp.Format(TimestampFormat),
+		Rules:     entry.Rules,
+	}
+}
@@ -0,0 +1,313 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets/cache/cachepb"
+	"github.com/networking-incubator/coraza-kubernetes-operator/test/utils"
+)
+
+func TestGRPCService_GetRules(t *testing.T) {
+	cache := NewRuleSetCache()
+	client := newTestGRPCClient(t, NewServer(cache, testServerAddr, utils.NewTestLogger(t), nil))
+	ctx := context.Background()
+
+	t.Log("Verifying instances which aren't cached are not found")
+	_, err := client.GetRules(ctx, &cachepb.GetRulesRequest{Instance: "test-instance"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetRules(ctx, &cachepb.GetRulesRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	t.Log("Verifying the latest rules are returned and marked as served")
+	cache.Put("test-instance", "SecRuleEngine DetectionOnly")
+	first, ok := cache.Get("test-instance")
+	require.True(t, ok)
+	cache.Put("test-instance", "SecRuleEngine On")
+	latest, ok := cache.Get("test-instance")
+	require.True(t, ok)
+	entry, err := client.GetRules(ctx, &cachepb.GetRulesRequest{Instance: "test-instance"})
+	require.NoError(t, err)
+	assert.Equal(t, latest.UUID, entry.GetUuid())
+	assert.Equal(t, "SecRuleEngine On", entry.GetRules())
+	assert.Equal(t, latest.Timestamp.Format(TimestampFormat), entry.GetTimestamp())
+	served, _, _ := cache.ServedVersion("test-instance")
+	assert.Equal(t, latest.UUID, served)
+
+	t.Log("Verifying a specific version can be selected")
+	entry, err = client.GetRules(ctx, &cachepb.GetRulesRequest{Instance: "test-instance", Uuid: first.UUID})
+	require.NoError(t, err)
+	assert.Equal(t, "SecRuleEngine DetectionOnly", entry.GetRules())
+	_, err = client.GetRules(ctx, &cachepb.GetRulesRequest{Instance: "test-instance", Uuid: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCService_GetLatest(t *testing.T) {
+	cache := NewRuleSetCache()
+	client := newTestGRPCClient(t, NewServer(cache, testServerAddr, utils.NewTestLogger(t), nil))
+	ctx := context.Background()
+
+	_, err := client.GetLatest(ctx, &cachepb.GetLatestRequest{Instance: "test-instance"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	cache.Put("test-instance", "SecRuleEngine On")
+	latest, ok := cache.Get("test-instance")
+	require.True(t, ok)
+	response, err := client.GetLatest(ctx, &cachepb.GetLatestRequest{Instance: "test-instance"})
+	require.NoError(t, err)
+	assert.Equal(t, latest.UUID, response.GetUuid())
+	assert.Equal(t, latest.Timestamp.Format(TimestampFormat), response.GetTimestamp())
+}
+
+func TestGRPCService_WatchRules(t *testing.T) {
+	cache := NewRuleSetCache()
+	client := newTestGRPCClient(t, NewServer(cache, testServerAddr, utils.NewTestLogger(t), nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Log("Watching an instance which isn't cached yet")
+	stream, err := client.WatchRules(ctx, &cachepb.WatchRulesRequest{Instance: "test-instance"})
+	require.NoError(t, err)
+	entries := make(chan *cachepb.RuleSetEntry)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			entry, err := stream.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			entries <- entry
+		}
+	}()
+
+	t.Log("Verifying the rules are pushed once they are cached")
+	cache.Put("test-instance", "SecRuleEngine DetectionOnly")
+	first := receiveEntry(t, entries)
+	assert.Equal(t, "SecRuleEngine DetectionOnly", first.GetRules())
+
+	t.Log("Verifying identical rules are not pushed again")
+	cache.Put("test-instance", "SecRuleEngine DetectionOnly")
+	cache.Put("other-instance", "SecRuleEngine On")
+	select {
+	case entry := <-entries:
+		t.Fatalf("unexpected entry pushed: %v", entry)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	t.Log("Verifying new versions are pushed")
+	cache.Put("test-instance", "SecRuleEngine On")
+	second := receiveEntry(t, entries)
+	assert.Equal(t, "SecRuleEngine On", second.GetRules())
+	assert.NotEqual(t, first.GetUuid(), second.GetUuid())
+	served, _, _ := cache.ServedVersion("test-instance")
+	assert.Equal(t, second.GetUuid(), served)
+
+	t.Log("Verifying the version the client holds isn't sent when the watch starts")
+	sinceStream, err := client.WatchRules(ctx, &cachepb.WatchRulesRequest{Instance: "test-instance", Since: second.GetUuid()})
+	require.NoError(t, err)
+	sinceEntries := make(chan *cachepb.RuleSetEntry)
+	go func() {
+		for {
+			entry, err := sinceStream.Recv()
+			if err != nil {
+				return
+			}
+			sinceEntries <- entry
+		}
+	}()
+	select {
+	case entry := <-sinceEntries:
+		t.Fatalf("unexpected entry pushed: %v", entry)
+	case <-time.After(100 * time.Millisecond):
+	}
+	cache.Replace("test-instance", "SecRuleEngine Off")
+	assert.Equal(t, "SecRuleEngine Off", receiveEntry(t, sinceEntries).GetRules())
+	assert.Equal(t, "SecRuleEngine Off", receiveEntry(t, entries).GetRules())
+
+	t.Log("Verifying cancelling the watch ends the stream")
+	cancel()
+	select {
+	case err := <-errs:
+		assert.Equal(t, codes.Canceled, status.Code(err))
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch did not end")
+	}
+}
+
+func TestGRPCService_WatchRulesEvicted(t *testing.T) {
+	cache := NewRuleSetCache()
+	client := newTestGRPCClient(t, NewServer(cache, testServerAddr, utils.NewTestLogger(t), nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Log("Watching a cached instance")
+	cache.Put("test-instance", "SecRuleEngine On")
+	stream, err := client.WatchRules(ctx, &cachepb.WatchRulesRequest{Instance: "test-instance"})
+	require.NoError(t, err)
+	entry, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "SecRuleEngine On", entry.GetRules())
+
+	t.Log("Verifying evicting the instance ends the watch with NotFound")
+	require.True(t, cache.Delete("test-instance"))
+	errs := make(chan error, 1)
+	go func() {
+		_, err := stream.Recv()
+		errs <- err
+	}()
+	select {
+	case err := <-errs:
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch did not end")
+	}
+}
+
+func TestGRPCService_AuthAndReadiness(t *testing.T) {
+	cache := NewRuleSetCache()
+	cache.Put("test-instance", "SecRuleEngine On")
+	gate := NewReadinessGate()
+	server := NewServer(cache, testServerAddr, utils.NewTestLogger(t), nil).
+		WithAuthToken("secret").
+		WithReadinessGate(gate)
+	client := newTestGRPCClient(t, server)
+	ctx := context.Background()
+	authorized := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+
+	t.Log("Verifying RPCs without the auth token are rejected")
+	_, err := client.GetRules(ctx, &cachepb.GetRulesRequest{Instance: "test-instance"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.GetRules(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer wrong"), &cachepb.GetRulesRequest{Instance: "test-instance"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	stream, err := client.WatchRules(ctx, &cachepb.WatchRulesRequest{Instance: "test-instance"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	t.Log("Verifying RPCs fail while the cache isn't ready")
+	_, err = client.GetRules(authorized, &cachepb.GetRulesRequest{Instance: "test-instance"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	t.Log("Verifying authorized RPCs succeed once the cache is ready")
+	gate.SetReady()
+	entry, err := client.GetRules(authorized, &cachepb.GetRulesRequest{Instance: "test-instance"})
+	require.NoError(t, err)
+	assert.Equal(t, "SecRuleEngine On", entry.GetRules())
+
+	t.Log("Verifying an instance token only grants access to its instance's rules")
+	cache.Put("other-instance", "SecRuleEngine DetectionOnly")
+	instanceAuthorized := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+InstanceToken("secret", "test-instance"))
+	entry, err = client.GetRules(instanceAuthorized, &cachepb.GetRulesRequest{Instance: "test-instance"})
+	require.NoError(t, err)
+	assert.Equal(t, "SecRuleEngine On", entry.GetRules())
+	_, err = client.GetRules(instanceAuthorized, &cachepb.GetRulesRequest{Instance: "other-instance"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	stream, err = client.WatchRules(instanceAuthorized, &cachepb.WatchRulesRequest{Instance: "other-instance"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_GRPCShutdownEndsWatches(t *testing.T) {
+	cache := NewRuleSetCache()
+	cache.Put("test-instance", "SecRuleEngine On")
+	grpcAddr := freeAddr(t)
+	server := NewServer(cache, freeAddr(t), utils.NewTestLogger(t), nil).WithGRPC(grpcAddr)
+
+	t.Log("Starting server in background goroutine")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Start(ctx)
+	}()
+
+	t.Log("Watching rules over gRPC")
+	conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	var stream grpc.ServerStreamingClient[cachepb.RuleSetEntry]
+	require.Eventually(t, func() bool {
+		stream, err = cachepb.NewRuleSetCacheClient(conn).WatchRules(context.Background(), &cachepb.WatchRulesRequest{Instance: "test-instance"}, grpc.WaitForReady(true))
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	entry, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "SecRuleEngine On", entry.GetRules())
+
+	t.Log("Verifying shutdown ends the watch rather than waiting on it")
+	cancel()
+	select {
+	case err := <-errChan:
+		require.NoError(t, err)
+	case <-time.After(GracefulShutdownTimeout / 2):
+		t.Fatal("Server did not shut down in time")
+	}
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+// newTestGRPCClient serves the server's gRPC service over an in-process
+// listener until the test ends, returning a client connected to it.
+func newTestGRPCClient(t *testing.T, server *ruleSetCacheServer) cachepb.RuleSetCacheClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	srv, err := server.newGRPCServer(make(chan struct{}))
+	require.NoError(t, err)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return cachepb.NewRuleSetCacheClient(conn)
+}
+
+// receiveEntry waits for an entry pushed to a watch.
+func receiveEntry(t *testing.T, entries <-chan *cachepb.RuleSetEntry) *cachepb.RuleSetEntry {
+	t.Helper()
+	select {
+	case entry := <-entries:
+		return entry
+	case <-time.After(2 * time.Second):
+		t.Fatal("no entry pushed")
+		return nil
+	}
+}
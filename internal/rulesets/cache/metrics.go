@@ -0,0 +1,143 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// -----------------------------------------------------------------------------
+// Metrics - Consts
+// -----------------------------------------------------------------------------
+
+// MetricsPath is the path the cache server serves its Prometheus metrics on.
+const MetricsPath = "/metrics"
+
+// Values of the "result" label of the requests counter.
+const (
+	// requestResultHit is a request served from the cache.
+	requestResultHit = "hit"
+
+	// requestResultMiss is a request the cache could not answer because it
+	// was not yet ready.
+	requestResultMiss = "miss"
+
+	// requestResultNotFound is a request for an instance which is not cached.
+	requestResultNotFound = "notfound"
+)
+
+// Values of the "strategy" label of the GC pruned counter.
+const (
+	gcStrategyAge  = "age"
+	gcStrategySize = "size"
+)
+
+// -----------------------------------------------------------------------------
+// Metrics
+// -----------------------------------------------------------------------------
+
+// serverMetrics holds the Prometheus metrics of a cache server. Each server
+// has its own registry, so that the metrics are available when the server
+// runs standalone as well as within the operator.
+type serverMetrics struct {
+	registry     *prometheus.Registry
+	requests     *prometheus.CounterVec
+	gcPruned     *prometheus.CounterVec
+	overCapacity prometheus.Gauge
+}
+
+// newServerMetrics creates and registers the metrics for a cache server
+// serving the given cache.
+func newServerMetrics(cache *RuleSetCache) *serverMetrics {
+	m := &serverMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "coraza_cache_requests_total",
+			Help: "Total number of rules requests served by the cache server, by result.",
+		}, []string{"result"}),
+		gcPruned: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "coraza_cache_gc_pruned_total",
+			Help: "Total number of cache entries removed by garbage collection, by strategy.",
+		}, []string{"strategy"}),
+		overCapacity: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "coraza_cache_over_capacity",
+			Help: "Whether (1) or not (0) the cache exceeded its max size after the last garbage collection cycle.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.gcPruned,
+		m.overCapacity,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "coraza_cache_entries",
+			Help: "Number of entries (versions) currently held in the cache.",
+		}, func() float64 {
+			entries := 0
+			for _, instance := range cache.ListKeys() {
+				entries += cache.CountEntries(instance)
+			}
+			return float64(entries)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "coraza_cache_bytes",
+			Help: "Approximate total size in bytes of all entries currently held in the cache.",
+		}, func() float64 {
+			return float64(cache.TotalSize())
+		}),
+	)
+
+	// Pre-initialize all label values so the series are exported (at zero)
+	// before the first request or GC cycle, which keeps rate() alerts sane.
+	for _, result := range []string{requestResultHit, requestResultMiss, requestResultNotFound} {
+		m.requests.WithLabelValues(result)
+	}
+	for _, strategy := range []string{gcStrategyAge, gcStrategySize} {
+		m.gcPruned.WithLabelValues(strategy)
+	}
+
+	return m
+}
+
+// handler returns the HTTP handler exposing the metrics.
+func (m *serverMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// observeRequest counts a rules request with the given result.
+func (m *serverMetrics) observeRequest(result string) {
+	m.requests.WithLabelValues(result).Inc()
+}
+
+// observePruned counts entries removed by garbage collection using the given
+// strategy.
+func (m *serverMetrics) observePruned(strategy string, count int) {
+	m.gcPruned.WithLabelValues(strategy).Add(float64(count))
+}
+
+// observeOverCapacity records whether the cache exceeded its max size after
+// a garbage collection cycle.
+func (m *serverMetrics) observeOverCapacity(overCapacity bool) {
+	if overCapacity {
+		m.overCapacity.Set(1)
+		return
+	}
+	m.overCapacity.Set(0)
+}
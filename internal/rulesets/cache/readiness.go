@@ -0,0 +1,48 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync/atomic"
+)
+
+// -----------------------------------------------------------------------------
+// ReadinessGate
+// -----------------------------------------------------------------------------
+
+// ReadinessGate signals whether the cache has been populated and is ready to
+// serve rules. Until the gate is marked ready, the cache server responds to
+// rules requests with 503 Service Unavailable so that clients retry rather
+// than treating a not-yet-populated instance as a definitive 404.
+type ReadinessGate struct {
+	ready atomic.Bool
+}
+
+// NewReadinessGate creates a new ReadinessGate which is not yet ready.
+func NewReadinessGate() *ReadinessGate {
+	return &ReadinessGate{}
+}
+
+// SetReady marks the gate as ready. It is safe to call more than once.
+func (g *ReadinessGate) SetReady() {
+	g.ready.Store(true)
+}
+
+// Ready reports whether the gate has been marked ready.
+func (g *ReadinessGate) Ready() bool {
+	return g.ready.Load()
+}
@@ -17,20 +17,35 @@ limitations under the License.
 package cache
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
+	"golang.org/x/net/netutil"
+	"google.golang.org/grpc"
 )
 
 // -----------------------------------------------------------------------------
 // Constants
 // -----------------------------------------------------------------------------
 
+// DefaultPort is the default port number the cache server listens on
+const DefaultPort = 18080
+
 // TimestampFormat is the RFC3339 format with milliseconds used for all timestamps
 const TimestampFormat = time.RFC3339Nano
 
@@ -40,18 +55,59 @@ const CacheGCInterval = 5 * time.Minute
 // CacheMaxAge is the maximum age of a cache entry before it's considered stale
 const CacheMaxAge = 24 * time.Hour
 
-// CacheMaxSize is the maximum total size of all cached rules in bytes (100MB)
+// CacheMaxSize is the maximum total size of all cache entries in bytes (100MB)
 const CacheMaxSize = 100 * 1024 * 1024
 
+// CacheMaxVersionsPerInstance is the maximum number of versions retained per
+// instance
+const CacheMaxVersionsPerInstance = 10
+
 // MaxHeaderSize is the maximum size of HTTP request headers (64KB)
 const MaxHeaderSize = 64 * 1024
 
 // MaxBodySize is the maximum size of HTTP request bodies (0 bytes - no body expected)
 const MaxBodySize = 0
 
+// MaxAdminBodySize is the maximum size of rules accepted by the admin PUT
+// endpoint (16MB)
+const MaxAdminBodySize = 16 * 1024 * 1024
+
+// DefaultRequestTimeout is the max time a handler may take to respond before
+// the request fails with 503 Service Unavailable
+const DefaultRequestTimeout = 30 * time.Second
+
+// DefaultReadTimeout is the max time to read a request, including its body,
+// which bounds how long slow clients can hold a connection open while
+// sending a request (e.g. slowloris)
+const DefaultReadTimeout = 30 * time.Second
+
+// WriteTimeoutGrace is added to the request timeout to derive the max time to
+// write a response, leaving time to send the response buffered by the
+// request timeout to clients
+const WriteTimeoutGrace = 10 * time.Second
+
+// DefaultIdleTimeout is the max time an idle keep-alive connection is kept
+// open between requests
+const DefaultIdleTimeout = 2 * time.Minute
+
+// MaxLongPollWait is the max time a long-polling rules request waits for a
+// new version. Waits are furthermore cut short ahead of the request timeout.
+const MaxLongPollWait = 5 * time.Minute
+
+// LongPollResponseMargin is how long ahead of the request timeout a
+// long-polling rules request stops waiting, leaving time to respond.
+const LongPollResponseMargin = time.Second
+
 // GracefulShutdownTimeout is the max time to drain existing connections on shutdown
 const GracefulShutdownTimeout = 10 * time.Second
 
+// NotReadyRetryAfterSeconds is the Retry-After value sent to clients while
+// the cache is not yet ready
+const NotReadyRetryAfterSeconds = "1"
+
+// StatsPath is the path the cache server serves a summary of the cache on.
+const StatsPath = "/stats"
+
 // -----------------------------------------------------------------------------
 // API Response Types
 // -----------------------------------------------------------------------------
@@ -62,18 +118,83 @@ type LatestResponse struct {
 	Timestamp string `json:"timestamp"`
 }
 
+// HistoryResponse lists the metadata of all retained versions of a ruleset,
+// ordered oldest to newest.
+type HistoryResponse []LatestResponse
+
+// StatsResponse summarizes the contents of the cache across all instances.
+// The entry timestamps are omitted if the cache is empty.
+type StatsResponse struct {
+	Instances    int      `json:"instances"`
+	TotalEntries int      `json:"totalEntries"`
+	TotalBytes   int      `json:"totalBytes"`
+	OldestEntry  string   `json:"oldestEntry,omitempty"`
+	NewestEntry  string   `json:"newestEntry,omitempty"`
+	Keys         []string `json:"keys"`
+}
+
 // -----------------------------------------------------------------------------
 // RuleSetCacheServer
 // -----------------------------------------------------------------------------
 
 // ruleSetCacheServer provides HTTP endpoints for accessing cached rulesets
 type ruleSetCacheServer struct {
-	cache  *RuleSetCache
-	srv    *http.Server
-	logger logr.Logger
-	gc     GarbageCollectionConfig
+	cache   *RuleSetCache
+	srv     *http.Server
+	mux     *http.ServeMux
+	logger  logr.Logger
+	gc      GarbageCollectionConfig
+	ready   *ReadinessGate
+	metrics *serverMetrics
+
+	// snapshotPath, when set, is where the cache is persisted on shutdown.
+	snapshotPath string
+
+	// adminToken, when set, enables the admin endpoints for requests bearing
+	// it in their Authorization header.
+	adminToken string
+
+	// authToken, when set, is required in the Authorization header of
+	// requests to the rules endpoints.
+	authToken string
+
+	// tlsCertFile and tlsKeyFile, when set, make the server serve HTTPS.
+	tlsCertFile string
+	tlsKeyFile  string
+
+	// onOverCapacity, when set, is called when the cache still exceeds its
+	// max size after garbage collection.
+	onOverCapacity OverCapacityFunc
+
+	// maxConnections, when positive, limits the number of concurrently open
+	// connections. Connections beyond it wait to be accepted.
+	maxConnections int
+
+	// grpcAddr, when set, is the address the gRPC service is served on.
+	grpcAddr string
+
+	// grpcSrv serves the gRPC service while the server runs, if enabled.
+	grpcSrv *grpc.Server
+
+	// grpcDone is closed on shutdown to end streaming RPCs, which would
+	// otherwise block the graceful stop of the gRPC server.
+	grpcDone chan struct{}
+
+	// requestTimeout is the max time a handler may take to respond, which
+	// bounds the wait of long-polling requests. Zero means no deadline.
+	requestTimeout time.Duration
+
+	// connectionTimeoutsSet is whether the connection timeouts were
+	// overridden, in which case the write timeout no longer follows the
+	// request timeout.
+	connectionTimeoutsSet bool
 }
 
+// OverCapacityFunc is called when the cache still exceeds its max size after
+// garbage collection. instance is the instance retaining the most data, and
+// size the total cache size in bytes.
+type OverCapacityFunc func(instance string, size, maxSize int)
+
 // NewServer creates a new RuleSetCacheServer instance.
 func NewServer(cache *RuleSetCache, addr string, logger logr.Logger, gc *GarbageCollectionConfig) *ruleSetCacheServer {
 	gcConfig := DefaultGC()
@@ -81,33 +202,171 @@ func NewServer(cache *RuleSetCache, addr string, logger logr.Logger, gc *Garbage
 		gcConfig = *gc
 	}
 
+	cache.SetMaxVersionsPerInstance(gcConfig.MaxVersionsPerInstance)
+
 	s := &ruleSetCacheServer{
-		cache:  cache,
-		logger: logger,
-		gc:     gcConfig,
+		cache:          cache,
+		logger:         logger,
+		gc:             gcConfig,
+		metrics:        newServerMetrics(cache),
+		requestTimeout: DefaultRequestTimeout,
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/rules/", s.handleRules)
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/rules/", s.handleRules)
+	s.mux.HandleFunc(StatsPath, s.handleStats)
+	s.mux.Handle(MetricsPath, s.metrics.handler())
 
 	s.srv = &http.Server{
 		Addr:              addr,
-		Handler:           mux,
+		Handler:           s.withAccessLog(withRequestTimeout(s.mux, DefaultRequestTimeout)),
 		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       DefaultReadTimeout,
+		WriteTimeout:      writeTimeoutFor(DefaultRequestTimeout),
+		IdleTimeout:       DefaultIdleTimeout,
 		MaxHeaderBytes:    MaxHeaderSize,
 	}
 
 	return s
 }
 
+// WithReadinessGate configures the server to respond to rules requests with
+// 503 Service Unavailable until the given gate is marked ready. Without a
+// gate, the server serves requests as soon as it starts.
+func (s *ruleSetCacheServer) WithReadinessGate(gate *ReadinessGate) *ruleSetCacheServer {
+	s.ready = gate
+	return s
+}
+
+// WithSnapshotPath configures the server to persist the cache to the file at
+// path on graceful shutdown, once in-flight requests have drained. The
+// snapshot can be loaded on the next boot with RuleSetCache.Restore.
+func (s *ruleSetCacheServer) WithSnapshotPath(path string) *ruleSetCacheServer {
+	s.snapshotPath = path
+	return s
+}
+
+// WithAdminToken enables the admin endpoints (PUT and DELETE /rules/{instance}),
+// which require an "Authorization: Bearer <token>" header matching the given
+// token. Without a token the admin endpoints are disabled.
+func (s *ruleSetCacheServer) WithAdminToken(token string) *ruleSetCacheServer {
+	s.adminToken = token
+	return s
+}
+
+// WithAuthToken requires requests to the rules endpoints (GET /rules/...) and
+// the stats endpoint to bear an "Authorization: Bearer <token>" header
+// matching the given token, responding with 401 Unauthorized otherwise.
+// Requests to the rules endpoints of an instance may instead bear the token
+// of the instance derived from it by InstanceToken. Without a token the
+// rules endpoints are open to anyone who can reach the server. The metrics
+// endpoint is never authenticated.
+func (s *ruleSetCacheServer) WithAuthToken(token string) *ruleSetCacheServer {
+	s.authToken = token
+	return s
+}
+
+// WithRequestTimeout overrides DefaultRequestTimeout, the max time a handler
+// may take to respond. Requests exceeding it fail with 503 Service
+// Unavailable. A timeout of zero or less disables the deadline. Unless the
+// connection timeouts were overridden, the write timeout is adjusted to
+// WriteTimeoutGrace beyond the request timeout.
+func (s *ruleSetCacheServer) WithRequestTimeout(timeout time.Duration) *ruleSetCacheServer {
+	s.srv.Handler = s.withAccessLog(withRequestTimeout(s.mux, timeout))
+	s.requestTimeout = max(timeout, 0)
+	if !s.connectionTimeoutsSet {
+		s.srv.WriteTimeout = writeTimeoutFor(timeout)
+	}
+	return s
+}
+
+// writeTimeoutFor derives the write timeout from the request timeout, so that
+// responses are never cut short while their handler is still within its
+// deadline. Without a request timeout, writes have no deadline either.
+func writeTimeoutFor(requestTimeout time.Duration) time.Duration {
+	if requestTimeout <= 0 {
+		return 0
+	}
+	return requestTimeout + WriteTimeoutGrace
+}
+
+// WithConnectionTimeouts overrides the max time to read a request
+// (DefaultReadTimeout), to write a response (WriteTimeoutGrace beyond the
+// request timeout) and to keep an idle connection open (DefaultIdleTimeout).
+// Connections exceeding them are closed, so that slow or stalled clients
+// can't hold onto the server's resources. A timeout of zero disables it.
+func (s *ruleSetCacheServer) WithConnectionTimeouts(read, write, idle time.Duration) *ruleSetCacheServer {
+	s.srv.ReadTimeout = read
+	s.srv.WriteTimeout = write
+	s.srv.IdleTimeout = idle
+	s.connectionTimeoutsSet = true
+	return s
+}
+
+// WithMaxConnections limits the number of concurrently open connections to
+// the server. Once reached, further connections aren't accepted until others
+// are closed. A limit of zero or less leaves connections unlimited.
+func (s *ruleSetCacheServer) WithMaxConnections(n int) *ruleSetCacheServer {
+	s.maxConnections = n
+	return s
+}
+
+// withRequestTimeout wraps the handler with a deadline, if timeout is
+// positive. Responses are buffered until the handler returns, so none of the
+// endpoints may stream.
+func withRequestTimeout(h http.Handler, timeout time.Duration) http.Handler {
+	if timeout <= 0 {
+		return h
+	}
+	return http.TimeoutHandler(h, timeout, "Request timed out")
+}
+
+// WithTLS configures the server to serve HTTPS using the given certificate
+// and key files.
+func (s *ruleSetCacheServer) WithTLS(certFile, keyFile string) *ruleSetCacheServer {
+	s.tlsCertFile = certFile
+	s.tlsKeyFile = keyFile
+	return s
+}
+
+// WithOverCapacityHandler configures a function called after each garbage
+// collection cycle which leaves the cache over its max size, e.g. because a
+// single latest entry is too large, so that the condition can be surfaced
+// beyond the server's logs.
+func (s *ruleSetCacheServer) WithOverCapacityHandler(fn OverCapacityFunc) *ruleSetCacheServer {
+	s.onOverCapacity = fn
+	return s
+}
+
 // Start the cache server.
 func (s *ruleSetCacheServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+	if s.maxConnections > 0 {
+		listener = netutil.LimitListener(listener, s.maxConnections)
+	}
+
+	errChan := make(chan error, 2)
+	if s.grpcAddr != "" {
+		if err := s.serveGRPC(errChan); err != nil {
+			_ = listener.Close()
+			return err
+		}
+	}
+
 	go s.rungc(ctx)
 
-	errChan := make(chan error, 1)
 	go func() {
-		s.logger.Info("Starting ruleset cache server", "addr", s.srv.Addr)
-		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		s.logger.Info("Starting ruleset cache server", "addr", s.srv.Addr, "maxConnections", s.maxConnections)
+		var err error
+		if s.tlsCertFile != "" {
+			err = s.srv.ServeTLS(listener, s.tlsCertFile, s.tlsKeyFile)
+		} else {
+			err = s.srv.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			errChan <- err
 		}
 	}()
@@ -119,11 +378,15 @@ func (s *ruleSetCacheServer) Start(ctx context.Context) error {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), GracefulShutdownTimeout)
 		defer cancel()
 
+		s.stopGRPC(shutdownCtx)
 		if err := s.srv.Shutdown(shutdownCtx); err != nil {
 			s.logger.Error(err, "Error during graceful shutdown, forcing close")
-			return s.srv.Close()
+			closeErr := s.srv.Close()
+			s.snapshot()
+			return closeErr
 		}
 
+		s.snapshot()
 		s.logger.Info("Cache server shutdown complete")
 		return nil
 	case err := <-errChan:
@@ -131,6 +394,22 @@ func (s *ruleSetCacheServer) Start(ctx context.Context) error {
 	}
 }
 
+// snapshot persists the cache to the configured snapshot path, if any. It is
+// called after connections have drained so the snapshot reflects the latest
+// entries served to clients.
+func (s *ruleSetCacheServer) snapshot() {
+	if s.snapshotPath == "" {
+		return
+	}
+
+	if err := s.cache.Snapshot(s.snapshotPath); err != nil {
+		s.logger.Error(err, "Failed to snapshot ruleset cache", "path", s.snapshotPath)
+		return
+	}
+
+	s.logger.Info("Snapshotted ruleset cache", "path", s.snapshotPath)
+}
+
 // NeedLeaderElection implements the LeaderElectionRunnable interface.
 func (s *ruleSetCacheServer) NeedLeaderElection() bool {
 	return false
@@ -141,6 +420,17 @@ func (s *ruleSetCacheServer) NeedLeaderElection() bool {
 // -----------------------------------------------------------------------------
 
 func (s *ruleSetCacheServer) handleRules(w http.ResponseWriter, r *http.Request) {
+	if s.adminToken != "" {
+		switch r.Method {
+		case http.MethodPut:
+			s.handlePutRules(w, r)
+			return
+		case http.MethodDelete:
+			s.handleDeleteRules(w, r)
+			return
+		}
+	}
+
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -152,17 +442,43 @@ func (s *ruleSetCacheServer) handleRules(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if cacheKey, ok := strings.CutSuffix(path, "/latest"); ok {
-		s.handleLatest(w, r, cacheKey)
+	// The cache key is resolved before authorizing the request, as it may
+	// bear the token of the instance rather than the auth token.
+	var cacheKey string
+	var serve func()
+	if key, ok := strings.CutSuffix(path, "/latest"); ok {
+		cacheKey, serve = key, func() { s.handleLatest(w, r, key) }
+	} else if key, ok := strings.CutSuffix(path, "/history"); ok {
+		cacheKey, serve = key, func() { s.handleHistory(w, r, key) }
+	} else if i := strings.LastIndex(path, "/versions/"); i >= 0 {
+		uuid := path[i+len("/versions/"):]
+		if uuid == "" || strings.Contains(uuid, "/") {
+			http.Error(w, "RuleSet version required", http.StatusBadRequest)
+			return
+		}
+		cacheKey, serve = path[:i], func() { s.handleGetByUUID(w, r, path[:i], uuid) }
+	} else {
+		cacheKey, serve = path, func() { s.handleGetRules(w, r, path) }
+	}
+
+	if !s.authorized(w, r, cacheKey) {
 		return
 	}
 
-	s.handleGetRules(w, r, path)
+	if s.ready != nil && !s.ready.Ready() {
+		s.metrics.observeRequest(requestResultMiss)
+		w.Header().Set("Retry-After", NotReadyRetryAfterSeconds)
+		http.Error(w, "RuleSet cache not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	serve()
 }
 
-func (s *ruleSetCacheServer) handleLatest(w http.ResponseWriter, _ *http.Request, cacheKey string) {
+func (s *ruleSetCacheServer) handleLatest(w http.ResponseWriter, r *http.Request, cacheKey string) {
 	entry, ok := s.cache.Get(cacheKey)
 	if !ok {
+		s.metrics.observeRequest(requestResultNotFound)
 		http.Error(w, "RuleSet not found", http.StatusNotFound)
 		return
 	}
@@ -171,30 +487,392 @@ func (s *ruleSetCacheServer) handleLatest(w http.ResponseWriter, _ *http.Request
 		UUID:      entry.UUID,
 		Timestamp: entry.Timestamp.Format(TimestampFormat),
 	}
+	s.metrics.observeRequest(requestResultHit)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		s.logger.Error(err, "Failed to encode latest response")
+		s.loggerFor(r).Error(err, "Failed to encode latest response")
 	}
 }
 
-func (s *ruleSetCacheServer) handleGetRules(w http.ResponseWriter, _ *http.Request, cacheKey string) {
+// handleHistory serves the metadata of all retained versions of an instance,
+// for auditing and rollback.
+func (s *ruleSetCacheServer) handleHistory(w http.ResponseWriter, r *http.Request, cacheKey string) {
+	entries := s.cache.ListEntries(cacheKey)
+	if entries == nil {
+		s.metrics.observeRequest(requestResultNotFound)
+		http.Error(w, "RuleSet not found", http.StatusNotFound)
+		return
+	}
+	s.metrics.observeRequest(requestResultHit)
+
+	response := make(HistoryResponse, 0, len(entries))
+	for _, entry := range entries {
+		response = append(response, LatestResponse{
+			UUID:      entry.UUID,
+			Timestamp: entry.Timestamp.Format(TimestampFormat),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.loggerFor(r).Error(err, "Failed to encode history response")
+	}
+}
+
+// handleStats serves a summary of the cache across all instances, for
+// dashboards and health checks. It requires the auth token, if any, as it
+// discloses the cached instances, so instance tokens aren't accepted.
+func (s *ruleSetCacheServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.authorized(w, r, "") {
+		return
+	}
+
+	stats := s.cache.Stats()
+	response := StatsResponse{
+		Instances:    stats.Instances,
+		TotalEntries: stats.TotalEntries,
+		TotalBytes:   stats.TotalBytes,
+		Keys:         stats.Keys,
+	}
+	if stats.TotalEntries > 0 {
+		response.OldestEntry = stats.OldestEntry.Format(TimestampFormat)
+		response.NewestEntry = stats.NewestEntry.Format(TimestampFormat)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.loggerFor(r).Error(err, "Failed to encode stats response")
+	}
+}
+
+// handleGetRules serves the latest rules for an instance. Requests with an
+// If-None-Match header matching the latest UUID get 304 Not Modified, so that
+// polling clients only download rules when they changed.
+//
+// Requests may long-poll by setting the "wait" query parameter to a duration
+// (e.g. "?wait=30s&since=<uuid>"): they are held until the latest UUID
+// differs from "since", and then served the new rules, or get 304 Not
+// Modified once the wait elapses. This lets clients pick up new rules as
+// soon as they're cached without polling frequently.
+func (s *ruleSetCacheServer) handleGetRules(w http.ResponseWriter, r *http.Request, cacheKey string) {
+	since := r.URL.Query().Get("since")
+	wait, err := s.longPollWait(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if wait > 0 {
+		s.awaitNewVersion(r.Context(), cacheKey, since, wait)
+	}
+
 	entry, ok := s.cache.Get(cacheKey)
 	if !ok {
+		s.metrics.observeRequest(requestResultNotFound)
 		http.Error(w, "RuleSet not found", http.StatusNotFound)
 		return
 	}
 
-	s.logger.Info("Serving rules from cache", "cacheKey", cacheKey, "uuid", entry.UUID, "availableKeys", s.cache.ListKeys(), "cacheSizeBytes", s.cache.TotalSize())
+	s.metrics.observeRequest(requestResultHit)
+	if wait > 0 && entry.UUID == since {
+		s.cache.MarkServed(cacheKey, entry.UUID)
+		w.Header().Set("ETag", fmt.Sprintf("%q", entry.UUID))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.writeEntry(w, r, cacheKey, entry)
+}
+
+// longPollWait returns how long the request asks to wait for a new version
+// with its "wait" query parameter, capped at MaxLongPollWait and at
+// LongPollResponseMargin ahead of the request timeout. It is zero for
+// requests which don't long-poll.
+func (s *ruleSetCacheServer) longPollWait(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get("wait")
+	if value == "" {
+		return 0, nil
+	}
+
+	wait, err := time.ParseDuration(value)
+	if err != nil || wait < 0 {
+		return 0, fmt.Errorf("invalid wait %q, must be a non-negative duration (e.g. 30s)", value)
+	}
+
+	wait = min(wait, MaxLongPollWait)
+	if s.requestTimeout > 0 {
+		wait = min(wait, s.requestTimeout-LongPollResponseMargin)
+	}
+	return max(wait, 0), nil
+}
+
+// awaitNewVersion blocks until the latest UUID of the instance differs from
+// since, the instance holding since is evicted, the wait elapses, or the
+// request is cancelled. Instances which aren't cached are waited on as well,
+// unless the client holds rules of them.
+func (s *ruleSetCacheServer) awaitNewVersion(ctx context.Context, cacheKey, since string, wait time.Duration) {
+	// Subscribe before checking the latest version, so that no version cached
+	// in between is missed.
+	updates, unsubscribe := s.cache.Subscribe(cacheKey)
+	defer unsubscribe()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		entry, ok := s.cache.Get(cacheKey)
+		if (ok && entry.UUID != since) || (!ok && since != "") {
+			return
+		}
+
+		select {
+		case <-updates:
+		case <-timer.C:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// handleGetByUUID serves a specific version of an instance's rules, so that
+// clients can keep serving a known-good version during a staged rollout.
+// Versions which were pruned are not found.
+func (s *ruleSetCacheServer) handleGetByUUID(w http.ResponseWriter, r *http.Request, cacheKey, uuid string) {
+	entry, ok := s.cache.GetByUUID(cacheKey, uuid)
+	if !ok {
+		s.metrics.observeRequest(requestResultNotFound)
+		http.Error(w, "RuleSet version not found", http.StatusNotFound)
+		return
+	}
+
+	s.metrics.observeRequest(requestResultHit)
+	s.writeEntry(w, r, cacheKey, entry)
+}
+
+// writeEntry writes a rules entry response. The response carries the entry's
+// UUID as its ETag and is 304 Not Modified if the request's If-None-Match
+// header matches it. Otherwise, the entry is gzip compressed for clients
+// which accept it. Either way the entry is marked as served, as the client
+// now holds it.
+func (s *ruleSetCacheServer) writeEntry(w http.ResponseWriter, r *http.Request, cacheKey string, entry *RuleSetEntry) {
+	s.cache.MarkServed(cacheKey, entry.UUID)
+
+	etag := fmt.Sprintf("%q", entry.UUID)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Vary", "Accept-Encoding")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	s.loggerFor(r).Info("Serving rules from cache", "cacheKey", cacheKey, "uuid", entry.UUID, "availableKeys", s.cache.ListKeys(), "cacheSizeBytes", s.cache.TotalSize())
 
 	w.Header().Set("Content-Type", "application/json")
+	if !acceptsGzip(r) {
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(entry); err != nil {
+			s.loggerFor(r).Error(err, "Failed to encode rules response")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
 	w.WriteHeader(http.StatusOK)
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(entry); err != nil {
+		s.loggerFor(r).Error(err, "Failed to encode rules response")
+	}
+	if err := gz.Close(); err != nil {
+		s.loggerFor(r).Error(err, "Failed to compress rules response")
+	}
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding header allows a
+// gzip encoded response.
+func acceptsGzip(r *http.Request) bool {
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for coding := range strings.SplitSeq(header, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(coding), ";")
+			if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+				continue
+			}
+			// A quality value of zero explicitly refuses the encoding.
+			q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+			if !ok {
+				return true
+			}
+			weight, err := strconv.ParseFloat(q, 64)
+			return err == nil && weight > 0
+		}
+	}
+	return false
+}
+
+// etagMatches reports whether the If-None-Match header value matches the
+// given ETag. Weak comparison is used, as is required for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
 
-	if err := json.NewEncoder(w).Encode(entry); err != nil {
-		s.logger.Error(err, "Failed to encode rules response")
+// adminCacheKey returns the instance an admin request (PUT or DELETE
+// /rules/{instance}) applies to. It returns false if there is none, or if
+// the path is one of the read endpoints of an instance (e.g. /latest), which
+// the admin endpoints don't apply to.
+func adminCacheKey(r *http.Request) (string, bool) {
+	cacheKey := strings.TrimPrefix(r.URL.Path, "/rules/")
+	if cacheKey == "" || strings.HasSuffix(cacheKey, "/latest") || strings.HasSuffix(cacheKey, "/history") || strings.Contains(cacheKey, "/versions/") {
+		return "", false
 	}
+	return cacheKey, true
+}
+
+// handlePutRules stores the request body as a new version of an instance's
+// rules, for external feeders when the server runs standalone. It responds
+// with the metadata of the new version.
+func (s *ruleSetCacheServer) handlePutRules(w http.ResponseWriter, r *http.Request) {
+	if !bearsToken(r, s.adminToken) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cacheKey, ok := adminCacheKey(r)
+	if !ok {
+		http.Error(w, "RuleSet key required", http.StatusBadRequest)
+		return
+	}
+
+	rules, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxAdminBodySize))
+	if err != nil {
+		http.Error(w, "Failed to read rules", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if len(rules) == 0 {
+		http.Error(w, "Rules required", http.StatusBadRequest)
+		return
+	}
+
+	s.cache.Put(cacheKey, string(rules))
+	entry, _ := s.cache.Peek(cacheKey)
+	s.loggerFor(r).Info("Stored rules from admin API", "cacheKey", cacheKey, "uuid", entry.UUID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(LatestResponse{
+		UUID:      entry.UUID,
+		Timestamp: entry.Timestamp.Format(TimestampFormat),
+	}); err != nil {
+		s.loggerFor(r).Error(err, "Failed to encode put response")
+	}
+}
+
+// handleDeleteRules force-expires all cached versions of an instance, so
+// clients stop receiving its rules. This is a break-glass action for
+// recovering from bad rules: the RuleSet controller caches the instance again
+// on its next reconcile.
+func (s *ruleSetCacheServer) handleDeleteRules(w http.ResponseWriter, r *http.Request) {
+	if !bearsToken(r, s.adminToken) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cacheKey, ok := adminCacheKey(r)
+	if !ok {
+		http.Error(w, "RuleSet key required", http.StatusBadRequest)
+		return
+	}
+
+	if !s.cache.Delete(cacheKey) {
+		http.Error(w, "RuleSet not found", http.StatusNotFound)
+		return
+	}
+
+	s.loggerFor(r).Info("Force-expired ruleset cache instance", "cacheKey", cacheKey)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LoadToken reads a token for WithAdminToken or WithAuthToken from the file
+// at path (e.g. a mounted Secret key), trimming surrounding whitespace. An
+// empty token is an error.
+func LoadToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// InstanceToken derives the token granting access to the rules of a single
+// cache instance from the server's auth token, as the hex encoded
+// HMAC-SHA256 of the instance keyed by the auth token. Data planes are given
+// the token of the instance they load rules from, so that it can't be used
+// to read the rules of any other instance.
+func InstanceToken(authToken, instance string) string {
+	mac := hmac.New(sha256.New, []byte(authToken))
+	mac.Write([]byte(instance))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// authorized reports whether the request may access the rules of the given
+// instance, or the stats endpoint if instance is empty, responding with 401
+// Unauthorized if not.
+func (s *ruleSetCacheServer) authorized(w http.ResponseWriter, r *http.Request, instance string) bool {
+	if s.authorizes(r.Header.Get("Authorization"), instance) {
+		return true
+	}
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	return false
+}
+
+// authorizes reports whether the value of an Authorization header grants
+// access to the rules of the given instance: it must bear the auth token, or
+// the token of the instance (see InstanceToken) if instance isn't empty.
+// Everything is accessible if no auth token is required.
+func (s *ruleSetCacheServer) authorizes(authorization, instance string) bool {
+	if s.authToken == "" || bearerTokenMatches(authorization, s.authToken) {
+		return true
+	}
+	return instance != "" && bearerTokenMatches(authorization, InstanceToken(s.authToken, instance))
+}
+
+// bearsToken reports whether the request bears the given token in its
+// Authorization header.
+func bearsToken(r *http.Request, expected string) bool {
+	return bearerTokenMatches(r.Header.Get("Authorization"), expected)
+}
+
+// bearerTokenMatches reports whether the value of an Authorization header
+// bears the given token.
+func bearerTokenMatches(authorization, expected string) bool {
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
 }
 
 // -----------------------------------------------------------------------------
@@ -209,22 +887,48 @@ type GarbageCollectionConfig struct {
 	// MaxAge is the maximum age of a cache entry before it's considered stale.
 	MaxAge time.Duration
 
-	// MaxSize is the maximum total size of all cached rules in bytes.
+	// MaxSize is the maximum total size of all cache entries in bytes, as
+	// accounted by RuleSetCache.TotalSize.
 	MaxSize int
+
+	// MaxVersionsPerInstance is the maximum number of versions retained per
+	// instance, enforced as new versions are stored so that rapidly changing
+	// rules don't accumulate between GC cycles. The latest and pinned
+	// versions are always retained. Zero means unlimited.
+	MaxVersionsPerInstance int
 }
 
 // DefaultGC returns the default garbage collection configuration.
 func DefaultGC() GarbageCollectionConfig {
 	return GarbageCollectionConfig{
-		GCInterval: CacheGCInterval,
-		MaxAge:     CacheMaxAge,
-		MaxSize:    CacheMaxSize,
+		GCInterval:             CacheGCInterval,
+		MaxAge:                 CacheMaxAge,
+		MaxSize:                CacheMaxSize,
+		MaxVersionsPerInstance: CacheMaxVersionsPerInstance,
+	}
+}
+
+// Validate checks that the GC interval and max age are positive, and that the
+// max size and max versions are not negative.
+func (c GarbageCollectionConfig) Validate() error {
+	if c.GCInterval <= 0 {
+		return fmt.Errorf("cache GC interval must be positive, got %s", c.GCInterval)
+	}
+	if c.MaxAge <= 0 {
+		return fmt.Errorf("cache max age must be positive, got %s", c.MaxAge)
+	}
+	if c.MaxSize < 0 {
+		return fmt.Errorf("cache max size must not be negative, got %d", c.MaxSize)
 	}
+	if c.MaxVersionsPerInstance < 0 {
+		return fmt.Errorf("cache max versions per instance must not be negative, got %d", c.MaxVersionsPerInstance)
+	}
+	return nil
 }
 
 // rungc periodically removes stale cache entries using two strategies:
-// 1. Age-based: entries older than MaxAge (except latest)
-// 2. Size-based: oldest entries when cache exceeds MaxSize (except latest)
+// 1. Age-based: entries older than MaxAge (except latest and pinned)
+// 2. Size-based: oldest entries when cache exceeds MaxSize (except latest and pinned)
 func (s *ruleSetCacheServer) rungc(ctx context.Context) {
 	ticker := time.NewTicker(s.gc.GCInterval)
 	defer ticker.Stop()
@@ -235,6 +939,7 @@ func (s *ruleSetCacheServer) rungc(ctx context.Context) {
 			return
 		case <-ticker.C:
 			prunedByAge := s.cache.Prune(s.gc.MaxAge)
+			s.metrics.observePruned(gcStrategyAge, prunedByAge)
 			if prunedByAge > 0 {
 				s.logger.Info("Pruned stale cache entries by age", "count", prunedByAge, "maxAge", s.gc.MaxAge)
 			}
@@ -242,14 +947,25 @@ func (s *ruleSetCacheServer) rungc(ctx context.Context) {
 			currentSize := s.cache.TotalSize()
 			if currentSize > s.gc.MaxSize {
 				prunedBySize := s.cache.PruneBySize(s.gc.MaxSize)
+				s.metrics.observePruned(gcStrategySize, prunedBySize)
 				if prunedBySize > 0 {
 					s.logger.Info("Pruned cache entries by size", "count", prunedBySize, "maxSize", s.gc.MaxSize, "currentSize", s.cache.TotalSize())
 				}
 
 				finalSize := s.cache.TotalSize()
-				if finalSize > s.gc.MaxSize {
+				if pinnedSize := s.cache.PinnedSize(); finalSize > s.gc.MaxSize && pinnedSize > 0 {
+					s.logger.Error(errors.New("cache size exceeds maximum"), "Cache size exceeds maximum after pruning - retaining pinned versions", "currentSize", finalSize, "maxSize", s.gc.MaxSize, "pinnedSize", pinnedSize)
+				} else if finalSize > s.gc.MaxSize {
 					s.logger.Error(errors.New("cache size exceeds maximum"), "CRITICAL: Cache size exceeds maximum even after pruning - latest entry is too large", "currentSize", finalSize, "maxSize", s.gc.MaxSize, "overage", finalSize-s.gc.MaxSize)
 				}
+				currentSize = finalSize
+			}
+
+			overCapacity := currentSize > s.gc.MaxSize
+			s.metrics.observeOverCapacity(overCapacity)
+			if overCapacity && s.onOverCapacity != nil {
+				instance, _ := s.cache.LargestInstance()
+				s.onOverCapacity(instance, currentSize, s.gc.MaxSize)
 			}
 		}
 	}
@@ -17,10 +17,17 @@ limitations under the License.
 package cache
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -70,6 +77,47 @@ func TestServer_StartAndStop(t *testing.T) {
 	}
 }
 
+func TestServer_SnapshotOnShutdown(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	path := filepath.Join(t.TempDir(), "cache.json")
+	server := NewServer(cache, ":0", logger, nil).WithSnapshotPath(path)
+
+	t.Log("Starting server in background goroutine")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Start(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	t.Log("Updating cache while the server is running")
+	cache.Put("test-instance", "SecRuleEngine DetectionOnly")
+	cache.Put("test-instance", "SecRuleEngine On")
+	latest, ok := cache.Get("test-instance")
+	require.True(t, ok)
+
+	t.Log("Cancelling context to stop server")
+	cancel()
+	select {
+	case err := <-errChan:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Server did not shut down in time")
+	}
+
+	t.Log("Verifying the snapshot exists and reflects the latest entries")
+	require.FileExists(t, path)
+	restored := NewRuleSetCache()
+	require.NoError(t, restored.Restore(path, utils.NewTestLogger(t)))
+	assert.Equal(t, 2, restored.CountEntries("test-instance"))
+	entry, ok := restored.Get("test-instance")
+	require.True(t, ok)
+	assert.Equal(t, latest.UUID, entry.UUID)
+	assert.Equal(t, "SecRuleEngine On", entry.Rules)
+}
+
 func TestServer_HandleGetRules_Success(t *testing.T) {
 	cache := NewRuleSetCache()
 	logger := utils.NewTestLogger(t)
@@ -268,6 +316,51 @@ func TestServer_GCBySize(t *testing.T) {
 	assert.Greater(t, finalSize, gc.MaxSize, "Cache size exceeds max")
 }
 
+func TestServer_GCRetainsPinned(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+
+	t.Log("Using aggressive age and size limits for testing")
+	gc := &GarbageCollectionConfig{
+		GCInterval: 50 * time.Millisecond,
+		MaxAge:     time.Millisecond,
+		MaxSize:    1,
+	}
+	server := NewServer(cache, testServerAddr, logger, gc)
+
+	t.Log("Adding three versions and pinning the oldest")
+	cache.Put("instance1", "pinned version")
+	pinned, ok := cache.Get("instance1")
+	require.True(t, ok)
+	cache.Put("instance1", "unpinned version")
+	cache.Put("instance1", "latest version")
+	cache.SetPinned("engine-a", "instance1", pinned.UUID)
+	cache.SetPinned("engine-b", "instance1", pinned.UUID)
+	assert.Equal(t, testEntrySize("pinned version"), cache.PinnedSize())
+
+	t.Log("Running the GC")
+	go server.rungc(t.Context())
+	time.Sleep(150 * time.Millisecond)
+
+	t.Log("Verifying the pinned and latest versions were retained")
+	assert.Equal(t, 2, cache.CountEntries("instance1"))
+	assert.Equal(t, testEntrySize("pinned version"), cache.PinnedSize())
+	latest, ok := cache.Get("instance1")
+	require.True(t, ok)
+	assert.Equal(t, "latest version", latest.Rules)
+
+	t.Log("Verifying the version stays pinned while any owner pins it")
+	cache.SetPinned("engine-a", "instance1")
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, 2, cache.CountEntries("instance1"))
+
+	t.Log("Unpinning and verifying the version is pruned")
+	cache.SetPinned("engine-b", "instance1")
+	assert.Eventually(t, func() bool {
+		return cache.CountEntries("instance1") == 1
+	}, time.Second, 50*time.Millisecond)
+}
+
 func TestServer_GCEmptyCache(t *testing.T) {
 	cache := NewRuleSetCache()
 	logger := utils.NewTestLogger(t)
@@ -334,3 +427,771 @@ func TestServer_HandleRules_MethodNotAllowed(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_HandleDeleteRules(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	server := NewServer(cache, testServerAddr, logger, nil).WithAdminToken("secret")
+	cache.Put("test-ns/test-instance", "SecRuleEngine On")
+	cache.Put("test-ns/test-instance", "SecRuleEngine DetectionOnly")
+
+	deleteRules := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodDelete, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		server.handleRules(w, req)
+		return w.Code
+	}
+
+	t.Log("Verifying unauthenticated deletes are rejected")
+	assert.Equal(t, http.StatusUnauthorized, deleteRules("/rules/test-ns/test-instance", ""))
+	assert.Equal(t, http.StatusUnauthorized, deleteRules("/rules/test-ns/test-instance", "wrong"))
+	assert.Equal(t, 2, cache.CountEntries("test-ns/test-instance"))
+
+	t.Log("Verifying an authenticated delete expires all versions")
+	assert.Equal(t, http.StatusNoContent, deleteRules("/rules/test-ns/test-instance", "secret"))
+	assert.Equal(t, 0, cache.CountEntries("test-ns/test-instance"))
+
+	t.Log("Verifying the instance is no longer served")
+	req := httptest.NewRequest(http.MethodGet, "/rules/test-ns/test-instance", nil)
+	w := httptest.NewRecorder()
+	server.handleRules(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	t.Log("Verifying deleting a missing instance returns 404")
+	assert.Equal(t, http.StatusNotFound, deleteRules("/rules/test-ns/test-instance", "secret"))
+
+	t.Log("Verifying the read endpoints of an instance can't be deleted")
+	cache.Put("test-ns/test-instance", "SecRuleEngine On")
+	for _, path := range []string{"/rules/", "/rules/test-ns/test-instance/latest", "/rules/test-ns/test-instance/history", "/rules/test-ns/test-instance/versions/some-uuid"} {
+		assert.Equal(t, http.StatusBadRequest, deleteRules(path, "secret"), path)
+	}
+	assert.Equal(t, 1, cache.CountEntries("test-ns/test-instance"))
+}
+
+func TestServer_RequestTimeout(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	server := NewServer(cache, testServerAddr, logger, nil).WithRequestTimeout(50 * time.Millisecond)
+	cache.Put("test-instance", "SecRuleEngine On")
+
+	release := make(chan struct{})
+	defer close(release)
+	server.mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+
+	t.Log("Verifying a slow handler fails with 503 once the deadline passes")
+	req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+	w := httptest.NewRecorder()
+	start := time.Now()
+	server.srv.Handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Less(t, time.Since(start), time.Second)
+
+	t.Log("Verifying fast handlers still respond normally")
+	req = httptest.NewRequest(http.MethodGet, "/rules/test-instance", nil)
+	w = httptest.NewRecorder()
+	server.srv.Handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "SecRuleEngine On")
+}
+
+func TestServer_ConnectionTimeouts(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+
+	t.Log("Verifying the server has connection timeouts by default")
+	server := NewServer(cache, testServerAddr, logger, nil)
+	assert.Equal(t, DefaultReadTimeout, server.srv.ReadTimeout)
+	assert.Equal(t, DefaultRequestTimeout+WriteTimeoutGrace, server.srv.WriteTimeout)
+	assert.Equal(t, DefaultIdleTimeout, server.srv.IdleTimeout)
+
+	t.Log("Verifying the write timeout follows the request timeout")
+	server.WithRequestTimeout(time.Minute)
+	assert.Equal(t, time.Minute+WriteTimeoutGrace, server.srv.WriteTimeout)
+	server.WithRequestTimeout(0)
+	assert.Zero(t, server.srv.WriteTimeout)
+
+	t.Log("Verifying overridden timeouts are kept")
+	server.WithConnectionTimeouts(time.Second, 2*time.Second, 3*time.Second).WithRequestTimeout(time.Minute)
+	assert.Equal(t, time.Second, server.srv.ReadTimeout)
+	assert.Equal(t, 2*time.Second, server.srv.WriteTimeout)
+	assert.Equal(t, 3*time.Second, server.srv.IdleTimeout)
+}
+
+func TestServer_MaxConnections(t *testing.T) {
+	cache := NewRuleSetCache()
+	cache.Put("test-instance", "SecRuleEngine On")
+	logger := utils.NewTestLogger(t)
+	addr := freeAddr(t)
+	server := NewServer(cache, addr, logger, nil).WithMaxConnections(1)
+	startServer(t, server)
+
+	get := func(timeout time.Duration) (*http.Response, error) {
+		client := &http.Client{Timeout: timeout, Transport: &http.Transport{DisableKeepAlives: true}}
+		return client.Get("http://" + addr + "/rules/test-instance")
+	}
+
+	t.Log("Holding the only connection the server accepts open")
+	held, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+
+	t.Log("Verifying further connections are throttled")
+	_, err = get(300 * time.Millisecond)
+	require.Error(t, err)
+
+	t.Log("Verifying connections are accepted again once the held one is closed")
+	require.NoError(t, held.Close())
+	resp, err := get(5 * time.Second)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServer_WriteTimeoutDisconnectsSlowClient(t *testing.T) {
+	cache := NewRuleSetCache()
+	rules := strings.Repeat("SecRuleEngine On\n", 2*1024*1024)
+	cache.Put("test-instance", rules)
+	logger := utils.NewTestLogger(t)
+	addr := freeAddr(t)
+	server := NewServer(cache, addr, logger, nil).WithConnectionTimeouts(time.Second, 500*time.Millisecond, time.Second)
+	startServer(t, server)
+
+	t.Log("Requesting rules larger than the socket buffers without reading the response")
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	_, err = fmt.Fprintf(conn, "GET /rules/test-instance HTTP/1.1\r\nHost: %s\r\n\r\n", addr)
+	require.NoError(t, err)
+	time.Sleep(1500 * time.Millisecond)
+
+	t.Log("Verifying the server gave up on writing the response and closed the connection")
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _ := io.Copy(io.Discard, conn)
+	assert.Less(t, n, int64(len(rules)))
+}
+
+// freeAddr returns a loopback address with a port which is free to listen on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+	return addr
+}
+
+// startServer starts the server until the test ends, waiting for it to
+// listen.
+func startServer(t *testing.T, server *ruleSetCacheServer) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-errChan:
+			assert.NoError(t, err)
+		case <-time.After(GracefulShutdownTimeout + time.Second):
+			t.Error("Server did not shut down in time")
+		}
+	})
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", server.srv.Addr)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestServer_ReadinessGate(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	gate := NewReadinessGate()
+	server := NewServer(cache, testServerAddr, logger, nil).WithReadinessGate(gate)
+	cache.Put("test-instance", "SecRuleEngine On")
+
+	t.Log("Verifying requests are rejected with 503 before the gate is ready")
+	for _, path := range []string{"/rules/test-instance", "/rules/test-instance/latest", "/rules/non-existent"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		server.handleRules(w, req)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, path)
+		assert.Equal(t, NotReadyRetryAfterSeconds, w.Header().Get("Retry-After"), path)
+	}
+
+	t.Log("Verifying requests are served once the gate is ready")
+	gate.SetReady()
+	req := httptest.NewRequest(http.MethodGet, "/rules/test-instance", nil)
+	w := httptest.NewRecorder()
+	server.handleRules(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/rules/non-existent", nil)
+	w = httptest.NewRecorder()
+	server.handleRules(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestServer_Metrics(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	gate := NewReadinessGate()
+	server := NewServer(cache, testServerAddr, logger, nil).WithReadinessGate(gate)
+	cache.Put("test-instance", "SecRuleEngine On")
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Log("Verifying all series are exported before any request")
+	w := get(MetricsPath)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `coraza_cache_requests_total{result="hit"} 0`)
+	assert.Contains(t, w.Body.String(), `coraza_cache_requests_total{result="miss"} 0`)
+	assert.Contains(t, w.Body.String(), `coraza_cache_requests_total{result="notfound"} 0`)
+	assert.Contains(t, w.Body.String(), `coraza_cache_gc_pruned_total{strategy="age"} 0`)
+	assert.Contains(t, w.Body.String(), `coraza_cache_gc_pruned_total{strategy="size"} 0`)
+
+	t.Log("Requesting rules before and after the cache is ready")
+	assert.Equal(t, http.StatusServiceUnavailable, get("/rules/test-instance").Code)
+	gate.SetReady()
+	assert.Equal(t, http.StatusOK, get("/rules/test-instance").Code)
+	assert.Equal(t, http.StatusOK, get("/rules/test-instance/latest").Code)
+	assert.Equal(t, http.StatusNotFound, get("/rules/non-existent").Code)
+
+	t.Log("Verifying the counters and gauges moved")
+	w = get(MetricsPath)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `coraza_cache_requests_total{result="hit"} 2`)
+	assert.Contains(t, w.Body.String(), `coraza_cache_requests_total{result="miss"} 1`)
+	assert.Contains(t, w.Body.String(), `coraza_cache_requests_total{result="notfound"} 1`)
+	assert.Contains(t, w.Body.String(), "coraza_cache_entries 1")
+	assert.Contains(t, w.Body.String(), fmt.Sprintf("coraza_cache_bytes %d", testEntrySize("SecRuleEngine On")))
+}
+
+func TestServer_MetricsGC(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	gc := &GarbageCollectionConfig{
+		GCInterval: 50 * time.Millisecond,
+		MaxAge:     100 * time.Millisecond,
+		MaxSize:    1024 * 1024 * 1024, // 1GB - disable size-based pruning
+	}
+	server := NewServer(cache, testServerAddr, logger, gc)
+
+	t.Log("Adding a stale entry behind the latest one")
+	cache.Put("instance", "old")
+	cache.Put("instance", "new")
+	cache.SetEntryTimestamp("instance", 0, time.Now().Add(-200*time.Millisecond))
+
+	t.Log("Starting the GC")
+	go server.rungc(t.Context())
+
+	t.Log("Verifying the pruned counter moved")
+	require.Eventually(t, func() bool {
+		req := httptest.NewRequest(http.MethodGet, MetricsPath, nil)
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		return strings.Contains(w.Body.String(), `coraza_cache_gc_pruned_total{strategy="age"} 1`)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServer_HandleGetRules_ETag(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	server := NewServer(cache, testServerAddr, logger, nil)
+	cache.Put("test-instance", "SecRuleEngine On")
+	entry, ok := cache.Get("test-instance")
+	require.True(t, ok)
+
+	t.Log("Verifying the first request returns the rules with an ETag")
+	req := httptest.NewRequest(http.MethodGet, "/rules/test-instance", nil)
+	w := httptest.NewRecorder()
+	server.handleRules(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"`+entry.UUID+`"`, etag)
+	served, _, _ := cache.ServedVersion("test-instance")
+	assert.Equal(t, entry.UUID, served)
+
+	t.Log("Verifying a request with a matching If-None-Match returns 304")
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		req = httptest.NewRequest(http.MethodGet, "/rules/test-instance", nil)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		w = httptest.NewRecorder()
+		server.handleRules(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code, ifNoneMatch)
+		assert.Empty(t, w.Body.String(), ifNoneMatch)
+		assert.Equal(t, etag, w.Header().Get("ETag"), ifNoneMatch)
+	}
+
+	t.Log("Verifying a request with a stale If-None-Match returns the new rules")
+	cache.Put("test-instance", "SecRuleEngine DetectionOnly")
+	req = httptest.NewRequest(http.MethodGet, "/rules/test-instance", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	server.handleRules(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	var response RuleSetEntry
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "SecRuleEngine DetectionOnly", response.Rules)
+	served, _, _ = cache.ServedVersion("test-instance")
+	assert.Equal(t, response.UUID, served)
+}
+
+func TestServer_HandleGetRules_LongPoll(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	server := NewServer(cache, testServerAddr, logger, nil)
+	cache.Put("test-instance", "SecRuleEngine DetectionOnly")
+	first, ok := cache.Get("test-instance")
+	require.True(t, ok)
+
+	longPoll := func(since, wait string) (*httptest.ResponseRecorder, time.Duration) {
+		req := httptest.NewRequest(http.MethodGet, "/rules/test-instance?wait="+wait+"&since="+since, nil)
+		w := httptest.NewRecorder()
+		start := time.Now()
+		server.srv.Handler.ServeHTTP(w, req)
+		return w, time.Since(start)
+	}
+
+	t.Log("Verifying a client holding an outdated version is served immediately")
+	w, elapsed := longPoll("outdated", "5s")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Less(t, elapsed, time.Second)
+	var response RuleSetEntry
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, first.UUID, response.UUID)
+
+	t.Log("Verifying a client holding the latest version is served once a new one is cached")
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cache.Put("test-instance", "SecRuleEngine On")
+	}()
+	w, elapsed = longPoll(first.UUID, "5s")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.GreaterOrEqual(t, elapsed, 200*time.Millisecond)
+	assert.Less(t, elapsed, 5*time.Second)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "SecRuleEngine On", response.Rules)
+	assert.Equal(t, `"`+response.UUID+`"`, w.Header().Get("ETag"))
+	served, _, _ := cache.ServedVersion("test-instance")
+	assert.Equal(t, response.UUID, served)
+
+	t.Log("Verifying a client holding the latest version gets 304 once the wait elapses")
+	w, elapsed = longPoll(response.UUID, "200ms")
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.GreaterOrEqual(t, elapsed, 200*time.Millisecond)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, `"`+response.UUID+`"`, w.Header().Get("ETag"))
+
+	t.Log("Verifying a client holding the latest version gets 404 once the instance is evicted")
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cache.Delete("test-instance")
+	}()
+	w, elapsed = longPoll(response.UUID, "5s")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.GreaterOrEqual(t, elapsed, 200*time.Millisecond)
+	assert.Less(t, elapsed, 5*time.Second)
+	cache.Put("test-instance", "SecRuleEngine On")
+
+	t.Log("Verifying an invalid wait is rejected")
+	w, _ = longPoll(response.UUID, "soon")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = longPoll(response.UUID, "-1s")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestServer_LongPollWait(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	server := NewServer(cache, testServerAddr, logger, nil)
+
+	wait := func(query string) time.Duration {
+		d, err := server.longPollWait(httptest.NewRequest(http.MethodGet, "/rules/test-instance?"+query, nil))
+		require.NoError(t, err)
+		return d
+	}
+
+	t.Log("Verifying requests without a wait don't long-poll")
+	assert.Zero(t, wait(""))
+
+	t.Log("Verifying the wait is cut short ahead of the request timeout")
+	assert.Equal(t, 10*time.Second, wait("wait=10s"))
+	assert.Equal(t, DefaultRequestTimeout-LongPollResponseMargin, wait("wait=1h"))
+
+	t.Log("Verifying the wait is capped without a request timeout")
+	server.WithRequestTimeout(0)
+	assert.Equal(t, MaxLongPollWait, wait("wait=1h"))
+}
+
+func TestServer_HandleGetRules_Gzip(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	server := NewServer(cache, testServerAddr, logger, nil)
+	testRules := strings.Repeat("SecRule REQUEST_URI \"@contains /admin\" \"id:1,deny\"\n", 100)
+	cache.Put("test-instance", testRules)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		wantGzip       bool
+	}{
+		{name: "no Accept-Encoding"},
+		{name: "gzip", acceptEncoding: "gzip", wantGzip: true},
+		{name: "gzip among others", acceptEncoding: "br, GZIP;q=0.5, deflate", wantGzip: true},
+		{name: "gzip refused", acceptEncoding: "gzip;q=0"},
+		{name: "other encodings only", acceptEncoding: "br, deflate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/rules/test-instance", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			server.handleRules(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+
+			body := io.Reader(w.Body)
+			if tt.wantGzip {
+				assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+				gz, err := gzip.NewReader(w.Body)
+				require.NoError(t, err)
+				defer func() { _ = gz.Close() }()
+				body = gz
+			} else {
+				assert.Empty(t, w.Header().Get("Content-Encoding"))
+			}
+
+			var response RuleSetEntry
+			require.NoError(t, json.NewDecoder(body).Decode(&response))
+			assert.Equal(t, testRules, response.Rules)
+		})
+	}
+
+	t.Log("Verifying the latest endpoint is never compressed")
+	req := httptest.NewRequest(http.MethodGet, "/rules/test-instance/latest", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	server.handleRules(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	var latest LatestResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&latest))
+	assert.NotEmpty(t, latest.UUID)
+}
+
+func TestServer_HandleHistory(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	server := NewServer(cache, testServerAddr, logger, nil)
+
+	history := func(path string) (int, HistoryResponse) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		server.handleRules(w, req)
+		var response HistoryResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		}
+		return w.Code, response
+	}
+
+	t.Log("Verifying unknown instances are not found")
+	code, _ := history("/rules/test-ns/non-existent/history")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	t.Log("Adding multiple versions of a ruleset")
+	cache.Put("test-ns/test-instance", "rules v1")
+	cache.Put("test-ns/test-instance", "rules v2")
+	cache.Put("test-ns/test-instance", "rules v3")
+	entries := cache.ListEntries("test-ns/test-instance")
+	require.Len(t, entries, 3)
+
+	t.Log("Verifying all versions are listed oldest to newest")
+	code, response := history("/rules/test-ns/test-instance/history")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, response, 3)
+	for i, entry := range entries {
+		assert.Equal(t, entry.UUID, response[i].UUID)
+		assert.Equal(t, entry.Timestamp.Format(TimestampFormat), response[i].Timestamp)
+	}
+
+	t.Log("Verifying pruning reduces the list")
+	cache.SetEntryTimestamp("test-ns/test-instance", 0, time.Now().Add(-time.Hour))
+	require.Equal(t, 1, cache.Prune(time.Minute))
+	code, response = history("/rules/test-ns/test-instance/history")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, response, 2)
+	assert.Equal(t, entries[1].UUID, response[0].UUID)
+	assert.Equal(t, entries[2].UUID, response[1].UUID)
+}
+
+func TestServer_HandleStats(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	server := NewServer(cache, testServerAddr, logger, nil)
+
+	stats := func() StatsResponse {
+		req := httptest.NewRequest(http.MethodGet, StatsPath, nil)
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		var response StatsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		return response
+	}
+
+	t.Log("Verifying an empty cache has no entries")
+	assert.Equal(t, StatsResponse{Keys: []string{}}, stats())
+
+	t.Log("Adding versions of two rulesets")
+	cache.Put("test-ns/instance-b", "rules b1")
+	cache.Put("test-ns/instance-a", "rules a1")
+	cache.Put("test-ns/instance-a", "rules a2")
+	oldest := time.Now().Add(-time.Hour)
+	cache.SetEntryTimestamp("test-ns/instance-b", 0, oldest)
+
+	t.Log("Verifying counts track the cached versions")
+	response := stats()
+	assert.Equal(t, 2, response.Instances)
+	assert.Equal(t, 3, response.TotalEntries)
+	assert.Equal(t, cache.TotalSize(), response.TotalBytes)
+	assert.Equal(t, []string{"test-ns/instance-a", "test-ns/instance-b"}, response.Keys)
+	assert.Equal(t, oldest.Format(TimestampFormat), response.OldestEntry)
+	newest := cache.ListEntries("test-ns/instance-a")[1].Timestamp
+	assert.Equal(t, newest.Format(TimestampFormat), response.NewestEntry)
+
+	t.Log("Verifying another version is counted")
+	cache.Put("test-ns/instance-b", "rules b2")
+	response = stats()
+	assert.Equal(t, 2, response.Instances)
+	assert.Equal(t, 4, response.TotalEntries)
+	assert.Equal(t, cache.TotalSize(), response.TotalBytes)
+
+	t.Log("Verifying other methods are rejected")
+	req := httptest.NewRequest(http.MethodPost, StatsPath, nil)
+	w := httptest.NewRecorder()
+	server.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestServer_HandleGetByUUID(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	server := NewServer(cache, testServerAddr, logger, nil)
+
+	t.Log("Adding multiple versions of a ruleset")
+	cache.Put("test-ns/test-instance", "rules v1")
+	cache.Put("test-ns/test-instance", "rules v2")
+	entries := cache.ListEntries("test-ns/test-instance")
+	require.Len(t, entries, 2)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		server.handleRules(w, req)
+		return w
+	}
+
+	t.Log("Verifying a prior version can be requested by UUID")
+	w := get("/rules/test-ns/test-instance/versions/" + entries[0].UUID)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response RuleSetEntry
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, entries[0].UUID, response.UUID)
+	assert.Equal(t, "rules v1", response.Rules)
+
+	t.Log("Verifying an unknown UUID is not found")
+	w = get("/rules/test-ns/test-instance/versions/" + entries[0].UUID + "-wrong")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = get("/rules/test-ns/non-existent/versions/" + entries[0].UUID)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	t.Log("Verifying a missing UUID is rejected")
+	w = get("/rules/test-ns/test-instance/versions/")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	t.Log("Verifying a pruned version is not found")
+	cache.SetEntryTimestamp("test-ns/test-instance", 0, time.Now().Add(-time.Hour))
+	require.Equal(t, 1, cache.Prune(time.Minute))
+	w = get("/rules/test-ns/test-instance/versions/" + entries[0].UUID)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = get("/rules/test-ns/test-instance/versions/" + entries[1].UUID)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestServer_AuthToken(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+	cache.Put("test-instance", "SecRuleEngine On")
+
+	get := func(server *ruleSetCacheServer, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Log("Verifying rules are served to anyone when auth is disabled")
+	server := NewServer(cache, testServerAddr, logger, nil)
+	assert.Equal(t, http.StatusOK, get(server, "/rules/test-instance", "").Code)
+	assert.Equal(t, http.StatusOK, get(server, "/rules/test-instance/latest", "").Code)
+
+	t.Log("Verifying unauthorized requests are rejected when auth is enabled")
+	server = NewServer(cache, testServerAddr, logger, nil).WithAuthToken("secret")
+	for _, path := range []string{"/rules/test-instance", "/rules/test-instance/latest", "/rules/test-instance/history", StatsPath} {
+		for _, token := range []string{"", "wrong"} {
+			w := get(server, path, token)
+			assert.Equal(t, http.StatusUnauthorized, w.Code, path)
+			assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"), path)
+			assert.NotContains(t, w.Body.String(), "SecRuleEngine", path)
+		}
+	}
+
+	t.Log("Verifying authorized requests are served")
+	assert.Equal(t, http.StatusOK, get(server, "/rules/test-instance", "secret").Code)
+	assert.Equal(t, http.StatusOK, get(server, "/rules/test-instance/latest", "secret").Code)
+	assert.Equal(t, http.StatusOK, get(server, StatsPath, "secret").Code)
+
+	t.Log("Verifying an instance token only grants access to its instance's rules")
+	cache.Put("other-instance", "SecRuleEngine DetectionOnly")
+	instanceToken := InstanceToken("secret", "test-instance")
+	assert.Equal(t, http.StatusOK, get(server, "/rules/test-instance", instanceToken).Code)
+	assert.Equal(t, http.StatusOK, get(server, "/rules/test-instance/latest", instanceToken).Code)
+	assert.Equal(t, http.StatusOK, get(server, "/rules/test-instance/history", instanceToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, get(server, "/rules/other-instance", instanceToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, get(server, StatsPath, instanceToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, get(server, "/rules/test-instance", InstanceToken("wrong", "test-instance")).Code)
+
+	t.Log("Verifying the metrics endpoint is not authenticated")
+	assert.Equal(t, http.StatusOK, get(server, MetricsPath, "").Code)
+}
+
+func TestLoadToken(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Log("Loading a token with surrounding whitespace")
+	path := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(path, []byte("  secret\n"), 0o600))
+	token, err := LoadToken(path)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", token)
+
+	t.Log("Verifying an empty token is rejected")
+	path = filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(path, []byte("\n"), 0o600))
+	_, err = LoadToken(path)
+	require.Error(t, err)
+
+	t.Log("Verifying a missing file is rejected")
+	_, err = LoadToken(filepath.Join(dir, "missing"))
+	require.Error(t, err)
+}
+
+func TestGarbageCollectionConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*GarbageCollectionConfig)
+		wantErr string
+	}{
+		{name: "defaults", modify: func(*GarbageCollectionConfig) {}},
+		{name: "zero max size", modify: func(c *GarbageCollectionConfig) { c.MaxSize = 0 }},
+		{name: "unlimited versions", modify: func(c *GarbageCollectionConfig) { c.MaxVersionsPerInstance = 0 }},
+		{name: "zero interval", modify: func(c *GarbageCollectionConfig) { c.GCInterval = 0 }, wantErr: "GC interval must be positive"},
+		{name: "negative max age", modify: func(c *GarbageCollectionConfig) { c.MaxAge = -time.Hour }, wantErr: "max age must be positive"},
+		{name: "negative max size", modify: func(c *GarbageCollectionConfig) { c.MaxSize = -1 }, wantErr: "max size must not be negative"},
+		{name: "negative max versions", modify: func(c *GarbageCollectionConfig) { c.MaxVersionsPerInstance = -1 }, wantErr: "max versions per instance must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gc := DefaultGC()
+			tt.modify(&gc)
+			err := gc.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestServer_GCOverCapacity(t *testing.T) {
+	cache := NewRuleSetCache()
+	logger := utils.NewTestLogger(t)
+
+	t.Log("Adding a single entry which alone exceeds the max size")
+	largeRules := strings.Repeat("SecRuleEngine On\n", 10)
+	cache.Put("test-ns/small", "SecRuleEngine On")
+	cache.Put("test-ns/large", largeRules)
+	gc := &GarbageCollectionConfig{
+		GCInterval: 50 * time.Millisecond,
+		MaxAge:     24 * time.Hour,
+		MaxSize:    testEntrySize(largeRules),
+	}
+
+	type call struct {
+		instance      string
+		size, maxSize int
+	}
+	calls := make(chan call, 10)
+	server := NewServer(cache, testServerAddr, logger, gc).WithOverCapacityHandler(func(instance string, size, maxSize int) {
+		calls <- call{instance: instance, size: size, maxSize: maxSize}
+	})
+
+	t.Log("Running the GC")
+	go server.rungc(t.Context())
+
+	t.Log("Verifying the handler fires for the largest instance")
+	select {
+	case c := <-calls:
+		assert.Equal(t, "test-ns/large", c.instance)
+		assert.Equal(t, cache.TotalSize(), c.size)
+		assert.Equal(t, gc.MaxSize, c.maxSize)
+	case <-time.After(time.Second):
+		t.Fatal("over capacity handler was not called")
+	}
+
+	t.Log("Verifying the over capacity gauge is set")
+	req := httptest.NewRequest(http.MethodGet, MetricsPath, nil)
+	w := httptest.NewRecorder()
+	server.mux.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), "coraza_cache_over_capacity 1")
+
+	t.Log("Verifying the gauge is cleared once the cache is back under its max size")
+	require.True(t, cache.Delete("test-ns/small"))
+	assert.Eventually(t, func() bool {
+		req := httptest.NewRequest(http.MethodGet, MetricsPath, nil)
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		return strings.Contains(w.Body.String(), "coraza_cache_over_capacity 0")
+	}, time.Second, 10*time.Millisecond)
+}
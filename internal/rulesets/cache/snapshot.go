@@ -0,0 +1,149 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+)
+
+// -----------------------------------------------------------------------------
+// RuleSetCache - Persistence
+// -----------------------------------------------------------------------------
+
+// Snapshot writes all cached entries for all instances to the file at path as
+// JSON. The file is written to a temporary file in the same directory and
+// renamed into place, so a crash mid-write never leaves a partial snapshot.
+func (c *RuleSetCache) Snapshot(path string) error {
+	c.mu.RLock()
+	data, err := json.Marshal(c.entries)
+	c.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode cache snapshot: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache snapshot: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write cache snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace cache snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Restore replaces the contents of the cache with the entries from a snapshot
+// previously written by Snapshot. If the file does not exist the returned
+// error wraps os.ErrNotExist and the cache is left unchanged. Malformed
+// instances (e.g. from a corrupt or hand-edited snapshot) are skipped and
+// logged rather than restored. The restored instances are tracked until
+// they're stored again, so that those whose RuleSet was deleted meanwhile can
+// be dropped with PruneRestored.
+func (c *RuleSetCache) Restore(path string, log logr.Logger) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read cache snapshot: %w", err)
+	}
+
+	var decoded map[string]*RuleSetEntries
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("failed to decode cache snapshot: %w", err)
+	}
+
+	entries := make(map[string]*RuleSetEntries, len(decoded))
+	for instance, instanceEntries := range decoded {
+		if err := validateRestoredEntries(instanceEntries); err != nil {
+			log.Info("Skipping malformed instance in cache snapshot", "instance", instance, "reason", err.Error())
+			continue
+		}
+		entries[instance] = instanceEntries
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = entries
+	c.restored = make(map[string]bool, len(entries))
+	for instance := range entries {
+		c.restored[instance] = true
+	}
+
+	return nil
+}
+
+// validateRestoredEntries returns why the entries of an instance decoded from
+// a snapshot can't be restored, or nil if they can: they must hold at least
+// one entry, every entry must have a UUID, and the latest UUID must be one of
+// them.
+func validateRestoredEntries(entries *RuleSetEntries) error {
+	if entries == nil {
+		return errors.New("instance is null")
+	}
+	if len(entries.Entries) == 0 {
+		return errors.New("instance has no entries")
+	}
+
+	latestFound := false
+	for _, entry := range entries.Entries {
+		if entry == nil || entry.UUID == "" {
+			return errors.New("instance has an entry without a UUID")
+		}
+		if entry.UUID == entries.Latest {
+			latestFound = true
+		}
+	}
+	if !latestFound {
+		return fmt.Errorf("latest entry %q is not among the instance's entries", entries.Latest)
+	}
+
+	return nil
+}
+
+// PruneRestored removes the instances restored from a snapshot which weren't
+// stored again since, unless keep reports they're still wanted (e.g. their
+// RuleSet still exists), and stops tracking restored instances. It returns
+// the number of instances removed.
+func (c *RuleSetCache) PruneRestored(keep func(instance string) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pruned := 0
+	for instance := range c.restored {
+		if !keep(instance) {
+			c.remove(instance)
+			pruned++
+		}
+	}
+	c.restored = make(map[string]bool)
+
+	return pruned
+}
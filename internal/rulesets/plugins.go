@@ -0,0 +1,74 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rulesets
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// CRS Plugins
+// -----------------------------------------------------------------------------
+
+// crsPluginInitIDs maps recognized Core Rule Set plugins to the id of the
+// SecAction which initializes them, within each plugin's reserved id range.
+var crsPluginInitIDs = map[string]int{
+	"body-decompress-plugin": 9505010,
+}
+
+// pluginSettingName matches the names of plugin transaction variables.
+var pluginSettingName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// RenderPluginInit renders the SecAction which initializes the named CRS
+// plugin: it enables the plugin and sets each of the given transaction
+// variables, in name order. Unknown plugins and settings which can't be
+// safely rendered are rejected.
+func RenderPluginInit(name string, settings map[string]string) (string, error) {
+	id, ok := crsPluginInitIDs[name]
+	if !ok {
+		return "", fmt.Errorf("unknown CRS plugin %q", name)
+	}
+
+	actions := []string{
+		fmt.Sprintf("id:%d", id),
+		"phase:1",
+		"pass",
+		"nolog",
+		fmt.Sprintf("setvar:'tx.%s_enabled=1'", name),
+	}
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		value := settings[key]
+		if !pluginSettingName.MatchString(key) {
+			return "", fmt.Errorf("invalid setting name %q for CRS plugin %s", key, name)
+		}
+		if strings.ContainsAny(value, "'\"\\\n") {
+			return "", fmt.Errorf("invalid value for setting %s of CRS plugin %s: quotes, backslashes and newlines are not allowed", key, name)
+		}
+		actions = append(actions, fmt.Sprintf("setvar:'tx.%s=%s'", key, value))
+	}
+
+	return fmt.Sprintf("SecAction \"%s\"", strings.Join(actions, ",")), nil
+}
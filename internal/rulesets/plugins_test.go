@@ -0,0 +1,82 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rulesets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderPluginInit(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   string
+		settings map[string]string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "defaults",
+			plugin:   "body-decompress-plugin",
+			expected: `SecAction "id:9505010,phase:1,pass,nolog,setvar:'tx.body-decompress-plugin_enabled=1'"`,
+		},
+		{
+			name:   "settings in name order",
+			plugin: "body-decompress-plugin",
+			settings: map[string]string{
+				"body-decompress-plugin_max_size": "1048576",
+				"body-decompress-plugin_formats":  "gzip deflate",
+			},
+			expected: `SecAction "id:9505010,phase:1,pass,nolog,setvar:'tx.body-decompress-plugin_enabled=1',` +
+				`setvar:'tx.body-decompress-plugin_formats=gzip deflate',` +
+				`setvar:'tx.body-decompress-plugin_max_size=1048576'"`,
+		},
+		{
+			name:    "unknown plugin",
+			plugin:  "not-a-plugin",
+			wantErr: true,
+		},
+		{
+			name:     "invalid setting name",
+			plugin:   "body-decompress-plugin",
+			settings: map[string]string{"max size": "1"},
+			wantErr:  true,
+		},
+		{
+			name:     "quote in setting value",
+			plugin:   "body-decompress-plugin",
+			settings: map[string]string{"body-decompress-plugin_max_size": "1',deny"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := RenderPluginInit(tt.plugin, tt.settings)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, rendered)
+			assert.Empty(t, FindDuplicateRuleIDs(rendered))
+			assert.Equal(t, 1, CountRules(rendered))
+		})
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rulesets
+
+import (
+	"fmt"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+// Sources
+// -----------------------------------------------------------------------------
+
+// Source is a named piece of SecLang rules which is aggregated with others,
+// e.g. the rules of a ConfigMap referenced by a RuleSet.
+type Source struct {
+	// Name identifies the source in messages, e.g. "ConfigMap base-rules".
+	Name string
+
+	// Rules contains the source's SecLang rules.
+	Rules string
+}
+
+// JoinSources aggregates the rules of the given sources in order, separated
+// by the given separator, which should start with a newline so that each
+// source starts on a new line regardless of whether the previous one ends
+// with one.
+func JoinSources(sources []Source, separator string) string {
+	rules := make([]string, 0, len(sources))
+	for _, source := range sources {
+		rules = append(rules, source.Rules)
+	}
+	return strings.Join(rules, separator)
+}
+
+// -----------------------------------------------------------------------------
+// Sources - Duplicate Rule IDs
+// -----------------------------------------------------------------------------
+
+// SourceDuplicateRuleID describes a rule id which is defined by more than one
+// source.
+type SourceDuplicateRuleID struct {
+	ID int
+
+	// FirstSource is the name of the source which previously defined the id.
+	FirstSource string
+
+	// Source is the name of the source which defines the id again.
+	Source string
+}
+
+// String returns a human readable description of the collision.
+func (d SourceDuplicateRuleID) String() string {
+	return fmt.Sprintf("id %d in %s duplicates %s", d.ID, d.Source, d.FirstSource)
+}
+
+// SourceDuplicateRuleIDsError is returned when rule ids collide across the
+// sources of an aggregate.
+type SourceDuplicateRuleIDsError struct {
+	Duplicates []SourceDuplicateRuleID
+}
+
+// Error implements error.
+func (e *SourceDuplicateRuleIDsError) Error() string {
+	collisions := make([]string, 0, len(e.Duplicates))
+	for _, d := range e.Duplicates {
+		collisions = append(collisions, d.String())
+	}
+	return fmt.Sprintf("duplicate rule ids across sources: %s", JoinLimited(collisions, "; "))
+}
+
+// FindSourceDuplicateRuleIDs returns every rule id which is defined by more
+// than one of the given sources, in the order the duplicates appear. Ids
+// defined more than once within a single source are reported by
+// FindDuplicateRuleIDs instead.
+func FindSourceDuplicateRuleIDs(sources []Source) []SourceDuplicateRuleID {
+	var duplicates []SourceDuplicateRuleID
+	definedBy := make(map[int]string)
+	for _, source := range sources {
+		seen := make(map[int]bool)
+		for _, d := range directives(source.Rules) {
+			id, ok := d.ruleID()
+			if !ok || seen[id] {
+				continue
+			}
+			seen[id] = true
+			if first, exists := definedBy[id]; exists {
+				duplicates = append(duplicates, SourceDuplicateRuleID{ID: id, FirstSource: first, Source: source.Name})
+			}
+			definedBy[id] = source.Name
+		}
+	}
+
+	return duplicates
+}
+
+// KeepLastRuleIDs returns a copy of the given sources in which every rule
+// whose id is defined again by a later source is removed, so that only the
+// last definition of each id remains. Rules chained to a removed rule are
+// removed with it.
+func KeepLastRuleIDs(sources []Source) []Source {
+	lastSource := make(map[int]int)
+	for i, source := range sources {
+		for _, d := range directives(source.Rules) {
+			if id, ok := d.ruleID(); ok {
+				lastSource[id] = i
+			}
+		}
+	}
+
+	result := make([]Source, len(sources))
+	for i, source := range sources {
+		result[i] = Source{Name: source.Name, Rules: removeRulesDefinedLater(source.Rules, i, lastSource)}
+	}
+
+	return result
+}
+
+// removeRulesDefinedLater removes the rules of the source at index from its
+// SecLang rules whose ids are last defined by a later source.
+func removeRulesDefinedLater(rules string, index int, lastSource map[int]int) string {
+	removed := make(map[int]bool)
+	removingChain := false
+	for _, d := range directives(rules) {
+		remove := removingChain
+		if !remove {
+			id, ok := d.ruleID()
+			remove = ok && lastSource[id] > index
+		}
+		removingChain = remove && d.hasAction(map[string]bool{"chain": true})
+		if !remove {
+			continue
+		}
+		for line := d.line; line <= d.endLine; line++ {
+			removed[line] = true
+		}
+	}
+	if len(removed) == 0 {
+		return rules
+	}
+
+	lines := strings.Split(rules, "\n")
+	kept := make([]string, 0, len(lines)-len(removed))
+	for i, line := range lines {
+		if !removed[i+1] {
+			kept = append(kept, line)
+		}
+	}
+
+	return strings.Join(kept, "\n")
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rulesets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinSources(t *testing.T) {
+	sources := []Source{
+		{Name: "ConfigMap a", Rules: "SecRuleEngine On"},
+		{Name: "ConfigMap b", Rules: `SecAction "id:1,phase:1,pass"`},
+	}
+	assert.Empty(t, JoinSources(nil, "\n"))
+	assert.Equal(t, "SecRuleEngine On\nSecAction \"id:1,phase:1,pass\"", JoinSources(sources, "\n"))
+	assert.Equal(t, "SecRuleEngine On\n\nSecAction \"id:1,phase:1,pass\"", JoinSources(sources, "\n\n"))
+
+	t.Log("Verifying sources lacking trailing newlines don't run together")
+	for _, separator := range []string{"\n", "\n\n"} {
+		assert.NoError(t, Validate(JoinSources(sources, separator)))
+	}
+}
+
+func TestFindSourceDuplicateRuleIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		sources    []Source
+		duplicates []SourceDuplicateRuleID
+	}{
+		{
+			name: "no sources",
+		},
+		{
+			name: "distinct ids across sources",
+			sources: []Source{
+				{Name: "ConfigMap base", Rules: `SecRule ARGS "@contains a" "id:1,phase:1,deny"`},
+				{Name: "ConfigMap overlay", Rules: `SecAction "id:2,phase:1,pass,nolog"`},
+			},
+		},
+		{
+			name: "colliding ids across sources",
+			sources: []Source{
+				{Name: "ConfigMap base", Rules: `SecRule ARGS "@contains a" "id:1,phase:1,deny"
+SecRule ARGS "@contains b" "id:2,phase:1,deny"`},
+				{Name: "ConfigMap overlay", Rules: `SecRule ARGS "@contains c" "id:2,phase:1,deny"`},
+			},
+			duplicates: []SourceDuplicateRuleID{{ID: 2, FirstSource: "ConfigMap base", Source: "ConfigMap overlay"}},
+		},
+		{
+			name: "id redefined by several sources",
+			sources: []Source{
+				{Name: "ConfigMap a", Rules: `SecAction "id:5,phase:1,pass"`},
+				{Name: "ConfigMap b", Rules: `SecAction "id:5,phase:1,pass"`},
+				{Name: "ConfigMap c", Rules: `SecAction "id:5,phase:1,pass"`},
+			},
+			duplicates: []SourceDuplicateRuleID{
+				{ID: 5, FirstSource: "ConfigMap a", Source: "ConfigMap b"},
+				{ID: 5, FirstSource: "ConfigMap b", Source: "ConfigMap c"},
+			},
+		},
+		{
+			name: "duplicates within a source are not reported",
+			sources: []Source{
+				{Name: "ConfigMap a", Rules: `SecAction "id:5,phase:1,pass"
+SecAction "id:5,phase:1,pass"`},
+			},
+		},
+		{
+			name: "commented out rules are ignored",
+			sources: []Source{
+				{Name: "ConfigMap a", Rules: `SecAction "id:5,phase:1,pass"`},
+				{Name: "ConfigMap b", Rules: `# SecAction "id:5,phase:1,pass"`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.duplicates, FindSourceDuplicateRuleIDs(tt.sources))
+		})
+	}
+}
+
+func TestSourceDuplicateRuleIDsError(t *testing.T) {
+	err := &SourceDuplicateRuleIDsError{Duplicates: []SourceDuplicateRuleID{
+		{ID: 2, FirstSource: "ConfigMap base", Source: "ConfigMap overlay"},
+		{ID: 7, FirstSource: "ConfigMap base", Source: "Inline rule source extra"},
+	}}
+	assert.Equal(t, "duplicate rule ids across sources: id 2 in ConfigMap overlay duplicates ConfigMap base; "+
+		"id 7 in Inline rule source extra duplicates ConfigMap base", err.Error())
+}
+
+func TestSourceDuplicateRuleIDsError_ManyDuplicates(t *testing.T) {
+	err := &SourceDuplicateRuleIDsError{}
+	for id := 1; id <= 50; id++ {
+		err.Duplicates = append(err.Duplicates, SourceDuplicateRuleID{ID: id, FirstSource: "ConfigMap a", Source: "ConfigMap b"})
+	}
+	assert.Contains(t, err.Error(), "id 5 in ConfigMap b duplicates ConfigMap a; ...and 45 more")
+	assert.NotContains(t, err.Error(), "id 6 ")
+}
+
+func TestKeepLastRuleIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		sources  []Source
+		expected []string
+	}{
+		{
+			name: "no collisions leaves sources untouched",
+			sources: []Source{
+				{Name: "a", Rules: `SecRule ARGS "@contains a" "id:1,phase:1,deny"`},
+				{Name: "b", Rules: `SecRule ARGS "@contains b" "id:2,phase:1,deny"`},
+			},
+			expected: []string{
+				`SecRule ARGS "@contains a" "id:1,phase:1,deny"`,
+				`SecRule ARGS "@contains b" "id:2,phase:1,deny"`,
+			},
+		},
+		{
+			name: "earlier definitions are removed",
+			sources: []Source{
+				{Name: "a", Rules: `SecRuleEngine On
+SecRule ARGS "@contains a" "id:1,phase:1,deny"
+SecRule ARGS "@contains b" "id:2,phase:1,deny"`},
+				{Name: "b", Rules: `SecRule ARGS "@contains c" "id:1,phase:1,pass"`},
+			},
+			expected: []string{
+				`SecRuleEngine On
+SecRule ARGS "@contains b" "id:2,phase:1,deny"`,
+				`SecRule ARGS "@contains c" "id:1,phase:1,pass"`,
+			},
+		},
+		{
+			name: "chained rules are removed with their starter",
+			sources: []Source{
+				{Name: "a", Rules: `SecRule ARGS "@contains a" "id:1,phase:1,deny,chain"
+    SecRule ARGS "@contains b" "t:none"
+SecRule ARGS "@contains c" "id:2,phase:1,deny"`},
+				{Name: "b", Rules: `SecAction "id:1,phase:1,pass"`},
+			},
+			expected: []string{
+				`SecRule ARGS "@contains c" "id:2,phase:1,deny"`,
+				`SecAction "id:1,phase:1,pass"`,
+			},
+		},
+		{
+			name: "continuation lines are removed with their directive",
+			sources: []Source{
+				{Name: "a", Rules: `SecRule ARGS "@contains a" \
+    "id:7,\
+    phase:1,\
+    deny"
+SecAction "id:8,phase:1,pass"`},
+				{Name: "b", Rules: `SecRule ARGS "@contains b" "id:7,phase:1,deny"`},
+			},
+			expected: []string{
+				`SecAction "id:8,phase:1,pass"`,
+				`SecRule ARGS "@contains b" "id:7,phase:1,deny"`,
+			},
+		},
+		{
+			name: "only the last of several definitions is kept",
+			sources: []Source{
+				{Name: "a", Rules: `SecAction "id:5,phase:1,pass,msg:'a'"`},
+				{Name: "b", Rules: `SecAction "id:5,phase:1,pass,msg:'b'"`},
+				{Name: "c", Rules: `SecAction "id:5,phase:1,pass,msg:'c'"`},
+			},
+			expected: []string{"", "", `SecAction "id:5,phase:1,pass,msg:'c'"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := KeepLastRuleIDs(tt.sources)
+			require.Len(t, result, len(tt.expected))
+			for i, source := range result {
+				assert.Equal(t, tt.sources[i].Name, source.Name)
+				assert.Equal(t, tt.expected[i], source.Rules)
+			}
+			assert.Empty(t, FindSourceDuplicateRuleIDs(result))
+			require.NoError(t, Validate(JoinSources(result, "\n")))
+		})
+	}
+}
@@ -0,0 +1,891 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rulesets provides utilities for working with WAF rulesets.
+package rulesets
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/corazawaf/coraza/v3"
+)
+
+// -----------------------------------------------------------------------------
+// Validation
+// -----------------------------------------------------------------------------
+
+// Validate checks that the given SecLang rules can be loaded by the Coraza
+// engine, returning the errors of ValidateDetailed joined into a single
+// error. Rules that define the same id more than once are rejected with a
+// *DuplicateRuleIDsError describing each collision, and rules with invalid
+// action lists (see FindActionViolations) with an *InvalidActionsError.
+// Warnings, such as rules with more than one disruptive action or an
+// unsupported operator, are ignored.
+func Validate(rules string) error {
+	return ValidateDetailed(rules).Err()
+}
+
+// ValidationResult separates problems which prevent rules from loading
+// (Errors) from questionable constructs the engine accepts (Warnings).
+type ValidationResult struct {
+	Errors   []error
+	Warnings []error
+}
+
+// Err returns the errors joined into a single error, or nil if there are
+// none. Warnings are ignored. Like errors.Join, the returned error unwraps to
+// each of the errors, but its message only lists the first
+// MaxListedProblems of them.
+func (r ValidationResult) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return &joinedErrors{errs: r.Errors}
+}
+
+// joinedErrors joins errors like errors.Join, listing only the first
+// MaxListedProblems of them in its message.
+type joinedErrors struct {
+	errs []error
+}
+
+// Error implements error.
+func (e *joinedErrors) Error() string {
+	messages := make([]string, 0, len(e.errs))
+	for _, err := range e.errs {
+		messages = append(messages, err.Error())
+	}
+	return JoinLimited(messages, "\n")
+}
+
+// Unwrap returns each of the joined errors, so callers can use errors.Is.
+func (e *joinedErrors) Unwrap() []error {
+	return e.errs
+}
+
+// PromoteWarnings returns a copy of the result with its warnings treated as
+// errors, for deployments which want to reject questionable rules outright.
+func (r ValidationResult) PromoteWarnings() ValidationResult {
+	errs := make([]error, 0, len(r.Errors)+len(r.Warnings))
+	errs = append(errs, r.Errors...)
+	return ValidationResult{Errors: append(errs, r.Warnings...)}
+}
+
+// ValidateDetailed checks the given SecLang rules like Validate, but
+// classifies each problem: duplicate ids, missing ids, invalid phases and
+// rules Coraza can't load are Errors, while rules with more than one
+// disruptive action (of which only the last takes effect) and rules with an
+// operator Coraza doesn't support (see FindUnsupportedOperators) are
+// Warnings. Coraza only compiles the rules if no other errors were found,
+// with unsupported operators replaced so the rest of the rules are checked.
+func ValidateDetailed(rules string) ValidationResult {
+	var result ValidationResult
+	if duplicates := FindDuplicateRuleIDs(rules); len(duplicates) > 0 {
+		result.Errors = append(result.Errors, &DuplicateRuleIDsError{Duplicates: duplicates})
+	}
+
+	var invalid []*ActionViolation
+	for _, v := range FindActionViolations(rules) {
+		if errors.Is(v, ErrMultipleDisruptiveActions) {
+			result.Warnings = append(result.Warnings, v)
+			continue
+		}
+		invalid = append(invalid, v)
+	}
+	if len(invalid) > 0 {
+		result.Errors = append(result.Errors, &InvalidActionsError{Violations: invalid})
+	}
+
+	unsupported := FindUnsupportedOperators(rules)
+	for _, u := range unsupported {
+		result.Warnings = append(result.Warnings, u)
+	}
+
+	if len(result.Errors) == 0 {
+		compiled := withSupportedOperators(rules, unsupported)
+		if _, err := coraza.NewWAF(coraza.NewWAFConfig().WithDirectives(compiled)); err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+	}
+
+	return result
+}
+
+// -----------------------------------------------------------------------------
+// Validation - Problem Lists
+// -----------------------------------------------------------------------------
+
+// MaxListedProblems is the number of problems (e.g. duplicate rule ids)
+// listed in the messages of the errors returned by validation, so that they
+// fit in events and status conditions however many problems the rules have.
+const MaxListedProblems = 5
+
+// JoinLimited joins the first MaxListedProblems items with sep, followed by
+// the number of items left out, if any.
+func JoinLimited(items []string, sep string) string {
+	if len(items) <= MaxListedProblems {
+		return strings.Join(items, sep)
+	}
+	return fmt.Sprintf("%s%s...and %d more", strings.Join(items[:MaxListedProblems], sep), sep, len(items)-MaxListedProblems)
+}
+
+// -----------------------------------------------------------------------------
+// Validation - Redaction
+// -----------------------------------------------------------------------------
+
+// RedactedError wraps an error returned by validating rules which mustn't be
+// disclosed (e.g. read from a Secret). Its message only describes the
+// position and kind of each problem, as Coraza's errors and the details of
+// ActionViolations quote the rules.
+type RedactedError struct {
+	Err error
+	msg string
+}
+
+// Redact wraps err, returned by Validate or ValidateDetailed for the given
+// rules, in a *RedactedError. Errors which only report positions (e.g. a
+// *DuplicateRuleIDsError) are described as is, while Coraza's errors are
+// reduced to the line of the directive it failed to compile.
+func Redact(rules string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &RedactedError{Err: err, msg: redactedMessage(rules, err)}
+}
+
+// Error implements error.
+func (e *RedactedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the redacted error, so callers can use errors.Is.
+func (e *RedactedError) Unwrap() error {
+	return e.Err
+}
+
+// redactedMessage describes err without quoting the rules.
+func redactedMessage(rules string, err error) string {
+	switch e := err.(type) {
+	case *RedactedError:
+		return e.msg
+	case *DuplicateRuleIDsError:
+		return e.Error()
+	case *ActionViolation:
+		return e.redacted()
+	case *UnsupportedOperator:
+		return e.redacted()
+	case *InvalidActionsError:
+		violations := make([]string, 0, len(e.Violations))
+		for _, v := range e.Violations {
+			violations = append(violations, v.redacted())
+		}
+		return fmt.Sprintf("invalid rule actions: %s", JoinLimited(violations, "; "))
+	case interface{ Unwrap() []error }:
+		errs := e.Unwrap()
+		messages := make([]string, 0, len(errs))
+		for _, err := range errs {
+			messages = append(messages, redactedMessage(rules, err))
+		}
+		return JoinLimited(messages, "\n")
+	}
+
+	if line := compileErrorLine(rules); line > 0 {
+		return fmt.Sprintf("line %d: directive can't be compiled", line)
+	}
+	return "rules can't be compiled"
+}
+
+// compileErrorLine returns the line on which the first directive Coraza fails
+// to compile starts, or zero if the rules compile. As Coraza stops at the
+// first directive it fails to compile without reporting its line, it is
+// found by binary search over prefixes of the rules ending at a directive,
+// which fail once they include it, taking about log2(n) compiles for n
+// directives. It is only used to redact errors, which are rare.
+func compileErrorLine(rules string) int {
+	ds := directives(rules)
+	lines := strings.Split(rules, "\n")
+	fails := func(i int) bool {
+		prefix := strings.Join(lines[:ds[i].endLine], "\n")
+		_, err := coraza.NewWAF(coraza.NewWAFConfig().WithDirectives(prefix))
+		return err != nil
+	}
+
+	if len(ds) == 0 || !fails(len(ds)-1) {
+		return 0
+	}
+	i := sort.Search(len(ds)-1, fails)
+	return ds[i].line
+}
+
+// -----------------------------------------------------------------------------
+// Validation - Duplicate Rule IDs
+// -----------------------------------------------------------------------------
+
+// DuplicateRuleID describes a rule id which is defined more than once. Line
+// numbers are 1-based and refer to the line on which each directive starts.
+type DuplicateRuleID struct {
+	ID        int
+	FirstLine int
+	Line      int
+}
+
+// String returns a human readable description of the collision.
+func (d DuplicateRuleID) String() string {
+	return fmt.Sprintf("id %d on line %d duplicates line %d", d.ID, d.Line, d.FirstLine)
+}
+
+// DuplicateRuleIDsError is returned by Validate when rule ids collide.
+type DuplicateRuleIDsError struct {
+	Duplicates []DuplicateRuleID
+}
+
+// Error implements error.
+func (e *DuplicateRuleIDsError) Error() string {
+	collisions := make([]string, 0, len(e.Duplicates))
+	for _, d := range e.Duplicates {
+		collisions = append(collisions, d.String())
+	}
+	return fmt.Sprintf("duplicate rule ids: %s", JoinLimited(collisions, "; "))
+}
+
+// FindDuplicateRuleIDs returns every rule id in the given SecLang rules which
+// is defined by more than one SecRule or SecAction directive, in the order the
+// duplicates appear.
+func FindDuplicateRuleIDs(rules string) []DuplicateRuleID {
+	var duplicates []DuplicateRuleID
+	seen := make(map[int]int)
+	for _, d := range directives(rules) {
+		id, ok := d.ruleID()
+		if !ok {
+			continue
+		}
+		if first, exists := seen[id]; exists {
+			duplicates = append(duplicates, DuplicateRuleID{ID: id, FirstLine: first, Line: d.line})
+			continue
+		}
+		seen[id] = d.line
+	}
+
+	return duplicates
+}
+
+// -----------------------------------------------------------------------------
+// Validation - Action Lists
+// -----------------------------------------------------------------------------
+
+var (
+	// ErrMissingRuleID indicates a SecRule or SecAction without an id
+	// action. Rules continuing a chain are exempt.
+	ErrMissingRuleID = errors.New("missing id action")
+
+	// ErrInvalidPhase indicates a phase action outside of 1-5 (or request,
+	// response and logging).
+	ErrInvalidPhase = errors.New("invalid phase")
+
+	// ErrMultipleDisruptiveActions indicates a rule with more than one
+	// disruptive action.
+	ErrMultipleDisruptiveActions = errors.New("multiple disruptive actions")
+
+	// ErrUnknownTransformation indicates a t action naming a transformation
+	// Coraza doesn't support, e.g. a misspelling.
+	ErrUnknownTransformation = errors.New("unknown transformation")
+)
+
+// knownTransformations are the transformations Coraza supports, lower cased
+// as Coraza looks them up case insensitively.
+var knownTransformations = map[string]bool{
+	"base64decode":       true,
+	"base64decodeext":    true,
+	"base64encode":       true,
+	"cmdline":            true,
+	"compresswhitespace": true,
+	"cssdecode":          true,
+	"escapeseqdecode":    true,
+	"hexdecode":          true,
+	"hexencode":          true,
+	"htmlentitydecode":   true,
+	"jsdecode":           true,
+	"length":             true,
+	"lowercase":          true,
+	"md5":                true,
+	"none":               true,
+	"normalisepath":      true,
+	"normalisepathwin":   true,
+	"normalizepath":      true,
+	"normalizepathwin":   true,
+	"removecomments":     true,
+	"removecommentschar": true,
+	"removenulls":        true,
+	"removewhitespace":   true,
+	"replacecomments":    true,
+	"replacenulls":       true,
+	"sha1":               true,
+	"trim":               true,
+	"trimleft":           true,
+	"trimright":          true,
+	"uppercase":          true,
+	"urldecode":          true,
+	"urldecodeuni":       true,
+	"urlencode":          true,
+	"utf8tounicode":      true,
+}
+
+// disruptiveActions are the actions which decide what happens to the
+// transaction when a rule matches. A rule may have at most one.
+var disruptiveActions = map[string]bool{
+	"allow":    true,
+	"block":    true,
+	"deny":     true,
+	"drop":     true,
+	"pass":     true,
+	"redirect": true,
+}
+
+// ActionViolation describes a problem with the action list of a SecRule or
+// SecAction directive. Line and Column are 1-based and refer to where the
+// directive starts. ID is zero if the rule has no id.
+type ActionViolation struct {
+	ID     int
+	Line   int
+	Column int
+
+	// Err is one of ErrMissingRuleID, ErrInvalidPhase,
+	// ErrMultipleDisruptiveActions or ErrUnknownTransformation.
+	Err error
+
+	// Detail optionally describes the offending actions.
+	Detail string
+}
+
+// Error implements error.
+func (v *ActionViolation) Error() string {
+	msg := v.redacted()
+	if v.Detail != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, v.Detail)
+	}
+	return msg
+}
+
+// redacted describes the violation without its Detail, which quotes the
+// offending actions.
+func (v *ActionViolation) redacted() string {
+	if v.ID != 0 {
+		return fmt.Sprintf("line %d, column %d: rule id %d: %s", v.Line, v.Column, v.ID, v.Err)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", v.Line, v.Column, v.Err)
+}
+
+// Unwrap returns the kind of violation, so callers can use errors.Is.
+func (v *ActionViolation) Unwrap() error {
+	return v.Err
+}
+
+// InvalidActionsError is returned by Validate when rules have invalid action
+// lists.
+type InvalidActionsError struct {
+	Violations []*ActionViolation
+}
+
+// Error implements error.
+func (e *InvalidActionsError) Error() string {
+	violations := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		violations = append(violations, v.Error())
+	}
+	return fmt.Sprintf("invalid rule actions: %s", JoinLimited(violations, "; "))
+}
+
+// Unwrap returns each violation as a distinct error.
+func (e *InvalidActionsError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		errs = append(errs, v)
+	}
+	return errs
+}
+
+// FindActionViolations returns every problem with the action lists of the
+// SecRule and SecAction directives in the given SecLang rules, in the order
+// they appear: a missing id (except on rules continuing a chain), a phase
+// outside of 1-5, more than one disruptive action (e.g. deny and pass), or a
+// transformation Coraza doesn't support.
+// Duplicate ids are reported separately by FindDuplicateRuleIDs.
+func FindActionViolations(rules string) []*ActionViolation {
+	var violations []*ActionViolation
+	chained := false
+	for _, d := range directives(rules) {
+		if len(d.args) == 0 {
+			continue
+		}
+		switch strings.ToLower(d.args[0]) {
+		case "secrule", "secaction":
+		default:
+			continue
+		}
+
+		id, hasID := d.ruleID()
+		violation := func(err error, detail string) {
+			violations = append(violations, &ActionViolation{
+				ID: id, Line: d.line, Column: d.column, Err: err, Detail: detail,
+			})
+		}
+
+		if !hasID && !chained {
+			violation(ErrMissingRuleID, "")
+		}
+
+		var disruptive []string
+		actions, _ := d.actions()
+		for _, action := range splitActions(actions) {
+			name, value, _ := strings.Cut(action, ":")
+			name = strings.ToLower(strings.TrimSpace(name))
+			switch {
+			case name == "phase":
+				if !validPhase(strings.Trim(strings.TrimSpace(value), "'")) {
+					violation(ErrInvalidPhase, fmt.Sprintf("phase:%s", strings.TrimSpace(value)))
+				}
+			case name == "t":
+				transformation := strings.Trim(strings.TrimSpace(value), "'")
+				if !knownTransformations[strings.ToLower(transformation)] {
+					violation(ErrUnknownTransformation, fmt.Sprintf("t:%s", transformation))
+				}
+			case disruptiveActions[name]:
+				disruptive = append(disruptive, name)
+			}
+		}
+		if len(disruptive) > 1 {
+			violation(ErrMultipleDisruptiveActions, strings.Join(disruptive, ", "))
+		}
+
+		chained = d.hasAction(map[string]bool{"chain": true})
+	}
+
+	return violations
+}
+
+// validPhase reports whether the value of a phase action is a phase Coraza
+// recognizes: 1 to 5, or one of the request, response and logging aliases.
+func validPhase(phase string) bool {
+	switch phase {
+	case "request", "response", "logging":
+		return true
+	}
+	n, err := strconv.Atoi(phase)
+	return err == nil && n >= 1 && n <= 5
+}
+
+// -----------------------------------------------------------------------------
+// Validation - Operators
+// -----------------------------------------------------------------------------
+
+// ErrUnsupportedOperator indicates a SecRule whose operator Coraza doesn't
+// support, e.g. a ModSecurity operator Coraza doesn't implement or a
+// misspelling.
+var ErrUnsupportedOperator = errors.New("unsupported operator")
+
+// supportedOperators are the operators Coraza supports. Unlike actions and
+// transformations, Coraza looks them up case sensitively.
+var supportedOperators = map[string]bool{
+	"beginsWith":           true,
+	"contains":             true,
+	"detectSQLi":           true,
+	"detectXSS":            true,
+	"endsWith":             true,
+	"eq":                   true,
+	"ge":                   true,
+	"geoLookup":            true,
+	"gt":                   true,
+	"inspectFile":          true,
+	"ipMatch":              true,
+	"ipMatchFromDataset":   true,
+	"ipMatchFromFile":      true,
+	"le":                   true,
+	"lt":                   true,
+	"noMatch":              true,
+	"pm":                   true,
+	"pmFromDataset":        true,
+	"pmFromFile":           true,
+	"rbl":                  true,
+	"restpath":             true,
+	"rx":                   true,
+	"streq":                true,
+	"unconditionalMatch":   true,
+	"validateByteRange":    true,
+	"validateNid":          true,
+	"validateUrlEncoding":  true,
+	"validateUtf8Encoding": true,
+	"within":               true,
+}
+
+// UnsupportedOperator describes a SecRule whose operator Coraza doesn't
+// support. Line and Column are 1-based and refer to where the directive
+// starts. ID is zero if the rule has no id.
+type UnsupportedOperator struct {
+	ID       int
+	Line     int
+	Column   int
+	Operator string
+
+	// endLine is the line on which the directive ends.
+	endLine int
+}
+
+// Error implements error.
+func (u *UnsupportedOperator) Error() string {
+	return fmt.Sprintf("%s (@%s)", u.redacted(), u.Operator)
+}
+
+// redacted describes the rule without naming its operator.
+func (u *UnsupportedOperator) redacted() string {
+	if u.ID != 0 {
+		return fmt.Sprintf("line %d, column %d: rule id %d: %s", u.Line, u.Column, u.ID, ErrUnsupportedOperator)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", u.Line, u.Column, ErrUnsupportedOperator)
+}
+
+// Unwrap returns ErrUnsupportedOperator, so callers can use errors.Is.
+func (u *UnsupportedOperator) Unwrap() error {
+	return ErrUnsupportedOperator
+}
+
+// FindUnsupportedOperators returns every SecRule in the given SecLang rules
+// whose operator Coraza doesn't support, in the order they appear. Such
+// rules fail to load in Coraza builds which don't provide the operator
+// through a plugin.
+func FindUnsupportedOperators(rules string) []*UnsupportedOperator {
+	var unsupported []*UnsupportedOperator
+	for _, d := range directives(rules) {
+		name, ok := d.operator()
+		if !ok || name == "" || supportedOperators[name] {
+			continue
+		}
+		id, _ := d.ruleID()
+		unsupported = append(unsupported, &UnsupportedOperator{
+			ID: id, Line: d.line, Column: d.column, Operator: name, endLine: d.endLine,
+		})
+	}
+
+	return unsupported
+}
+
+// withSupportedOperators returns the rules with each of the given unsupported
+// operators replaced by @unconditionalMatch, which ignores its argument, so
+// that the rest of the rules can be compiled. Lines and chains are kept
+// intact, so compile errors still refer to the original rules.
+func withSupportedOperators(rules string, unsupported []*UnsupportedOperator) string {
+	if len(unsupported) == 0 {
+		return rules
+	}
+
+	lines := strings.Split(rules, "\n")
+	for _, u := range unsupported {
+		for i := u.Line - 1; i < u.endLine && i < len(lines); i++ {
+			if strings.Contains(lines[i], "@"+u.Operator) {
+				lines[i] = strings.Replace(lines[i], "@"+u.Operator, "@unconditionalMatch", 1)
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// -----------------------------------------------------------------------------
+// Validation - Missing Actions
+// -----------------------------------------------------------------------------
+
+// effectiveActions are the disruptive and flow control actions which give a
+// matching SecRule an effect on the transaction.
+var effectiveActions = map[string]bool{
+	"allow":     true,
+	"block":     true,
+	"chain":     true,
+	"deny":      true,
+	"drop":      true,
+	"pass":      true,
+	"pause":     true,
+	"proxy":     true,
+	"redirect":  true,
+	"skip":      true,
+	"skipafter": true,
+}
+
+// RuleWithoutAction describes a SecRule which has no disruptive or flow
+// control action. Line numbers are 1-based and refer to the line on which the
+// directive starts. ID is zero if the rule has no id.
+type RuleWithoutAction struct {
+	ID   int
+	Line int
+}
+
+// String returns a human readable description of the rule.
+func (r RuleWithoutAction) String() string {
+	if r.ID == 0 {
+		return fmt.Sprintf("SecRule on line %d has no disruptive or flow action", r.Line)
+	}
+	return fmt.Sprintf("SecRule id %d on line %d has no disruptive or flow action", r.ID, r.Line)
+}
+
+// FindRulesWithoutAction returns every SecRule in the given SecLang rules
+// which has no disruptive (e.g. deny, block, pass) or flow control (chain,
+// skip, skipAfter) action, and so likely matches without doing anything.
+// SecAction directives are unconditional by design and are never reported,
+// nor are rules continuing a chain, which inherit the chain's disruptive
+// action.
+func FindRulesWithoutAction(rules string) []RuleWithoutAction {
+	var missing []RuleWithoutAction
+	chained := false
+	for _, d := range directives(rules) {
+		if len(d.args) == 0 || !strings.EqualFold(d.args[0], "secrule") {
+			continue
+		}
+
+		effective := d.hasAction(effectiveActions)
+		if !chained && !effective {
+			id, _ := d.ruleID()
+			missing = append(missing, RuleWithoutAction{ID: id, Line: d.line})
+		}
+		chained = d.hasAction(map[string]bool{"chain": true})
+	}
+
+	return missing
+}
+
+// -----------------------------------------------------------------------------
+// Validation - Rule Counting
+// -----------------------------------------------------------------------------
+
+// CountRules returns the number of SecRule and SecAction directives in the
+// given SecLang rules. Configuration directives (e.g. SecRuleEngine), blank
+// lines and comments are not counted, so rules which count zero enforce
+// nothing.
+func CountRules(rules string) int {
+	count := 0
+	for _, d := range directives(rules) {
+		if len(d.args) == 0 {
+			continue
+		}
+		switch strings.ToLower(d.args[0]) {
+		case "secrule", "secaction":
+			count++
+		}
+	}
+
+	return count
+}
+
+// -----------------------------------------------------------------------------
+// Validation - Directive Scanning
+// -----------------------------------------------------------------------------
+
+// Coraza doesn't expose the directives it parses, so the checks above scan
+// the rules themselves, following Coraza's parser where it matters to them:
+// a line ending with a backslash is joined as is (without the backslash) to
+// the next non-blank, non-comment line, arguments may be quoted with
+// backslash escapes, and action lists split on commas outside single
+// quotes, skipping escaped characters. The scanner doesn't follow Include
+// directives or backtick-delimited SecDataset blocks (whose lines it reads
+// as directives). Problems it misses are still caught when Coraza compiles
+// the rules.
+
+// directive is a single (possibly multi-line) SecLang directive. Line and
+// column are 1-based and refer to where the directive starts, endLine to the
+// line on which it ends.
+type directive struct {
+	line    int
+	endLine int
+	column  int
+	args    []string
+}
+
+// directives splits SecLang rules into directives, joining lines continued
+// with a trailing backslash and skipping blank lines and comments, including
+// within continued directives.
+func directives(rules string) []directive {
+	var result []directive
+	var current strings.Builder
+	start, column := 0, 0
+
+	for i, line := range strings.Split(rules, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if current.Len() == 0 {
+			start = i + 1
+			column = len(line) - len(strings.TrimLeft(line, " \t")) + 1
+		}
+
+		if continued, ok := strings.CutSuffix(trimmed, "\\"); ok {
+			current.WriteString(continued)
+			continue
+		}
+
+		current.WriteString(trimmed)
+		result = append(result, directive{line: start, endLine: i + 1, column: column, args: splitArgs(current.String())})
+		current.Reset()
+	}
+
+	if current.Len() > 0 {
+		result = append(result, directive{line: start, endLine: strings.Count(rules, "\n") + 1, column: column, args: splitArgs(current.String())})
+	}
+
+	return result
+}
+
+// actions returns the action list of a SecRule or SecAction directive, if
+// any.
+func (d directive) actions() (string, bool) {
+	if len(d.args) == 0 {
+		return "", false
+	}
+
+	switch strings.ToLower(d.args[0]) {
+	case "secrule":
+		if len(d.args) < 4 {
+			return "", false
+		}
+		return d.args[3], true
+	case "secaction":
+		if len(d.args) < 2 {
+			return "", false
+		}
+		return d.args[1], true
+	default:
+		return "", false
+	}
+}
+
+// operator returns the name of the operator of a SecRule directive, if any.
+// Like Coraza, an operator without an @ name defaults to rx.
+func (d directive) operator() (string, bool) {
+	if len(d.args) < 3 || !strings.EqualFold(d.args[0], "secrule") {
+		return "", false
+	}
+
+	op := strings.TrimPrefix(d.args[2], "!")
+	if !strings.HasPrefix(op, "@") {
+		return "rx", true
+	}
+	name, _, _ := strings.Cut(op[1:], " ")
+	return name, true
+}
+
+// ruleID returns the id action of a SecRule or SecAction directive, if any.
+func (d directive) ruleID() (int, bool) {
+	actions, ok := d.actions()
+	if !ok {
+		return 0, false
+	}
+
+	for _, action := range splitActions(actions) {
+		key, value, ok := strings.Cut(action, ":")
+		if !ok || strings.ToLower(strings.TrimSpace(key)) != "id" {
+			continue
+		}
+		id, err := strconv.Atoi(strings.Trim(strings.TrimSpace(value), "'"))
+		if err != nil {
+			return 0, false
+		}
+		return id, true
+	}
+
+	return 0, false
+}
+
+// hasAction reports whether the directive's action list contains any of the
+// named actions, ignoring arguments (e.g. "skipAfter:END" matches
+// "skipafter").
+func (d directive) hasAction(names map[string]bool) bool {
+	actions, ok := d.actions()
+	if !ok {
+		return false
+	}
+
+	for _, action := range splitActions(actions) {
+		name, _, _ := strings.Cut(action, ":")
+		if names[strings.ToLower(strings.TrimSpace(name))] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitArgs splits a directive into whitespace separated arguments, honoring
+// double and single quotes and backslash escapes within them.
+func splitArgs(s string) []string {
+	var args []string
+	var arg strings.Builder
+	var quote rune
+	inArg := false
+
+	for i := 0; i < len(s); i++ {
+		c := rune(s[i])
+		switch {
+		case quote != 0 && c == '\\' && i+1 < len(s):
+			arg.WriteByte(s[i])
+			arg.WriteByte(s[i+1])
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+			inArg = true
+		case quote == 0 && (c == ' ' || c == '\t'):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteByte(s[i])
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args
+}
+
+// splitActions splits a SecLang action list on commas which are not within
+// single quotes. Like Coraza, a character following a backslash is skipped,
+// so escaped quotes and commas don't split actions.
+func splitActions(s string) []string {
+	var actions []string
+	inQuote := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if i > 0 && s[i-1] == '\\' {
+			continue
+		}
+		switch s[i] {
+		case '\'':
+			inQuote = !inQuote
+		case ',':
+			if !inQuote {
+				actions = append(actions, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(actions, s[start:])
+}
@@ -0,0 +1,630 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rulesets
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		wantErr bool
+	}{
+		{
+			name:  "empty rules",
+			rules: "",
+		},
+		{
+			name:  "valid rule",
+			rules: "SecRule REQUEST_URI \"@contains /admin\" \"id:1,phase:1,deny,status:403\"",
+		},
+		{
+			name:    "unknown directive",
+			rules:   "SecNotARealDirective On",
+			wantErr: true,
+		},
+		{
+			name:    "malformed rule",
+			rules:   "SecRule REQUEST_URI",
+			wantErr: true,
+		},
+		{
+			name: "distinct ids",
+			rules: `SecRule ARGS "@contains a" "id:1,phase:1,deny"
+SecRule ARGS "@contains b" "id:2,phase:1,deny"`,
+		},
+		{
+			name: "duplicate ids",
+			rules: `SecRule ARGS "@contains a" "id:1,phase:1,deny"
+SecRule ARGS "@contains b" "id:1,phase:1,deny"`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid actions",
+			rules:   `SecRule ARGS "@contains a" "id:1,phase:6,deny"`,
+			wantErr: true,
+		},
+		{
+			name:  "multiple disruptive actions",
+			rules: `SecRule ARGS "@contains a" "id:1,phase:1,deny,drop"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.rules)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateDetailed(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    string
+		errors   int
+		warnings int
+	}{
+		{
+			name:  "valid rule",
+			rules: `SecRule REQUEST_URI "@contains /admin" "id:1,phase:1,deny,status:403"`,
+		},
+		{
+			name:   "syntax error",
+			rules:  "SecNotARealDirective On",
+			errors: 1,
+		},
+		{
+			name: "duplicate ids and invalid actions",
+			rules: `SecRule ARGS "@contains a" "id:1,phase:1,deny"
+SecRule ARGS "@contains b" "id:1,phase:8,deny"`,
+			errors: 2,
+		},
+		{
+			name:     "multiple disruptive actions is a warning",
+			rules:    `SecRule ARGS "@contains a" "id:1,phase:1,deny,pass"`,
+			warnings: 1,
+		},
+		{
+			name: "errors and warnings",
+			rules: `SecRule ARGS "@contains a" "phase:1,deny"
+SecRule ARGS "@contains b" "id:2,phase:1,deny,drop"`,
+			errors:   1,
+			warnings: 1,
+		},
+		{
+			name: "continuations and escaped quotes are read like Coraza",
+			rules: `SecRule ARGS "@rx \"a" \
+    # the actions
+    "id:1,phase:1,\
+    block,msg:'it\',deny,s'"`,
+		},
+		{
+			name:     "unsupported operator is a warning",
+			rules:    `SecRule ARGS "@verifyCC \\d{13,16}" "id:1,phase:2,deny"`,
+			warnings: 1,
+		},
+		{
+			name: "compile errors are reported alongside unsupported operators",
+			rules: `SecRule ARGS "@verifyCC \\d{13,16}" "id:1,phase:2,deny"
+SecRule ARGS "@rx (unclosed" "id:2,phase:2,deny"`,
+			errors:   1,
+			warnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ValidateDetailed(tt.rules)
+			assert.Len(t, result.Errors, tt.errors)
+			assert.Len(t, result.Warnings, tt.warnings)
+			if tt.errors == 0 {
+				assert.NoError(t, result.Err())
+			} else {
+				assert.Error(t, result.Err())
+			}
+		})
+	}
+}
+
+func TestValidationResult_PromoteWarnings(t *testing.T) {
+	result := ValidateDetailed(`SecRule ARGS "@contains a" "id:1,phase:1,deny,pass"`)
+	require.NoError(t, result.Err())
+	require.Len(t, result.Warnings, 1)
+
+	promoted := result.PromoteWarnings()
+	assert.Empty(t, promoted.Warnings)
+	require.Error(t, promoted.Err())
+	assert.ErrorIs(t, promoted.Err(), ErrMultipleDisruptiveActions)
+	assert.Len(t, result.Warnings, 1, "the original result must not be modified")
+
+	t.Log("Verifying unsupported operators are promoted to errors")
+	result = ValidateDetailed(`SecRule ARGS "@containsWord a" "id:1,phase:1,deny"`)
+	require.NoError(t, result.Err())
+	require.Len(t, result.Warnings, 1)
+	assert.ErrorIs(t, result.Warnings[0], ErrUnsupportedOperator)
+	assert.ErrorIs(t, result.PromoteWarnings().Err(), ErrUnsupportedOperator)
+}
+
+func TestFindUnsupportedOperators(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		want  []*UnsupportedOperator
+	}{
+		{
+			name: "supported operators",
+			rules: `SecRule ARGS "@contains a" "id:1,phase:1,deny"
+SecRule ARGS "!@rx ^b" "id:2,phase:1,deny"
+SecRule ARGS "implicit rx" "id:3,phase:1,deny"
+SecRule ARGS "@unconditionalMatch" "id:4,phase:1,pass"`,
+		},
+		{
+			name:  "unsupported operator",
+			rules: `SecRule ARGS "@verifyCC \\d{13,16}" "id:1,phase:2,deny"`,
+			want:  []*UnsupportedOperator{{ID: 1, Line: 1, Column: 1, Operator: "verifyCC", endLine: 1}},
+		},
+		{
+			name:  "negated unsupported operator",
+			rules: `SecRule ARGS "!@containsWord a" "id:1,phase:2,deny"`,
+			want:  []*UnsupportedOperator{{ID: 1, Line: 1, Column: 1, Operator: "containsWord", endLine: 1}},
+		},
+		{
+			name:  "operators are case sensitive",
+			rules: `SecRule ARGS "@Contains a" "id:1,phase:2,deny"`,
+			want:  []*UnsupportedOperator{{ID: 1, Line: 1, Column: 1, Operator: "Contains", endLine: 1}},
+		},
+		{
+			name: "chained rule spanning lines",
+			rules: `SecRule ARGS "@contains a" "id:1,phase:2,deny,chain"
+  SecRule ARGS \
+    "@strmatch b" \
+    "t:none"`,
+			want: []*UnsupportedOperator{{Line: 2, Column: 3, Operator: "strmatch", endLine: 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FindUnsupportedOperators(tt.rules))
+		})
+	}
+}
+
+func TestValidateDetailed_UnsupportedOperatorInChain(t *testing.T) {
+	t.Log("Validating a chain whose second rule has an unsupported operator")
+	result := ValidateDetailed(`SecRule ARGS "@contains a" "id:1,phase:2,deny,chain"
+  SecRule ARGS "@strmatch b" "t:none"`)
+	assert.NoError(t, result.Err(), "the chain should compile once the operator is replaced")
+	require.Len(t, result.Warnings, 1)
+
+	t.Log("Verifying the redacted warning doesn't name the operator")
+	redacted := Redact("", result.Warnings[0])
+	assert.Equal(t, "line 2, column 3: unsupported operator", redacted.Error())
+	assert.ErrorIs(t, redacted, ErrUnsupportedOperator)
+}
+
+func TestFindDuplicateRuleIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      string
+		duplicates []DuplicateRuleID
+	}{
+		{
+			name:  "no rules",
+			rules: "",
+		},
+		{
+			name:  "single rule",
+			rules: `SecRule REQUEST_URI "@contains /admin" "id:1,phase:1,deny,status:403"`,
+		},
+		{
+			name: "distinct ids",
+			rules: `SecRuleEngine On
+SecRule ARGS "@contains a" "id:1,phase:1,deny"
+SecAction "id:2,phase:1,pass,nolog"`,
+		},
+		{
+			name: "duplicate ids",
+			rules: `SecRule ARGS "@contains a" "id:10,phase:1,deny"
+SecRule ARGS "@contains b" "id:11,phase:1,deny"
+
+SecRule ARGS "@contains c" "id:10,phase:1,deny"`,
+			duplicates: []DuplicateRuleID{{ID: 10, FirstLine: 1, Line: 4}},
+		},
+		{
+			name: "duplicate between SecRule and SecAction",
+			rules: `SecAction "id:5,phase:1,pass,nolog"
+SecRule ARGS "@contains a" "phase:1,deny,id:5"`,
+			duplicates: []DuplicateRuleID{{ID: 5, FirstLine: 1, Line: 2}},
+		},
+		{
+			name: "multiple collisions reported in order",
+			rules: `SecRule ARGS "@contains a" "id:1,deny"
+SecRule ARGS "@contains b" "id:2,deny"
+SecRule ARGS "@contains c" "id:2,deny"
+SecRule ARGS "@contains d" "id:1,deny"`,
+			duplicates: []DuplicateRuleID{
+				{ID: 2, FirstLine: 2, Line: 3},
+				{ID: 1, FirstLine: 1, Line: 4},
+			},
+		},
+		{
+			name: "continuation lines report the starting line",
+			rules: `SecRule ARGS "@contains a" \
+    "id:7,\
+    phase:1,\
+    deny"
+SecRule ARGS "@contains b" "id:7,deny"`,
+			duplicates: []DuplicateRuleID{{ID: 7, FirstLine: 1, Line: 5}},
+		},
+		{
+			name: "commented out rules are ignored",
+			rules: `SecRule ARGS "@contains a" "id:1,deny"
+# SecRule ARGS "@contains b" "id:1,deny"`,
+		},
+		{
+			name: "id text inside operator is ignored",
+			rules: `SecRule ARGS "@contains id:1" "id:2,deny"
+SecRule ARGS "@contains b" "id:1,deny"`,
+		},
+		{
+			name: "quoted action values containing commas",
+			rules: `SecRule ARGS "@contains a" "id:3,msg:'a, id:4',deny"
+SecRule ARGS "@contains b" "id:4,deny"`,
+		},
+		{
+			name: "continuation lines are joined as is",
+			rules: `SecRule ARGS "@contains a" "id:1\
+2,deny"
+SecRule ARGS "@contains b" "id:12,deny"`,
+			duplicates: []DuplicateRuleID{{ID: 12, FirstLine: 1, Line: 3}},
+		},
+		{
+			name: "blank and comment lines within continuations are skipped",
+			rules: `SecRule ARGS "@contains a" \
+
+# a comment within the rule
+    "id:7,deny"
+SecRule ARGS "@contains b" "id:7,deny"`,
+			duplicates: []DuplicateRuleID{{ID: 7, FirstLine: 1, Line: 5}},
+		},
+		{
+			name: "escaped double quotes within arguments",
+			rules: `SecRule ARGS "@rx \" id:5" "id:6,deny"
+SecRule ARGS "@contains b" "id:5,deny"`,
+		},
+		{
+			name: "escaped single quotes within action values",
+			rules: `SecRule ARGS "@contains a" "msg:'it\',id:9,',id:8,deny"
+SecRule ARGS "@contains b" "id:9,deny"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.duplicates, FindDuplicateRuleIDs(tt.rules))
+		})
+	}
+}
+
+func TestCountRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		count int
+	}{
+		{name: "empty", rules: "", count: 0},
+		{
+			name: "comments and configuration only",
+			rules: `# SecRule ARGS "@contains a" "id:1,deny"
+SecRuleEngine On
+
+SecRequestBodyAccess On`,
+			count: 0,
+		},
+		{
+			name: "rules and actions",
+			rules: `SecRuleEngine On
+SecRule ARGS "@contains a" \
+    "id:1,phase:1,deny"
+secaction "id:2,phase:1,pass,nolog"`,
+			count: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.count, CountRules(tt.rules))
+		})
+	}
+}
+
+func TestFindRulesWithoutAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		missing []RuleWithoutAction
+	}{
+		{name: "empty", rules: ""},
+		{
+			name:  "rule with disruptive action",
+			rules: `SecRule ARGS "@contains a" "id:1,phase:1,deny,status:403"`,
+		},
+		{
+			name: "rule without action",
+			rules: `SecRuleEngine On
+SecRule ARGS "@contains a" "id:1,phase:1,log,msg:'matched'"`,
+			missing: []RuleWithoutAction{{ID: 1, Line: 2}},
+		},
+		{
+			name:  "action is intentionally unconditional",
+			rules: `SecAction "id:1,phase:1,nolog,setvar:tx.score=0"`,
+		},
+		{
+			name: "complex rules",
+			rules: `SecRule REQUEST_HEADERS:User-Agent "@pm sqlmap nikto" \
+    "id:1,phase:1,block,msg:'Scanner detected'"
+SecRule ARGS "@rx select" \
+    "id:2,phase:2,chain,deny"
+    SecRule ARGS "@rx from" "t:lowercase"
+SecRule REQUEST_URI "@beginsWith /health" "id:3,phase:1,skipAfter:END"
+SecMarker END`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.missing, FindRulesWithoutAction(tt.rules))
+		})
+	}
+}
+
+func TestValidate_DuplicateRuleIDsError(t *testing.T) {
+	err := Validate(`SecRule ARGS "@contains a" "id:1,deny"
+SecRule ARGS "@contains b" "id:1,deny"`)
+	require.Error(t, err)
+
+	var dupErr *DuplicateRuleIDsError
+	require.ErrorAs(t, err, &dupErr)
+	assert.Equal(t, []DuplicateRuleID{{ID: 1, FirstLine: 1, Line: 2}}, dupErr.Duplicates)
+	assert.Contains(t, err.Error(), "id 1 on line 2 duplicates line 1")
+}
+
+func TestFindActionViolations(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      string
+		violations []*ActionViolation
+	}{
+		{name: "empty", rules: ""},
+		{
+			name: "valid rules",
+			rules: `SecRuleEngine On
+SecRule ARGS "@contains a" "id:1,phase:1,deny,status:403"
+SecAction "id:2,phase:request,pass,nolog"
+SecRule ARGS "@contains b" "id:3,phase:logging,log"`,
+		},
+		{
+			name:  "missing id",
+			rules: `SecRule ARGS "@contains a" "phase:1,deny"`,
+			violations: []*ActionViolation{
+				{Line: 1, Column: 1, Err: ErrMissingRuleID},
+			},
+		},
+		{
+			name: "missing id on SecAction",
+			rules: `SecRuleEngine On
+  SecAction "phase:1,pass,nolog"`,
+			violations: []*ActionViolation{
+				{Line: 2, Column: 3, Err: ErrMissingRuleID},
+			},
+		},
+		{
+			name: "chained rules do not need an id",
+			rules: `SecRule ARGS "@rx select" \
+    "id:1,phase:2,chain,deny"
+    SecRule ARGS "@rx from" "t:lowercase"`,
+		},
+		{
+			name:  "phase too high",
+			rules: `SecRule ARGS "@contains a" "id:1,phase:6,deny"`,
+			violations: []*ActionViolation{
+				{ID: 1, Line: 1, Column: 1, Err: ErrInvalidPhase, Detail: "phase:6"},
+			},
+		},
+		{
+			name:  "phase zero",
+			rules: `SecAction "id:1,phase:0,pass"`,
+			violations: []*ActionViolation{
+				{ID: 1, Line: 1, Column: 1, Err: ErrInvalidPhase, Detail: "phase:0"},
+			},
+		},
+		{
+			name:  "unknown phase name",
+			rules: `SecRule ARGS "@contains a" "id:1,phase:headers,deny"`,
+			violations: []*ActionViolation{
+				{ID: 1, Line: 1, Column: 1, Err: ErrInvalidPhase, Detail: "phase:headers"},
+			},
+		},
+		{
+			name:  "multiple disruptive actions",
+			rules: `SecRule ARGS "@contains a" "id:1,phase:1,deny,pass"`,
+			violations: []*ActionViolation{
+				{ID: 1, Line: 1, Column: 1, Err: ErrMultipleDisruptiveActions, Detail: "deny, pass"},
+			},
+		},
+		{
+			name:  "disruptive action text inside msg is ignored",
+			rules: `SecRule ARGS "@contains a" "id:1,phase:1,msg:'deny, drop',block"`,
+		},
+		{
+			name:  "escaped quotes inside msg are skipped",
+			rules: `SecRule ARGS "@contains a" "id:1,phase:1,block,msg:'it\',deny,s'"`,
+		},
+		{
+			name: "action list continued across lines",
+			rules: `SecRule ARGS "@contains a" "id:1,\
+    phase:1,deny,\
+    # deny and drop
+    drop"`,
+			violations: []*ActionViolation{
+				{ID: 1, Line: 1, Column: 1, Err: ErrMultipleDisruptiveActions, Detail: "deny, drop"},
+			},
+		},
+		{
+			name: "valid transformation chain",
+			rules: `SecRule ARGS "@rx select" \
+    "id:1,phase:2,t:none,t:urlDecodeUni,t:base64Decode,t:LOWERCASE,t:removeWhitespace,deny"`,
+		},
+		{
+			name:  "misspelled transformation",
+			rules: `SecRule ARGS "@rx select" "id:1,phase:2,t:none,t:urldecoduni,deny"`,
+			violations: []*ActionViolation{
+				{ID: 1, Line: 1, Column: 1, Err: ErrUnknownTransformation, Detail: "t:urldecoduni"},
+			},
+		},
+		{
+			name: "unknown transformation on chained rule",
+			rules: `SecRule ARGS "@rx select" "id:1,phase:2,chain,deny"
+    SecRule ARGS "@rx from" "t:lowercas"`,
+			violations: []*ActionViolation{
+				{Line: 2, Column: 5, Err: ErrUnknownTransformation, Detail: "t:lowercas"},
+			},
+		},
+		{
+			name: "violations reported in order",
+			rules: `SecRule ARGS "@contains a" "id:1,phase:1,deny"
+SecRule ARGS "@contains b" "phase:9,drop,block"`,
+			violations: []*ActionViolation{
+				{Line: 2, Column: 1, Err: ErrMissingRuleID},
+				{Line: 2, Column: 1, Err: ErrInvalidPhase, Detail: "phase:9"},
+				{Line: 2, Column: 1, Err: ErrMultipleDisruptiveActions, Detail: "drop, block"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.violations, FindActionViolations(tt.rules))
+		})
+	}
+}
+
+func TestValidate_InvalidActionsError(t *testing.T) {
+	err := Validate(`SecRule ARGS "@contains a" "id:1,phase:7,deny"
+SecRule ARGS "@contains b" "id:2,phase:1,deny,drop"`)
+	require.Error(t, err)
+
+	var actionsErr *InvalidActionsError
+	require.ErrorAs(t, err, &actionsErr)
+	assert.Len(t, actionsErr.Violations, 1)
+	assert.ErrorIs(t, err, ErrInvalidPhase)
+	assert.NotErrorIs(t, err, ErrMultipleDisruptiveActions, "multiple disruptive actions are only a warning")
+	assert.NotErrorIs(t, err, ErrMissingRuleID)
+	assert.Contains(t, err.Error(), "line 1, column 1: rule id 1: invalid phase (phase:7)")
+	assert.NotContains(t, err.Error(), "(deny, drop)")
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		wantIs  error
+		wantMsg string
+	}{
+		{
+			name: "duplicate rule ids",
+			rules: `SecRule ARGS "@contains secret-a" "id:1,phase:1,deny"
+SecRule ARGS "@contains secret-b" "id:1,phase:1,deny"`,
+			wantMsg: "duplicate rule ids: id 1 on line 2 duplicates line 1",
+		},
+		{
+			name:    "invalid actions",
+			rules:   `SecRule ARGS "@contains secret" "id:1,phase:1,t:secret,deny"`,
+			wantIs:  ErrUnknownTransformation,
+			wantMsg: "invalid rule actions: line 1, column 1: rule id 1: unknown transformation",
+		},
+		{
+			name: "coraza error",
+			rules: `SecRuleEngine On
+
+# Rules
+SecRule ARGS "@contains a" "id:1,phase:1,deny"
+SecRule ARGS "@rx (secret" \
+    "id:2,phase:1,deny"
+SecRule ARGS "@contains b" "id:3,phase:1,deny"`,
+			wantMsg: "line 5: directive can't be compiled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validationErr := ValidateDetailed(tt.rules).Err()
+			require.Error(t, validationErr)
+
+			err := Redact(tt.rules, validationErr)
+			var redacted *RedactedError
+			require.ErrorAs(t, err, &redacted)
+			assert.ErrorIs(t, err, validationErr)
+			if tt.wantIs != nil {
+				assert.ErrorIs(t, err, tt.wantIs)
+			}
+			assert.Contains(t, err.Error(), tt.wantMsg)
+			assert.NotContains(t, err.Error(), "secret")
+		})
+	}
+
+	assert.NoError(t, Redact("SecRuleEngine On", nil))
+}
+
+func TestJoinLimited(t *testing.T) {
+	assert.Equal(t, "", JoinLimited(nil, "; "))
+	assert.Equal(t, "a; b", JoinLimited([]string{"a", "b"}, "; "))
+	assert.Equal(t, "1\n2\n3\n4\n5\n...and 2 more", JoinLimited([]string{"1", "2", "3", "4", "5", "6", "7"}, "\n"))
+}
+
+func TestValidate_ManyDuplicateRuleIDs(t *testing.T) {
+	t.Log("Validating rules which define the same 200 rule ids twice")
+	var rules strings.Builder
+	for range 2 {
+		for id := 1; id <= 200; id++ {
+			fmt.Fprintf(&rules, "SecRule ARGS \"@contains a\" \"id:%d,phase:1,deny\"\n", id)
+		}
+	}
+	err := Validate(rules.String())
+	require.Error(t, err)
+
+	t.Log("Verifying the error still unwraps to every duplicate but only lists a few")
+	var dupErr *DuplicateRuleIDsError
+	require.ErrorAs(t, err, &dupErr)
+	assert.Len(t, dupErr.Duplicates, 200)
+	assert.Contains(t, err.Error(), fmt.Sprintf("...and %d more", 200-MaxListedProblems))
+	assert.Less(t, len(err.Error()), 1024)
+	assert.Less(t, len(Redact(rules.String(), err).Error()), 1024)
+}
@@ -97,6 +97,42 @@ func (s *Scenario) ExpectWasmPluginExists(namespace, name string) {
 	}, DefaultTimeout, DefaultInterval, "WasmPlugin %s/%s should exist", namespace, name)
 }
 
+// ExpectWasmPluginPriority polls until the WasmPlugin's spec.priority equals
+// the given priority. An unset priority is treated as 0, matching Istio.
+func (s *Scenario) ExpectWasmPluginPriority(namespace, name string, priority int32) {
+	s.T.Helper()
+	s.T.Logf("Waiting for WasmPlugin %s/%s to have priority %d", namespace, name, priority)
+	require.EventuallyWithT(s.T, func(collect *assert.CollectT) {
+		actual, err := s.wasmPluginPriority(namespace, name)
+		if !assert.NoError(collect, err) {
+			return
+		}
+		assert.Equal(collect, int64(priority), actual,
+			"WasmPlugin %s/%s: unexpected priority", namespace, name)
+	}, DefaultTimeout, DefaultInterval)
+}
+
+// ExpectWasmPluginOrder polls until the first WasmPlugin has a strictly
+// higher spec.priority than the second, meaning Istio runs it earlier in the
+// filter chain. Unset priorities are treated as 0, matching Istio.
+func (s *Scenario) ExpectWasmPluginOrder(namespace, first, second string) {
+	s.T.Helper()
+	s.T.Logf("Waiting for WasmPlugin %s/%s to be ordered before %s", namespace, first, second)
+	require.EventuallyWithT(s.T, func(collect *assert.CollectT) {
+		firstPriority, err := s.wasmPluginPriority(namespace, first)
+		if !assert.NoError(collect, err) {
+			return
+		}
+		secondPriority, err := s.wasmPluginPriority(namespace, second)
+		if !assert.NoError(collect, err) {
+			return
+		}
+		assert.Greater(collect, firstPriority, secondPriority,
+			"WasmPlugin %s/%s (priority %d) should be ordered before %s (priority %d)",
+			namespace, first, firstPriority, second, secondPriority)
+	}, DefaultTimeout, DefaultInterval)
+}
+
 // ExpectResourceGone polls until the specified resource no longer exists.
 func (s *Scenario) ExpectResourceGone(namespace, name string, gvr schema.GroupVersionResource) {
 	s.T.Helper()
@@ -130,6 +166,22 @@ func (s *Scenario) ExpectCreateFails(msg string, fn func() error) {
 // Helpers
 // -----------------------------------------------------------------------------
 
+// wasmPluginPriority returns the spec.priority of the named WasmPlugin, or 0
+// if unset.
+func (s *Scenario) wasmPluginPriority(namespace, name string) (int64, error) {
+	obj, err := s.F.DynamicClient.Resource(WasmPluginGVR).Namespace(namespace).Get(
+		s.T.Context(), name, metav1.GetOptions{},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("get WasmPlugin %s/%s: %w", namespace, name, err)
+	}
+	priority, _, err := unstructured.NestedInt64(obj.Object, "spec", "priority")
+	if err != nil {
+		return 0, fmt.Errorf("read WasmPlugin %s/%s priority: %w", namespace, name, err)
+	}
+	return priority, nil
+}
+
 func hasCondition(obj *unstructured.Unstructured, condType, status string) bool {
 	conditions, found, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
 	if err != nil || !found {
@@ -0,0 +1,153 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// -----------------------------------------------------------------------------
+// RuleSet Cache - Proxy
+// -----------------------------------------------------------------------------
+
+// CacheProxy manages a port-forward to the RuleSet cache server of the
+// operator's leader and provides helpers for asserting which version of a
+// RuleSet's rules the control plane cached.
+type CacheProxy struct {
+	s       *Scenario
+	pod     string
+	baseURL string
+	httpc   *http.Client
+}
+
+// ProxyToCache sets up a SPDY port-forward to the RuleSet cache server of the
+// operator's leader pod, which is the replica reconciling RuleSets, and
+// returns a CacheProxy for querying it. The port-forward is automatically
+// cleaned up when the scenario ends.
+func (s *Scenario) ProxyToCache() *CacheProxy {
+	s.T.Helper()
+	pod := s.OperatorLeader()
+	port := AllocatePort()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		if err := s.forwardPodPort(ctx, OperatorNamespace, pod, port, operatorCachePort); err != nil && ctx.Err() == nil {
+			s.T.Logf("port-forward to operator pod %s: %v", pod, err)
+		}
+	}()
+	s.OnCleanup(cancel)
+
+	proxy := &CacheProxy{
+		s:       s,
+		pod:     pod,
+		baseURL: fmt.Sprintf("http://localhost:%s", port),
+		httpc:   &http.Client{Timeout: 5 * time.Second},
+	}
+
+	// Wait for the port-forward to accept connections.
+	require.Eventually(s.T, func() bool {
+		resp, err := proxy.httpc.Get(proxy.baseURL + "/rules/")
+		if err != nil {
+			return false
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return true
+	}, DefaultTimeout, time.Second,
+		"port-forward to cache server on operator pod %s (localhost:%s) not ready", pod, port,
+	)
+
+	s.T.Logf("Port-forwarding cache server on operator pod %s -> localhost:%s", pod, port)
+	return proxy
+}
+
+// LatestUUID returns the UUID of the latest cached version of the RuleSet's
+// rules, or an empty string if the RuleSet isn't cached.
+func (c *CacheProxy) LatestUUID(namespace, name string) (string, error) {
+	resp, err := c.httpc.Get(fmt.Sprintf("%s/rules/%s/%s/latest", c.baseURL, namespace, name))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	var latest struct {
+		UUID string `json:"uuid"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&latest); err != nil {
+		return "", fmt.Errorf("decode latest response: %w", err)
+	}
+	return latest.UUID, nil
+}
+
+// -----------------------------------------------------------------------------
+// RuleSet Cache - Assertions
+// -----------------------------------------------------------------------------
+
+// ExpectCached polls until the RuleSet's rules are cached and returns the
+// UUID of the latest version.
+func (c *CacheProxy) ExpectCached(namespace, name string) string {
+	c.s.T.Helper()
+	return c.expectUUID(namespace, name, "")
+}
+
+// ExpectRulesUpdated applies a change (e.g. UpdateConfigMap) and polls until
+// a new version of the RuleSet's rules is cached, returning its UUID. The
+// RuleSet must be cached before the change.
+func (c *CacheProxy) ExpectRulesUpdated(namespace, name string, change func()) string {
+	c.s.T.Helper()
+	previous := c.ExpectCached(namespace, name)
+	change()
+	uuid := c.expectUUID(namespace, name, previous)
+	c.s.T.Logf("RuleSet %s/%s cached new rules: %s -> %s", namespace, name, previous, uuid)
+	return uuid
+}
+
+// expectUUID polls until the RuleSet's latest cached UUID is set and differs
+// from previous, and returns it.
+func (c *CacheProxy) expectUUID(namespace, name, previous string) string {
+	c.s.T.Helper()
+
+	var uuid string
+	require.EventuallyWithT(c.s.T, func(collect *assert.CollectT) {
+		var err error
+		uuid, err = c.LatestUUID(namespace, name)
+		if !assert.NoError(collect, err, "cache server on operator pod %s", c.pod) {
+			return
+		}
+		assert.NotEmpty(collect, uuid, "RuleSet %s/%s not cached", namespace, name)
+		assert.NotEqual(collect, previous, uuid, "RuleSet %s/%s still cached at %s", namespace, name, previous)
+	}, DefaultTimeout, DefaultInterval)
+
+	return uuid
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheProxy_LatestUUID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rules/ns/cached/latest":
+			_, _ = w.Write([]byte(`{"uuid":"1234","timestamp":"2026-01-01T00:00:00Z"}`))
+		case "/rules/ns/missing/latest":
+			http.Error(w, "RuleSet not found", http.StatusNotFound)
+		default:
+			http.Error(w, "RuleSet cache not ready", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	c := &CacheProxy{baseURL: srv.URL, httpc: srv.Client()}
+
+	uuid, err := c.LatestUUID("ns", "cached")
+	require.NoError(t, err)
+	assert.Equal(t, "1234", uuid)
+
+	uuid, err = c.LatestUUID("ns", "missing")
+	require.NoError(t, err)
+	assert.Empty(t, uuid)
+
+	_, err = c.LatestUUID("ns", "unavailable")
+	require.ErrorContains(t, err, "unexpected status 503")
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// -----------------------------------------------------------------------------
+// Load - Consts
+// -----------------------------------------------------------------------------
+
+// DefaultConcurrency is the number of workers used by the load assertions.
+const DefaultConcurrency = 10
+
+// -----------------------------------------------------------------------------
+// Load - Concurrent Requests
+// -----------------------------------------------------------------------------
+
+// ConcurrentResult summarizes the results of concurrent requests.
+type ConcurrentResult struct {
+	// StatusCodes counts the responses per HTTP status code.
+	StatusCodes map[int]int
+
+	// Errors holds the errors of requests which got no response.
+	Errors []error
+
+	// P50, P90 and P99 are latency percentiles of the requests which got a
+	// response.
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+}
+
+// ConcurrentGet makes n GET requests for path through the proxy, spread
+// across the given number of workers, and summarizes the results.
+func (g *GatewayProxy) ConcurrentGet(path string, n, workers int) ConcurrentResult {
+	result := ConcurrentResult{StatusCodes: map[int]int{}}
+	latencies := make([]time.Duration, 0, n)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	requests := make(chan struct{})
+	for range max(workers, 1) {
+		wg.Go(func() {
+			for range requests {
+				start := time.Now()
+				resp, err := g.httpc.Get(g.URL(path))
+				if err == nil {
+					_, _ = io.Copy(io.Discard, resp.Body)
+					_ = resp.Body.Close()
+				}
+				latency := time.Since(start)
+
+				mu.Lock()
+				if err != nil {
+					result.Errors = append(result.Errors, err)
+				} else {
+					result.StatusCodes[resp.StatusCode]++
+					latencies = append(latencies, latency)
+				}
+				mu.Unlock()
+			}
+		})
+	}
+	for range n {
+		requests <- struct{}{}
+	}
+	close(requests)
+	wg.Wait()
+
+	slices.Sort(latencies)
+	result.P50 = percentile(latencies, 0.50)
+	result.P90 = percentile(latencies, 0.90)
+	result.P99 = percentile(latencies, 0.99)
+	return result
+}
+
+// percentile returns the p-th percentile (0 < p <= 1) of the sorted
+// durations using the nearest-rank method, or 0 if there are none.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// -----------------------------------------------------------------------------
+// Load - Assertions
+// -----------------------------------------------------------------------------
+
+// ExpectAllBlocked makes n concurrent GET requests for path and fails the
+// test unless every one of them returns HTTP 403. Unlike ExpectBlocked it
+// doesn't poll, so callers should first wait for the blocking rule to be
+// enforced (e.g. with ExpectBlocked).
+func (g *GatewayProxy) ExpectAllBlocked(path string, n int) ConcurrentResult {
+	g.s.T.Helper()
+	result := g.ConcurrentGet(path, n, DefaultConcurrency)
+	g.s.T.Logf("%d concurrent requests for %s: status codes %v, %d errors, latency p50=%s p90=%s p99=%s",
+		n, path, result.StatusCodes, len(result.Errors), result.P50, result.P90, result.P99)
+
+	assert.Empty(g.s.T, result.Errors, "expected every request for %s to get a response", path)
+	assert.Equal(g.s.T, map[int]int{http.StatusForbidden: n}, result.StatusCodes,
+		"expected all %d concurrent requests for %s to be blocked (403)", n, path)
+	return result
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// -----------------------------------------------------------------------------
+// Operator - Consts
+// -----------------------------------------------------------------------------
+
+const (
+	// OperatorNamespace is the namespace the operator is deployed to.
+	OperatorNamespace = "coraza-system"
+
+	// OperatorDeployment is the name of the operator's Deployment.
+	OperatorDeployment = "coraza-controller-manager"
+
+	// operatorPodSelector selects the operator's pods.
+	operatorPodSelector = "control-plane=coraza-controller-manager"
+
+	// operatorLeaseName is the operator's leader election Lease.
+	operatorLeaseName = "waf.k8s.coraza.io"
+
+	// operatorCachePort is the port the operator's RuleSet cache server
+	// listens on.
+	operatorCachePort = "18080"
+)
+
+// operatorControllers are the reporting controllers of the operator's events.
+var operatorControllers = map[string]bool{
+	"ruleset-controller": true,
+	"engine-controller":  true,
+}
+
+// -----------------------------------------------------------------------------
+// Operator - Scaling
+// -----------------------------------------------------------------------------
+
+// ScaleOperator scales the operator Deployment to the given number of
+// replicas and waits until that many pods are ready, returning their names.
+// The original replica count is restored during cleanup.
+func (s *Scenario) ScaleOperator(replicas int32) []string {
+	s.T.Helper()
+	deployments := s.F.KubeClient.AppsV1().Deployments(OperatorNamespace)
+
+	scale, err := deployments.GetScale(s.T.Context(), OperatorDeployment, metav1.GetOptions{})
+	require.NoError(s.T, err, "get operator scale")
+	original := scale.Spec.Replicas
+
+	if original != replicas {
+		s.T.Logf("Scaling operator from %d to %d replicas", original, replicas)
+		scale.Spec.Replicas = replicas
+		_, err = deployments.UpdateScale(s.T.Context(), OperatorDeployment, scale, metav1.UpdateOptions{})
+		require.NoError(s.T, err, "scale operator to %d replicas", replicas)
+
+		s.OnCleanup(func() {
+			ctx := context.Background()
+			scale, err := deployments.GetScale(ctx, OperatorDeployment, metav1.GetOptions{})
+			if err != nil {
+				s.T.Logf("cleanup: get operator scale: %v", err)
+				return
+			}
+			scale.Spec.Replicas = original
+			if _, err := deployments.UpdateScale(ctx, OperatorDeployment, scale, metav1.UpdateOptions{}); err != nil {
+				s.T.Logf("cleanup: restore operator to %d replicas: %v", original, err)
+			}
+		})
+	}
+
+	var pods []string
+	require.EventuallyWithT(s.T, func(collect *assert.CollectT) {
+		pods = s.readyOperatorPods(collect)
+		assert.Len(collect, pods, int(replicas), "ready operator pods: %v", pods)
+	}, DefaultTimeout, DefaultInterval)
+
+	return pods
+}
+
+// readyOperatorPods returns the names of the operator's ready, running pods.
+func (s *Scenario) readyOperatorPods(collect *assert.CollectT) []string {
+	list, err := s.F.KubeClient.CoreV1().Pods(OperatorNamespace).List(
+		s.T.Context(), metav1.ListOptions{LabelSelector: operatorPodSelector},
+	)
+	if !assert.NoError(collect, err) {
+		return nil
+	}
+
+	var pods []string
+	for _, pod := range list.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, c := range pod.Status.Conditions {
+			if c.Type == "Ready" && c.Status == "True" {
+				pods = append(pods, pod.Name)
+				break
+			}
+		}
+	}
+	return pods
+}
+
+// -----------------------------------------------------------------------------
+// Operator - Leader Election
+// -----------------------------------------------------------------------------
+
+// OperatorLeader polls until the operator's leader election Lease is held and
+// returns the name of the leader pod.
+func (s *Scenario) OperatorLeader() string {
+	s.T.Helper()
+
+	var leader string
+	require.EventuallyWithT(s.T, func(collect *assert.CollectT) {
+		lease, err := s.F.KubeClient.CoordinationV1().Leases(OperatorNamespace).Get(
+			s.T.Context(), operatorLeaseName, metav1.GetOptions{},
+		)
+		if !assert.NoError(collect, err) {
+			return
+		}
+		if !assert.NotNil(collect, lease.Spec.HolderIdentity, "lease %s has no holder", operatorLeaseName) {
+			return
+		}
+		// Holder identities are "<pod name>_<uuid>".
+		leader, _, _ = strings.Cut(*lease.Spec.HolderIdentity, "_")
+	}, DefaultTimeout, DefaultInterval)
+
+	return leader
+}
+
+// ExpectOperatorHA scales the operator to 2 replicas and asserts the HA
+// design: only the elected leader reconciles, so every operator event in
+// namespace was reported by the leader, while the RuleSet cache server runs
+// on every replica, so each pod answers cache requests.
+//
+// Cache contents are not compared: followers don't reconcile, so they only
+// answer with the rules they cached while leading (typically none).
+func (s *Scenario) ExpectOperatorHA(namespace string) {
+	s.T.Helper()
+
+	pods := s.ScaleOperator(2)
+	leader := s.OperatorLeader()
+	require.Contains(s.T, pods, leader, "leader %s is not a ready operator pod", leader)
+	s.T.Logf("Operator leader is %s (replicas: %v)", leader, pods)
+
+	s.expectEventsReportedBy(namespace, leader)
+	for _, pod := range pods {
+		s.expectCacheServing(pod)
+	}
+}
+
+// expectEventsReportedBy asserts that every operator event in the namespace
+// was reported by the given operator pod.
+func (s *Scenario) expectEventsReportedBy(namespace, pod string) {
+	s.T.Helper()
+
+	reported := 0
+	for _, e := range s.GetEvents(namespace) {
+		if !operatorControllers[e.ReportingController] {
+			continue
+		}
+		reported++
+		// Reporting instances are "<controller>-<hostname>", and a pod's
+		// hostname is its name.
+		if !strings.HasSuffix(e.ReportingInstance, "-"+pod) {
+			s.T.Errorf("%s event %s/%s reported by %s, expected only leader %s to reconcile",
+				e.ReportingController, e.Type, e.Reason, e.ReportingInstance, pod)
+		}
+	}
+	require.Positive(s.T, reported, "no operator events in %s", namespace)
+}
+
+// expectCacheServing polls until the RuleSet cache server on the given
+// operator pod answers an HTTP request.
+func (s *Scenario) expectCacheServing(pod string) {
+	s.T.Helper()
+
+	port := AllocatePort()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := s.forwardPodPort(ctx, OperatorNamespace, pod, port, operatorCachePort); err != nil && ctx.Err() == nil {
+			s.T.Logf("port-forward to operator pod %s: %v", pod, err)
+		}
+	}()
+
+	httpc := &http.Client{Timeout: 5 * time.Second}
+	url := fmt.Sprintf("http://localhost:%s/rules/", port)
+	require.EventuallyWithT(s.T, func(collect *assert.CollectT) {
+		resp, err := httpc.Get(url)
+		if !assert.NoError(collect, err, "cache server on operator pod %s", pod) {
+			return
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		s.T.Logf("Cache server on operator pod %s answered with %d", pod, resp.StatusCode)
+	}, DefaultTimeout, time.Second)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"net/http"
+	"time"
+
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+)
+
+// -----------------------------------------------------------------------------
+// Propagation SLA - Consts
+// -----------------------------------------------------------------------------
+
+const (
+	// DefaultPropagationSLA is the default upper bound on the time from a
+	// RuleSet or ConfigMap change to the data plane enforcing it. It is the
+	// default test poll interval (5s) plus a margin for reconciliation, cache
+	// rotation, and the WASM plugin reloading its rules.
+	DefaultPropagationSLA = 30 * time.Second
+
+	// propagationInterval is the polling interval used while measuring
+	// propagation. It is shorter than DefaultInterval so the measured
+	// latency is reasonably precise.
+	propagationInterval = 250 * time.Millisecond
+)
+
+// -----------------------------------------------------------------------------
+// Propagation SLA - Assertions
+// -----------------------------------------------------------------------------
+
+// PropagationResult holds the measured latencies of a rule change.
+type PropagationResult struct {
+	// ControlPlane is the time from the change until the operator cached a
+	// new version of the RuleSet's rules.
+	ControlPlane time.Duration
+
+	// DataPlane is the time from the change until the gateway returned the
+	// expected status. It includes the ControlPlane latency.
+	DataPlane time.Duration
+}
+
+// ExpectPropagationWithin applies a rule change and asserts that it reaches
+// the data plane within the given SLA, failing the test with the measured
+// latency otherwise.
+//
+// Detection is two-phase: first the control plane is watched for a new
+// RulesCached event on the RuleSet, then the data plane is polled until path
+// returns the expected status code. Both latencies are logged and returned.
+func (g *GatewayProxy) ExpectPropagationWithin(sla time.Duration, ruleSetNamespace, ruleSetName, path string, code int, change func()) PropagationResult {
+	g.s.T.Helper()
+
+	start := time.Now()
+	deadline := start.Add(sla)
+	change()
+
+	var result PropagationResult
+	for !g.s.rulesCachedSince(ruleSetNamespace, ruleSetName, start) {
+		if time.Now().After(deadline) {
+			g.s.T.Fatalf("control plane did not cache new rules for RuleSet %s/%s within SLA %s",
+				ruleSetNamespace, ruleSetName, sla)
+		}
+		time.Sleep(propagationInterval)
+	}
+	result.ControlPlane = time.Since(start)
+
+	var last *HTTPResult
+	for {
+		last = g.Get(path)
+		if last.Err == nil && last.StatusCode == code {
+			break
+		}
+		if time.Now().After(deadline) {
+			g.s.T.Fatalf("data plane did not return %d for %s within SLA %s (control plane: %s, last status: %d, last error: %v)",
+				code, path, sla, result.ControlPlane, last.StatusCode, last.Err)
+		}
+		time.Sleep(propagationInterval)
+	}
+	result.DataPlane = time.Since(start)
+
+	g.s.T.Logf("Rule change for RuleSet %s/%s propagated in %s (control plane: %s, SLA: %s)",
+		ruleSetNamespace, ruleSetName, result.DataPlane, result.ControlPlane, sla)
+	return result
+}
+
+// ExpectRuleToggleCycle verifies that a rule can be disabled and re-enabled
+// end to end. It asserts path is blocked, applies disable and expects path to
+// be allowed, then applies enable and expects path to be blocked again. Each
+// transition must re-cache the RuleSet's rules and reach the data plane
+// within the given SLA (see ExpectPropagationWithin).
+func (g *GatewayProxy) ExpectRuleToggleCycle(sla time.Duration, ruleSetNamespace, ruleSetName, path string, disable, enable func()) {
+	g.s.T.Helper()
+
+	g.s.T.Logf("Verifying %s is blocked before disabling the rule", path)
+	g.ExpectBlocked(path)
+
+	g.s.T.Logf("Disabling the rule and waiting for %s to be allowed", path)
+	g.ExpectPropagationWithin(sla, ruleSetNamespace, ruleSetName, path, http.StatusOK, disable)
+
+	g.s.T.Logf("Re-enabling the rule and waiting for %s to be blocked", path)
+	g.ExpectPropagationWithin(sla, ruleSetNamespace, ruleSetName, path, http.StatusForbidden, enable)
+}
+
+// rulesCachedSince reports whether a RulesCached event for the named RuleSet
+// was emitted at or after since.
+func (s *Scenario) rulesCachedSince(namespace, name string, since time.Time) bool {
+	events, err := s.F.KubeClient.EventsV1().Events(namespace).List(
+		s.T.Context(), metav1.ListOptions{},
+	)
+	if err != nil {
+		return false
+	}
+	for _, e := range events.Items {
+		if e.Reason != wafv1alpha1.EventReasonRulesCached || e.Regarding.Kind != "RuleSet" || e.Regarding.Name != name {
+			continue
+		}
+		if observedSince(e, since) {
+			return true
+		}
+	}
+	return false
+}
+
+// observedSince reports whether the event was most recently observed at or
+// after since. EventTime and Series have microsecond precision, so they're
+// compared to since directly; only events falling back to their creation
+// timestamp, which has second precision, are compared to since truncated to
+// the second, so as not to miss an event within the same second.
+func observedSince(e eventsv1.Event, since time.Time) bool {
+	if e.Series != nil && !e.Series.LastObservedTime.IsZero() {
+		return !e.Series.LastObservedTime.Time.Before(since)
+	}
+	if !e.EventTime.IsZero() {
+		return !e.EventTime.Time.Before(since)
+	}
+	return !e.CreationTimestamp.Time.Before(since.Truncate(time.Second))
+}
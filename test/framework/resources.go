@@ -59,6 +59,11 @@ var (
 		Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes",
 	}
 
+	// ReferenceGrantGVR is the GroupVersionResource for ReferenceGrant resources.
+	ReferenceGrantGVR = schema.GroupVersionResource{
+		Group: "gateway.networking.k8s.io", Version: "v1beta1", Resource: "referencegrants",
+	}
+
 	// WasmPluginGVR is the GroupVersionResource for WasmPlugin resources.
 	WasmPluginGVR = schema.GroupVersionResource{
 		Group: "extensions.istio.io", Version: "v1alpha1", Resource: "wasmplugins",
@@ -90,9 +95,20 @@ type EngineOpts struct {
 	// FailurePolicy is "fail" or "allow". Defaults to "fail".
 	FailurePolicy string
 
+	// Mode is the Istio integration mode, "gateway" or "sidecar". Defaults
+	// to "gateway".
+	Mode string
+
+	// MatchContext is the traffic context the WAF is applied in. Defaults
+	// to "gateway" in gateway mode and to "inbound" in sidecar mode.
+	MatchContext string
+
 	// PollInterval is the ruleSetCacheServer poll interval in seconds.
 	// Defaults to 5.
 	PollInterval int64
+
+	// Priority sets the Engine's priority when non-nil.
+	Priority *int32
 }
 
 // -----------------------------------------------------------------------------
@@ -165,6 +181,34 @@ func BuildGateway(namespace, name string) *unstructured.Unstructured {
 	}
 }
 
+// BuildGatewayTLS builds an unstructured Gateway object like BuildGateway,
+// with an additional HTTPS listener on port 443 terminating TLS with the
+// named kubernetes.io/tls Secret (see CreateTLSSecret).
+func BuildGatewayTLS(namespace, name, secretName string) *unstructured.Unstructured {
+	gateway := BuildGateway(namespace, name)
+	spec := gateway.Object["spec"].(map[string]interface{})
+	spec["listeners"] = append(spec["listeners"].([]interface{}), map[string]interface{}{
+		"name":     "https",
+		"port":     int64(443),
+		"protocol": "HTTPS",
+		"tls": map[string]interface{}{
+			"mode": "Terminate",
+			"certificateRefs": []interface{}{
+				map[string]interface{}{
+					"kind": "Secret",
+					"name": secretName,
+				},
+			},
+		},
+		"allowedRoutes": map[string]interface{}{
+			"namespaces": map[string]interface{}{
+				"from": "All",
+			},
+		},
+	})
+	return gateway
+}
+
 // BuildRuleSet builds an unstructured RuleSet object.
 // Each entry in configMapNames refers to a ConfigMap by name in the same
 // namespace as the RuleSet.
@@ -199,6 +243,9 @@ func BuildEngine(namespace, name string, opts EngineOpts) *unstructured.Unstruct
 	if opts.FailurePolicy == "" {
 		opts.FailurePolicy = "fail"
 	}
+	if opts.Mode == "" {
+		opts.Mode = "gateway"
+	}
 	if opts.PollInterval == 0 {
 		opts.PollInterval = 5
 	}
@@ -223,7 +270,7 @@ func BuildEngine(namespace, name string, opts EngineOpts) *unstructured.Unstruct
 		"name": opts.RuleSetName,
 	}
 
-	return &unstructured.Unstructured{
+	engine := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"apiVersion": "waf.k8s.coraza.io/v1alpha1",
 			"kind":       "Engine",
@@ -238,7 +285,7 @@ func BuildEngine(namespace, name string, opts EngineOpts) *unstructured.Unstruct
 					"istio": map[string]interface{}{
 						"wasm": map[string]interface{}{
 							"image": opts.WasmImage,
-							"mode":  "gateway",
+							"mode":  opts.Mode,
 							"workloadSelector": map[string]interface{}{
 								"matchLabels": labels,
 							},
@@ -251,6 +298,15 @@ func BuildEngine(namespace, name string, opts EngineOpts) *unstructured.Unstruct
 			},
 		},
 	}
+
+	if opts.MatchContext != "" {
+		_ = unstructured.SetNestedField(engine.Object, opts.MatchContext, "spec", "driver", "istio", "wasm", "matchContext")
+	}
+	if opts.Priority != nil {
+		engine.Object["spec"].(map[string]interface{})["priority"] = int64(*opts.Priority)
+	}
+
+	return engine
 }
 
 // BuildHTTPRoute builds an unstructured HTTPRoute that routes all traffic
@@ -285,6 +341,49 @@ func BuildHTTPRoute(namespace, name, gatewayName, backendName string) *unstructu
 	}
 }
 
+// BuildCrossNamespaceHTTPRoute builds an unstructured HTTPRoute that routes all
+// traffic from the named Gateway (in the route's namespace) to the named
+// backend Service on port 80 in backendNamespace. The backend namespace must
+// contain a ReferenceGrant permitting the reference (see BuildReferenceGrant).
+func BuildCrossNamespaceHTTPRoute(namespace, name, gatewayName, backendNamespace, backendName string) *unstructured.Unstructured {
+	route := BuildHTTPRoute(namespace, name, gatewayName, backendName)
+	rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+	backendRefs := rules[0].(map[string]interface{})["backendRefs"].([]interface{})
+	backendRefs[0].(map[string]interface{})["namespace"] = backendNamespace
+	_ = unstructured.SetNestedSlice(route.Object, rules, "spec", "rules")
+	return route
+}
+
+// BuildReferenceGrant builds an unstructured ReferenceGrant which permits
+// HTTPRoutes in fromNamespace to reference Services in namespace.
+func BuildReferenceGrant(namespace, name, fromNamespace string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "gateway.networking.k8s.io/v1beta1",
+			"kind":       "ReferenceGrant",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"from": []interface{}{
+					map[string]interface{}{
+						"group":     "gateway.networking.k8s.io",
+						"kind":      "HTTPRoute",
+						"namespace": fromNamespace,
+					},
+				},
+				"to": []interface{}{
+					map[string]interface{}{
+						"group": "",
+						"kind":  "Service",
+					},
+				},
+			},
+		},
+	}
+}
+
 // -----------------------------------------------------------------------------
 // Scenario - Resource Creation Methods
 // -----------------------------------------------------------------------------
@@ -319,10 +418,24 @@ func (s *Scenario) CreateConfigMap(namespace, name, rules string) {
 
 // CreateGateway creates a Gateway resource and registers cleanup.
 func (s *Scenario) CreateGateway(namespace, name string) {
+	s.T.Helper()
+	s.createGateway(BuildGateway(namespace, name))
+}
+
+// CreateGatewayTLS creates a Gateway resource with an additional HTTPS
+// listener terminating TLS with the named Secret (see BuildGatewayTLS), and
+// registers cleanup.
+func (s *Scenario) CreateGatewayTLS(namespace, name, secretName string) {
+	s.T.Helper()
+	s.createGateway(BuildGatewayTLS(namespace, name, secretName))
+}
+
+// createGateway creates the Gateway resource and registers cleanup.
+func (s *Scenario) createGateway(obj *unstructured.Unstructured) {
 	s.T.Helper()
 	ctx := s.T.Context()
+	namespace, name := obj.GetNamespace(), obj.GetName()
 
-	obj := BuildGateway(namespace, name)
 	_, err := s.F.DynamicClient.Resource(GatewayGVR).Namespace(namespace).Create(
 		ctx, obj, metav1.CreateOptions{},
 	)
@@ -339,6 +452,40 @@ func (s *Scenario) CreateGateway(namespace, name string) {
 	})
 }
 
+// CreateTLSSecret creates a kubernetes.io/tls Secret holding a self-signed
+// certificate valid for the given hosts, and registers cleanup.
+func (s *Scenario) CreateTLSSecret(namespace, name string, hosts ...string) {
+	s.T.Helper()
+	ctx := s.T.Context()
+
+	certPEM, keyPEM, err := GenerateSelfSignedCert(hosts...)
+	require.NoError(s.T, err, "generate certificate for Secret %s/%s", namespace, name)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+		},
+	}
+	_, err = s.F.KubeClient.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+	require.NoError(s.T, err, "create Secret %s/%s", namespace, name)
+
+	s.T.Logf("Created TLS Secret: %s/%s", namespace, name)
+	s.OnCleanup(func() {
+		// Background: test context may already be cancelled; cleanup must still run.
+		if err := s.F.KubeClient.CoreV1().Secrets(namespace).Delete(
+			context.Background(), name, metav1.DeleteOptions{},
+		); err != nil {
+			s.T.Logf("cleanup: failed to delete Secret %s/%s: %v", namespace, name, err)
+		}
+	})
+}
+
 // CreateRuleSet creates a RuleSet resource and registers cleanup. Fails the
 // test on error. Use TryCreateRuleSet to get the error instead.
 func (s *Scenario) CreateRuleSet(namespace, name string, configMapNames []string) {
@@ -395,6 +542,18 @@ func (s *Scenario) TryCreateEngine(namespace, name string, opts EngineOpts) erro
 	return err
 }
 
+// DeleteEngine deletes an Engine resource. Fails the test on error. The
+// Engine may linger until the operator has cleaned up its resources and
+// removed its finalizer; use ExpectResourceGone to wait for that.
+func (s *Scenario) DeleteEngine(namespace, name string) {
+	s.T.Helper()
+	err := s.F.DynamicClient.Resource(EngineGVR).Namespace(namespace).Delete(
+		s.T.Context(), name, metav1.DeleteOptions{},
+	)
+	require.NoError(s.T, err, "delete Engine %s/%s", namespace, name)
+	s.T.Logf("Deleted Engine: %s/%s", namespace, name)
+}
+
 // CreateHTTPRoute creates an HTTPRoute that routes traffic from the named
 // Gateway to the named backend Service and registers cleanup.
 func (s *Scenario) CreateHTTPRoute(namespace, name, gatewayName, backendName string) {
@@ -417,6 +576,52 @@ func (s *Scenario) CreateHTTPRoute(namespace, name, gatewayName, backendName str
 	})
 }
 
+// CreateCrossNamespaceHTTPRoute creates an HTTPRoute in namespace that routes
+// traffic from the named Gateway to a backend Service in backendNamespace, and
+// registers cleanup. Use CreateReferenceGrant to permit the reference.
+func (s *Scenario) CreateCrossNamespaceHTTPRoute(namespace, name, gatewayName, backendNamespace, backendName string) {
+	s.T.Helper()
+	ctx := s.T.Context()
+
+	obj := BuildCrossNamespaceHTTPRoute(namespace, name, gatewayName, backendNamespace, backendName)
+	_, err := s.F.DynamicClient.Resource(HTTPRouteGVR).Namespace(namespace).Create(
+		ctx, obj, metav1.CreateOptions{},
+	)
+	require.NoError(s.T, err, "create HTTPRoute %s/%s", namespace, name)
+
+	s.T.Logf("Created HTTPRoute: %s/%s (gateway=%s, backend=%s/%s)", namespace, name, gatewayName, backendNamespace, backendName)
+	s.OnCleanup(func() {
+		if err := s.F.DynamicClient.Resource(HTTPRouteGVR).Namespace(namespace).Delete(
+			context.Background(), name, metav1.DeleteOptions{},
+		); err != nil {
+			s.T.Logf("cleanup: failed to delete HTTPRoute %s/%s: %v", namespace, name, err)
+		}
+	})
+}
+
+// CreateReferenceGrant creates a ReferenceGrant in namespace permitting
+// HTTPRoutes in fromNamespace to reference its Services, and registers
+// cleanup.
+func (s *Scenario) CreateReferenceGrant(namespace, name, fromNamespace string) {
+	s.T.Helper()
+	ctx := s.T.Context()
+
+	obj := BuildReferenceGrant(namespace, name, fromNamespace)
+	_, err := s.F.DynamicClient.Resource(ReferenceGrantGVR).Namespace(namespace).Create(
+		ctx, obj, metav1.CreateOptions{},
+	)
+	require.NoError(s.T, err, "create ReferenceGrant %s/%s", namespace, name)
+
+	s.T.Logf("Created ReferenceGrant: %s/%s (from HTTPRoutes in %s)", namespace, name, fromNamespace)
+	s.OnCleanup(func() {
+		if err := s.F.DynamicClient.Resource(ReferenceGrantGVR).Namespace(namespace).Delete(
+			context.Background(), name, metav1.DeleteOptions{},
+		); err != nil {
+			s.T.Logf("cleanup: failed to delete ReferenceGrant %s/%s: %v", namespace, name, err)
+		}
+	})
+}
+
 // CreateEchoBackend deploys the Gateway API echo server (Deployment + Service)
 // and waits for at least one pod to be Ready. The echo image defaults to
 // ECHO_IMAGE env var or the built-in Gateway API conformance echo image.
@@ -110,13 +110,31 @@ func (s *Scenario) GenerateNamespace(prefix string) string {
 	return name
 }
 
+// GenerateInjectedNamespace is like GenerateNamespace, but labels the
+// namespace for Istio sidecar injection using the same revision as the
+// Gateways created by the framework.
+func (s *Scenario) GenerateInjectedNamespace(prefix string) string {
+	s.T.Helper()
+	b := make([]byte, 3)
+	_, err := rand.Read(b)
+	require.NoError(s.T, err, "generate random suffix")
+	name := fmt.Sprintf("%s-%x", prefix, b)
+	s.createNamespace(name, map[string]string{"istio.io/rev": "coraza"})
+	return name
+}
+
 // CreateNamespace creates a namespace and registers it for cleanup.
 func (s *Scenario) CreateNamespace(name string) {
+	s.T.Helper()
+	s.createNamespace(name, nil)
+}
+
+func (s *Scenario) createNamespace(name string, labels map[string]string) {
 	s.T.Helper()
 	ctx := s.T.Context()
 
 	ns := &corev1.Namespace{
-		ObjectMeta: metav1.ObjectMeta{Name: name},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
 	}
 	_, err := s.F.KubeClient.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
 	require.NoError(s.T, err, "create namespace %s", name)
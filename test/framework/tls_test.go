@@ -0,0 +1,65 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSelfSignedCert(t *testing.T) {
+	certPEM, keyPEM, err := GenerateSelfSignedCert("gateway.example.com", "127.0.0.1")
+	require.NoError(t, err)
+
+	t.Log("Verifying the certificate and key form a usable key pair")
+	_, err = tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	t.Log("Verifying the certificate is valid for the hosts")
+	block, _ := pem.Decode(certPEM)
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gateway.example.com"}, cert.DNSNames)
+	require.Len(t, cert.IPAddresses, 1)
+	assert.True(t, cert.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")))
+	assert.NoError(t, cert.VerifyHostname("gateway.example.com"))
+}
+
+func TestBuildGatewayTLS(t *testing.T) {
+	gateway := BuildGatewayTLS("ns", "gw", "gw-tls")
+
+	listeners := gateway.Object["spec"].(map[string]interface{})["listeners"].([]interface{})
+	require.Len(t, listeners, 2)
+	assert.Equal(t, "HTTP", listeners[0].(map[string]interface{})["protocol"])
+
+	https := listeners[1].(map[string]interface{})
+	assert.Equal(t, "HTTPS", https["protocol"])
+	assert.Equal(t, int64(443), https["port"])
+	assert.Equal(t, map[string]interface{}{
+		"mode": "Terminate",
+		"certificateRefs": []interface{}{
+			map[string]interface{}{"kind": "Secret", "name": "gw-tls"},
+		},
+	}, https["tls"])
+}
@@ -17,10 +17,13 @@ limitations under the License.
 package framework
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/stretchr/testify/assert"
@@ -33,31 +36,56 @@ import (
 // GatewayProxy manages a port-forward to a Gateway and provides HTTP
 // assertion helpers for testing WAF behavior.
 type GatewayProxy struct {
-	s         *Scenario
-	namespace string
-	gateway   string
-	localPort string
-	baseURL   string
-	httpc     *http.Client
-	cancel    context.CancelFunc
+	s          *Scenario
+	namespace  string
+	gateway    string
+	localPort  string
+	remotePort string
+	baseURL    string
+	httpc      *http.Client
+	cancel     context.CancelFunc
 }
 
 // ProxyToGateway sets up a SPDY port-forward to the named Gateway's pod
 // and returns a GatewayProxy for making HTTP requests. The port-forward is
 // automatically cleaned up when the scenario ends.
 func (s *Scenario) ProxyToGateway(namespace, gatewayName string) *GatewayProxy {
+	s.T.Helper()
+	return s.proxyToGateway(namespace, gatewayName, "http", "80", &http.Client{Timeout: 10 * time.Second})
+}
+
+// ProxyToGatewayTLS sets up a SPDY port-forward to the HTTPS listener of the
+// named Gateway's pod (see CreateGatewayTLS) and returns a GatewayProxy for
+// making HTTPS requests. The Gateway's certificate isn't verified, so it may
+// be self-signed (see CreateTLSSecret). The port-forward is automatically
+// cleaned up when the scenario ends.
+func (s *Scenario) ProxyToGatewayTLS(namespace, gatewayName string) *GatewayProxy {
+	s.T.Helper()
+	return s.proxyToGateway(namespace, gatewayName, "https", "443", &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	})
+}
+
+// proxyToGateway sets up a SPDY port-forward to the remote port of the named
+// Gateway's pod and returns a GatewayProxy using the given URL scheme and
+// client.
+func (s *Scenario) proxyToGateway(namespace, gatewayName, scheme, remotePort string, httpc *http.Client) *GatewayProxy {
 	s.T.Helper()
 	port := AllocatePort()
 	ctx, cancel := context.WithCancel(context.Background())
 
 	proxy := &GatewayProxy{
-		s:         s,
-		namespace: namespace,
-		gateway:   gatewayName,
-		localPort: port,
-		baseURL:   fmt.Sprintf("http://localhost:%s", port),
-		httpc:     &http.Client{Timeout: 10 * time.Second},
-		cancel:    cancel,
+		s:          s,
+		namespace:  namespace,
+		gateway:    gatewayName,
+		localPort:  port,
+		remotePort: remotePort,
+		baseURL:    fmt.Sprintf("%s://localhost:%s", scheme, port),
+		httpc:      httpc,
+		cancel:     cancel,
 	}
 
 	go proxy.maintain(ctx)
@@ -81,7 +109,7 @@ func (s *Scenario) ProxyToGateway(namespace, gatewayName string) *GatewayProxy {
 		cancel()
 	})
 
-	s.T.Logf("Port-forwarding %s/%s -> localhost:%s", namespace, gatewayName, port)
+	s.T.Logf("Port-forwarding %s/%s:%s -> localhost:%s", namespace, gatewayName, remotePort, port)
 	return proxy
 }
 
@@ -92,17 +120,59 @@ func (g *GatewayProxy) URL(path string) string {
 
 // Get makes a GET request through the proxy and returns the result.
 func (g *GatewayProxy) Get(path string) *HTTPResult {
-	resp, err := g.httpc.Get(g.URL(path))
+	return g.Do(http.MethodGet, path, nil, nil)
+}
+
+// Post makes a POST request with the given body through the proxy and
+// returns the result.
+func (g *GatewayProxy) Post(path, contentType string, body []byte) *HTTPResult {
+	return g.Do(http.MethodPost, path, http.Header{"Content-Type": {contentType}}, bytes.NewReader(body))
+}
+
+// Do makes a request with the given method, headers and body (which may be
+// nil) through the proxy and returns the result. A "Host" header sets the
+// request's host.
+func (g *GatewayProxy) Do(method, path string, headers http.Header, body io.Reader) *HTTPResult {
+	req, err := http.NewRequest(method, g.URL(path), body)
+	if err != nil {
+		return &HTTPResult{Err: err}
+	}
+	for name, values := range headers {
+		if http.CanonicalHeaderKey(name) == "Host" && len(values) > 0 {
+			req.Host = values[0]
+			continue
+		}
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+
+	resp, err := g.httpc.Do(req)
 	if err != nil {
 		return &HTTPResult{Err: err}
 	}
 	defer func() { _ = resp.Body.Close() }()
-	body, _ := io.ReadAll(resp.Body)
+	respBody, _ := io.ReadAll(resp.Body)
 	return &HTTPResult{
 		StatusCode: resp.StatusCode,
 		Headers:    resp.Header,
-		Body:       body,
+		Body:       respBody,
+	}
+}
+
+// DoRaw sends req through the proxy and returns the live response, for
+// assertions the buffered helpers can't express (streaming bodies, trailers,
+// transfer encoding). Relative request URLs are resolved against the proxy's
+// base URL. The caller must close the response body.
+func (g *GatewayProxy) DoRaw(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if !req.URL.IsAbs() {
+		base, err := url.Parse(g.baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy base URL %q: %w", g.baseURL, err)
+		}
+		req.URL = base.ResolveReference(req.URL)
+		req.Host = ""
 	}
+	return g.httpc.Do(req)
 }
 
 // ExpectBlocked polls until the given path returns HTTP 403 (blocked by WAF).
@@ -118,35 +188,48 @@ func (g *GatewayProxy) ExpectBlocked(path string) {
 // a 404 without a backend doesn't prove the WAF allowed the request — it
 // could also mean the route is misconfigured.
 func (g *GatewayProxy) ExpectAllowed(path string) {
+	g.s.T.Helper()
+	g.ExpectStatusWithHeaders(path, nil, http.StatusOK)
+}
+
+// ExpectStatus polls until the given path returns the expected HTTP status.
+func (g *GatewayProxy) ExpectStatus(path string, code int) {
+	g.s.T.Helper()
+	g.ExpectStatusWithHeaders(path, nil, code)
+}
+
+// ExpectStatusWithHeaders polls until a GET of the given path with the given
+// request headers returns the expected HTTP status.
+func (g *GatewayProxy) ExpectStatusWithHeaders(path string, headers http.Header, code int) {
 	g.s.T.Helper()
 	require.EventuallyWithT(g.s.T, func(collect *assert.CollectT) {
-		resp, err := g.httpc.Get(g.URL(path))
-		if !assert.NoError(collect, err) {
+		result := g.Do(http.MethodGet, path, headers, nil)
+		if !assert.NoError(collect, result.Err) {
 			return
 		}
-		defer func() {
-			_, _ = io.ReadAll(resp.Body)
-			_ = resp.Body.Close()
-		}()
-		assert.Equal(collect, http.StatusOK, resp.StatusCode,
-			"expected %s to be allowed (200), got: %d", path, resp.StatusCode)
+		assert.Equal(collect, code, result.StatusCode,
+			"expected %s to return %d, got: %d", path, code, result.StatusCode)
 	}, DefaultTimeout, DefaultInterval)
 }
 
-// ExpectStatus polls until the given path returns the expected HTTP status.
-func (g *GatewayProxy) ExpectStatus(path string, code int) {
+// ExpectPostBlocked polls until POSTing the body to the given path returns
+// HTTP 403 (blocked by WAF).
+func (g *GatewayProxy) ExpectPostBlocked(path, contentType string, body []byte) {
+	g.s.T.Helper()
+	g.ExpectPostStatus(path, contentType, body, http.StatusForbidden)
+}
+
+// ExpectPostStatus polls until POSTing the body to the given path returns the
+// expected HTTP status. The full body is sent on every attempt.
+func (g *GatewayProxy) ExpectPostStatus(path, contentType string, body []byte, code int) {
 	g.s.T.Helper()
 	require.EventuallyWithT(g.s.T, func(collect *assert.CollectT) {
-		resp, err := g.httpc.Get(g.URL(path))
-		if !assert.NoError(collect, err) {
+		result := g.Post(path, contentType, body)
+		if !assert.NoError(collect, result.Err) {
 			return
 		}
-		defer func() {
-			_, _ = io.ReadAll(resp.Body)
-			_ = resp.Body.Close()
-		}()
-		assert.Equal(collect, code, resp.StatusCode,
-			"expected %s to return %d, got: %d", path, code, resp.StatusCode)
+		assert.Equal(collect, code, result.StatusCode,
+			"expected POST %s to return %d, got: %d", path, code, result.StatusCode)
 	}, DefaultTimeout, DefaultInterval)
 }
 
@@ -223,16 +306,20 @@ func (g *GatewayProxy) runPortForward(ctx context.Context) error {
 		return fmt.Errorf("no pods matching %s", labelSelector)
 	}
 
-	podName := pods.Items[0].Name
+	return g.s.forwardPodPort(ctx, g.namespace, pods.Items[0].Name, g.localPort, g.remotePort)
+}
 
-	transport, upgrader, err := spdy.RoundTripperFor(g.s.F.RestConfig)
+// forwardPodPort forwards localPort to remotePort on the named pod until ctx
+// is cancelled or the connection fails.
+func (s *Scenario) forwardPodPort(ctx context.Context, namespace, podName, localPort, remotePort string) error {
+	transport, upgrader, err := spdy.RoundTripperFor(s.F.RestConfig)
 	if err != nil {
 		return fmt.Errorf("create SPDY transport: %w", err)
 	}
 
-	pfURL := g.s.F.KubeClient.CoreV1().RESTClient().Post().
+	pfURL := s.F.KubeClient.CoreV1().RESTClient().Post().
 		Resource("pods").
-		Namespace(g.namespace).
+		Namespace(namespace).
 		Name(podName).
 		SubResource("portforward").
 		URL()
@@ -253,7 +340,7 @@ func (g *GatewayProxy) runPortForward(ctx context.Context) error {
 	}()
 
 	pf, err := portforward.New(dialer,
-		[]string{fmt.Sprintf("%s:80", g.localPort)},
+		[]string{fmt.Sprintf("%s:%s", localPort, remotePort)},
 		stopCh, nil, io.Discard, io.Discard,
 	)
 	if err != nil {
@@ -0,0 +1,112 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatewayProxy_DoRaw(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/stream", r.URL.Path)
+		w.Header().Set("Trailer", "X-Stream-Status")
+		for i := range 3 {
+			_, _ = fmt.Fprintf(w, "chunk-%d\n", i)
+			w.(http.Flusher).Flush()
+		}
+		w.Header().Set("X-Stream-Status", "done")
+	}))
+	defer srv.Close()
+
+	g := &GatewayProxy{baseURL: srv.URL, httpc: srv.Client()}
+
+	t.Log("Sending a request with a relative URL through DoRaw")
+	req, err := http.NewRequest(http.MethodGet, "/stream", nil)
+	require.NoError(t, err)
+	resp, err := g.DoRaw(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	t.Log("Verifying the live response is chunked and exposes trailers")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "chunk-0\nchunk-1\nchunk-2\n", string(body))
+	assert.Equal(t, "done", resp.Trailer.Get("X-Stream-Status"))
+
+	t.Log("Verifying the caller's request was not modified")
+	assert.False(t, req.URL.IsAbs())
+}
+
+func TestGatewayProxy_Post(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	g := &GatewayProxy{baseURL: srv.URL, httpc: srv.Client()}
+
+	t.Log("Verifying the full body is sent on every request")
+	body := []byte("user=admin&pass=secret")
+	for range 2 {
+		result := g.Post("/login", "application/x-www-form-urlencoded", body)
+		require.NoError(t, result.Err)
+		assert.Equal(t, http.StatusCreated, result.StatusCode)
+		assert.Equal(t, "ok", string(result.Body))
+	}
+	assert.Equal(t, []string{string(body), string(body)}, bodies)
+}
+
+func TestGatewayProxy_Do(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/resource", r.URL.Path)
+		assert.Equal(t, "example.com", r.Host)
+		assert.Equal(t, []string{"a", "b"}, r.Header.Values("X-Test"))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "payload", string(body))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	g := &GatewayProxy{baseURL: srv.URL, httpc: srv.Client()}
+
+	t.Log("Sending a request with a custom method, headers, host and body")
+	result := g.Do(http.MethodPut, "/resource", http.Header{
+		"host":   {"example.com"},
+		"x-test": {"a", "b"},
+	}, strings.NewReader("payload"))
+	require.NoError(t, result.Err)
+	assert.Equal(t, http.StatusAccepted, result.StatusCode)
+}
@@ -0,0 +1,65 @@
+//go:build integration
+
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/test/framework"
+)
+
+// TestRuleCacheUpdate validates that a rule change is cached as a new version
+// by the control plane, before asserting the data plane enforces it.
+func TestRuleCacheUpdate(t *testing.T) {
+	t.Parallel()
+	s := fw.NewScenario(t)
+
+	ns := s.GenerateNamespace("cache-update")
+
+	s.Step("create gateway")
+	s.CreateGateway(ns, "cache-gw")
+	s.ExpectGatewayProgrammed(ns, "cache-gw")
+
+	s.Step("deploy rules without a blocking rule")
+	s.CreateConfigMap(ns, "base-rules", `SecRuleEngine On`)
+	s.CreateConfigMap(ns, "block-rules", `# no rules yet`)
+	s.CreateRuleSet(ns, "ruleset", []string{"base-rules", "block-rules"})
+
+	s.CreateEngine(ns, "engine", framework.EngineOpts{
+		RuleSetName: "ruleset",
+		GatewayName: "cache-gw",
+	})
+	s.ExpectEngineReady(ns, "engine")
+
+	s.Step("deploy echo backend")
+	s.CreateEchoBackend(ns, "echo")
+	s.CreateHTTPRoute(ns, "echo-route", "cache-gw", "echo")
+
+	gw := s.ProxyToGateway(ns, "cache-gw")
+	gw.ExpectAllowed("/?test=cachemonkey")
+
+	s.Step("add a blocking rule and wait for the cache to update")
+	cache := s.ProxyToCache()
+	cache.ExpectRulesUpdated(ns, "ruleset", func() {
+		s.UpdateConfigMap(ns, "block-rules", framework.SimpleBlockRule(9001, "cachemonkey"))
+	})
+
+	s.Step("verify the data plane enforces the new rules")
+	gw.ExpectBlocked("/?test=cachemonkey")
+}
@@ -0,0 +1,57 @@
+//go:build integration
+
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/test/framework"
+)
+
+// TestConcurrentBlocking validates that blocking verdicts stay consistent
+// when the gateway receives many concurrent attack requests.
+func TestConcurrentBlocking(t *testing.T) {
+	t.Parallel()
+	s := fw.NewScenario(t)
+
+	ns := s.GenerateNamespace("concurrent-blocking")
+
+	s.Step("create gateway")
+	s.CreateGateway(ns, "load-gw")
+	s.ExpectGatewayProgrammed(ns, "load-gw")
+
+	s.Step("deploy a blocking rule")
+	s.CreateConfigMap(ns, "base-rules", `SecRuleEngine On`)
+	s.CreateConfigMap(ns, "block-rules", framework.SimpleBlockRule(7001, "loadmonkey"))
+	s.CreateRuleSet(ns, "ruleset", []string{"base-rules", "block-rules"})
+
+	s.CreateEngine(ns, "engine", framework.EngineOpts{
+		RuleSetName: "ruleset",
+		GatewayName: "load-gw",
+	})
+	s.ExpectEngineReady(ns, "engine")
+
+	gw := s.ProxyToGateway(ns, "load-gw")
+
+	s.Step("wait for the rule to be enforced")
+	gw.ExpectBlocked("/?test=loadmonkey")
+
+	s.Step("verify 100 concurrent attack requests are all blocked")
+	gw.ExpectAllBlocked("/?test=loadmonkey", 100)
+}
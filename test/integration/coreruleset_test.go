@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"testing"
 
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
 	"github.com/networking-incubator/coraza-kubernetes-operator/test/framework"
 )
 
@@ -104,8 +105,8 @@ SecRule ARGS "@rx (?i:<script[^>]*>)" \
 	s.ExpectWasmPluginExists(ns, "coraza-engine-crs-engine")
 
 	s.Step("verify operator emitted expected events")
-	s.ExpectEvent(ns, framework.EventMatch{Type: "Normal", Reason: "RulesCached"})
-	s.ExpectEvent(ns, framework.EventMatch{Type: "Normal", Reason: "WasmPluginCreated"})
+	s.ExpectEvent(ns, framework.EventMatch{Type: "Normal", Reason: wafv1alpha1.EventReasonRulesCached})
+	s.ExpectEvent(ns, framework.EventMatch{Type: "Normal", Reason: wafv1alpha1.EventReasonWasmPluginCreated})
 
 	// -------------------------------------------------------------------------
 	// Step 4: Deploy backend and verify WAF enforcement
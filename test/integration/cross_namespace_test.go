@@ -0,0 +1,79 @@
+//go:build integration
+
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/test/framework"
+)
+
+// TestCrossNamespaceRouting validates that an Engine attached to a Gateway in
+// one namespace enforces its RuleSet on traffic routed to a backend in another
+// (Istio-injected) namespace via an HTTPRoute and ReferenceGrant.
+func TestCrossNamespaceRouting(t *testing.T) {
+	t.Parallel()
+	s := fw.NewScenario(t)
+
+	gatewayNS := s.GenerateNamespace("xns-gateway")
+	backendNS := s.GenerateInjectedNamespace("xns-backend")
+
+	// -------------------------------------------------------------------------
+	// Step 1: Deploy the backend in its own namespace
+	// -------------------------------------------------------------------------
+
+	s.Step("deploy echo backend")
+	s.CreateEchoBackend(backendNS, "echo")
+	s.CreateReferenceGrant(backendNS, "allow-gateway-routes", gatewayNS)
+
+	// -------------------------------------------------------------------------
+	// Step 2: Create the gateway, rules and engine
+	// -------------------------------------------------------------------------
+
+	s.Step("create rules")
+	s.CreateConfigMap(gatewayNS, "base-rules", `SecRuleEngine On`)
+	s.CreateConfigMap(gatewayNS, "block-rules",
+		framework.SimpleBlockRule(1001, "blocked"),
+	)
+	s.CreateRuleSet(gatewayNS, "ruleset", []string{"base-rules", "block-rules"})
+
+	s.Step("create gateway and engine")
+	s.CreateGateway(gatewayNS, "gateway")
+	s.ExpectGatewayProgrammed(gatewayNS, "gateway")
+
+	s.CreateEngine(gatewayNS, "engine", framework.EngineOpts{
+		RuleSetName: "ruleset",
+		GatewayName: "gateway",
+	})
+	s.ExpectEngineReady(gatewayNS, "engine")
+
+	s.Step("route to backend across namespaces")
+	s.CreateCrossNamespaceHTTPRoute(gatewayNS, "echo-route", "gateway", backendNS, "echo")
+	gw := s.ProxyToGateway(gatewayNS, "gateway")
+
+	// -------------------------------------------------------------------------
+	// Step 3: Verify enforcement
+	// -------------------------------------------------------------------------
+
+	s.Step("verify malicious traffic is blocked")
+	gw.ExpectBlocked("/?test=blocked")
+
+	s.Step("verify clean traffic reaches the backend")
+	gw.ExpectAllowed("/?test=safe")
+}
@@ -0,0 +1,60 @@
+//go:build integration
+
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/test/framework"
+)
+
+// TestEngineCleanup validates that deleting an Engine removes the WasmPlugin
+// the operator provisioned for it, and that the Engine's finalizer is
+// cleared so the deletion completes.
+func TestEngineCleanup(t *testing.T) {
+	t.Parallel()
+	s := fw.NewScenario(t)
+
+	ns := s.GenerateNamespace("engine-cleanup")
+
+	s.Step("create gateway")
+	s.CreateGateway(ns, "cleanup-gw")
+	s.ExpectGatewayProgrammed(ns, "cleanup-gw")
+
+	s.Step("deploy rules and engine")
+	s.CreateConfigMap(ns, "base-rules", `SecRuleEngine On`)
+	s.CreateConfigMap(ns, "block-evil",
+		framework.SimpleBlockRule(3101, "evilmonkey"),
+	)
+	s.CreateRuleSet(ns, "ruleset", []string{"base-rules", "block-evil"})
+
+	s.CreateEngine(ns, "engine", framework.EngineOpts{
+		RuleSetName: "ruleset",
+		GatewayName: "cleanup-gw",
+	})
+	s.ExpectEngineReady(ns, "engine")
+	s.ExpectWasmPluginExists(ns, "coraza-engine-engine")
+
+	s.Step("delete engine")
+	s.DeleteEngine(ns, "engine")
+
+	s.Step("verify the wasmplugin is removed and the engine finalizer cleared")
+	s.ExpectResourceGone(ns, "coraza-engine-engine", framework.WasmPluginGVR)
+	s.ExpectResourceGone(ns, "engine", framework.EngineGVR)
+}
@@ -0,0 +1,66 @@
+//go:build integration
+
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/test/framework"
+)
+
+// unloadableWasmImage is a WASM plugin image which doesn't exist, so the
+// data plane fails to load the plugin.
+const unloadableWasmImage = "oci://ghcr.io/networking-incubator/coraza-proxy-wasm:does-not-exist"
+
+// TestFailurePolicy validates that an Engine's failurePolicy is enforced by
+// the data plane when the WAF can't be loaded.
+//
+// The RuleSet cache server is shared by every Engine, so it can't be made
+// unreachable for a single test without affecting the others running in
+// parallel. Instead the plugin itself fails to load, which the WasmPlugin's
+// failStrategy governs just the same.
+func TestFailurePolicy(t *testing.T) {
+	t.Parallel()
+	s := fw.NewScenario(t)
+
+	ns := s.GenerateNamespace("failure-policy")
+
+	s.Step("create gateway and backend")
+	s.CreateGateway(ns, "fail-open-gw")
+	s.ExpectGatewayProgrammed(ns, "fail-open-gw")
+	s.CreateEchoBackend(ns, "echo")
+	s.CreateHTTPRoute(ns, "echo-route", "fail-open-gw", "echo")
+
+	s.Step("create an engine which fails open with an unloadable plugin")
+	s.CreateConfigMap(ns, "rules", framework.SimpleBlockRule(6001, "evil"))
+	s.CreateRuleSet(ns, "ruleset", []string{"rules"})
+	s.CreateEngine(ns, "engine", framework.EngineOpts{
+		RuleSetName:   "ruleset",
+		GatewayName:   "fail-open-gw",
+		WasmImage:     unloadableWasmImage,
+		FailurePolicy: "allow",
+	})
+	s.ExpectEngineReady(ns, "engine")
+	s.ExpectWasmPluginExists(ns, "coraza-engine-engine")
+
+	s.Step("verify traffic is allowed through without the WAF")
+	gw := s.ProxyToGateway(ns, "fail-open-gw")
+	gw.ExpectAllowed("/?test=safe")
+	gw.ExpectAllowed("/?test=evil")
+}
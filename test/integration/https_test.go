@@ -0,0 +1,62 @@
+//go:build integration
+
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/test/framework"
+)
+
+// TestHTTPSBlocking validates that the WAF blocks attacks sent over HTTPS to a
+// Gateway terminating TLS.
+func TestHTTPSBlocking(t *testing.T) {
+	t.Parallel()
+	s := fw.NewScenario(t)
+
+	ns := s.GenerateNamespace("https-blocking")
+
+	s.Step("create gateway with an HTTPS listener")
+	s.CreateTLSSecret(ns, "tls-gw-cert")
+	s.CreateGatewayTLS(ns, "tls-gw", "tls-gw-cert")
+	s.ExpectGatewayProgrammed(ns, "tls-gw")
+
+	s.Step("deploy a blocking rule")
+	s.CreateConfigMap(ns, "base-rules", `SecRuleEngine On`)
+	s.CreateConfigMap(ns, "block-rules", framework.SimpleBlockRule(8001, "tlsmonkey"))
+	s.CreateRuleSet(ns, "ruleset", []string{"base-rules", "block-rules"})
+
+	s.CreateEngine(ns, "engine", framework.EngineOpts{
+		RuleSetName: "ruleset",
+		GatewayName: "tls-gw",
+	})
+	s.ExpectEngineReady(ns, "engine")
+
+	s.Step("deploy echo backend")
+	s.CreateEchoBackend(ns, "echo")
+	s.CreateHTTPRoute(ns, "echo-route", "tls-gw", "echo")
+
+	gw := s.ProxyToGatewayTLS(ns, "tls-gw")
+
+	s.Step("verify an attack over HTTPS is blocked")
+	gw.ExpectBlocked("/?test=tlsmonkey")
+
+	s.Step("verify clean traffic over HTTPS is allowed")
+	gw.ExpectAllowed("/?test=safe")
+}
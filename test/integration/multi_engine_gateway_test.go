@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"testing"
 
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
 	"github.com/networking-incubator/coraza-kubernetes-operator/test/framework"
 )
 
@@ -30,8 +31,12 @@ import (
 //
 //   - An Engine targeting multiple Gateways (via separate engines per gateway,
 //     or a shared label selector when supported).
-//   - Multiple Engines attempting to attach to a single Gateway.
-//   - An Engine whose label selector matches no Gateways.
+//   - Multiple Engines attempting to attach to a single Gateway, of which
+//     only the first is provisioned.
+//   - Multiple Engines with distinct priorities layered on a single Gateway,
+//     all of which are provisioned.
+//   - An Engine whose label selector matches no Gateways until one is
+//     created.
 //
 // Related: https://github.com/networking-incubator/coraza-kubernetes-operator/issues/52
 func TestMultiEngineMultiGateway(t *testing.T) {
@@ -114,11 +119,9 @@ func TestMultiEngineMultiGateway(t *testing.T) {
 		s.CreateHTTPRoute(ns, "echo-route", "target-gw", "echo")
 
 		s.Step("attach both engines to the gateway")
-		// The operator currently accepts multiple engines targeting the
-		// same gateway — there is no admission webhook preventing it.
-		// Each engine creates its own WasmPlugin, and both enforce rules.
-		// If issue #52 adds rejection logic, this test should be updated
-		// to use TryCreateEngine and assert the error.
+		// Overlapping workload selectors would make rule precedence between
+		// the engines' WasmPlugins undefined, so the operator provisions the
+		// first engine and rejects the second with a SelectorConflict.
 		s.CreateEngine(ns, "engine-a", framework.EngineOpts{
 			RuleSetName: "ruleset-a",
 			GatewayName: "target-gw",
@@ -129,12 +132,73 @@ func TestMultiEngineMultiGateway(t *testing.T) {
 			RuleSetName: "ruleset-b",
 			GatewayName: "target-gw",
 		})
-		s.ExpectEngineReady(ns, "engine-b")
+		s.ExpectEngineDegraded(ns, "engine-b")
+		s.ExpectEvent(ns, framework.EventMatch{Type: "Warning", Reason: wafv1alpha1.ReasonSelectorConflict})
+		s.ExpectResourceGone(ns, "coraza-engine-engine-b", framework.WasmPluginGVR)
 
-		s.Step("verify both engines enforce their rules")
+		s.Step("verify only the first engine enforces its rules")
 		gw := s.ProxyToGateway(ns, "target-gw")
 		gw.ExpectBlocked("/?test=attackA")
-		gw.ExpectBlocked("/?test=attackB")
+		gw.ExpectAllowed("/?test=attackB")
+		gw.ExpectAllowed("/?test=safe")
+	})
+
+	// -------------------------------------------------------------------------
+	// Sub-test: Multiple Engines with distinct priorities on the same Gateway
+	// -------------------------------------------------------------------------
+
+	t.Run("prioritized_engines_single_gateway", func(t *testing.T) {
+		t.Parallel()
+		s := fw.NewScenario(t)
+
+		ns := s.GenerateNamespace("prioritized-engines")
+
+		s.Step("create a single gateway")
+		s.CreateGateway(ns, "target-gw")
+		s.ExpectGatewayProgrammed(ns, "target-gw")
+
+		s.Step("create a global blocklist and app-specific rules")
+		s.CreateConfigMap(ns, "base-rules", `SecRuleEngine On`)
+		s.CreateConfigMap(ns, "blocklist-rules",
+			framework.SimpleBlockRule(3001, "blocklisted"),
+		)
+		s.CreateConfigMap(ns, "app-rules",
+			framework.SimpleBlockRule(3002, "appattack"),
+		)
+		s.CreateRuleSet(ns, "blocklist", []string{"base-rules", "blocklist-rules"})
+		s.CreateRuleSet(ns, "app", []string{"base-rules", "app-rules"})
+
+		s.Step("deploy echo backend")
+		s.CreateEchoBackend(ns, "echo")
+		s.CreateHTTPRoute(ns, "echo-route", "target-gw", "echo")
+
+		s.Step("attach both engines to the gateway with distinct priorities")
+		// Distinct priorities define the order of the engines' WasmPlugins,
+		// so their overlapping workload selectors don't conflict.
+		blocklistPriority, appPriority := int32(100), int32(10)
+		s.CreateEngine(ns, "blocklist-engine", framework.EngineOpts{
+			RuleSetName: "blocklist",
+			GatewayName: "target-gw",
+			Priority:    &blocklistPriority,
+		})
+		s.ExpectEngineReady(ns, "blocklist-engine")
+
+		s.CreateEngine(ns, "app-engine", framework.EngineOpts{
+			RuleSetName: "app",
+			GatewayName: "target-gw",
+			Priority:    &appPriority,
+		})
+		s.ExpectEngineReady(ns, "app-engine")
+
+		s.Step("verify the blocklist engine's WasmPlugin runs first")
+		s.ExpectWasmPluginPriority(ns, "coraza-engine-blocklist-engine", blocklistPriority)
+		s.ExpectWasmPluginPriority(ns, "coraza-engine-app-engine", appPriority)
+		s.ExpectWasmPluginOrder(ns, "coraza-engine-blocklist-engine", "coraza-engine-app-engine")
+
+		s.Step("verify both engines enforce their rules")
+		gw := s.ProxyToGateway(ns, "target-gw")
+		gw.ExpectBlocked("/?test=blocklisted")
+		gw.ExpectBlocked("/?test=appattack")
 		gw.ExpectAllowed("/?test=safe")
 	})
 
@@ -148,20 +212,21 @@ func TestMultiEngineMultiGateway(t *testing.T) {
 
 		ns := s.GenerateNamespace("no-target")
 
-		s.Step("create rules and engine targeting non-existent gateway")
+		s.Step("create rules and engine targeting a gateway which does not exist yet")
 		s.CreateConfigMap(ns, "base-rules", `SecRuleEngine On`)
 		s.CreateRuleSet(ns, "ruleset", []string{"base-rules"})
 		s.CreateEngine(ns, "orphan-engine", framework.EngineOpts{
 			RuleSetName: "ruleset",
-			GatewayName: "nonexistent-gateway",
+			GatewayName: "late-gateway",
 		})
 
-		s.Step("verify engine status")
-		// The WasmPlugin is still created (targeting pods that don't exist).
-		// Per issue #52, the engine status should reflect the degraded state
-		// when Gateway watches are implemented. For now we verify the engine
-		// still reaches Ready since the operator doesn't currently check
-		// whether the label selector matches any pods.
+		s.Step("verify engine is degraded until its gateway exists")
+		s.ExpectEngineDegraded(ns, "orphan-engine")
+		s.ExpectEvent(ns, framework.EventMatch{Type: "Warning", Reason: wafv1alpha1.ReasonGatewayNotFound})
+		s.ExpectResourceGone(ns, "coraza-engine-orphan-engine", framework.WasmPluginGVR)
+
+		s.Step("create the gateway and verify the engine is provisioned")
+		s.CreateGateway(ns, "late-gateway")
 		s.ExpectEngineReady(ns, "orphan-engine")
 		s.ExpectWasmPluginExists(ns, "coraza-engine-orphan-engine")
 	})
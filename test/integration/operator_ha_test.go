@@ -0,0 +1,61 @@
+//go:build integration
+
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"os"
+	"testing"
+
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
+	"github.com/networking-incubator/coraza-kubernetes-operator/test/framework"
+)
+
+// TestOperatorHA validates leader election with multiple operator replicas:
+// only the leader reconciles while every replica serves the RuleSet cache.
+//
+// It scales the shared operator Deployment, so it only runs when
+// MULTI_REPLICA_TESTS=true and is not parallel: parallel tests wait until it
+// has finished and the original replica count is restored.
+func TestOperatorHA(t *testing.T) {
+	if os.Getenv("MULTI_REPLICA_TESTS") != "true" {
+		t.Skip("MULTI_REPLICA_TESTS is not true, skipping multi-replica operator test")
+	}
+	s := fw.NewScenario(t)
+
+	ns := s.GenerateNamespace("operator-ha")
+
+	s.Step("create WAF resources")
+	s.CreateGateway(ns, "ha-gw")
+	s.ExpectGatewayProgrammed(ns, "ha-gw")
+	s.CreateConfigMap(ns, "rules", framework.SimpleBlockRule(5001, "hamonkey"))
+	s.CreateRuleSet(ns, "ruleset", []string{"rules"})
+	s.CreateEngine(ns, "engine", framework.EngineOpts{
+		RuleSetName: "ruleset",
+		GatewayName: "ha-gw",
+	})
+	s.ExpectEngineReady(ns, "engine")
+
+	s.Step("scale the operator and verify only the leader reconciles")
+	s.ExpectOperatorHA(ns)
+
+	s.Step("change the rules and verify the leader still reconciles alone")
+	s.UpdateConfigMap(ns, "rules", framework.SimpleBlockRule(5001, "hamonkey2"))
+	s.ExpectEvent(ns, framework.EventMatch{Type: "Normal", Reason: wafv1alpha1.EventReasonRulesCached})
+	s.ExpectOperatorHA(ns)
+}
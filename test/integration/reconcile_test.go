@@ -19,8 +19,10 @@ limitations under the License.
 package integration
 
 import (
+	"net/http"
 	"testing"
 
+	wafv1alpha1 "github.com/networking-incubator/coraza-kubernetes-operator/api/v1alpha1"
 	"github.com/networking-incubator/coraza-kubernetes-operator/test/framework"
 )
 
@@ -54,8 +56,8 @@ func TestReconciliation(t *testing.T) {
 	s.ExpectWasmPluginExists(ns, "coraza-engine-engine")
 
 	s.Step("verify operator emitted expected events")
-	s.ExpectEvent(ns, framework.EventMatch{Type: "Normal", Reason: "RulesCached"})
-	s.ExpectEvent(ns, framework.EventMatch{Type: "Normal", Reason: "WasmPluginCreated"})
+	s.ExpectEvent(ns, framework.EventMatch{Type: "Normal", Reason: wafv1alpha1.EventReasonRulesCached})
+	s.ExpectEvent(ns, framework.EventMatch{Type: "Normal", Reason: wafv1alpha1.EventReasonWasmPluginCreated})
 
 	s.Step("deploy echo backend")
 	s.CreateEchoBackend(ns, "echo")
@@ -79,11 +81,12 @@ func TestReconciliation(t *testing.T) {
 
 	// --- ConfigMap content update: replace rule in-place ---
 
-	s.Step("replace sinistermonkey rule with maniacalmonkey")
-	s.UpdateConfigMap(ns, "block-sinister",
-		framework.SimpleBlockRule(3002, "maniacalmonkey"),
-	)
+	s.Step("replace sinistermonkey rule with maniacalmonkey within the propagation SLA")
+	gw.ExpectPropagationWithin(framework.DefaultPropagationSLA, ns, "ruleset", "/maniacalmonkey", http.StatusForbidden, func() {
+		s.UpdateConfigMap(ns, "block-sinister",
+			framework.SimpleBlockRule(3002, "maniacalmonkey"),
+		)
+	})
 
 	gw.ExpectAllowed("/sinistermonkey")
-	gw.ExpectBlocked("/maniacalmonkey")
 }
@@ -0,0 +1,64 @@
+//go:build integration
+
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/test/framework"
+)
+
+// TestRequestBodyInspection validates that rules inspecting request bodies
+// (phase:2) block malicious form submissions while allowing clean ones.
+func TestRequestBodyInspection(t *testing.T) {
+	t.Parallel()
+	s := fw.NewScenario(t)
+
+	ns := s.GenerateNamespace("request-body")
+
+	s.Step("create gateway")
+	s.CreateGateway(ns, "body-gw")
+	s.ExpectGatewayProgrammed(ns, "body-gw")
+
+	s.Step("deploy a rule detecting SQL injection in form bodies")
+	s.CreateConfigMap(ns, "body-rules", `SecRuleEngine On
+SecRequestBodyAccess On
+SecRule ARGS_POST "@detectSQLi" "id:5001,phase:2,deny,status:403,msg:'SQL injection in request body'"`)
+	s.CreateRuleSet(ns, "ruleset", []string{"body-rules"})
+
+	s.CreateEngine(ns, "engine", framework.EngineOpts{
+		RuleSetName: "ruleset",
+		GatewayName: "body-gw",
+	})
+	s.ExpectEngineReady(ns, "engine")
+
+	s.Step("deploy echo backend")
+	s.CreateEchoBackend(ns, "echo")
+	s.CreateHTTPRoute(ns, "echo-route", "body-gw", "echo")
+
+	gw := s.ProxyToGateway(ns, "body-gw")
+	const formContentType = "application/x-www-form-urlencoded"
+
+	s.Step("verify SQL injection in a form body is blocked")
+	gw.ExpectPostBlocked("/login", formContentType, []byte("user=admin' OR '1'='1&pass=x"))
+
+	s.Step("verify a clean form body is allowed")
+	gw.ExpectPostStatus("/login", formContentType, []byte("user=admin&pass=secret"), http.StatusOK)
+}
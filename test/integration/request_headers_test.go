@@ -0,0 +1,63 @@
+//go:build integration
+
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/test/framework"
+)
+
+// TestRequestHeaderInspection validates that rules inspecting request
+// headers block requests carrying a malicious User-Agent while allowing
+// others.
+func TestRequestHeaderInspection(t *testing.T) {
+	t.Parallel()
+	s := fw.NewScenario(t)
+
+	ns := s.GenerateNamespace("request-headers")
+
+	s.Step("create gateway")
+	s.CreateGateway(ns, "headers-gw")
+	s.ExpectGatewayProgrammed(ns, "headers-gw")
+
+	s.Step("deploy a rule blocking a malicious User-Agent")
+	s.CreateConfigMap(ns, "header-rules", `SecRuleEngine On
+SecRule REQUEST_HEADERS:User-Agent "@contains sqlmap" "id:6001,phase:1,deny,status:403,msg:'Malicious User-Agent'"`)
+	s.CreateRuleSet(ns, "ruleset", []string{"header-rules"})
+
+	s.CreateEngine(ns, "engine", framework.EngineOpts{
+		RuleSetName: "ruleset",
+		GatewayName: "headers-gw",
+	})
+	s.ExpectEngineReady(ns, "engine")
+
+	s.Step("deploy echo backend")
+	s.CreateEchoBackend(ns, "echo")
+	s.CreateHTTPRoute(ns, "echo-route", "headers-gw", "echo")
+
+	gw := s.ProxyToGateway(ns, "headers-gw")
+
+	s.Step("verify a request with a malicious User-Agent is blocked")
+	gw.ExpectStatusWithHeaders("/", http.Header{"User-Agent": {"sqlmap/1.7"}}, http.StatusForbidden)
+
+	s.Step("verify a request with a benign User-Agent is allowed")
+	gw.ExpectStatusWithHeaders("/", http.Header{"User-Agent": {"Mozilla/5.0"}}, http.StatusOK)
+}
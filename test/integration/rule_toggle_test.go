@@ -0,0 +1,68 @@
+//go:build integration
+
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/test/framework"
+)
+
+// TestRuleToggleCycle validates that disabling a rule (with
+// SecRuleRemoveById in a later rule source) and re-enabling it both
+// propagate to the data plane.
+func TestRuleToggleCycle(t *testing.T) {
+	t.Parallel()
+	s := fw.NewScenario(t)
+
+	ns := s.GenerateNamespace("rule-toggle")
+
+	s.Step("create gateway")
+	s.CreateGateway(ns, "toggle-gw")
+	s.ExpectGatewayProgrammed(ns, "toggle-gw")
+
+	s.Step("deploy rules with an empty overrides source")
+	s.CreateConfigMap(ns, "base-rules", `SecRuleEngine On`)
+	s.CreateConfigMap(ns, "block-rules",
+		framework.SimpleBlockRule(4001, "togglemonkey"),
+	)
+	s.CreateConfigMap(ns, "overrides", `# no overrides`)
+	s.CreateRuleSet(ns, "ruleset", []string{"base-rules", "block-rules", "overrides"})
+
+	s.CreateEngine(ns, "engine", framework.EngineOpts{
+		RuleSetName: "ruleset",
+		GatewayName: "toggle-gw",
+	})
+	s.ExpectEngineReady(ns, "engine")
+
+	s.Step("deploy echo backend")
+	s.CreateEchoBackend(ns, "echo")
+	s.CreateHTTPRoute(ns, "echo-route", "toggle-gw", "echo")
+
+	gw := s.ProxyToGateway(ns, "toggle-gw")
+
+	s.Step("disable and re-enable the rule")
+	gw.ExpectRuleToggleCycle(framework.DefaultPropagationSLA, ns, "ruleset", "/?test=togglemonkey",
+		func() { s.UpdateConfigMap(ns, "overrides", `SecRuleRemoveById 4001`) },
+		func() { s.UpdateConfigMap(ns, "overrides", `# no overrides`) },
+	)
+
+	s.Step("verify clean traffic is unaffected")
+	gw.ExpectAllowed("/?test=safe")
+}
@@ -58,6 +58,7 @@ func (r *testRecorder) WithLogger(logger klog.Logger) events.EventRecorderLogger
 
 // RecordedEvent holds a single event captured by FakeRecorder.
 type RecordedEvent struct {
+	Object runtime.Object
 	Type   string
 	Reason string
 	Action string
@@ -76,8 +77,9 @@ func NewFakeRecorder() *FakeRecorder {
 }
 
 // Eventf implements events.EventRecorder.
-func (r *FakeRecorder) Eventf(_ runtime.Object, _ runtime.Object, eventtype, reason, action, note string, args ...any) {
+func (r *FakeRecorder) Eventf(regarding runtime.Object, _ runtime.Object, eventtype, reason, action, note string, args ...any) {
 	r.Events = append(r.Events, RecordedEvent{
+		Object: regarding,
 		Type:   eventtype,
 		Reason: reason,
 		Action: action,
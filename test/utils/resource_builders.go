@@ -74,6 +74,19 @@ func NewTestConfigMap(name, namespace, rules string) *corev1.ConfigMap {
 	}
 }
 
+// NewTestSecret creates a test Secret with the given rules
+func NewTestSecret(name, namespace, rules string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			"rules": []byte(rules),
+		},
+	}
+}
+
 // -----------------------------------------------------------------------------
 // Test Resource Builders - Engine
 // -----------------------------------------------------------------------------
@@ -22,6 +22,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -30,8 +32,22 @@ const (
 	defaultBaseURL = "https://api.github.com"
 	apiVersion     = "2022-11-28"
 	userAgent      = "github_issue_manager/1.0"
+
+	// defaultMaxRetries is how many times a rate limited request is retried.
+	defaultMaxRetries = 3
+
+	// searchPageSize is the number of issues requested per page of search
+	// results, which is the maximum GitHub allows.
+	searchPageSize = 100
+
+	// maxRateLimitWait bounds how long a rate limited request waits before
+	// retrying. Requests which would have to wait longer fail instead.
+	maxRateLimitWait = 5 * time.Minute
 )
 
+// linkNextPattern matches the URL of the next page in a Link header.
+var linkNextPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
 // Issue represents a GitHub issue with the fields we care about.
 type Issue struct {
 	Number    int       `json:"number"`
@@ -72,21 +88,27 @@ func (i *Issue) HasMilestone() bool {
 
 // GitHubClient wraps the GitHub REST API for a specific repository.
 type GitHubClient struct {
-	token   string
-	owner   string
-	repo    string
-	baseURL string
-	client  *http.Client
+	token      string
+	owner      string
+	repo       string
+	baseURL    string
+	client     *http.Client
+	maxRetries int
+	sleep      func(time.Duration)
+	now        func() time.Time
 }
 
 // NewGitHubClient creates a new GitHubClient for the given repository.
 func NewGitHubClient(token, owner, repo string) *GitHubClient {
 	return &GitHubClient{
-		token:   token,
-		owner:   owner,
-		repo:    repo,
-		baseURL: defaultBaseURL,
-		client:  &http.Client{Timeout: 30 * time.Second},
+		token:      token,
+		owner:      owner,
+		repo:       repo,
+		baseURL:    defaultBaseURL,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		maxRetries: defaultMaxRetries,
+		sleep:      time.Sleep,
+		now:        time.Now,
 	}
 }
 
@@ -103,38 +125,120 @@ func (c *GitHubClient) issueLabelURL(number int, label string) string {
 }
 
 func (c *GitHubClient) doRequest(method, url string, body string) ([]byte, int, error) {
-	var bodyReader io.Reader
-	if body != "" {
-		bodyReader = strings.NewReader(body)
+	respBody, status, _, err := c.doPageRequest(method, url, body)
+	return respBody, status, err
+}
+
+// doPageRequest performs a request like doRequest, additionally returning the
+// URL of the next page from the response's Link header, which is empty on
+// the last page. Rate limited requests are retried up to maxRetries times
+// after waiting as instructed by the response (see rateLimitWait).
+func (c *GitHubClient) doPageRequest(method, url string, body string) ([]byte, int, string, error) {
+	for attempt := 0; ; attempt++ {
+		var bodyReader io.Reader
+		if body != "" {
+			bodyReader = strings.NewReader(body)
+		}
+
+		req, err := http.NewRequest(method, url, bodyReader)
+		if err != nil {
+			return nil, 0, "", fmt.Errorf("creating request: %w", err)
+		}
+
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", apiVersion)
+		req.Header.Set("User-Agent", userAgent)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, 0, "", fmt.Errorf("executing request: %w", err)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, resp.StatusCode, "", fmt.Errorf("reading response: %w", err)
+		}
+
+		if wait, limited := c.rateLimitWait(resp); limited && attempt < c.maxRetries && wait <= maxRateLimitWait {
+			c.sleep(wait)
+			continue
+		}
+
+		return respBody, resp.StatusCode, nextPageURL(resp.Header), nil
 	}
+}
 
-	req, err := http.NewRequest(method, url, bodyReader)
-	if err != nil {
-		return nil, 0, fmt.Errorf("creating request: %w", err)
+// rateLimitWait reports whether the response is a primary or secondary rate
+// limit response, and how long to wait before retrying: the Retry-After
+// seconds if set, or else until the X-RateLimit-Reset time. Secondary rate
+// limits are identified by a Retry-After header, and primary rate limits by
+// an X-RateLimit-Remaining header of 0, so that other forbidden responses,
+// which carry the X-RateLimit-Reset header as well, aren't retried.
+func (c *GitHubClient) rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
 	}
 
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("X-GitHub-Api-Version", apiVersion)
-	req.Header.Set("User-Agent", userAgent)
-	if c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
 	}
-	if body != "" {
-		req.Header.Set("Content-Type", "application/json")
+
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
 	}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, 0, fmt.Errorf("executing request: %w", err)
+	if v := resp.Header.Get("X-RateLimit-Reset"); v != "" {
+		reset, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return max(time.Unix(reset, 0).Sub(c.now()), 0), true
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, resp.StatusCode, fmt.Errorf("reading response: %w", err)
+	return 0, false
+}
+
+// nextPageURL returns the URL of the next page from a Link header, or an
+// empty string if there is none.
+func nextPageURL(header http.Header) string {
+	for _, link := range header.Values("Link") {
+		if m := linkNextPattern.FindStringSubmatch(link); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// getPages fetches every page of a list endpoint, following the Link header
+// from the given URL, and returns the body of each page.
+func (c *GitHubClient) getPages(url string) ([][]byte, error) {
+	var pages [][]byte
+	for url != "" {
+		body, status, next, err := c.doPageRequest("GET", url, "")
+		if err != nil {
+			return nil, err
+		}
+
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("status %d: %s", status, string(body))
+		}
+
+		pages = append(pages, body)
+		url = next
 	}
 
-	return respBody, resp.StatusCode, nil
+	return pages, nil
 }
 
 // GetIssue fetches an issue by number.
@@ -156,6 +260,31 @@ func (c *GitHubClient) GetIssue(number int) (*Issue, error) {
 	return &issue, nil
 }
 
+// ListIssues lists the repository's issues in the given state (e.g. "open"),
+// excluding pull requests, following every page of search results.
+func (c *GitHubClient) ListIssues(state string) ([]Issue, error) {
+	query := fmt.Sprintf("repo:%s/%s is:issue state:%s", c.owner, c.repo, state)
+	searchURL := fmt.Sprintf("%s/search/issues?q=%s&per_page=%d", c.baseURL, url.QueryEscape(query), searchPageSize)
+
+	pages, err := c.getPages(searchURL)
+	if err != nil {
+		return nil, fmt.Errorf("listing %s issues: %w", state, err)
+	}
+
+	var issues []Issue
+	for _, page := range pages {
+		var result struct {
+			Items []Issue `json:"items"`
+		}
+		if err := json.Unmarshal(page, &result); err != nil {
+			return nil, fmt.Errorf("decoding %s issues: %w", state, err)
+		}
+		issues = append(issues, result.Items...)
+	}
+
+	return issues, nil
+}
+
 // AddLabels adds labels to an issue.
 func (c *GitHubClient) AddLabels(number int, labels []string) error {
 	payload, err := json.Marshal(map[string][]string{"labels": labels})
@@ -0,0 +1,207 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestGitHubClient returns a GitHubClient for the server which records
+// the waits before retries instead of sleeping.
+func newTestGitHubClient(server *httptest.Server, waits *[]time.Duration) *GitHubClient {
+	client := NewGitHubClient("token", "owner", "repo")
+	client.baseURL = server.URL
+	client.sleep = func(d time.Duration) { *waits = append(*waits, d) }
+	return client
+}
+
+func TestGitHubClientRateLimitRetry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name      string
+		status    int
+		headers   map[string]string
+		limited   int
+		wantWaits []time.Duration
+		wantErr   bool
+	}{
+		{
+			name:      "secondary rate limit with Retry-After",
+			status:    http.StatusForbidden,
+			headers:   map[string]string{"Retry-After": "2"},
+			limited:   1,
+			wantWaits: []time.Duration{2 * time.Second},
+		},
+		{
+			name:   "primary rate limit with X-RateLimit-Reset",
+			status: http.StatusForbidden,
+			headers: map[string]string{
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     strconv.FormatInt(now.Add(30*time.Second).Unix(), 10),
+			},
+			limited:   2,
+			wantWaits: []time.Duration{30 * time.Second, 30 * time.Second},
+		},
+		{
+			name:      "too many requests with Retry-After",
+			status:    http.StatusTooManyRequests,
+			headers:   map[string]string{"Retry-After": "1"},
+			limited:   1,
+			wantWaits: []time.Duration{time.Second},
+		},
+		{
+			name:      "retries exhausted",
+			status:    http.StatusTooManyRequests,
+			headers:   map[string]string{"Retry-After": "1"},
+			limited:   defaultMaxRetries + 1,
+			wantWaits: []time.Duration{time.Second, time.Second, time.Second},
+			wantErr:   true,
+		},
+		{
+			name:      "wait exceeds the maximum",
+			status:    http.StatusForbidden,
+			headers:   map[string]string{"Retry-After": strconv.Itoa(int(maxRateLimitWait.Seconds()) + 1)},
+			limited:   1,
+			wantWaits: nil,
+			wantErr:   true,
+		},
+		{
+			name:   "forbidden with remaining rate limit",
+			status: http.StatusForbidden,
+			headers: map[string]string{
+				"X-RateLimit-Remaining": "4999",
+				"X-RateLimit-Reset":     strconv.FormatInt(now.Add(30*time.Second).Unix(), 10),
+			},
+			limited:   1,
+			wantWaits: nil,
+			wantErr:   true,
+		},
+		{
+			name:      "forbidden without rate limit headers",
+			status:    http.StatusForbidden,
+			limited:   1,
+			wantWaits: nil,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tt.limited {
+					for k, v := range tt.headers {
+						w.Header().Set(k, v)
+					}
+					w.WriteHeader(tt.status)
+					fmt.Fprint(w, `{"message":"rate limit exceeded"}`)
+					return
+				}
+				fmt.Fprint(w, `{"number":1,"state":"open","labels":[{"name":"bug"}]}`)
+			}))
+			defer server.Close()
+
+			var waits []time.Duration
+			client := newTestGitHubClient(server, &waits)
+			client.now = func() time.Time { return now }
+
+			issue, err := client.GetIssue(1)
+			assert.Equal(t, tt.wantWaits, waits)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "rate limit exceeded")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{"bug"}, issue.Labels)
+			assert.Equal(t, tt.limited+1, requests)
+		})
+	}
+}
+
+func TestGitHubClientGetPages(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/items?page=%d>; rel="next", <%s/items?page=3>; rel="last"`, server.URL, page+1, server.URL))
+		}
+		fmt.Fprintf(w, `[%d]`, page)
+	}))
+	defer server.Close()
+
+	var waits []time.Duration
+	client := newTestGitHubClient(server, &waits)
+
+	pages, err := client.getPages(server.URL + "/items?page=1")
+	require.NoError(t, err)
+	require.Len(t, pages, 3)
+	for i, page := range pages {
+		assert.Equal(t, fmt.Sprintf("[%d]", i+1), string(page))
+	}
+	assert.Empty(t, waits)
+}
+
+func TestNextPageURL(t *testing.T) {
+	header := http.Header{}
+	assert.Empty(t, nextPageURL(header))
+
+	header.Set("Link", `<https://api.github.com/x?page=1>; rel="prev", <https://api.github.com/x?page=3>; rel="next"`)
+	assert.Equal(t, "https://api.github.com/x?page=3", nextPageURL(header))
+
+	header.Set("Link", `<https://api.github.com/x?page=1>; rel="first", <https://api.github.com/x?page=2>; rel="prev"`)
+	assert.Empty(t, nextPageURL(header))
+}
+
+func TestGitHubClientListIssues(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search/issues", r.URL.Path)
+		if r.URL.Query().Get("page") == "" {
+			assert.Equal(t, "repo:owner/repo is:issue state:open", r.URL.Query().Get("q"))
+			assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+			w.Header().Set("Link", fmt.Sprintf(`<%s/search/issues?page=2>; rel="next"`, server.URL))
+			fmt.Fprint(w, `{"total_count":3,"items":[{"number":1,"state":"open","labels":[]},{"number":2,"state":"open","labels":[{"name":"bug"}],"milestone":{"title":"v1"}}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"total_count":3,"items":[{"number":3,"state":"open","labels":[{"name":"triage/declined"}]}]}`)
+	}))
+	defer server.Close()
+
+	var waits []time.Duration
+	client := newTestGitHubClient(server, &waits)
+
+	issues, err := client.ListIssues("open")
+	require.NoError(t, err)
+	require.Len(t, issues, 3)
+	assert.Equal(t, 1, issues[0].Number)
+	assert.False(t, issues[0].HasMilestone())
+	assert.Equal(t, 2, issues[1].Number)
+	assert.True(t, issues[1].HasMilestone())
+	assert.Equal(t, []string{"bug"}, issues[1].Labels)
+	assert.Equal(t, []string{"triage/declined"}, issues[2].Labels)
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 func main() {
@@ -54,7 +55,7 @@ func run(args []string) error {
 
 	remaining := fs.Args()
 	if len(remaining) == 0 {
-		return fmt.Errorf("missing command: expected 'update-labels' or 'close-declined'\n\n%s", usage())
+		return fmt.Errorf("missing command: expected 'update-labels', 'close-declined' or 'triage-all'\n\n%s", usage())
 	}
 
 	command := remaining[0]
@@ -75,8 +76,12 @@ func run(args []string) error {
 		}
 	}
 
-	if owner == "" || repo == "" || issue == 0 {
-		return fmt.Errorf("--owner, --repo, and --issue are required (or set GITHUB_OWNER, GITHUB_REPO, GITHUB_ISSUE)")
+	if owner == "" || repo == "" {
+		return fmt.Errorf("--owner and --repo are required (or set GITHUB_OWNER, GITHUB_REPO)")
+	}
+
+	if issue == 0 && command != "triage-all" {
+		return fmt.Errorf("--issue is required (or set GITHUB_ISSUE)")
 	}
 
 	token := os.Getenv("GITHUB_TOKEN")
@@ -91,6 +96,13 @@ func run(args []string) error {
 	}
 
 	client := NewGitHubClient(token, owner, repo)
+	if apiURL := os.Getenv("GITHUB_API_URL"); apiURL != "" {
+		client.baseURL = strings.TrimSuffix(apiURL, "/")
+	}
+
+	if command == "triage-all" {
+		return runTriageAll(client, owner, repo, dryRun, log)
+	}
 
 	log("Fetching issue #%d from %s/%s", issue, owner, repo)
 	iss, err := client.GetIssue(issue)
@@ -108,8 +120,31 @@ func run(args []string) error {
 		return runCloseDeclined(client, issue, iss.Labels, iss.HasMilestone(), iss.State, dryRun, log)
 
 	default:
-		return fmt.Errorf("unknown command %q: expected 'update-labels' or 'close-declined'\n\n%s", command, usage())
+		return fmt.Errorf("unknown command %q: expected 'update-labels', 'close-declined' or 'triage-all'\n\n%s", command, usage())
+	}
+}
+
+func runTriageAll(client *GitHubClient, owner, repo string, dryRun bool, log func(string, ...any)) error {
+	log("Listing open issues from %s/%s", owner, repo)
+	issues, err := client.ListIssues("open")
+	if err != nil {
+		return err
 	}
+
+	log("Triaging %d open issues", len(issues))
+	for _, iss := range issues {
+		log("Issue #%d: state=%s milestone=%v labels=%v", iss.Number, iss.State, iss.HasMilestone(), iss.Labels)
+
+		if err := runCloseDeclined(client, iss.Number, iss.Labels, iss.HasMilestone(), iss.State, dryRun, log); err != nil {
+			return err
+		}
+
+		if err := runUpdateLabels(client, iss.Number, iss.Labels, iss.HasMilestone(), dryRun, log); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func runUpdateLabels(client *GitHubClient, number int, labels []string, hasMilestone, dryRun bool, log func(string, ...any)) error {
@@ -203,14 +238,16 @@ func usage() string {
 Commands:
   update-labels     Apply triage label rules based on milestone status
   close-declined    Handle declined issues (close, remove labels/milestone)
+  triage-all        Apply update-labels and close-declined to all open issues
 
 Flags:
   -v, --verbose     Enable verbose output
   --dry-run         Display changes without making them
   --owner           Repository owner (or GITHUB_OWNER env)
   --repo            Repository name (or GITHUB_REPO env)
-  --issue           Issue number (or GITHUB_ISSUE env)
+  --issue           Issue number (or GITHUB_ISSUE env), not used by triage-all
 
 Environment:
-  GITHUB_TOKEN      GitHub API token (required)`
+  GITHUB_TOKEN      GitHub API token (required)
+  GITHUB_API_URL    GitHub API URL (default: https://api.github.com)`
 }
@@ -0,0 +1,80 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunTriageAll(t *testing.T) {
+	tests := []struct {
+		name        string
+		dryRun      bool
+		wantChanges []string
+	}{
+		{
+			name:   "dry run",
+			dryRun: true,
+		},
+		{
+			name: "applies changes",
+			wantChanges: []string{
+				"POST /repos/owner/repo/issues/1/labels",
+				"POST /repos/owner/repo/issues/2/labels",
+				"DELETE /repos/owner/repo/issues/2/labels/triage%2Fneeds-triage",
+				"PATCH /repos/owner/repo/issues/3",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var changes []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet && r.URL.Path == "/search/issues" {
+					fmt.Fprint(w, `{"items":[
+						{"number":1,"state":"open","labels":[]},
+						{"number":2,"state":"open","labels":[{"name":"triage/needs-triage"}],"milestone":{"title":"v1"}},
+						{"number":3,"state":"open","labels":[{"name":"triage/declined"}]}
+					]}`)
+					return
+				}
+				changes = append(changes, r.Method+" "+r.URL.EscapedPath())
+				fmt.Fprint(w, `{}`)
+			}))
+			defer server.Close()
+
+			t.Setenv("GITHUB_TOKEN", "token")
+			t.Setenv("GITHUB_API_URL", server.URL)
+			t.Setenv("GITHUB_ISSUE", "")
+
+			args := []string{"--owner", "owner", "--repo", "repo"}
+			if tt.dryRun {
+				args = append(args, "--dry-run")
+			}
+			require.NoError(t, run(append(args, "triage-all")))
+
+			assert.Equal(t, tt.wantChanges, changes)
+		})
+	}
+}
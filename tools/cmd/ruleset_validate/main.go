@@ -0,0 +1,149 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command ruleset_validate validates SecLang rules files without deploying
+// the operator, printing each problem with its position and exiting non-zero
+// if any file has invalid rules.
+//
+// Usage:
+//
+//	ruleset_validate [--configmap] [--strict] FILE...
+//
+// With --configmap, each file is a ConfigMap manifest and the rules in its
+// "rules" key are validated, as the RuleSet controller would.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/networking-incubator/coraza-kubernetes-operator/internal/rulesets"
+)
+
+// Exit codes.
+const (
+	exitOK      = 0
+	exitInvalid = 1
+	exitUsage   = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run validates the files named in args, writing problems to stdout and usage
+// errors to stderr, and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ruleset_validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configMap := fs.Bool("configmap", false, "If set, each file is a ConfigMap manifest and the rules in its \"rules\" key are validated")
+	strict := fs.Bool("strict", false, "If set, warnings (e.g. multiple disruptive actions on a rule or an unsupported operator) are treated as errors")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: ruleset_validate [flags] FILE...\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, path := range fs.Args() {
+		rules, err := readRules(path, *configMap)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			return exitUsage
+		}
+
+		result := rulesets.ValidateDetailed(rules)
+		if *strict {
+			result = result.PromoteWarnings()
+		}
+		for _, w := range result.Warnings {
+			fmt.Fprintf(stdout, "%s: warning: %v\n", path, w)
+		}
+		for _, err := range result.Errors {
+			for _, problem := range problems(err) {
+				fmt.Fprintf(stdout, "%s: %s\n", path, problem)
+			}
+		}
+		if len(result.Errors) > 0 {
+			code = exitInvalid
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: OK (%d rules)\n", path, rulesets.CountRules(rules))
+	}
+
+	return code
+}
+
+// readRules returns the rules in the file at path, extracting them from the
+// "rules" key if the file is a ConfigMap manifest.
+func readRules(path string, configMap bool) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !configMap {
+		return string(data), nil
+	}
+
+	var cm corev1.ConfigMap
+	if err := yaml.Unmarshal(data, &cm); err != nil {
+		return "", fmt.Errorf("invalid ConfigMap manifest: %w", err)
+	}
+	if cm.Kind != "ConfigMap" {
+		return "", fmt.Errorf("expected a ConfigMap manifest, got kind %q", cm.Kind)
+	}
+	rules, ok := cm.Data["rules"]
+	if !ok {
+		return "", fmt.Errorf("ConfigMap %s is missing required 'rules' key", cm.Name)
+	}
+	return rules, nil
+}
+
+// problems splits a validation error into one line per problem, so each
+// duplicate id or action violation is reported with its own position.
+func problems(err error) []string {
+	var dupErr *rulesets.DuplicateRuleIDsError
+	if errors.As(err, &dupErr) {
+		lines := make([]string, 0, len(dupErr.Duplicates))
+		for _, d := range dupErr.Duplicates {
+			lines = append(lines, fmt.Sprintf("duplicate rule %s", d))
+		}
+		return lines
+	}
+
+	var actionsErr *rulesets.InvalidActionsError
+	if errors.As(err, &actionsErr) {
+		lines := make([]string, 0, len(actionsErr.Violations))
+		for _, v := range actionsErr.Violations {
+			lines = append(lines, v.Error())
+		}
+		return lines
+	}
+
+	return []string{err.Error()}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2026 Shane Utt.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	fixture := func(name string) string {
+		return filepath.Join("testdata", name)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout []string
+		stderr []string
+	}{
+		{
+			name:   "no files",
+			code:   exitUsage,
+			stderr: []string{"Usage: ruleset_validate"},
+		},
+		{
+			name:   "unknown flag",
+			args:   []string{"--bogus", fixture("valid.conf")},
+			code:   exitUsage,
+			stderr: []string{"flag provided but not defined"},
+		},
+		{
+			name:   "missing file",
+			args:   []string{fixture("missing.conf")},
+			code:   exitUsage,
+			stderr: []string{"missing.conf"},
+		},
+		{
+			name:   "valid rules",
+			args:   []string{fixture("valid.conf")},
+			code:   exitOK,
+			stdout: []string{"valid.conf: OK (3 rules)"},
+		},
+		{
+			name:   "duplicate ids",
+			args:   []string{fixture("invalid.conf")},
+			code:   exitInvalid,
+			stdout: []string{"invalid.conf: duplicate rule id 1001 on line 2 duplicates line 1"},
+		},
+		{
+			name: "invalid actions are reported with positions",
+			args: []string{fixture("invalid-actions.conf")},
+			code: exitInvalid,
+			stdout: []string{
+				"invalid-actions.conf: line 1, column 1: rule id 1001: invalid phase (phase:6)",
+				"invalid-actions.conf: line 2, column 3: rule id 1002: unknown transformation (t:urldecoduni)",
+				"invalid-actions.conf: warning: line 3, column 1: rule id 1003: multiple disruptive actions (deny, pass)",
+			},
+		},
+		{
+			name:   "multiple files are all validated",
+			args:   []string{fixture("invalid.conf"), fixture("valid.conf")},
+			code:   exitInvalid,
+			stdout: []string{"invalid.conf: duplicate rule", "valid.conf: OK"},
+		},
+		{
+			name:   "configmap",
+			args:   []string{"--configmap", fixture("configmap.yaml")},
+			code:   exitOK,
+			stdout: []string{"configmap.yaml: OK (1 rules)"},
+		},
+		{
+			name:   "configmap missing rules key",
+			args:   []string{"--configmap", fixture("configmap-missing-rules.yaml")},
+			code:   exitUsage,
+			stderr: []string{"ConfigMap admin-rules is missing required 'rules' key"},
+		},
+		{
+			name:   "rules file in configmap mode",
+			args:   []string{"--configmap", fixture("valid.conf")},
+			code:   exitUsage,
+			stderr: []string{"valid.conf"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, tt.code, run(tt.args, &stdout, &stderr),
+				"stdout: %s\nstderr: %s", stdout.String(), stderr.String())
+			for _, s := range tt.stdout {
+				assert.Contains(t, stdout.String(), s)
+			}
+			for _, s := range tt.stderr {
+				assert.Contains(t, stderr.String(), s)
+			}
+		})
+	}
+}
+
+func TestRun_Strict(t *testing.T) {
+	args := []string{filepath.Join("testdata", "invalid-actions.conf")}
+
+	t.Log("Verifying warnings are reported as errors in strict mode")
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, exitInvalid, run(append([]string{"--strict"}, args...), &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "invalid-actions.conf: line 3, column 1: rule id 1003: multiple disruptive actions (deny, pass)")
+	assert.NotContains(t, stdout.String(), "warning:")
+}